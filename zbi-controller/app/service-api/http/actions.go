@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/secrets"
+	"github.com/zbitech/controller/internal/upgrade"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const MAX_BULK_CONCURRENCY = 16
+
+// bulkActions are the project-wide actions and whether they run in reverse
+// dependency order, so that lightwallet instances stop before their zcash
+// backend and start after it.
+var bulkActions = map[model.EventAction]bool{
+	model.EventActionStopInstance:  true,
+	model.EventActionStartInstance: false,
+	model.EventActionRepair:        false,
+	model.EventActionSnapshot:      false,
+	model.EventActionRotate:        false,
+}
+
+// RunProjectAction runs a lifecycle action for every instance of a project.
+// The response reports the result of each instance and is a 207 when any of
+// them failed or was skipped.
+func RunProjectAction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var log = logger.GetServiceLogger(ctx, "http.RunProjectAction")
+	defer func() { logger.LogServiceTime(log) }()
+
+	action := model.EventAction(request.GetParameterValue(r, request.PATH_PARAM, "action"))
+	reverse, ok := bulkActions[action]
+	if !ok {
+		response.BadRequestResponse(w, r, fmt.Errorf("unsupported action %s", action))
+		return
+	}
+
+	concurrency := vars.ZBI_BULK_CONCURRENCY
+	if value := request.GetParameterValue(r, request.GET_PARAM, "concurrency"); value != "" {
+		var err error
+		if concurrency, err = strconv.Atoi(value); err != nil || concurrency <= 0 {
+			response.BadRequestResponse(w, r, fmt.Errorf("concurrency must be a positive integer"))
+			return
+		}
+	}
+	if concurrency > MAX_BULK_CONCURRENCY {
+		concurrency = MAX_BULK_CONCURRENCY
+	}
+
+	project, _, ok := getOwnedProject(w, r, action)
+	if !ok {
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instances, err := repository.GetInstances(ctx, project.Id)
+	if err != nil {
+		log.Errorf("failed to retrieve instances of project %s - %s", project.Id, err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	log.WithFields(logrus.Fields{"project": project.Name, "action": action, "instances": len(instances),
+		"concurrency": concurrency}).Infof("running project action")
+
+	stages := helper.OrderInstances(instances, reverse)
+	results := helper.RunInstanceStages(ctx, stages, concurrency, reverse, func(ctx context.Context, instance *model.Instance) error {
+		if err := runInstanceAction(ctx, action, instance); err != nil {
+			log.WithFields(logrus.Fields{"instance": instance.Name, "error": err}).Errorf("project action failed")
+			return err
+		}
+		if err := repository.AddInstanceActivity(ctx, instance.Id, action); err != nil {
+			log.Errorf("failed to add %s activity for instance %s - %s", action, instance.Id, err)
+		}
+		return nil
+	})
+
+	var result = model.BulkActionResponse{Project: project.Name, Action: action, Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case model.BulkResultSucceeded:
+			result.Succeeded++
+		case model.BulkResultFailed:
+			result.Failed++
+		case model.BulkResultSkipped:
+			result.Skipped++
+		}
+	}
+
+	if err = repository.AddProjectActivity(ctx, project.Id, action); err != nil {
+		log.Errorf("failed to add %s activity for project %s - %s", action, project.Id, err)
+	}
+
+	var status = http.StatusOK
+	if result.Failed > 0 || result.Skipped > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	if err = response.JSON(w, status, result); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// runInstanceAction runs the operation of a single instance behind a project
+// action.
+func runInstanceAction(ctx context.Context, action model.EventAction, instance *model.Instance) error {
+	zclient := vars.KlientFactory.GetZBIClient()
+
+	switch action {
+	case model.EventActionStopInstance:
+		return zclient.StopInstance(ctx, instance.Project, instance)
+	case model.EventActionStartInstance:
+		return zclient.StartInstance(ctx, instance.Project, instance)
+	case model.EventActionRepair:
+		if err := upgrade.PinInstance(ctx, instance); err != nil {
+			logger.GetLogger(ctx).Errorf("failed to pin image of instance %s - %s", instance.Id, err)
+		}
+		return zclient.RepairInstance(ctx, instance.Project, instance)
+	case model.EventActionSnapshot:
+		return zclient.CreateSnapshot(ctx, instance.Project, instance)
+	case model.EventActionRotate:
+		_, grace, err := secrets.ParseRotationPolicy(secrets.GetRotationPolicy(instance))
+		if err != nil {
+			logger.GetLogger(ctx).Errorf("invalid rotation policy for instance %s - %s", instance.Id, err)
+		}
+		_, err = secrets.RotateInstanceCredentials(ctx, instance, model.RotationTriggerManual, grace)
+		return err
+	}
+
+	return fmt.Errorf("unsupported action %s", action)
+}
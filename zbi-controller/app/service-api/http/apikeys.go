@@ -0,0 +1,125 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/authz"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionIssueKey)
+	if !ok {
+		return
+	}
+
+	var keyRequest model.APIKeyRequest
+	if err := request.ReadJSON(w, r, &keyRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if errorMap := request.Validate(&keyRequest); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	key, token, err := authz.GenerateAPIKey(&keyRequest, instance, userid)
+	if err != nil {
+		audit.Errorf("api key generation failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	key, err = repository.CreateAPIKey(ctx, key)
+	if err != nil {
+		audit.Errorf("api key creation failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"key": key.Id, "consumer": key.Consumer}).Infof("api key issued")
+	if err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionIssueKey); err != nil {
+		audit.Errorf("failed to add issue key activity for instance %s - %s", instance.Id, err)
+	}
+
+	// the key is only returned once; the repository keeps a hash of it
+	key.Hash = ""
+	w.Header().Set("Cache-Control", "no-store")
+	envelope := response.Envelope{"apikey": key, "key": token}
+	if err = response.JSON(w, http.StatusCreated, envelope); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionIssueKey)
+	if !ok {
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	keys, err := repository.GetAPIKeys(ctx, instance.Id)
+	if err != nil {
+		audit.Errorf("failed to retrieve api keys - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	for index := range keys {
+		keys[index].Hash = ""
+	}
+
+	if err = response.JSON(w, http.StatusOK, keys); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRevokeKey)
+	if !ok {
+		return
+	}
+
+	keyId := request.GetParameterValue(r, request.PATH_PARAM, "key")
+	audit = audit.WithFields(logrus.Fields{"key": keyId})
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	key, err := repository.GetAPIKey(ctx, keyId)
+	if err != nil {
+		audit.Errorf("failed to retrieve api key - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if key.InstanceId != instance.Id {
+		response.NotFoundResponse(w, r)
+		return
+	}
+
+	if err = repository.RevokeAPIKey(ctx, keyId); err != nil {
+		audit.Errorf("api key revocation failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"consumer": key.Consumer}).Infof("api key revoked")
+	if err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionRevokeKey); err != nil {
+		audit.Errorf("failed to add revoke key activity for instance %s - %s", instance.Id, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+const (
+	DEFAULT_AUDIT_LIMIT = 100
+	MAX_AUDIT_LIMIT     = 1000
+)
+
+// GetAuditRecords returns audit records, newest first, filtered by actor,
+// target and an RFC 3339 time range. It is restricted to admins.
+func GetAuditRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var log = logger.GetServiceLogger(ctx, "http.GetAuditRecords")
+	defer func() { logger.LogServiceTime(log) }()
+
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	if role != helper.ADMIN_ROLE {
+		log.Warnf("audit access denied")
+		response.NotPermittedResponse(w, r)
+		return
+	}
+
+	query, err := readAuditQuery(r)
+	if err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	records, err := repository.GetAuditRecords(ctx, query)
+	if err != nil {
+		log.Errorf("failed to retrieve audit records - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, response.Envelope{"audit": records}); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func readAuditQuery(r *http.Request) (*model.AuditQuery, error) {
+	var query = model.AuditQuery{
+		Actor:  request.GetParameterValue(r, request.GET_PARAM, "actor"),
+		Target: request.GetParameterValue(r, request.GET_PARAM, "target"),
+		Limit:  DEFAULT_AUDIT_LIMIT,
+	}
+
+	var err error
+	if query.Since, err = readTimeParameter(r, "since"); err != nil {
+		return nil, err
+	}
+	if query.Until, err = readTimeParameter(r, "until"); err != nil {
+		return nil, err
+	}
+
+	if value := request.GetParameterValue(r, request.GET_PARAM, "limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > MAX_AUDIT_LIMIT {
+			limit = MAX_AUDIT_LIMIT
+		}
+		query.Limit = limit
+	}
+
+	if query.Since != nil && query.Until != nil && query.Until.Before(*query.Since) {
+		return nil, fmt.Errorf("until must not be before since")
+	}
+
+	return &query, nil
+}
+
+func readTimeParameter(r *http.Request, name string) (*time.Time, error) {
+	value := request.GetParameterValue(r, request.GET_PARAM, name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+	return &t, nil
+}
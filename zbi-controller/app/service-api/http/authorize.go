@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+// authorizeProject reports whether the caller may perform action on a
+// project. Projects of a team are authorized through team membership.
+func authorizeProject(ctx context.Context, project *model.Project, action model.EventAction) (bool, error) {
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+
+	var team *model.Team
+	if project.TeamId != "" && role != helper.ADMIN_ROLE {
+		var err error
+		repository := vars.RepositoryFactory.GetRepositoryService()
+		if team, err = repository.GetTeam(ctx, project.TeamId); err != nil {
+			return false, err
+		}
+	}
+
+	return helper.CanAccessProject(project, team, userid, role, helper.GetActionAccess(action)), nil
+}
+
+// getOwnedProject retrieves the project in the request path and checks that
+// the caller may perform action on it. It writes the error response on
+// failure.
+func getOwnedProject(w http.ResponseWriter, r *http.Request, action model.EventAction) (*model.Project, *logrus.Entry, bool) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
+	if len(projectId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("project is required"))
+		return nil, nil, false
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	project, err := repository.GetProject(ctx, projectId)
+	if err != nil {
+		log.Errorf("failed to retrieve project %s", projectId)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return nil, nil, false
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	audit := log.WithFields(logrus.Fields{"audit": true, "action": action, "project": project.Id,
+		"owner": project.Owner, "team": project.TeamId, rctx.USERID: userid, rctx.ROLE: role})
+
+	allowed, err := authorizeProject(ctx, project, action)
+	if err != nil {
+		audit.Errorf("failed to authorize project access - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return nil, nil, false
+	} else if !allowed {
+		audit.Warnf("project access denied")
+		response.NotPermittedResponse(w, r)
+		return nil, nil, false
+	}
+
+	return project, audit, true
+}
+
+// getOwnedInstance retrieves the instance in the request path and checks that
+// the caller may perform action on its project. It writes the error response
+// on failure.
+func getOwnedInstance(w http.ResponseWriter, r *http.Request, action model.EventAction) (*model.Instance, *logrus.Entry, bool) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
+	if len(instanceId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("instance is required"))
+		return nil, nil, false
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instance, err := repository.GetInstance(ctx, instanceId)
+	if err != nil {
+		log.Errorf("failed to retrieve instance %s", instanceId)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return nil, nil, false
+	}
+
+	project := instance.Project
+	if project == nil {
+		project = &model.Project{Owner: instance.Owner}
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	audit := log.WithFields(logrus.Fields{"audit": true, "action": action, "instance": instance.Id,
+		"owner": instance.Owner, "team": project.TeamId, rctx.USERID: userid, rctx.ROLE: role})
+
+	allowed, err := authorizeProject(ctx, project, action)
+	if err != nil {
+		audit.Errorf("failed to authorize instance access - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return nil, nil, false
+	} else if !allowed {
+		audit.Warnf("instance access denied")
+		response.NotPermittedResponse(w, r)
+		return nil, nil, false
+	}
+
+	return instance, audit, true
+}
+
+// checkProjectQuota verifies that a team can own one more project. It writes
+// the error response on failure.
+func checkProjectQuota(w http.ResponseWriter, r *http.Request, team *model.Team) bool {
+	ctx := r.Context()
+	if team.Quota.MaxProjects == 0 {
+		return true
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	projects, err := repository.GetTeamProjects(ctx, team.Id)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return false
+	}
+
+	return quotaResponse(w, r, team, helper.CheckProjectQuota(team, len(projects)))
+}
+
+// checkInstanceQuota verifies that a team can own one more instance across
+// its projects. It writes the error response on failure.
+func checkInstanceQuota(w http.ResponseWriter, r *http.Request, team *model.Team) bool {
+	ctx := r.Context()
+	if team.Quota.MaxInstances == 0 {
+		return true
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instances, err := repository.CountTeamInstances(ctx, team.Id)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return false
+	}
+
+	return quotaResponse(w, r, team, helper.CheckInstanceQuota(team, instances))
+}
+
+func quotaResponse(w http.ResponseWriter, r *http.Request, team *model.Team, err error) bool {
+	if err != nil {
+		logger.GetLogger(r.Context()).WithFields(logrus.Fields{"audit": true, "team": team.Id}).Warnf("team quota exceeded - %s", err)
+		response.QuotaExceededResponse(w, r, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// readDomainRequest reads and validates a custom domain request and returns
+// the domain to store. It writes the error response on failure.
+func readDomainRequest(w http.ResponseWriter, r *http.Request, instance *model.Instance) (*model.DomainRequest, *model.Domain, bool) {
+
+	var domainRequest model.DomainRequest
+	if err := request.ReadJSON(w, r, &domainRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return nil, nil, false
+	}
+
+	policy := helper.GetPolicyInfo(r.Context())
+	if errorMap := request.ValidateDomainRequest(&domainRequest, policy.DomainName, time.Now()); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return nil, nil, false
+	}
+
+	if domainRequest.TLS == model.DomainTLSCertManager && len(policy.CertificateIssuer) == 0 {
+		response.FailedValidationResponse(w, r, map[string]string{"tls": "certManager is not available, upload a certificate"})
+		return nil, nil, false
+	}
+
+	createdAt := time.Now().UTC()
+	domain := &model.Domain{
+		Host:       strings.ToLower(domainRequest.Host),
+		TLS:        domainRequest.TLS,
+		SecretName: helper.DomainSecretName(instance),
+		CreatedAt:  &createdAt,
+	}
+	if domain.TLS == model.DomainTLSCertManager {
+		domain.Issuer = policy.CertificateIssuer
+	}
+
+	return &domainRequest, domain, true
+}
+
+// SetProjectDomain serves all instances of a project on a custom domain.
+func SetProjectDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionSetDomain)
+	if !ok {
+		return
+	}
+
+	domainRequest, domain, ok := readDomainRequest(w, r, nil)
+	if !ok {
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"host": domain.Host, "tls": domain.TLS})
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateProjectDomain(ctx, project.Id, domain); err != nil {
+		audit.Errorf("project domain update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err := zclient.UpdateDomain(ctx, project, nil, domain, domainRequest.Certificate, domainRequest.PrivateKey); err != nil {
+		audit.Errorf("project domain creation failed - %s", err)
+		if err := repository.UpdateProjectDomain(ctx, project.Id, project.Domain); err != nil {
+			audit.Errorf("failed to restore project domain - %s", err)
+		}
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("project domain set")
+	if err := repository.AddProjectActivity(ctx, project.Id, model.EventActionSetDomain); err != nil {
+		audit.Errorf("failed to add set domain activity for project %s - %s", project.Id, err)
+	}
+
+	if err := response.JSON(w, http.StatusOK, response.Envelope{"domain": domain}); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// DeleteProjectDomain removes the custom domain of a project.
+func DeleteProjectDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionRemoveDomain)
+	if !ok {
+		return
+	}
+
+	if project.Domain == nil {
+		response.NotFoundResponse(w, r)
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"host": project.Domain.Host})
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err := zclient.DeleteDomain(ctx, project, nil); err != nil {
+		audit.Errorf("project domain removal failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateProjectDomain(ctx, project.Id, nil); err != nil {
+		audit.Errorf("project domain update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("project domain removed")
+	if err := repository.AddProjectActivity(ctx, project.Id, model.EventActionRemoveDomain); err != nil {
+		audit.Errorf("failed to add remove domain activity for project %s - %s", project.Id, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetInstanceDomain serves an instance on a custom domain.
+func SetInstanceDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionSetDomain)
+	if !ok {
+		return
+	}
+
+	domainRequest, domain, ok := readDomainRequest(w, r, instance)
+	if !ok {
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"host": domain.Host, "tls": domain.TLS})
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstanceDomain(ctx, instance.Id, domain); err != nil {
+		audit.Errorf("instance domain update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err := zclient.UpdateDomain(ctx, instance.Project, instance, domain, domainRequest.Certificate, domainRequest.PrivateKey); err != nil {
+		audit.Errorf("instance domain creation failed - %s", err)
+		if err := repository.UpdateInstanceDomain(ctx, instance.Id, instance.Domain); err != nil {
+			audit.Errorf("failed to restore instance domain - %s", err)
+		}
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("instance domain set")
+	if err := repository.AddInstanceActivity(ctx, instance.Id, model.EventActionSetDomain); err != nil {
+		audit.Errorf("failed to add set domain activity for instance %s - %s", instance.Id, err)
+	}
+
+	if err := response.JSON(w, http.StatusOK, response.Envelope{"domain": domain}); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// DeleteInstanceDomain removes the custom domain of an instance.
+func DeleteInstanceDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRemoveDomain)
+	if !ok {
+		return
+	}
+
+	if instance.Domain == nil {
+		response.NotFoundResponse(w, r)
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"host": instance.Domain.Host})
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err := zclient.DeleteDomain(ctx, instance.Project, instance); err != nil {
+		audit.Errorf("instance domain removal failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstanceDomain(ctx, instance.Id, nil); err != nil {
+		audit.Errorf("instance domain update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("instance domain removed")
+	if err := repository.AddInstanceActivity(ctx, instance.Id, model.EventActionRemoveDomain); err != nil {
+		audit.Errorf("failed to add remove domain activity for instance %s - %s", instance.Id, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
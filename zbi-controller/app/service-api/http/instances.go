@@ -3,10 +3,15 @@ package http
 import (
 	"errors"
 	"net/http"
+	"reflect"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/app/service-api/request"
 	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/secrets"
+	"github.com/zbitech/controller/internal/upgrade"
+	"github.com/zbitech/controller/internal/utils"
 	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -16,26 +21,19 @@ func DeleteInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionDelete)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	repository.GetProject(ctx, "")
 	repository.GetInstance(ctx, "")
 
 	zclient := vars.KlientFactory.GetZBIClient()
 
-	err = zclient.DeleteInstance(ctx, instance.Project, instance)
+	err := zclient.DeleteInstance(ctx, instance.Project, instance)
 	if err != nil {
 		log.Errorf("failed to delete instance resources - %s", err)
 		response.ServerErrorResponse(w, r, ctx, err)
@@ -56,28 +54,41 @@ func UpdateInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionUpdate)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	var instance_req model.InstanceRequest
 	if err := request.ReadJSON(w, r, &instance_req); err != nil {
-		log.WithFields(logrus.Fields{"error": err, "instance": instanceId}).Errorf("failed to read input")
+		log.WithFields(logrus.Fields{"error": err, "instance": instance.Id}).Errorf("failed to read input")
 		response.BadRequestResponse(w, r, err)
 		return
 	}
 	log.WithFields(logrus.Fields{"instance": instance_req}).Infof("instance details")
 
+	if fieldErrors := applyInstanceUpdate(instance, &instance_req); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	fieldErrors, err := validateInstanceRequest(ctx, instance.Project, instance.Id, &instance_req)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	} else if fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	var previousAllowList []string
+	if instance.Request != nil {
+		previousAllowList = instance.Request.IPAllowList
+	}
+	instance_req.IPAllowList = helper.NormalizeIPAllowList(instance_req.IPAllowList)
+
 	instance, err = repository.UpdateInstance(ctx, instance.Id, &instance_req)
 	if err != nil {
 		log.Errorf("failed to create instance")
@@ -94,6 +105,13 @@ func UpdateInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !reflect.DeepEqual(helper.NormalizeIPAllowList(previousAllowList), instance_req.IPAllowList) {
+		if err = zclient.UpdateInstanceIngress(ctx, instance.Project, instance); err != nil {
+			response.ServerErrorResponse(w, r, ctx, err)
+			return
+		}
+	}
+
 	err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionUpdate)
 	if err != nil {
 		log.Errorf("failed to add update activity for instance %s - %s", instance.Id, err)
@@ -108,24 +126,20 @@ func RepairInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionRepair)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	log.WithFields(logrus.Fields{"instance": instance}).Infof("repairing instance")
+	if err := upgrade.PinInstance(ctx, instance); err != nil {
+		log.Errorf("failed to pin image of instance %s - %s", instance.Id, err)
+	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
-	err = zclient.RepairInstance(ctx, instance.Project, instance)
+	err := zclient.RepairInstance(ctx, instance.Project, instance)
 	if err != nil {
 		//		service.HandleError(ctx, w, r, err)
 		response.ServerErrorResponse(w, r, ctx, err)
@@ -148,26 +162,19 @@ func StartInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionStartInstance)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
 
-	err = zclient.StartInstance(ctx, instance.Project, instance)
+	err := zclient.StartInstance(ctx, instance.Project, instance)
 
 	if err != nil {
-		log.Errorf("failed to start instance %s - %s", instanceId, err)
+		log.Errorf("failed to start instance %s - %s", instance.Id, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
@@ -187,25 +194,18 @@ func StopInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionStopInstance)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
 
-	err = zclient.StopInstance(ctx, instance.Project, instance)
+	err := zclient.StopInstance(ctx, instance.Project, instance)
 	if err != nil {
-		log.Errorf("failed to stop instance %s - %s", instanceId, err)
+		log.Errorf("failed to stop instance %s - %s", instance.Id, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
@@ -225,25 +225,18 @@ func CreateSnapshot(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionSnapshot)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
 
-	err = zclient.CreateSnapshot(ctx, instance.Project, instance)
+	err := zclient.CreateSnapshot(ctx, instance.Project, instance)
 	if err != nil {
-		log.Errorf("failed to stop instance %s - %s", instanceId, err)
+		log.Errorf("failed to stop instance %s - %s", instance.Id, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
@@ -264,34 +257,31 @@ func RotateInstanceCredentials(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionRotate)
+	if !ok {
 		return
 	}
 
-	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
+	// the grace period defaults to the one in the instance rotation policy
+	_, grace, err := secrets.ParseRotationPolicy(secrets.GetRotationPolicy(instance))
 	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
+		log.Errorf("invalid rotation policy for instance %s - %s", instance.Id, err)
 	}
 
-	zclient := vars.KlientFactory.GetZBIClient()
+	if value := request.GetParameterValue(r, request.GET_PARAM, "grace"); value != "" {
+		if grace, err = utils.ParseDuration(value); err != nil {
+			response.FailedValidationResponse(w, r, map[string]string{"grace": "must be a duration such as 30d or 12h"})
+			return
+		}
+	}
 
-	err = zclient.RotateInstanceCredentials(ctx, instance.Project, instance)
+	instance.Rotation, err = secrets.RotateInstanceCredentials(ctx, instance, model.RotationTriggerManual, grace)
 	if err != nil {
-		log.Errorf("failed to stop instance %s - %s", instanceId, err)
+		log.Errorf("failed to rotate credentials for instance %s - %s", instance.Id, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
 
-	err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionRotate)
-	if err != nil {
-		log.Errorf("failed to add rotate activity for instance %s - %s", instance.Id, err)
-	}
-
 	if err = response.JSON(w, http.StatusOK, instance); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
@@ -302,33 +292,26 @@ func CreateSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionSchedule)
+	if !ok {
 		return
 	}
 
 	var schedule model.SnapshotScheduleType
 
 	if err := request.ReadJSON(w, r, &schedule); err != nil {
-		log.WithFields(logrus.Fields{"error": err, "instance": instanceId, "schedule": schedule}).Errorf("failed to read input")
+		log.WithFields(logrus.Fields{"error": err, "instance": instance.Id, "schedule": schedule}).Errorf("failed to read input")
 		response.BadRequestResponse(w, r, err)
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
 
-	err = zclient.CreateSnapshotSchedule(ctx, instance.Project, instance, schedule)
+	err := zclient.CreateSnapshotSchedule(ctx, instance.Project, instance, schedule)
 	if err != nil {
-		log.Errorf("failed to stop instance %s - %s", instanceId, err)
+		log.Errorf("failed to stop instance %s - %s", instance.Id, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
@@ -376,19 +359,14 @@ func GetInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-
-	log.Infof("getting instance %s", instanceId)
-
-	repository := vars.RepositoryFactory.GetRepositoryService()
-	instance, err := repository.GetInstance(ctx, instanceId)
-	if err != nil {
-		log.Errorf("failed to retrieve instance %s", instanceId)
-		response.ServerErrorResponse(w, r, ctx, err)
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionView)
+	if !ok {
 		return
 	}
 
-	if err = response.JSON(w, http.StatusOK, instance); err != nil {
+	log.Infof("getting instance %s", instance.Id)
+
+	if err := response.JSON(w, http.StatusOK, instance); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
 }
@@ -397,12 +375,6 @@ func DeleteInstanceResource(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	instanceId := request.GetParameterValue(r, request.PATH_PARAM, "instance")
-	if len(instanceId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("instance is required"))
-		return
-	}
-
 	resourceName := request.GetParameterValue(r, request.GET_PARAM, "name")
 	if len(resourceName) == 0 {
 		response.BadRequestResponse(w, r, errors.New("resource name is required"))
@@ -410,38 +382,66 @@ func DeleteInstanceResource(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resourceType := request.GetParameterValue(r, request.GET_PARAM, "type")
-	if len(instanceId) == 0 {
+	if len(resourceType) == 0 {
 		response.BadRequestResponse(w, r, errors.New("resource type is required"))
 		return
 	}
 
-	log.Infof("getting instance %s", instanceId)
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionDeleteResource)
+	if !ok {
+		return
+	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
 
-	instance, err := repository.GetInstance(ctx, instanceId)
+	log.Infof("deleting resource %s (%s) for instance %s", resourceName, resourceType, instance.Id)
+	zclient := vars.KlientFactory.GetZBIClient()
+	err := zclient.DeleteInstanceResource(ctx, instance.Project, instance, resourceName, model.ResourceObjectType(resourceType))
+	if err != nil {
+		log.Errorf("failed to delete resource %s (%s) for instance %s", resourceName, resourceType, instance.Id)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionDeleteResource)
 	if err != nil {
-		log.Errorf("failed to retrieve instance %s", instanceId)
+		log.Errorf("failed to add delete resource (%s.%s) activity for instance %s - %s", resourceType, resourceName, instance.Id, err)
+	}
+
+	envelope := response.Envelope{}
+	if err = response.JSON(w, http.StatusNoContent, envelope); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetInstanceCredentials returns the RPC credentials of an instance to its
+// owner. Every request is logged and recorded as an instance activity.
+func GetInstanceCredentials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionCredentials)
+	if !ok {
 		return
 	}
 
-	log.Infof("deleting resource %s (%s) for instance %s", resourceName, resourceType, instanceId)
 	zclient := vars.KlientFactory.GetZBIClient()
-	err = zclient.DeleteInstanceResource(ctx, instance.Project, instance, resourceName, model.ResourceObjectType(resourceType))
+	credentials, err := zclient.GetInstanceCredentials(ctx, instance.Project, instance)
 	if err != nil {
-		log.Errorf("failed to delete resource %s (%s) for instance %s", resourceName, resourceType, instanceId)
+		audit.Errorf("credentials access failed - %s", err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
 
-	err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionDeleteResource)
+	audit.Infof("credentials accessed")
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	err = repository.AddInstanceActivity(ctx, instance.Id, model.EventActionCredentials)
 	if err != nil {
-		log.Errorf("failed to add delete resource (%s.%s) activity for instance %s - %s", resourceType, resourceName, instance.Id, err)
+		log.Errorf("failed to add credentials activity for instance %s - %s", instance.Id, err)
 	}
 
-	envelope := response.Envelope{}
-	if err = response.JSON(w, http.StatusNoContent, envelope); err != nil {
+	w.Header().Set("Cache-Control", "no-store")
+	if err = response.JSON(w, http.StatusOK, credentials); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
 }
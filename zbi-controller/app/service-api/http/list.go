@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	MAX_LIST_LIMIT = 1000
+
+	TOTAL_COUNT_HEADER = "X-Total-Count"
+	NEXT_CURSOR_HEADER = "X-Next-Cursor"
+)
+
+// readListQuery reads the filter, sort, paging and field parameters of a
+// listing. Without a limit all matching objects are returned.
+func readListQuery(r *http.Request) (*model.ListQuery, error) {
+	var query = model.ListQuery{
+		Owner:         request.GetParameterValue(r, request.GET_PARAM, "owner"),
+		Status:        request.GetParameterValue(r, request.GET_PARAM, "status"),
+		State:         request.GetParameterValue(r, request.GET_PARAM, "state"),
+		Type:          request.GetParameterValue(r, request.GET_PARAM, "type"),
+		Network:       request.GetParameterValue(r, request.GET_PARAM, "network"),
+		LabelSelector: request.GetParameterValue(r, request.GET_PARAM, "labelSelector"),
+		Sort:          request.GetParameterValue(r, request.GET_PARAM, "sort"),
+		Cursor:        request.GetParameterValue(r, request.GET_PARAM, "cursor"),
+	}
+
+	var err error
+	if query.CreatedBefore, err = readTimeParameter(r, "createdBefore"); err != nil {
+		return nil, err
+	}
+	if query.CreatedAfter, err = readTimeParameter(r, "createdAfter"); err != nil {
+		return nil, err
+	}
+
+	if value := request.GetParameterValue(r, request.GET_PARAM, "limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > MAX_LIST_LIMIT {
+			limit = MAX_LIST_LIMIT
+		}
+		query.Limit = limit
+	}
+
+	for _, field := range strings.Split(request.GetParameterValue(r, request.GET_PARAM, "fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			query.Fields = append(query.Fields, field)
+		}
+	}
+
+	return &query, nil
+}
+
+// writeList writes a page of a listing. The body stays an array for existing
+// clients and the total and next cursor are returned in headers.
+func writeList(w http.ResponseWriter, total int, next string, items interface{}, fields []string) error {
+	var body = items
+	if len(fields) > 0 {
+		var err error
+		if body, err = selectFields(items, fields); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set(TOTAL_COUNT_HEADER, strconv.Itoa(total))
+	if next != "" {
+		w.Header().Set(NEXT_CURSOR_HEADER, next)
+	}
+	return response.JSON(w, http.StatusOK, body)
+}
+
+// selectFields keeps the id and the given json fields of each item.
+func selectFields(items interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	content, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]json.RawMessage
+	if err = json.Unmarshal(content, &objects); err != nil {
+		return nil, err
+	}
+
+	var result = make([]map[string]json.RawMessage, len(objects))
+	for index, object := range objects {
+		result[index] = map[string]json.RawMessage{"id": object["id"]}
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				result[index][field] = value
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zbitech/controller/internal/audit"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// maxAuditBodyBytes matches the request size accepted by request.ReadJSON;
+// larger bodies are rejected by the handler and only their prefix is hashed.
+const maxAuditBodyBytes = 1_038_576
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+// Audit records every mutating request with a hash of its body, the
+// Kubernetes objects it changed and its outcome. It must run after
+// InitRequest so the actor and txid are available.
+func Audit(auditor interfaces.AuditServiceIF) mux.MiddlewareFunc {
+	return func(f http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				f.ServeHTTP(w, r)
+				return
+			}
+
+			var bodyHash string
+			if r.Body != nil {
+				body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBodyBytes+1))
+				if err == nil && len(body) > 0 {
+					sum := sha256.Sum256(body)
+					bodyHash = hex.EncodeToString(sum[:])
+				}
+				r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			}
+
+			ctx := audit.WithObjects(r.Context())
+			recorder := &statusRecorder{ResponseWriter: w}
+			f.ServeHTTP(recorder, r.WithContext(ctx))
+
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			record := &model.AuditRecord{
+				Source:   model.AuditSourceAPI,
+				Action:   auditAction(r),
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				Target:   auditTarget(r),
+				BodyHash: bodyHash,
+				Objects:  audit.Objects(ctx),
+				Detail:   audit.Detail(ctx),
+				Outcome:  model.AuditOutcomeSuccess,
+				Status:   status,
+			}
+			if status >= http.StatusBadRequest {
+				record.Outcome = model.AuditOutcomeFailure
+				record.Error = http.StatusText(status)
+			}
+			auditor.Record(ctx, record)
+		})
+	}
+}
+
+// auditAction is the route template of the request, e.g.
+// /api/instances/{instance}/allowlist.
+func auditAction(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+func auditTarget(r *http.Request) string {
+	params := mux.Vars(r)
+	if key, ok := params["key"]; ok {
+		return "apikey/" + key
+	}
+	if instance, ok := params["instance"]; ok {
+		return "instance/" + instance
+	}
+	if project, ok := params["project"]; ok {
+		return "project/" + project
+	}
+	if team, ok := params["team"]; ok {
+		return "team/" + team
+	}
+	return ""
+}
@@ -2,13 +2,21 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
 	"github.com/zbitech/controller/pkg/rctx"
 )
 
@@ -23,25 +31,59 @@ func Chain(f http.HandlerFunc, middlewares ...mux.MiddlewareFunc) http.Handler {
 	return middlewares[0](Chain(f, middlewares[1:cap(middlewares)]...))
 }
 
+// Identity headers set by the platform API for the user it authenticated.
+// They are only trusted on requests carrying the internal client secret.
+const (
+	INTERNAL_SECRET_HEADER = "x-internal-secret"
+	USERID_HEADER          = "x-user-id"
+	ROLE_HEADER            = "x-user-role"
+)
+
+// getIdentity returns the caller and role of a request, or empty values when
+// the request is not authenticated.
+func getIdentity(r *http.Request) (string, string) {
+	secret := r.Header.Get(INTERNAL_SECRET_HEADER)
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(vars.ZBI_INTERNAL_CLIENT_SECRET)) != 1 {
+		return "", ""
+	}
+	return r.Header.Get(USERID_HEADER), r.Header.Get(ROLE_HEADER)
+}
+
 func InitRequest(f http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := logger.GetLogger(r.Context())
-		userid := uuid.New().String()
-		role := "admin"
+		txid := uuid.New().String()
+		userid, role := getIdentity(r)
 		contextLogger := log.WithFields(logrus.Fields{
-			rctx.TXID:   uuid.New().String(),
+			rctx.TXID:   txid,
 			rctx.USERID: userid,
 			rctx.IP:     r.RemoteAddr,
 			rctx.XIP:    r.Header.Get("X-Forwarded-For"),
 		})
 
 		ctx := context.WithValue(r.Context(), rctx.LOGGER, contextLogger)
+		ctx = context.WithValue(ctx, rctx.TXID, txid)
 		ctx = context.WithValue(ctx, rctx.USERID, userid)
 		ctx = context.WithValue(ctx, rctx.ROLE, role)
+		ctx = context.WithValue(ctx, rctx.IP, r.RemoteAddr)
+		ctx = context.WithValue(ctx, rctx.XIP, r.Header.Get("X-Forwarded-For"))
 		f.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Authenticate rejects API requests without a caller identity. It must run
+// after InitRequest.
+func Authenticate(f http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userid, _ := r.Context().Value(rctx.USERID).(string)
+		if userid == "" && strings.HasPrefix(r.URL.Path, "/api/") {
+			response.AuthenticationRequiredResponse(w, r)
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+}
+
 func Logging(f http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := logger.GetLogger(r.Context())
@@ -53,3 +95,31 @@ func Logging(f http.Handler) http.Handler {
 		f.ServeHTTP(w, r)
 	})
 }
+
+// Recover turns a panic in a handler into a 500 response carrying the request
+// txid. It must run after InitRequest so the txid is available.
+func Recover(f http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				metrics.PanicsTotal.Inc("http")
+
+				ctx := r.Context()
+				fields := logrus.Fields{"method": r.Method, "url": r.URL.String(), "stack": string(debug.Stack())}
+				for key, value := range mux.Vars(r) {
+					fields[key] = value
+				}
+				logger.GetLogger(ctx).WithFields(fields).Errorf("recovered from panic - %v", rec)
+
+				w.Header().Set("Connection", "close")
+				response.ServerErrorResponse(w, r, ctx, errs.NewApplicationError(errs.InternalError, fmt.Errorf("panic: %v", rec)))
+			}
+		}()
+
+		f.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/internal/audit"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+type testAuditor struct {
+	records []model.AuditRecord
+}
+
+func (a *testAuditor) Record(ctx context.Context, record *model.AuditRecord) {
+	a.records = append(a.records, *record)
+}
+
+type testSink struct {
+	records []model.AuditRecord
+}
+
+func (s *testSink) Name() string { return "test" }
+
+func (s *testSink) Write(ctx context.Context, record *model.AuditRecord) error {
+	s.records = append(s.records, *record)
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	handler := InitRequest(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var properties map[string]interface{}
+		_ = properties["username"].(string)
+	})))
+
+	panics := metrics.PanicsTotal.Value("http")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/instances/test", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, panics+1, metrics.PanicsTotal.Value("http"))
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, false, body["success"])
+	assert.Equal(t, errs.InternalError.Code, body["code"])
+	assert.Equal(t, errs.InternalError.Message, body["error"])
+	assert.NotEmpty(t, body["txid"])
+}
+
+func TestAuthenticate(t *testing.T) {
+	var userid, role interface{}
+	handler := InitRequest(Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userid = r.Context().Value(rctx.USERID)
+		role = r.Context().Value(rctx.ROLE)
+	})))
+
+	serve := func(path, secret string) int {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set(USERID_HEADER, "alice")
+		request.Header.Set(ROLE_HEADER, "admin")
+		if secret != "" {
+			request.Header.Set(INTERNAL_SECRET_HEADER, secret)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/projects", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/projects", "wrong"))
+	assert.Equal(t, http.StatusOK, serve("/metrics", ""))
+	assert.Equal(t, "", userid)
+
+	assert.Equal(t, http.StatusOK, serve("/api/projects", vars.ZBI_INTERNAL_CLIENT_SECRET))
+	assert.Equal(t, "alice", userid)
+	assert.Equal(t, "admin", role)
+}
+
+func TestAudit(t *testing.T) {
+	auditor := &testAuditor{}
+	router := mux.NewRouter()
+	router.Use(InitRequest, Audit(auditor))
+	router.HandleFunc("/api/instances/{instance}/allowlist", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"ipAllowList":[]}`, string(body))
+		audit.AddObject(r.Context(), model.AuditOperationApply, "httpproxies", "proj", "ingress-node")
+		audit.SetDetail(r.Context(), "ipAllowList=0")
+		w.WriteHeader(http.StatusForbidden)
+	}).Methods(http.MethodPut)
+	router.HandleFunc("/api/instances/{instance}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	body := `{"ipAllowList":[]}`
+	request := httptest.NewRequest(http.MethodPut, "/api/instances/inst-1/allowlist", strings.NewReader(body))
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/instances/inst-1", nil))
+
+	assert.Len(t, auditor.records, 1)
+	record := auditor.records[0]
+	sum := sha256.Sum256([]byte(body))
+	assert.Equal(t, model.AuditSourceAPI, record.Source)
+	assert.Equal(t, "/api/instances/{instance}/allowlist", record.Action)
+	assert.Equal(t, "instance/inst-1", record.Target)
+	assert.Equal(t, hex.EncodeToString(sum[:]), record.BodyHash)
+	assert.Equal(t, model.AuditOutcomeFailure, record.Outcome)
+	assert.Equal(t, http.StatusForbidden, record.Status)
+	assert.Equal(t, []model.AuditObject{{Operation: model.AuditOperationApply, Resource: "httpproxies", Namespace: "proj", Name: "ingress-node"}}, record.Objects)
+	assert.Equal(t, "ipAllowList=0", record.Detail)
+}
+
+func TestAuditActor(t *testing.T) {
+	sink := &testSink{}
+	router := mux.NewRouter()
+	router.Use(InitRequest, Audit(audit.NewAuditor(sink)), Authenticate)
+	router.HandleFunc("/api/projects/{project}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodPut)
+
+	serve := func(secret string) {
+		request := httptest.NewRequest(http.MethodPut, "/api/projects/proj-1", strings.NewReader("{}"))
+		request.Header.Set(USERID_HEADER, "alice")
+		request.Header.Set(ROLE_HEADER, "admin")
+		if secret != "" {
+			request.Header.Set(INTERNAL_SECRET_HEADER, secret)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	serve(vars.ZBI_INTERNAL_CLIENT_SECRET)
+	serve("")
+
+	assert.Len(t, sink.records, 2)
+	assert.Equal(t, "alice", sink.records[0].Actor)
+	assert.Equal(t, "admin", sink.records[0].Role)
+	assert.Equal(t, model.AuditOutcomeSuccess, sink.records[0].Outcome)
+
+	assert.Equal(t, "", sink.records[1].Actor)
+	assert.Equal(t, "", sink.records[1].Role)
+	assert.Equal(t, http.StatusUnauthorized, sink.records[1].Status)
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+var errNotMiner = errors.New("instance is not a miner")
+
+var rpcClient = &http.Client{Timeout: 10 * time.Second}
+
+// GetInstanceMining returns the mining options of a zcash miner with the
+// getmininginfo and getlocalsolps results of the node.
+func GetInstanceMining(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	if instance.InstanceType != model.InstanceTypeZCASH || instance.Request == nil {
+		response.BadRequestResponse(w, r, errNotMiner)
+		return
+	}
+	if miner, _ := instance.Request.Properties[request.MINER_PROPERTY].(bool); !miner {
+		response.BadRequestResponse(w, r, errNotMiner)
+		return
+	}
+
+	var status = model.MiningStatus{Instance: instance.Name, Mining: instance.Request.Mining, Info: &model.MiningInfo{}}
+	if err := helper.CallInstanceRPC(ctx, rpcClient, instance.Project, instance, "getmininginfo", nil, status.Info); err != nil {
+		audit.Errorf("getmininginfo failed - %s", err)
+		response.BadGatewayResponse(w, r, err)
+		return
+	}
+	if err := helper.CallInstanceRPC(ctx, rpcClient, instance.Project, instance, "getlocalsolps", nil, &status.LocalSolPs); err != nil {
+		audit.Errorf("getlocalsolps failed - %s", err)
+		response.BadGatewayResponse(w, r, err)
+		return
+	}
+
+	if err := response.JSON(w, http.StatusOK, status); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// SetInstanceAllowList replaces the source IP allow-list of the instance
+// ingress. An empty list allows all sources.
+func SetInstanceAllowList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionSetAllowList)
+	if !ok {
+		return
+	}
+
+	var allowListRequest model.IPAllowListRequest
+	if err := request.ReadJSON(w, r, &allowListRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if errorMap := request.Validate(&allowListRequest); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return
+	}
+
+	var previous []string
+	if instance.Request != nil {
+		previous = instance.Request.IPAllowList
+	} else {
+		instance.Request = &model.ResourceRequest{}
+	}
+
+	allowList := helper.NormalizeIPAllowList(allowListRequest.IPAllowList)
+	audit = audit.WithFields(logrus.Fields{"ipAllowList": allowList})
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstanceAllowList(ctx, instance.Id, allowList); err != nil {
+		audit.Errorf("instance allow-list update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	instance.Request.IPAllowList = allowList
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err := zclient.UpdateInstanceIngress(ctx, instance.Project, instance); err != nil {
+		audit.Errorf("instance ingress update failed - %s", err)
+		if err := repository.UpdateInstanceAllowList(ctx, instance.Id, previous); err != nil {
+			audit.Errorf("failed to restore instance allow-list - %s", err)
+		}
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("instance allow-list set")
+	if err := repository.AddInstanceActivity(ctx, instance.Id, model.EventActionSetAllowList); err != nil {
+		audit.Errorf("failed to add allow-list activity for instance %s - %s", instance.Id, err)
+	}
+
+	if err := response.JSON(w, http.StatusOK, response.Envelope{"ipAllowList": allowList}); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetInstanceNetworkPolicies returns the NetworkPolicies selecting the pods
+// of an instance and the allow-list of its ingress.
+func GetInstanceNetworkPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionGetPolicies)
+	if !ok {
+		return
+	}
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	policies, err := zclient.GetNetworkPolicies(ctx, instance.Project, instance)
+	if err != nil {
+		audit.Errorf("failed to retrieve network policies - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, policies); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetProjectNetworkPolicies returns the NetworkPolicies of a project.
+func GetProjectNetworkPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionGetPolicies)
+	if !ok {
+		return
+	}
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	policies, err := zclient.GetNetworkPolicies(ctx, project, nil)
+	if err != nil {
+		audit.Errorf("failed to retrieve network policies - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, policies); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
@@ -0,0 +1,266 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/schedule"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+// GetProjectPower returns the power schedule of a project and its next
+// transition.
+func GetProjectPower(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, _, ok := getOwnedProject(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	info := newScheduleInfo(project.Schedule, schedule.SCHEDULE_SOURCE_PROJECT, time.Now())
+	if err := response.JSON(w, http.StatusOK, info); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// SetProjectSchedule sets the power schedule of a project, which applies to
+// its instances without a schedule of their own.
+func SetProjectSchedule(w http.ResponseWriter, r *http.Request) {
+	project, audit, ok := getOwnedProject(w, r, model.EventActionSetSchedule)
+	if !ok {
+		return
+	}
+
+	powerSchedule, ok := readPowerSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	updateProjectSchedule(w, r, project, powerSchedule, model.EventActionSetSchedule, audit)
+}
+
+func DeleteProjectSchedule(w http.ResponseWriter, r *http.Request) {
+	project, audit, ok := getOwnedProject(w, r, model.EventActionRemoveSchedule)
+	if !ok {
+		return
+	}
+
+	updateProjectSchedule(w, r, project, nil, model.EventActionRemoveSchedule, audit)
+}
+
+func updateProjectSchedule(w http.ResponseWriter, r *http.Request, project *model.Project, powerSchedule *model.PowerSchedule,
+	action model.EventAction, audit *logrus.Entry) {
+	ctx := r.Context()
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateProjectSchedule(ctx, project.Id, powerSchedule); err != nil {
+		audit.Errorf("project schedule update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+	project.Schedule = powerSchedule
+
+	instances, err := repository.GetInstances(ctx, project.Id)
+	if err != nil {
+		audit.Errorf("failed to retrieve instances - %s", err)
+	}
+	for index := range instances {
+		if instances[index].Schedule == nil {
+			resetPowerState(ctx, &instances[index], powerSchedule, audit)
+		}
+	}
+
+	audit.Infof("project schedule updated")
+	if err = repository.AddProjectActivity(ctx, project.Id, action); err != nil {
+		audit.Errorf("failed to add %s activity for project %s - %s", action, project.Id, err)
+	}
+
+	info := newScheduleInfo(powerSchedule, schedule.SCHEDULE_SOURCE_PROJECT, time.Now())
+	if err = response.JSON(w, http.StatusOK, info); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetInstancePower returns the schedule that applies to an instance, its
+// hold and its scheduler state.
+func GetInstancePower(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	if err := response.JSON(w, http.StatusOK, instanceScheduleInfo(instance)); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// SetInstanceSchedule sets the power schedule of an instance, overriding the
+// schedule of its project.
+func SetInstanceSchedule(w http.ResponseWriter, r *http.Request) {
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionSetSchedule)
+	if !ok {
+		return
+	}
+
+	powerSchedule, ok := readPowerSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	updateInstanceSchedule(w, r, instance, powerSchedule, model.EventActionSetSchedule, audit)
+}
+
+func DeleteInstanceSchedule(w http.ResponseWriter, r *http.Request) {
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRemoveSchedule)
+	if !ok {
+		return
+	}
+
+	updateInstanceSchedule(w, r, instance, nil, model.EventActionRemoveSchedule, audit)
+}
+
+func updateInstanceSchedule(w http.ResponseWriter, r *http.Request, instance *model.Instance, powerSchedule *model.PowerSchedule,
+	action model.EventAction, audit *logrus.Entry) {
+	ctx := r.Context()
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstanceSchedule(ctx, instance.Id, powerSchedule); err != nil {
+		audit.Errorf("instance schedule update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+	instance.Schedule = powerSchedule
+
+	effective, _ := schedule.GetSchedule(instance, instance.Project)
+	instance.Power = resetPowerState(ctx, instance, effective, audit)
+
+	audit.Infof("instance schedule updated")
+	if err := repository.AddInstanceActivity(ctx, instance.Id, action); err != nil {
+		audit.Errorf("failed to add %s activity for instance %s - %s", action, instance.Id, err)
+	}
+
+	if err := response.JSON(w, http.StatusOK, instanceScheduleInfo(instance)); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// SetInstanceHold suspends the scheduled transitions of an instance until
+// the hold expires or is released.
+func SetInstanceHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionHold)
+	if !ok {
+		return
+	}
+
+	var hold model.ScheduleHold
+	if err := request.ReadJSON(w, r, &hold); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	var now = time.Now()
+	if fieldErrors := request.Validate(&hold); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+	if hold.Until != nil && !hold.Until.After(now) {
+		response.FailedValidationResponse(w, r, map[string]string{"until": "must be in the future"})
+		return
+	}
+
+	hold.CreatedBy, _ = ctx.Value(rctx.USERID).(string)
+	hold.CreatedAt = &now
+
+	updateInstanceHold(w, r, instance, &hold, model.EventActionHold, audit)
+}
+
+func DeleteInstanceHold(w http.ResponseWriter, r *http.Request) {
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRelease)
+	if !ok {
+		return
+	}
+
+	updateInstanceHold(w, r, instance, nil, model.EventActionRelease, audit)
+}
+
+func updateInstanceHold(w http.ResponseWriter, r *http.Request, instance *model.Instance, hold *model.ScheduleHold,
+	action model.EventAction, audit *logrus.Entry) {
+	ctx := r.Context()
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstanceHold(ctx, instance.Id, hold); err != nil {
+		audit.Errorf("instance hold update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+	instance.Hold = hold
+
+	audit.Infof("instance hold updated")
+	if err := repository.AddInstanceActivity(ctx, instance.Id, action); err != nil {
+		audit.Errorf("failed to add %s activity for instance %s - %s", action, instance.Id, err)
+	}
+
+	if err := response.JSON(w, http.StatusOK, instanceScheduleInfo(instance)); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func readPowerSchedule(w http.ResponseWriter, r *http.Request) (*model.PowerSchedule, bool) {
+	var powerSchedule model.PowerSchedule
+	if err := request.ReadJSON(w, r, &powerSchedule); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	if fieldErrors := request.ValidatePowerSchedule(&powerSchedule); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return nil, false
+	}
+
+	return &powerSchedule, true
+}
+
+// resetPowerState records that transitions of a changed schedule before now
+// are not to be caught up.
+func resetPowerState(ctx context.Context, instance *model.Instance, powerSchedule *model.PowerSchedule, audit *logrus.Entry) *model.PowerState {
+	state := schedule.ResetState(instance, powerSchedule, time.Now())
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateInstancePower(ctx, instance.Id, state); err != nil {
+		audit.WithFields(logrus.Fields{"instance": instance.Name}).Errorf("failed to reset schedule state - %s", err)
+	}
+	return state
+}
+
+func instanceScheduleInfo(instance *model.Instance) *model.ScheduleInfo {
+	powerSchedule, source := schedule.GetSchedule(instance, instance.Project)
+	info := newScheduleInfo(powerSchedule, source, time.Now())
+	info.Hold = instance.Hold
+	info.State = instance.Power
+	return info
+}
+
+func newScheduleInfo(powerSchedule *model.PowerSchedule, source string, now time.Time) *model.ScheduleInfo {
+	var info = model.ScheduleInfo{Schedule: powerSchedule}
+	if powerSchedule == nil {
+		return &info
+	}
+
+	info.Source = source
+	if table, err := schedule.NewTimetable(powerSchedule); err == nil {
+		if next, action, ok := table.Next(now); ok {
+			info.NextTransition, info.NextAction = &next, action
+		}
+	}
+	return &info
+}
@@ -3,13 +3,15 @@ package http
 import (
 	"net/http"
 
-	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/app/service-api/request"
 	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/upgrade"
 	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
 )
 
 func CreateProject(w http.ResponseWriter, r *http.Request) {
@@ -22,12 +24,41 @@ func CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//	projectRequest.Owner = r.Header.Get("x-owner-id") //user := ctx.Value(rctx.USERID).(string)
+	if fieldErrors := request.ValidateProject(&projectRequest); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	// only admins can create projects on behalf of another user
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	if projectRequest.Owner == "" || role != helper.ADMIN_ROLE {
+		projectRequest.Owner = userid
+	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
+	if projectRequest.TeamId != "" {
+		team, err := repository.GetTeam(ctx, projectRequest.TeamId)
+		if err != nil {
+			log.Errorf("failed to retrieve team %s - %s", projectRequest.TeamId, err)
+			response.ServerErrorResponse(w, r, ctx, err)
+			return
+		}
+
+		if !helper.CanAccessProject(&projectRequest, team, userid, role, model.AccessManage) {
+			log.WithFields(logrus.Fields{"audit": true, "action": model.EventActionCreate, "team": team.Id, rctx.USERID: userid}).Warnf("team project creation denied")
+			response.NotPermittedResponse(w, r)
+			return
+		}
+
+		if !checkProjectQuota(w, r, team) {
+			return
+		}
+	}
+
 	project, err := repository.CreateProject(ctx, &projectRequest)
 	if err != nil {
-		log.Errorf("Failed to create project in repository %s - %s", project.Name, err)
+		log.Errorf("Failed to create project in repository %s - %s", projectRequest.Name, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
@@ -54,19 +85,12 @@ func DeleteProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionDelete)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	project, err := repository.GetProject(ctx, projectId)
-	if err != nil {
-		log.Errorf("Failed to retrieve project %s - %s", project.Name, err)
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	instances, err := repository.GetInstances(ctx, project.Id)
 
@@ -86,30 +110,49 @@ func DeleteProject(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// TODO - is this allowed?
+// UpdateProject changes the description, labels and rotation policy of a
+// project. The project objects are re-rendered when the labels change.
 func UpdateProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionUpdate)
+	if !ok {
 		return
 	}
 
-	var project model.Project
-	if err := request.ReadJSON(w, r, &project); err != nil {
+	var update model.ProjectUpdateRequest
+	if err := request.ReadJSON(w, r, &update); err != nil {
 		response.BadRequestResponse(w, r, err)
 		return
 	}
 
-	zclient := vars.KlientFactory.GetZBIClient()
-	err := zclient.RepairProject(ctx, &project)
+	if fieldErrors := request.ValidateProjectUpdate(&update, project); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	updated, err := repository.UpdateProject(ctx, project.Id, &update)
 	if err != nil {
 		log.Errorf("Failed to update project %s - %s", project.Name, err)
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
+	project = updated
+
+	if update.Labels != nil {
+		zclient := vars.KlientFactory.GetZBIClient()
+		if err = zclient.UpdateProject(ctx, project); err != nil {
+			log.Errorf("Failed to update project %s resources - %s", project.Name, err)
+			response.ServerErrorResponse(w, r, ctx, err)
+			return
+		}
+	}
+
+	if err = repository.AddProjectActivity(ctx, project.Id, model.EventActionUpdate); err != nil {
+		log.Errorf("failed to add update activity for project %s - %s", project.Id, err)
+	}
 
 	envelope := response.Envelope{"project": project}
 	if err = response.JSON(w, http.StatusOK, envelope); err != nil {
@@ -121,22 +164,15 @@ func RepairProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionRepair)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	project, err := repository.GetProject(ctx, projectId)
-	if err != nil {
-		log.Errorf("Failed to retrieve project %s - %s", project.Name, err)
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
 
 	zclient := vars.KlientFactory.GetZBIClient()
-	err = zclient.RepairProject(ctx, project)
+	err := zclient.RepairProject(ctx, project)
 	if err != nil {
 		log.Errorf("Failed to update project %s - %s", project.Name, err)
 		response.ServerErrorResponse(w, r, ctx, err)
@@ -160,19 +196,30 @@ func GetProjects(w http.ResponseWriter, r *http.Request) {
 	log.Infof("getting projects")
 	repository := vars.RepositoryFactory.GetRepositoryService()
 
-	owner := r.Header.Get("x-owner-id")
-	projects, err := repository.GetProjects(ctx, owner)
+	query, err := readListQuery(r)
+	if err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
 
-	//	zclient := vars.KlientFactory.GetZBIClient()
-	//	projects, err := zclient.GetProjects(ctx)
+	// admins see all projects; other users see their own projects and those
+	// of their teams
+	if role, _ := ctx.Value(rctx.ROLE).(string); role == helper.ADMIN_ROLE {
+		if query.Owner == "" {
+			query.Owner = r.Header.Get("x-owner-id")
+		}
+	} else {
+		query.Member, _ = ctx.Value(rctx.USERID).(string)
+	}
 
+	projects, err := repository.ListProjects(ctx, query)
 	if err != nil {
 		log.Errorf("failed to retrieve projects")
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
 
-	if err = response.JSON(w, http.StatusOK, projects); err != nil {
+	if err = writeList(w, projects.Total, projects.NextCursor, projects.Projects, query.Fields); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
 }
@@ -181,23 +228,15 @@ func GetProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionView)
+	if !ok {
 		return
 	}
 
-	log.Infof("getting project - %s", projectId)
-	repository := vars.RepositoryFactory.GetRepositoryService()
-	project, err := repository.GetProject(ctx, projectId)
-	if err != nil {
-		log.Errorf("failed to retrieve projects")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
-	}
+	log.Infof("getting project - %s", project.Id)
 
 	// envelope := response.Envelope{"project": project}
-	if err = response.JSON(w, http.StatusOK, project); err != nil {
+	if err := response.JSON(w, http.StatusOK, project); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
 }
@@ -211,28 +250,43 @@ func CreateInstance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionCreate)
+	if !ok {
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	project, err := repository.GetProject(ctx, projectId)
-	if err != nil {
-		log.Errorf("failed to retrieve projects")
-		response.ServerErrorResponse(w, r, ctx, err)
-		return
+	if project.TeamId != "" {
+		team, err := repository.GetTeam(ctx, project.TeamId)
+		if err != nil {
+			log.Errorf("failed to retrieve team %s - %s", project.TeamId, err)
+			response.ServerErrorResponse(w, r, ctx, err)
+			return
+		}
+
+		if !checkInstanceQuota(w, r, team) {
+			return
+		}
 	}
 
 	var instance_req model.InstanceRequest
 	if err := request.ReadJSON(w, r, &instance_req); err != nil {
-		log.WithFields(logrus.Fields{"error": err, "project": projectId}).Errorf("failed to read input")
+		log.WithFields(logrus.Fields{"error": err, "project": project.Id}).Errorf("failed to read input")
 		response.BadRequestResponse(w, r, err)
 		return
 	}
 	log.WithFields(logrus.Fields{"instance": instance_req}).Infof("instance details")
 
+	fieldErrors, err := validateInstanceRequest(ctx, project, "", &instance_req)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	} else if fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	instance_req.IPAllowList = helper.NormalizeIPAllowList(instance_req.IPAllowList)
 	instance, err := repository.CreateInstance(ctx, project.Id, project.Owner, &instance_req)
 	if err != nil {
 		log.Errorf("failed to create instance")
@@ -240,6 +294,10 @@ func CreateInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = upgrade.PinInstance(ctx, instance); err != nil {
+		log.Errorf("failed to pin image of instance %s - %s", instance.Id, err)
+	}
+
 	zclient := vars.KlientFactory.GetZBIClient()
 	err = zclient.CreateInstance(ctx, project, instance)
 	if err != nil {
@@ -261,21 +319,26 @@ func GetInstances(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLogger(ctx)
 
-	projectId := request.GetParameterValue(r, request.PATH_PARAM, "project")
-	if len(projectId) == 0 {
-		response.BadRequestResponse(w, r, errors.New("project is required"))
+	project, _, ok := getOwnedProject(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	query, err := readListQuery(r)
+	if err != nil {
+		response.BadRequestResponse(w, r, err)
 		return
 	}
 
 	repository := vars.RepositoryFactory.GetRepositoryService()
-	instances, err := repository.GetInstances(ctx, projectId)
+	instances, err := repository.ListInstances(ctx, project.Id, query)
 	if err != nil {
 		log.Errorf("failed to retrieve instances")
 		response.ServerErrorResponse(w, r, ctx, err)
 		return
 	}
 
-	if err = response.JSON(w, http.StatusOK, instances); err != nil {
+	if err = writeList(w, instances.Total, instances.NextCursor, instances.Instances, query.Fields); err != nil {
 		response.ServerErrorResponse(w, r, ctx, err)
 	}
 }
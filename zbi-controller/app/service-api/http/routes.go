@@ -4,10 +4,13 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/zbitech/controller/app/service-api/http/middleware"
 	"github.com/zbitech/controller/app/service-api/response"
 	"github.com/zbitech/controller/app/service-api/server"
+	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
 )
 
 func SetupRoutes(ctx context.Context, server *server.HttpServer) {
@@ -17,19 +20,56 @@ func SetupRoutes(ctx context.Context, server *server.HttpServer) {
 	router := server.GetRouter()
 
 	log.Infof("initializing middlewares")
-	router.Use(middleware.InitRequest, middleware.Logging)
+	middlewares := []mux.MiddlewareFunc{middleware.InitRequest, middleware.Logging}
+	if vars.AuditService != nil {
+		middlewares = append(middlewares, middleware.Audit(vars.AuditService))
+	}
+	router.Use(append(middlewares, middleware.Authenticate, middleware.Recover)...)
 
 	router.NotFoundHandler = http.HandlerFunc(response.NotFoundResponse)
 	router.MethodNotAllowedHandler = http.HandlerFunc(response.MethodNotAllowedResponse)
 
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	router.Handle("/api/audit", middleware.Chain(GetAuditRecords)).Methods(http.MethodGet)
+	router.Handle("/api/images", middleware.Chain(GetImages)).Methods(http.MethodGet)
+
+	log.Infof("setting seed routers")
+	seeds := router.PathPrefix("/api/seeds").Subrouter()
+	seeds.Handle("", middleware.Chain(GetSeeds)).Methods(http.MethodGet)
+	seeds.Handle("", middleware.Chain(CreateSeed)).Methods(http.MethodPost)
+	seeds.Handle("/{seed}", middleware.Chain(GetSeed)).Methods(http.MethodGet)
+	seeds.Handle("/{seed}", middleware.Chain(UpdateSeed)).Methods(http.MethodPut)
+	seeds.Handle("/{seed}", middleware.Chain(DeleteSeed)).Methods(http.MethodDelete)
+
+	log.Infof("setting team routers")
+	teams := router.PathPrefix("/api/teams").Subrouter()
+	teams.Handle("", middleware.Chain(GetTeams)).Methods(http.MethodGet)
+	teams.Handle("", middleware.Chain(CreateTeam)).Methods(http.MethodPost)
+	teams.Handle("/{team}", middleware.Chain(GetTeam)).Methods(http.MethodGet)
+	teams.Handle("/{team}/members/{member}", middleware.Chain(SetTeamMember)).Methods(http.MethodPut)
+	teams.Handle("/{team}/members/{member}", middleware.Chain(RemoveTeamMember)).Methods(http.MethodDelete)
+	teams.Handle("/{team}/quota", middleware.Chain(SetTeamQuota)).Methods(http.MethodPut)
+
 	log.Infof("setting project routers")
 	project := router.PathPrefix("/api/projects").Subrouter()
 	project.Handle("", middleware.Chain(GetProjects)).Methods(http.MethodGet)
 	project.Handle("", middleware.Chain(CreateProject)).Methods(http.MethodPost)
 	project.Handle("/{project}", middleware.Chain(GetProject)).Methods(http.MethodGet)
 	project.Handle("/{project}", middleware.Chain(DeleteProject)).Methods(http.MethodDelete)
-	project.Handle("/{project}", middleware.Chain(UpdateProject)).Methods(http.MethodPut)          // update
-	project.Handle("/{project}/repair", middleware.Chain(RepairProject)).Methods(http.MethodPatch) // repair
+	project.Handle("/{project}", middleware.Chain(UpdateProject)).Methods(http.MethodPut, http.MethodPatch) // update
+	project.Handle("/{project}/repair", middleware.Chain(RepairProject)).Methods(http.MethodPatch)          // repair
+	project.Handle("/{project}/actions/{action}", middleware.Chain(RunProjectAction)).Methods(http.MethodPost)
+	project.Handle("/{project}/domain", middleware.Chain(SetProjectDomain)).Methods(http.MethodPut)
+	project.Handle("/{project}/power", middleware.Chain(GetProjectPower)).Methods(http.MethodGet)
+	project.Handle("/{project}/power/schedule", middleware.Chain(SetProjectSchedule)).Methods(http.MethodPut)
+	project.Handle("/{project}/power/schedule", middleware.Chain(DeleteProjectSchedule)).Methods(http.MethodDelete)
+	project.Handle("/{project}/domain", middleware.Chain(DeleteProjectDomain)).Methods(http.MethodDelete)
+	project.Handle("/{project}/upgrade", middleware.Chain(GetProjectUpgrade)).Methods(http.MethodGet)
+	project.Handle("/{project}/upgrade", middleware.Chain(StartProjectUpgrade)).Methods(http.MethodPost)
+	project.Handle("/{project}/upgrade/pause", middleware.Chain(PauseProjectUpgrade)).Methods(http.MethodPost)
+	project.Handle("/{project}/upgrade/resume", middleware.Chain(ResumeProjectUpgrade)).Methods(http.MethodPost)
+	project.Handle("/{project}/upgrade/rollback", middleware.Chain(RollbackProjectUpgrade)).Methods(http.MethodPost)
+	project.Handle("/{project}/policies", middleware.Chain(GetProjectNetworkPolicies)).Methods(http.MethodGet)
 
 	project.Handle("/{project}/instances", middleware.Chain(GetInstances)).Methods(http.MethodGet)
 	project.Handle("/{project}/instances", middleware.Chain(CreateInstance)).Methods(http.MethodPost)
@@ -40,7 +80,28 @@ func SetupRoutes(ctx context.Context, server *server.HttpServer) {
 	instances.Handle("/{instance}", middleware.Chain(UpdateInstance)).Methods(http.MethodPut)
 	instances.Handle("/{instance}", middleware.Chain(DeleteInstance)).Methods(http.MethodDelete)
 
+	instances.Handle("/{instance}/credentials", middleware.Chain(GetInstanceCredentials)).Methods(http.MethodGet)
 	instances.Handle("/{instance}/repair", middleware.Chain(RepairInstance)).Methods(http.MethodPatch)                                    // repair
 	instances.Handle("/{instance}/{action:stop|start|snapshot|schedule|rotate}", middleware.Chain(PatchInstance)).Methods(http.MethodPut) // activate, deactivate, snapshot, backup
 
+	instances.Handle("/{instance}/apikeys", middleware.Chain(GetAPIKeys)).Methods(http.MethodGet)
+	instances.Handle("/{instance}/apikeys", middleware.Chain(CreateAPIKey)).Methods(http.MethodPost)
+	instances.Handle("/{instance}/apikeys/{key}", middleware.Chain(RevokeAPIKey)).Methods(http.MethodDelete)
+
+	instances.Handle("/{instance}/domain", middleware.Chain(SetInstanceDomain)).Methods(http.MethodPut)
+	instances.Handle("/{instance}/domain", middleware.Chain(DeleteInstanceDomain)).Methods(http.MethodDelete)
+
+	instances.Handle("/{instance}/allowlist", middleware.Chain(SetInstanceAllowList)).Methods(http.MethodPut)
+	instances.Handle("/{instance}/power", middleware.Chain(GetInstancePower)).Methods(http.MethodGet)
+	instances.Handle("/{instance}/power/schedule", middleware.Chain(SetInstanceSchedule)).Methods(http.MethodPut)
+	instances.Handle("/{instance}/power/schedule", middleware.Chain(DeleteInstanceSchedule)).Methods(http.MethodDelete)
+	instances.Handle("/{instance}/power/hold", middleware.Chain(SetInstanceHold)).Methods(http.MethodPut)
+	instances.Handle("/{instance}/power/hold", middleware.Chain(DeleteInstanceHold)).Methods(http.MethodDelete)
+	instances.Handle("/{instance}/policies", middleware.Chain(GetInstanceNetworkPolicies)).Methods(http.MethodGet)
+	instances.Handle("/{instance}/image", middleware.Chain(GetInstanceImage)).Methods(http.MethodGet)
+	instances.Handle("/{instance}/upgrade", middleware.Chain(UpgradeInstance)).Methods(http.MethodPost)
+	instances.Handle("/{instance}/rollback", middleware.Chain(RollbackInstance)).Methods(http.MethodPost)
+	instances.Handle("/{instance}/mining", middleware.Chain(GetInstanceMining)).Methods(http.MethodGet)
+	instances.Handle("/{instance}/rpc", middleware.Chain(ForwardInstanceRPC)).Methods(http.MethodPost)
+
 }
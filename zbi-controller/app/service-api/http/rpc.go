@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	zaudit "github.com/zbitech/controller/internal/audit"
+	"github.com/zbitech/controller/internal/authz"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	DEFAULT_RPC_TIMEOUT = 10 * time.Second
+	MAX_RPC_TIMEOUT     = 60 * time.Second
+)
+
+var errNoRPC = errors.New("instance does not serve json-rpc")
+
+// consoleClient forwards console calls. Each call sets its own timeout.
+var consoleClient = &http.Client{}
+
+// ForwardInstanceRPC forwards a JSON-RPC call or batch from the console to the
+// node service of a zcash instance and returns the node response. Every
+// method must belong to an endpoint group of the node and be allowed by the
+// endpoint policy of the instance. The timeout parameter limits the call to
+// at most MAX_RPC_TIMEOUT.
+func ForwardInstanceRPC(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRPC)
+	if !ok {
+		return
+	}
+
+	if instanceType(instance) != model.InstanceTypeZCASH {
+		response.BadRequestResponse(w, r, errNoRPC)
+		return
+	}
+
+	timeout := DEFAULT_RPC_TIMEOUT
+	if value := request.GetParameterValue(r, request.GET_PARAM, "timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 || timeout > MAX_RPC_TIMEOUT {
+			response.FailedValidationResponse(w, r, map[string]string{"timeout": fmt.Sprintf("must be a duration of at most %s", MAX_RPC_TIMEOUT)})
+			return
+		}
+	}
+
+	var body json.RawMessage
+	if err := request.ReadJSON(w, r, &body); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	methods, err := helper.RPCMethods(body)
+	if err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	rpc := strings.Join(methods, ",")
+	zaudit.SetDetail(ctx, "rpc "+rpc)
+	audit = audit.WithFields(logrus.Fields{"rpc": rpc, "timeout": timeout.String()})
+
+	node, err := getRequestNodeInfo(ctx, instance.Project, model.InstanceTypeZCASH)
+	if err != nil {
+		audit.Errorf("failed to retrieve node info - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	filter := authz.NewMethodFilter(authz.GetEndpointPolicy(instance), node.Endpoints)
+	for _, method := range methods {
+		if !authz.IsEndpointMethod(method, node.Endpoints) || !filter.Allowed(method) {
+			audit.Warnf("rpc method %s denied", method)
+			response.Error(w, http.StatusForbidden, fmt.Sprintf("rpc method %s is not allowed", method))
+			return
+		}
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := helper.ForwardInstanceRPC(callCtx, consoleClient, instance.Project, instance, body, helper.MAX_RPC_RESPONSE_SIZE)
+	audit = audit.WithFields(logrus.Fields{"duration": time.Since(start).String()})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			audit.Warnf("rpc call timed out")
+			response.Error(w, http.StatusGatewayTimeout, fmt.Sprintf("rpc call timed out after %s", timeout))
+			return
+		}
+		audit.Errorf("rpc call failed - %s", err)
+		response.BadGatewayResponse(w, r, err)
+		return
+	}
+
+	audit.Infof("rpc call forwarded")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(result)
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+// getSeedAudit checks that the caller is an admin and returns the audit
+// logger of a seed action. It writes the error response on failure.
+func getSeedAudit(w http.ResponseWriter, r *http.Request, action model.EventAction) (*logrus.Entry, bool) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	audit := log.WithFields(logrus.Fields{"audit": true, "action": action, rctx.USERID: userid, rctx.ROLE: role})
+
+	if role != helper.ADMIN_ROLE {
+		audit.Warnf("seed access denied")
+		response.NotPermittedResponse(w, r)
+		return nil, false
+	}
+	return audit, true
+}
+
+// readSeedRequest reads and validates a seed request and returns the seed to
+// store. It writes the error response on failure.
+func readSeedRequest(w http.ResponseWriter, r *http.Request) (*model.Seed, bool) {
+
+	var seedRequest model.SeedRequest
+	if err := request.ReadJSON(w, r, &seedRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	if errorMap := request.ValidateSeedRequest(&seedRequest); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return nil, false
+	}
+
+	if seedRequest.Blockchain == "" {
+		seedRequest.Blockchain = "zcash"
+	}
+
+	return &model.Seed{
+		Name:        seedRequest.Name,
+		Blockchain:  seedRequest.Blockchain,
+		Network:     seedRequest.Network,
+		Type:        seedRequest.Type,
+		Description: seedRequest.Description,
+		Height:      seedRequest.Height,
+		Size:        seedRequest.Size,
+		Snapshot:    seedRequest.Snapshot,
+		Archive:     seedRequest.Archive,
+	}, true
+}
+
+// GetSeeds returns the seeds of the catalog, optionally of one network.
+func GetSeeds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	network := request.GetParameterValue(r, request.GET_PARAM, "network")
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	seeds, err := repository.GetSeeds(ctx, network)
+	if err != nil {
+		log.Errorf("failed to retrieve seeds - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, seeds); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func GetSeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	seedId := request.GetParameterValue(r, request.PATH_PARAM, "seed")
+	if len(seedId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("seed is required"))
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	seed, err := repository.GetSeed(ctx, seedId)
+	if err != nil {
+		log.Errorf("failed to retrieve seed %s - %s", seedId, err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, seed); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// CreateSeed adds a seed to the catalog. It is restricted to admins.
+func CreateSeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	audit, ok := getSeedAudit(w, r, model.EventActionCreate)
+	if !ok {
+		return
+	}
+
+	seed, ok := readSeedRequest(w, r)
+	if !ok {
+		return
+	}
+
+	seed.CreatedBy, _ = ctx.Value(rctx.USERID).(string)
+
+	audit = audit.WithFields(logrus.Fields{"seed": seed.Name, "network": seed.Network, "type": seed.Type})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	seed, err := repository.CreateSeed(ctx, seed)
+	if err != nil {
+		audit.Errorf("seed creation failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"id": seed.Id}).Infof("seed created")
+	if err = response.JSON(w, http.StatusCreated, seed); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// UpdateSeed replaces a seed of the catalog. Instances already loaded from
+// the seed keep their data. It is restricted to admins.
+func UpdateSeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	audit, ok := getSeedAudit(w, r, model.EventActionUpdate)
+	if !ok {
+		return
+	}
+
+	seedId := request.GetParameterValue(r, request.PATH_PARAM, "seed")
+	if len(seedId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("seed is required"))
+		return
+	}
+
+	seed, ok := readSeedRequest(w, r)
+	if !ok {
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"seed": seedId})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	seed, err := repository.UpdateSeed(ctx, seedId, seed)
+	if err != nil {
+		audit.Errorf("seed update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("seed updated")
+	if err = response.JSON(w, http.StatusOK, seed); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// DeleteSeed removes a seed from the catalog. Seeds that instances were
+// created from cannot be removed. It is restricted to admins.
+func DeleteSeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	audit, ok := getSeedAudit(w, r, model.EventActionDelete)
+	if !ok {
+		return
+	}
+
+	seedId := request.GetParameterValue(r, request.PATH_PARAM, "seed")
+	if len(seedId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("seed is required"))
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"seed": seedId})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.DeleteSeed(ctx, seedId); err != nil {
+		audit.Errorf("seed deletion failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("seed deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+// getMemberTeam retrieves the team in the request path and checks that the
+// caller is a member, or a team admin when manage is set. Admins have access
+// to every team. It writes the error response on failure.
+func getMemberTeam(w http.ResponseWriter, r *http.Request, action model.EventAction, manage bool) (*model.Team, *logrus.Entry, bool) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	teamId := request.GetParameterValue(r, request.PATH_PARAM, "team")
+	if len(teamId) == 0 {
+		response.BadRequestResponse(w, r, errors.New("team is required"))
+		return nil, nil, false
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	team, err := repository.GetTeam(ctx, teamId)
+	if err != nil {
+		log.Errorf("failed to retrieve team %s", teamId)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return nil, nil, false
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	audit := log.WithFields(logrus.Fields{"audit": true, "action": action, "team": team.Id, rctx.USERID: userid, rctx.ROLE: role})
+
+	allowed := role == helper.ADMIN_ROLE || team.GetMember(userid) != nil
+	if manage {
+		allowed = helper.CanManageTeam(team, userid, role)
+	}
+
+	if !allowed {
+		audit.Warnf("team access denied")
+		response.NotPermittedResponse(w, r)
+		return nil, nil, false
+	}
+
+	return team, audit, true
+}
+
+// CreateTeam creates a team with the caller as its admin. Only admins can
+// set the quota of a team.
+func CreateTeam(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	var teamRequest model.TeamRequest
+	if err := request.ReadJSON(w, r, &teamRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if errorMap := request.Validate(&teamRequest); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	if teamRequest.Quota != nil && role != helper.ADMIN_ROLE {
+		response.FailedValidationResponse(w, r, map[string]string{"quota": "can only be set by an admin"})
+		return
+	}
+
+	team := &model.Team{Name: teamRequest.Name, Members: []model.TeamMember{{UserId: userid, Role: model.TeamRoleAdmin}}}
+	if teamRequest.Quota != nil {
+		team.Quota = *teamRequest.Quota
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	team, err := repository.CreateTeam(ctx, team)
+	if err != nil {
+		log.Errorf("failed to create team %s - %s", teamRequest.Name, err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	log.WithFields(logrus.Fields{"audit": true, "action": model.EventActionCreate, "team": team.Id, rctx.USERID: userid}).Infof("team created")
+	if err = response.JSON(w, http.StatusCreated, team); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetTeams returns the teams of the caller, or every team to an admin.
+func GetTeams(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLogger(ctx)
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	role, _ := ctx.Value(rctx.ROLE).(string)
+
+	var member = userid
+	if role == helper.ADMIN_ROLE {
+		member = ""
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	teams, err := repository.GetTeams(ctx, member)
+	if err != nil {
+		log.Errorf("failed to retrieve teams - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, teams); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+func GetTeam(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	team, _, ok := getMemberTeam(w, r, model.EventActionView, false)
+	if !ok {
+		return
+	}
+
+	if err := response.JSON(w, http.StatusOK, team); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// SetTeamMember adds a member to a team or changes the role of a member. A
+// team must keep at least one admin.
+func SetTeamMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	team, audit, ok := getMemberTeam(w, r, model.EventActionAddMember, true)
+	if !ok {
+		return
+	}
+
+	var member model.TeamMember
+	if err := request.ReadJSON(w, r, &member); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	member.UserId = request.GetParameterValue(r, request.PATH_PARAM, "member")
+	if errorMap := request.Validate(&member); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return
+	}
+
+	if current := team.GetMember(member.UserId); current != nil && current.Role == model.TeamRoleAdmin &&
+		member.Role != model.TeamRoleAdmin && helper.CountAdmins(team) == 1 {
+		response.FailedValidationResponse(w, r, map[string]string{"role": "a team must have an admin"})
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"member": member.UserId, "role": member.Role})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateTeamMember(ctx, team.Id, &member); err != nil {
+		audit.Errorf("team member update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("team member set")
+	if err := response.JSON(w, http.StatusOK, member); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// RemoveTeamMember removes a member from a team. The last admin of a team
+// cannot be removed.
+func RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	team, audit, ok := getMemberTeam(w, r, model.EventActionRemoveMember, true)
+	if !ok {
+		return
+	}
+
+	userid := request.GetParameterValue(r, request.PATH_PARAM, "member")
+	if current := team.GetMember(userid); current != nil && current.Role == model.TeamRoleAdmin && helper.CountAdmins(team) == 1 {
+		response.FailedValidationResponse(w, r, map[string]string{"member": "a team must have an admin"})
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"member": userid})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.RemoveTeamMember(ctx, team.Id, userid); err != nil {
+		audit.Errorf("team member removal failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("team member removed")
+	if err := response.JSON(w, http.StatusNoContent, response.Envelope{}); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// SetTeamQuota replaces the quota of a team. It is restricted to admins.
+func SetTeamQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	role, _ := ctx.Value(rctx.ROLE).(string)
+	if role != helper.ADMIN_ROLE {
+		response.NotPermittedResponse(w, r)
+		return
+	}
+
+	team, audit, ok := getMemberTeam(w, r, model.EventActionSetQuota, true)
+	if !ok {
+		return
+	}
+
+	var quota model.TeamQuota
+	if err := request.ReadJSON(w, r, &quota); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if errorMap := request.Validate(&quota); errorMap != nil {
+		response.FailedValidationResponse(w, r, errorMap)
+		return
+	}
+
+	audit = audit.WithFields(logrus.Fields{"quota": quota})
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateTeamQuota(ctx, team.Id, &quota); err != nil {
+		audit.Errorf("team quota update failed - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.Infof("team quota set")
+	if err := response.JSON(w, http.StatusOK, quota); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/app/service-api/response"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/upgrade"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+var upgrader = upgrade.NewUpgrader(time.Duration(vars.ZBI_UPGRADE_HEALTH_INTERVAL)*time.Second,
+	time.Duration(vars.ZBI_UPGRADE_HEALTH_TIMEOUT)*time.Second)
+
+// GetImages returns the image versions available to each node type.
+func GetImages(w http.ResponseWriter, r *http.Request) {
+	if err := response.JSON(w, http.StatusOK, helper.GetImageCatalog()); err != nil {
+		response.ServerErrorResponse(w, r, r.Context(), err)
+	}
+}
+
+// GetInstanceImage returns the image version an instance is pinned to.
+func GetInstanceImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, _, ok := getOwnedInstance(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, http.StatusOK, helper.GetImagePin(ic, instance)); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// UpgradeInstance pins an instance to an image version and applies it.
+func UpgradeInstance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionUpgrade)
+	if !ok {
+		return
+	}
+
+	var upgradeRequest model.ImageUpgradeRequest
+	if err := request.ReadJSON(w, r, &upgradeRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if fieldErrors := request.Validate(&upgradeRequest); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	if !checkProjectUpgrade(w, r, instance) {
+		return
+	}
+
+	err := upgrade.UpgradeInstance(ctx, instance.Project, instance, upgradeRequest.Version)
+	writeInstanceImage(w, r, instance, err, model.EventActionUpgrade, audit)
+}
+
+// RollbackInstance returns an instance to the image version it ran before
+// its last upgrade.
+func RollbackInstance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instance, audit, ok := getOwnedInstance(w, r, model.EventActionRollback)
+	if !ok {
+		return
+	}
+
+	if !checkProjectUpgrade(w, r, instance) {
+		return
+	}
+
+	err := upgrade.RollbackInstance(ctx, instance.Project, instance)
+	writeInstanceImage(w, r, instance, err, model.EventActionRollback, audit)
+}
+
+// checkProjectUpgrade rejects changes to the image of an instance while a
+// rolling upgrade of its project is running.
+func checkProjectUpgrade(w http.ResponseWriter, r *http.Request, instance *model.Instance) bool {
+	if instance.Project != nil && upgrader.Active(instance.Project.Id) {
+		response.ConflictResponse(w, r, upgrade.ErrUpgradeActive)
+		return false
+	}
+	return true
+}
+
+func writeInstanceImage(w http.ResponseWriter, r *http.Request, instance *model.Instance, err error, action model.EventAction, audit *logrus.Entry) {
+	ctx := r.Context()
+
+	if errors.Is(err, upgrade.ErrUnknownVersion) || errors.Is(err, upgrade.ErrNoPreviousVersion) {
+		response.BadRequestResponse(w, r, err)
+		return
+	} else if err != nil {
+		audit.Errorf("instance image %s failed - %s", action, err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"image": instance.Image.Name, "version": instance.Image.Version}).Infof("instance image updated")
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err = repository.AddInstanceActivity(ctx, instance.Id, action); err != nil {
+		audit.Errorf("failed to add %s activity for instance %s - %s", action, instance.Id, err)
+	}
+
+	if err = response.JSON(w, http.StatusOK, instance); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// GetProjectUpgrade returns the state of the rolling upgrade of a project.
+func GetProjectUpgrade(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, _, ok := getOwnedProject(w, r, model.EventActionView)
+	if !ok {
+		return
+	}
+
+	if project.Upgrade == nil {
+		response.NotFoundResponse(w, r)
+		return
+	}
+
+	if err := response.JSON(w, http.StatusOK, project.Upgrade); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
+
+// StartProjectUpgrade starts a rolling upgrade of the instances of a type in
+// a project. It runs in the background and is followed with
+// GetProjectUpgrade.
+func StartProjectUpgrade(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionUpgrade)
+	if !ok {
+		return
+	}
+
+	var upgradeRequest model.ProjectUpgradeRequest
+	if err := request.ReadJSON(w, r, &upgradeRequest); err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if fieldErrors := request.Validate(&upgradeRequest); fieldErrors != nil {
+		response.FailedValidationResponse(w, r, fieldErrors)
+		return
+	}
+
+	if !upgrade.Finished(project.Upgrade) || upgrader.Active(project.Id) {
+		response.ConflictResponse(w, r, upgrade.ErrUpgradeActive)
+		return
+	}
+
+	ic, err := helper.GetBlockchainNodeInfo(ctx, upgradeRequest.Type)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instances, err := repository.GetInstances(ctx, project.Id)
+	if err != nil {
+		audit.Errorf("failed to retrieve instances - %s", err)
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	userid, _ := ctx.Value(rctx.USERID).(string)
+	plan, err := upgrade.Plan(ic, instances, &upgradeRequest, userid, time.Now())
+	if err != nil {
+		response.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = upgrader.Start(ctx, project, plan); err != nil {
+		writeUpgradeError(w, r, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"type": plan.Type, "version": plan.Version, "batches": len(plan.Batches)}).Infof("project upgrade started")
+	writeProjectUpgrade(w, r, project, model.EventActionUpgrade, http.StatusAccepted, audit)
+}
+
+// PauseProjectUpgrade pauses a rolling upgrade once its current batch has
+// passed its health gates.
+func PauseProjectUpgrade(w http.ResponseWriter, r *http.Request) {
+	project, audit, ok := getOwnedProject(w, r, model.EventActionPauseUpgrade)
+	if !ok {
+		return
+	}
+
+	if err := upgrader.Pause(project.Id); err != nil {
+		writeUpgradeError(w, r, err)
+		return
+	}
+
+	audit.Infof("project upgrade pause requested")
+	writeProjectUpgrade(w, r, project, model.EventActionPauseUpgrade, http.StatusAccepted, audit)
+}
+
+// ResumeProjectUpgrade resumes a paused rolling upgrade, retrying the
+// instances that failed.
+func ResumeProjectUpgrade(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionResumeUpgrade)
+	if !ok {
+		return
+	}
+
+	if err := upgrader.Resume(ctx, project); err != nil {
+		writeUpgradeError(w, r, err)
+		return
+	}
+
+	audit.Infof("project upgrade resumed")
+	writeProjectUpgrade(w, r, project, model.EventActionResumeUpgrade, http.StatusAccepted, audit)
+}
+
+// RollbackProjectUpgrade returns the instances upgraded by the rolling
+// upgrade of a project to their previous version.
+func RollbackProjectUpgrade(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	project, audit, ok := getOwnedProject(w, r, model.EventActionRollback)
+	if !ok {
+		return
+	}
+
+	if err := upgrader.Rollback(ctx, project); err != nil {
+		writeUpgradeError(w, r, err)
+		return
+	}
+
+	audit.WithFields(logrus.Fields{"status": project.Upgrade.Status}).Infof("project upgrade rolled back")
+	writeProjectUpgrade(w, r, project, model.EventActionRollback, http.StatusOK, audit)
+}
+
+func writeUpgradeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, upgrade.ErrNoUpgrade):
+		response.NotFoundResponse(w, r)
+	case errors.Is(err, upgrade.ErrUpgradeActive), errors.Is(err, upgrade.ErrUpgradeNotRunning), errors.Is(err, upgrade.ErrUpgradeFinished):
+		response.ConflictResponse(w, r, err)
+	default:
+		response.ServerErrorResponse(w, r, r.Context(), err)
+	}
+}
+
+// writeProjectUpgrade writes the saved state of the upgrade of a project,
+// since the state held by the upgrader may be changing.
+func writeProjectUpgrade(w http.ResponseWriter, r *http.Request, project *model.Project, action model.EventAction, status int, audit *logrus.Entry) {
+	ctx := r.Context()
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.AddProjectActivity(ctx, project.Id, action); err != nil {
+		audit.Errorf("failed to add %s activity for project %s - %s", action, project.Id, err)
+	}
+
+	saved, err := repository.GetProject(ctx, project.Id)
+	if err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+		return
+	}
+
+	if err = response.JSON(w, status, saved.Upgrade); err != nil {
+		response.ServerErrorResponse(w, r, ctx, err)
+	}
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zbitech/controller/app/service-api/request"
+	"github.com/zbitech/controller/internal/authz"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// validateInstanceRequest runs the request checks and verifies that peers
+// are instances of the same project. Lightwallet instances must be paired
+// with a zcash instance, and endpoint policies must name known groups.
+func validateInstanceRequest(ctx context.Context, project *model.Project, instanceId string, instanceRequest *model.InstanceRequest) (map[string]string, error) {
+
+	fieldErrors := request.ValidateInstanceRequest(instanceRequest)
+	if fieldErrors == nil {
+		fieldErrors = make(map[string]string)
+	}
+
+	if _, ok := fieldErrors["peers"]; !ok {
+		repository := vars.RepositoryFactory.GetRepositoryService()
+		for _, peerId := range instanceRequest.Peers {
+			if peerId == instanceId {
+				fieldErrors["peers"] = "instance cannot be its own peer"
+				break
+			}
+
+			peer, err := repository.GetInstance(ctx, peerId)
+			if err != nil {
+				if errs.Code(err).Category == errs.CategoryNotFound {
+					fieldErrors["peers"] = "peer " + peerId + " does not exist"
+					break
+				}
+				return nil, err
+			}
+
+			if project == nil || peer.Project == nil || peer.Project.Id != project.Id {
+				fieldErrors["peers"] = "peer " + peerId + " does not belong to the same project"
+				break
+			}
+
+			if instanceRequest.Type == model.InstanceTypeLWD && instanceType(peer) != model.InstanceTypeZCASH {
+				fieldErrors["peers"] = "lightwallet instances must be paired with a zcash instance"
+				break
+			}
+		}
+	}
+
+	// regtest nodes have no public network to join
+	key := "properties." + request.PUBLIC_P2P_PROPERTY
+	if publicP2P, _ := instanceRequest.Properties[request.PUBLIC_P2P_PROPERTY].(bool); publicP2P {
+		if _, ok := fieldErrors[key]; !ok && (project == nil || (model.NetworkType(project.Network) != model.NetworkTypeMain && model.NetworkType(project.Network) != model.NetworkTypeTest)) {
+			fieldErrors[key] = "is only supported on mainnet and testnet"
+		}
+	}
+
+	// the endpoint policy is enforced by the ext-authz check, which only runs
+	// when access authorization is enabled
+	key = "properties." + request.ENDPOINTS_PROPERTY
+	if _, ok := fieldErrors[key]; !ok && instanceRequest.Properties[request.ENDPOINTS_PROPERTY] != nil {
+		var policy model.EndpointPolicy
+		if !helper.GetPolicyInfo(ctx).EnvoyConfig.AccessAuthorization {
+			fieldErrors[key] = "requires access authorization to be enabled"
+		} else if err := utils.UnMarshalObject(utils.MarshalObject(instanceRequest.Properties[request.ENDPOINTS_PROPERTY]), &policy); err != nil {
+			fieldErrors[key] = "must be an object with allow and deny"
+		} else {
+			node, err := getRequestNodeInfo(ctx, project, instanceRequest.Type)
+			if err != nil {
+				return nil, err
+			}
+
+			if invalid := authz.ValidateEndpointPolicy(&policy, node.Endpoints); len(invalid) > 0 {
+				fieldErrors[key] = "unknown endpoint groups or methods: " + strings.Join(invalid, ", ")
+			}
+		}
+	}
+
+	if instanceRequest.Mining != nil && instanceRequest.Mining.MinerAddress != "" {
+		if _, ok := fieldErrors["mining.minerAddress"]; !ok {
+			network := model.NetworkTypeTest
+			if project != nil && project.Network != "" {
+				network = model.NetworkType(project.Network)
+			}
+			if err := helper.ValidateZcashAddress(network, instanceRequest.Mining.MinerAddress); err != nil {
+				fieldErrors["mining.minerAddress"] = err.Error()
+			}
+		}
+	}
+
+	if instanceRequest.Volume.Source == model.SeedDataSource {
+		if _, ok := fieldErrors["volume.ref"]; !ok {
+			repository := vars.RepositoryFactory.GetRepositoryService()
+			seed, err := repository.GetSeed(ctx, instanceRequest.Volume.Ref)
+			if err != nil {
+				if errs.Code(err).Category != errs.CategoryNotFound {
+					return nil, err
+				}
+				fieldErrors["volume.ref"] = "seed " + instanceRequest.Volume.Ref + " does not exist"
+			} else if project == nil || seed.Blockchain != project.Blockchain || string(seed.Network) != project.Network {
+				fieldErrors["volume.ref"] = "seed " + seed.Name + " is not a seed of the project network"
+			} else if !helper.SeedFits(seed, instanceRequest.Volume.Size) {
+				fieldErrors["volume.size"] = "must be at least " + seed.Size
+			}
+		}
+	}
+
+	// config overrides are replaced with their normalized values
+	if _, ok := fieldErrors["type"]; !ok && len(instanceRequest.Config) > 0 {
+		node, err := getRequestNodeInfo(ctx, project, instanceRequest.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		config, configErrors := helper.ValidateConfigOverrides(instanceRequest.Type, node.Config, instanceRequest.Config)
+		for key, message := range configErrors {
+			fieldErrors[key] = message
+		}
+		if len(configErrors) == 0 {
+			instanceRequest.Config = config
+		}
+	}
+
+	if len(fieldErrors) == 0 {
+		return nil, nil
+	}
+	return fieldErrors, nil
+}
+
+// getRequestNodeInfo returns the node info of an instance type on the
+// blockchain of a project.
+func getRequestNodeInfo(ctx context.Context, project *model.Project, iType model.InstanceType) (*model.BlockchainNodeInfo, error) {
+	blockchain := "zcash"
+	if project != nil && project.Blockchain != "" {
+		blockchain = project.Blockchain
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	return repository.GetBlockchainNodeInfo(ctx, blockchain, string(iType))
+}
+
+// applyInstanceUpdate copies immutable fields from the instance into an
+// update request and reports attempts to change them.
+func applyInstanceUpdate(instance *model.Instance, instanceRequest *model.InstanceRequest) map[string]string {
+	fieldErrors := make(map[string]string)
+
+	if instanceRequest.Name == "" {
+		instanceRequest.Name = instance.Name
+	} else if instanceRequest.Name != instance.Name {
+		fieldErrors["name"] = "cannot be changed"
+	}
+
+	if instanceRequest.Type == "" {
+		instanceRequest.Type = instanceType(instance)
+	} else if instanceRequest.Type != instanceType(instance) {
+		fieldErrors["type"] = "cannot be changed"
+	}
+
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return fieldErrors
+}
+
+// instanceType returns the instance type, which older records only carry in Type.
+func instanceType(instance *model.Instance) model.InstanceType {
+	if instance.InstanceType != "" {
+		return instance.InstanceType
+	}
+	return instance.Type
+}
@@ -3,15 +3,39 @@ package request
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/schedule"
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/model"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func Validate(input interface{}) map[string]string {
-	validate := validator.New()
+const (
+	MINER_PROPERTY             = "miner"
+	TRANSACTION_INDEX_PROPERTY = "transactionIndex"
+	ZCASH_INSTANCE_PROPERTY    = "zcashInstance"
+	LOG_LEVEL_PROPERTY         = "logLevel"
+	ROTATION_PROPERTY          = "rotation"
+	ENDPOINTS_PROPERTY         = "endpoints"
+	PUBLIC_P2P_PROPERTY        = "publicP2P"
+)
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// labelName matches Kubernetes label names and non-empty label values
+var labelName = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
 
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
 		if name == "-" {
 			return ""
@@ -19,13 +43,48 @@ func Validate(input interface{}) map[string]string {
 		return name
 	})
 
+	// dnslabel accepts RFC 1123 labels, which are used for namespaces and resource names
+	_ = v.RegisterValidation("dnslabel", func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		return len(value) <= 63 && dnsLabel.MatchString(value)
+	})
+
+	// quantity accepts Kubernetes resource quantities such as 10Gi
+	_ = v.RegisterValidation("quantity", func(fl validator.FieldLevel) bool {
+		q, err := resource.ParseQuantity(fl.Field().String())
+		return err == nil && q.Sign() > 0
+	})
+
+	// duration accepts durations such as 12h or 30d
+	_ = v.RegisterValidation("duration", func(fl validator.FieldLevel) bool {
+		_, err := utils.ParseDuration(fl.Field().String())
+		return err == nil
+	})
+
+	// cron accepts five field cron expressions
+	_ = v.RegisterValidation("cron", func(fl validator.FieldLevel) bool {
+		_, err := schedule.ParseCron(fl.Field().String())
+		return err == nil
+	})
+
+	// clock accepts times of day such as 08:30
+	_ = v.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		_, err := schedule.ParseClock(fl.Field().String())
+		return err == nil
+	})
+
+	return v
+}
+
+func Validate(input interface{}) map[string]string {
+
 	if err := validate.Struct(input); err != nil {
 		errs := err.(validator.ValidationErrors)
 		errorMap := make(map[string]string)
 
 		for _, fieldError := range errs {
 
-			key := fieldError.Field()
+			key := fieldName(fieldError)
 			switch {
 			case fieldError.Tag() == "required":
 				errorMap[key] = "must be provided"
@@ -36,7 +95,7 @@ func Validate(input interface{}) map[string]string {
 			case fieldError.Tag() == "lt":
 				errorMap[key] = fmt.Sprintf("must be less than %s", fieldError.Param())
 			case fieldError.Tag() == "oneof":
-				errorMap[key] = fmt.Sprintf("must be one of than %s", fieldError.Param())
+				errorMap[key] = fmt.Sprintf("must be one of %s", fieldError.Param())
 			case fieldError.Tag() == "max":
 				errorMap[key] = fmt.Sprintf("length must not be more than %s", fieldError.Param())
 			case fieldError.Tag() == "min":
@@ -45,6 +104,24 @@ func Validate(input interface{}) map[string]string {
 				errorMap[key] = "must be a valid email"
 			case fieldError.Tag() == "required_with":
 				errorMap[key] = fmt.Sprintf("must be provided with %s", fieldError.Param())
+			case fieldError.Tag() == "required_if":
+				errorMap[key] = fmt.Sprintf("must be provided when %s", fieldError.Param())
+			case fieldError.Tag() == "dnslabel":
+				errorMap[key] = "must be a lowercase RFC 1123 label of at most 63 characters"
+			case fieldError.Tag() == "quantity":
+				errorMap[key] = "must be a positive quantity such as 10Gi"
+			case fieldError.Tag() == "duration":
+				errorMap[key] = "must be a duration such as 30d or 12h"
+			case fieldError.Tag() == "fqdn":
+				errorMap[key] = "must be a fully qualified domain name"
+			case fieldError.Tag() == "timezone":
+				errorMap[key] = "must be an IANA time zone such as Europe/Berlin"
+			case fieldError.Tag() == "cron":
+				errorMap[key] = "must be a five field cron expression"
+			case fieldError.Tag() == "clock":
+				errorMap[key] = "must be a time of day such as 08:30"
+			case fieldError.Tag() == "cidr|ip":
+				errorMap[key] = "must be an IP address or CIDR range"
 			default:
 				errorMap[key] = fmt.Sprintf(fieldError.Error())
 			}
@@ -55,3 +132,297 @@ func Validate(input interface{}) map[string]string {
 
 	return nil
 }
+
+// fieldName returns the json path of the field without the struct name,
+// e.g. volume.size.
+func fieldName(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+	if index := strings.Index(namespace, "."); index >= 0 {
+		return namespace[index+1:]
+	}
+	return fieldError.Field()
+}
+
+// ValidateProject checks a project create request.
+func ValidateProject(project *model.Project) map[string]string {
+	errorMap := Validate(project)
+	if errorMap == nil {
+		errorMap = make(map[string]string)
+	}
+
+	validateLabels(project.Labels, errorMap)
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+// ValidateProjectUpdate checks a project update request against the stored
+// project. The name, blockchain, network, owner and team of a project are
+// fixed when it is created.
+func ValidateProjectUpdate(request *model.ProjectUpdateRequest, project *model.Project) map[string]string {
+	errorMap := Validate(request)
+	if errorMap == nil {
+		errorMap = make(map[string]string)
+	}
+
+	fixed := []struct{ key, value, current string }{
+		{"name", request.Name, project.Name},
+		{"blockchain", request.Blockchain, project.Blockchain},
+		{"network", request.Network, project.Network},
+		{"owner", request.Owner, project.Owner},
+		{"team", request.TeamId, project.TeamId},
+	}
+	for _, field := range fixed {
+		if len(field.value) > 0 && field.value != field.current {
+			errorMap[field.key] = "cannot be changed after the project is created"
+		}
+	}
+
+	validateLabels(request.Labels, errorMap)
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+// validateLabels checks that user labels are valid Kubernetes labels that do
+// not override the platform labels.
+func validateLabels(labels map[string]string, errorMap map[string]string) {
+	for key, value := range labels {
+		field := "labels." + key
+		switch {
+		case len(key) > 63 || !labelName.MatchString(key):
+			errorMap[field] = "name must be a Kubernetes label name of at most 63 characters"
+		case helper.IsReservedLabel(key):
+			errorMap[field] = "is reserved by the platform"
+		case len(value) > 63 || (len(value) > 0 && !labelName.MatchString(value)):
+			errorMap[field] = "value must be a Kubernetes label value of at most 63 characters"
+		}
+	}
+}
+
+// ValidatePowerSchedule checks a power schedule, which needs either cron
+// expressions or windows.
+func ValidatePowerSchedule(powerSchedule *model.PowerSchedule) map[string]string {
+	if errorMap := Validate(powerSchedule); errorMap != nil {
+		return errorMap
+	}
+
+	if _, err := schedule.NewTimetable(powerSchedule); err != nil {
+		return map[string]string{"schedule": err.Error()}
+	}
+	return nil
+}
+
+// ValidateDomainRequest checks a custom domain request. The host must not be
+// a subdomain of the platform domain, and an uploaded certificate must match
+// its key and be valid for the host.
+func ValidateDomainRequest(request *model.DomainRequest, platformDomain string, now time.Time) map[string]string {
+	errorMap := Validate(request)
+	if errorMap == nil {
+		errorMap = make(map[string]string)
+	}
+
+	host := strings.ToLower(request.Host)
+	platformDomain = strings.ToLower(platformDomain)
+	if _, ok := errorMap["host"]; !ok && len(platformDomain) > 0 {
+		if host == platformDomain || strings.HasSuffix(host, "."+platformDomain) {
+			errorMap["host"] = fmt.Sprintf("must not be a subdomain of %s", platformDomain)
+		}
+	}
+
+	if request.TLS == model.DomainTLSUploaded && len(errorMap) == 0 {
+		cert, err := helper.ParseCertificate(request.Certificate, request.PrivateKey)
+		if err != nil {
+			errorMap["certificate"] = fmt.Sprintf("must be a PEM certificate matching the private key - %s", err)
+		} else if err = helper.VerifyCertificateHost(cert, host, now); err != nil {
+			errorMap["certificate"] = err.Error()
+		}
+	}
+
+	if len(errorMap) > 0 {
+		return errorMap
+	}
+	return nil
+}
+
+// ValidateSeedRequest checks a seed request. A seed holds either a snapshot
+// or an archive, matching its type.
+func ValidateSeedRequest(request *model.SeedRequest) map[string]string {
+	errorMap := Validate(request)
+	if errorMap == nil {
+		errorMap = make(map[string]string)
+	}
+
+	if request.Type != model.SeedTypeSnapshot && request.Snapshot != nil {
+		errorMap["snapshot"] = fmt.Sprintf("must not be provided when type is %s", request.Type)
+	}
+	if request.Type != model.SeedTypeArchive && request.Archive != nil {
+		errorMap["archive"] = fmt.Sprintf("must not be provided when type is %s", request.Type)
+	}
+
+	if len(errorMap) > 0 {
+		return errorMap
+	}
+	return nil
+}
+
+// ValidateInstanceRequest checks an instance create or update request,
+// including rules that span several fields.
+func ValidateInstanceRequest(request *model.InstanceRequest) map[string]string {
+	errorMap := Validate(request)
+	if errorMap == nil {
+		errorMap = make(map[string]string)
+	}
+
+	if request.Type == model.InstanceTypeLWD && len(request.Peers) != 1 {
+		errorMap["peers"] = "lightwallet instances require exactly one zcash peer"
+	}
+
+	switch request.Volume.Source {
+	case model.VolumeDataSource, model.SnapshotDataSource, model.SeedDataSource:
+		if request.Volume.Ref == "" {
+			errorMap["volume.ref"] = fmt.Sprintf("must be provided when volume.source is %s", request.Volume.Source)
+		}
+		if request.Volume.Type == model.EphemeralDataVolume {
+			errorMap["volume.type"] = fmt.Sprintf("must be pvc when volume.source is %s", request.Volume.Source)
+		}
+	}
+	if request.Volume.Source == model.SeedDataSource && request.Type != model.InstanceTypeZCASH {
+		errorMap["volume.source"] = "seed is only supported for zcash instances"
+	}
+
+	validateProperties(request, errorMap)
+	validateMining(request, errorMap)
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+func validateProperties(request *model.InstanceRequest, errorMap map[string]string) {
+	for name, value := range request.Properties {
+		key := "properties." + name
+		switch name {
+		case MINER_PROPERTY:
+			if _, ok := value.(bool); !ok {
+				errorMap[key] = "must be a boolean"
+			} else if request.Type != model.InstanceTypeZCASH {
+				errorMap[key] = "is only supported for zcash instances"
+			}
+		case PUBLIC_P2P_PROPERTY:
+			if _, ok := value.(bool); !ok {
+				errorMap[key] = "must be a boolean"
+			} else if request.Type != model.InstanceTypeZCASH {
+				errorMap[key] = "is only supported for zcash instances"
+			}
+		case TRANSACTION_INDEX_PROPERTY:
+			if _, ok := value.(bool); !ok {
+				errorMap[key] = "must be a boolean"
+			}
+		case ZCASH_INSTANCE_PROPERTY:
+			if _, ok := value.(string); !ok {
+				errorMap[key] = "must be a string"
+			}
+		case LOG_LEVEL_PROPERTY:
+			if number, ok := value.(float64); !ok || number < 0 || number != float64(int(number)) {
+				errorMap[key] = "must be a non-negative integer"
+			}
+		case ROTATION_PROPERTY:
+			validateRotationProperty(key, value, request.Type, errorMap)
+		case ENDPOINTS_PROPERTY:
+			validateEndpointsProperty(key, value, request.Type, errorMap)
+		}
+	}
+}
+
+// validateMining checks the mining options of a request. The miner address
+// is checked against the project network by the handlers.
+func validateMining(request *model.InstanceRequest, errorMap map[string]string) {
+	if request.Mining == nil {
+		return
+	}
+
+	if request.Type != model.InstanceTypeZCASH {
+		errorMap["mining"] = "is only supported for zcash instances"
+		return
+	}
+
+	if miner, _ := request.Properties[MINER_PROPERTY].(bool); !miner {
+		if _, ok := errorMap["properties."+MINER_PROPERTY]; !ok {
+			errorMap["mining"] = "requires the miner property"
+		}
+		return
+	}
+
+	if request.Mining.MineToLocalWallet != nil && !*request.Mining.MineToLocalWallet && request.Mining.MinerAddress == "" {
+		errorMap["mining.minerAddress"] = "must be provided when mineToLocalWallet is false"
+	}
+}
+
+// validateRotationProperty checks a credentials rotation policy set on an
+// instance, e.g. {"interval": "30d", "gracePeriod": "24h"}.
+func validateRotationProperty(key string, value interface{}, instanceType model.InstanceType, errorMap map[string]string) {
+	properties, ok := value.(map[string]interface{})
+	if !ok {
+		errorMap[key] = "must be an object with interval and gracePeriod"
+		return
+	}
+
+	if instanceType != model.InstanceTypeZCASH {
+		errorMap[key] = "is only supported for zcash instances"
+		return
+	}
+
+	for name, item := range properties {
+		switch name {
+		case "interval", "gracePeriod":
+			if str, ok := item.(string); !ok {
+				errorMap[key+"."+name] = "must be a duration such as 30d or 12h"
+			} else if _, err := utils.ParseDuration(str); err != nil {
+				errorMap[key+"."+name] = "must be a duration such as 30d or 12h"
+			}
+		default:
+			errorMap[key+"."+name] = "is not a supported rotation setting"
+		}
+	}
+}
+
+// validateEndpointsProperty checks the shape of an endpoint policy, e.g.
+// {"allow": ["blockchain", "control"], "deny": ["control.stop"]}. Group names
+// are checked against the blockchain node info by the caller.
+func validateEndpointsProperty(key string, value interface{}, instanceType model.InstanceType, errorMap map[string]string) {
+	properties, ok := value.(map[string]interface{})
+	if !ok {
+		errorMap[key] = "must be an object with allow and deny"
+		return
+	}
+
+	if instanceType != model.InstanceTypeZCASH {
+		errorMap[key] = "is only supported for zcash instances"
+		return
+	}
+
+	for name, item := range properties {
+		switch name {
+		case "allow", "deny":
+			entries, ok := item.([]interface{})
+			if !ok {
+				errorMap[key+"."+name] = "must be a list of endpoint groups or methods"
+				continue
+			}
+			for _, entry := range entries {
+				if str, ok := entry.(string); !ok || str == "" {
+					errorMap[key+"."+name] = "must be a list of endpoint groups or methods"
+					break
+				}
+			}
+		default:
+			errorMap[key+"."+name] = "is not a supported endpoint setting"
+		}
+	}
+}
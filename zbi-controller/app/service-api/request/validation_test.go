@@ -0,0 +1,231 @@
+package request
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func newInstanceRequest(t *testing.T, content string) *model.InstanceRequest {
+	var request model.InstanceRequest
+	assert.NoError(t, json.Unmarshal([]byte(content), &request))
+	return &request
+}
+
+func TestValidateProject(t *testing.T) {
+	project := &model.Project{Name: "project-1", Blockchain: "zcash", Network: "testnet"}
+	assert.Nil(t, ValidateProject(project))
+
+	project.Rotation = &model.RotationPolicy{Interval: "30d", GracePeriod: "12h"}
+	assert.Nil(t, ValidateProject(project))
+
+	project.Rotation = &model.RotationPolicy{Interval: "monthly"}
+	assert.Equal(t, "must be a duration such as 30d or 12h", ValidateProject(project)["rotation.interval"])
+
+	project = &model.Project{Name: "Project_1", Blockchain: "bitcoin", Network: "regtest"}
+	errorMap := ValidateProject(project)
+	assert.Contains(t, errorMap, "name")
+	assert.Contains(t, errorMap, "blockchain")
+	assert.Contains(t, errorMap, "network")
+}
+
+func TestValidateProjectUpdate(t *testing.T) {
+	project := &model.Project{Name: "project-1", Blockchain: "zcash", Network: "testnet", Owner: "alice"}
+	description := "updated"
+
+	update := &model.ProjectUpdateRequest{Name: "project-1", Description: &description,
+		Labels: map[string]string{"env": "dev", "tier": ""}}
+	assert.Nil(t, ValidateProjectUpdate(update, project))
+
+	update = &model.ProjectUpdateRequest{Network: "mainnet", Blockchain: "bitcoin", Owner: "bob",
+		Labels: map[string]string{"platform": "other", "bad key": "x", "env": "no spaces"}}
+	errorMap := ValidateProjectUpdate(update, project)
+	assert.Contains(t, errorMap, "network")
+	assert.Contains(t, errorMap, "blockchain")
+	assert.Contains(t, errorMap, "owner")
+	assert.Equal(t, "is reserved by the platform", errorMap["labels.platform"])
+	assert.Contains(t, errorMap, "labels.bad key")
+	assert.Contains(t, errorMap, "labels.env")
+
+	project.Labels = map[string]string{"id": "1"}
+	assert.Contains(t, ValidateProject(project), "labels.id")
+}
+
+func TestValidatePowerSchedule(t *testing.T) {
+	assert.Nil(t, ValidatePowerSchedule(&model.PowerSchedule{Timezone: "Europe/Berlin", StartCron: "0 8 * * 1-5", StopCron: "0 18 * * 1-5"}))
+	assert.Nil(t, ValidatePowerSchedule(&model.PowerSchedule{Windows: []model.ScheduleWindow{{Days: []string{"mon", "tue"}, Start: "08:00", End: "18:00"}}}))
+
+	errorMap := ValidatePowerSchedule(&model.PowerSchedule{Timezone: "Nowhere", StartCron: "0 8 * *",
+		Windows: []model.ScheduleWindow{{Days: []string{"monday"}, Start: "8am", End: "18:00"}}})
+	assert.Equal(t, "must be an IANA time zone such as Europe/Berlin", errorMap["timezone"])
+	assert.Equal(t, "must be a five field cron expression", errorMap["startCron"])
+	assert.Contains(t, errorMap, "windows[0].days[0]")
+	assert.Equal(t, "must be a time of day such as 08:30", errorMap["windows[0].start"])
+
+	assert.Contains(t, ValidatePowerSchedule(&model.PowerSchedule{}), "schedule")
+}
+
+func TestValidateInstanceRequest(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"miner": true},
+		"volume": {"type": "pvc", "size": "10Gi", "source": "new"}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "properties": {"logLevel": 5}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+}
+
+func TestValidateInstanceRequest_Fields(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash.1", "type": "bitcoin", "volume": {"type": "pvc"}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Contains(t, errorMap, "name")
+	assert.Contains(t, errorMap, "type")
+	assert.Equal(t, "must be provided when Type pvc", errorMap["volume.size"])
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "volume": {"type": "pvc", "size": "-1Gi"}}`)
+	assert.Contains(t, ValidateInstanceRequest(request), "volume.size")
+}
+
+func TestValidateInstanceRequest_Rules(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd"}`)
+	assert.Contains(t, ValidateInstanceRequest(request), "peers")
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "volume": {"type": "ephemeral", "source": "snapshot"}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Contains(t, errorMap, "volume.ref")
+	assert.Contains(t, errorMap, "volume.type")
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "volume": {"type": "pvc", "size": "10Gi", "source": "seed"}}`)
+	errorMap = ValidateInstanceRequest(request)
+	assert.Contains(t, errorMap, "volume.ref")
+	assert.Equal(t, "seed is only supported for zcash instances", errorMap["volume.source"])
+}
+
+func TestValidateInstanceRequest_Properties(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash",
+		"properties": {"miner": "yes", "transactionIndex": 1, "zcashInstance": false, "logLevel": 1.5}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Equal(t, "must be a boolean", errorMap["properties.miner"])
+	assert.Equal(t, "must be a boolean", errorMap["properties.transactionIndex"])
+	assert.Equal(t, "must be a string", errorMap["properties.zcashInstance"])
+	assert.Equal(t, "must be a non-negative integer", errorMap["properties.logLevel"])
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "properties": {"miner": true}}`)
+	assert.Equal(t, "is only supported for zcash instances", ValidateInstanceRequest(request)["properties.miner"])
+}
+
+func TestValidateInstanceRequest_PublicP2P(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"publicP2P": true}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"publicP2P": "yes"}}`)
+	assert.Equal(t, "must be a boolean", ValidateInstanceRequest(request)["properties.publicP2P"])
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "properties": {"publicP2P": true}}`)
+	assert.Equal(t, "is only supported for zcash instances", ValidateInstanceRequest(request)["properties.publicP2P"])
+}
+
+func TestValidateInstanceRequest_Rotation(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"rotation": {"interval": "30d", "gracePeriod": "24h"}}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"rotation": {"interval": 30, "grace": "1h"}}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Equal(t, "must be a duration such as 30d or 12h", errorMap["properties.rotation.interval"])
+	assert.Equal(t, "is not a supported rotation setting", errorMap["properties.rotation.grace"])
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "properties": {"rotation": {"interval": "30d"}}}`)
+	assert.Equal(t, "is only supported for zcash instances", ValidateInstanceRequest(request)["properties.rotation"])
+}
+
+func TestValidateInstanceRequest_Endpoints(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"endpoints": {"allow": ["blockchain"], "deny": ["control.stop"]}}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"endpoints": {"allow": "wallet", "deny": [1], "block": []}}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Equal(t, "must be a list of endpoint groups or methods", errorMap["properties.endpoints.allow"])
+	assert.Equal(t, "must be a list of endpoint groups or methods", errorMap["properties.endpoints.deny"])
+	assert.Equal(t, "is not a supported endpoint setting", errorMap["properties.endpoints.block"])
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "properties": {"endpoints": {"allow": ["blockchain"]}}}`)
+	assert.Equal(t, "is only supported for zcash instances", ValidateInstanceRequest(request)["properties.endpoints"])
+}
+
+func TestValidateInstanceRequest_Mining(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"miner": true},
+		"mining": {"minerAddress": "tm9ogR9KukTCiTKvrsSxQwFv2x1vhZTydav", "genProcLimit": 2, "solver": "tromp", "mineToLocalWallet": false}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "properties": {"miner": true},
+		"mining": {"genProcLimit": 100, "solver": "fast", "mineToLocalWallet": false}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Contains(t, errorMap, "mining.genProcLimit")
+	assert.Contains(t, errorMap, "mining.solver")
+	assert.Equal(t, "must be provided when mineToLocalWallet is false", errorMap["mining.minerAddress"])
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "mining": {"genProcLimit": 1}}`)
+	assert.Equal(t, "requires the miner property", ValidateInstanceRequest(request)["mining"])
+
+	request = newInstanceRequest(t, `{"name": "lwd-1", "type": "lwd", "peers": ["zcash-1"], "mining": {"genProcLimit": 1}}`)
+	assert.Equal(t, "is only supported for zcash instances", ValidateInstanceRequest(request)["mining"])
+}
+
+func TestValidateInstanceRequest_RateLimit(t *testing.T) {
+	request := newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "rateLimit": {"requestsPerSecond": 10, "burst": 20}, "keyRateLimit": {"dailyQuota": 1000}}`)
+	assert.Nil(t, ValidateInstanceRequest(request))
+
+	request = newInstanceRequest(t, `{"name": "zcash-1", "type": "zcash", "rateLimit": {"requestsPerSecond": -1}, "keyRateLimit": {"dailyQuota": -5}}`)
+	errorMap := ValidateInstanceRequest(request)
+	assert.Contains(t, errorMap, "rateLimit.requestsPerSecond")
+	assert.Contains(t, errorMap, "keyRateLimit.dailyQuota")
+}
+
+func TestValidateInstanceRequest_IPAllowList(t *testing.T) {
+	req := newInstanceRequest(t, `{"name":"node","type":"zcash","ipAllowList":["203.0.113.7","198.51.100.0/24","2001:db8::/32"]}`)
+	assert.Nil(t, ValidateInstanceRequest(req))
+
+	req = newInstanceRequest(t, `{"name":"node","type":"zcash","ipAllowList":["203.0.113.7","example.com"]}`)
+	assert.Equal(t, map[string]string{"ipAllowList[1]": "must be an IP address or CIDR range"}, ValidateInstanceRequest(req))
+
+	assert.Contains(t, Validate(&model.IPAllowListRequest{IPAllowList: []string{"10.0.0.0/33"}}), "ipAllowList[0]")
+}
+
+func TestValidateDomainRequest(t *testing.T) {
+	now := time.Now()
+
+	errorMap := ValidateDomainRequest(&model.DomainRequest{Host: "rpc.example.com", TLS: model.DomainTLSCertManager}, "zbitech.net", now)
+	assert.Nil(t, errorMap)
+
+	errorMap = ValidateDomainRequest(&model.DomainRequest{Host: "node.ZBITECH.net", TLS: model.DomainTLSCertManager}, "zbitech.net", now)
+	assert.Contains(t, errorMap, "host")
+
+	errorMap = ValidateDomainRequest(&model.DomainRequest{Host: "not a host", TLS: "letsencrypt"}, "zbitech.net", now)
+	assert.Contains(t, errorMap, "host")
+	assert.Contains(t, errorMap, "tls")
+
+	errorMap = ValidateDomainRequest(&model.DomainRequest{Host: "rpc.example.com", TLS: model.DomainTLSUploaded}, "zbitech.net", now)
+	assert.Contains(t, errorMap, "certificate")
+
+	errorMap = ValidateDomainRequest(&model.DomainRequest{Host: "rpc.example.com", TLS: model.DomainTLSUploaded,
+		Certificate: "invalid", PrivateKey: "invalid"}, "zbitech.net", now)
+	assert.Contains(t, errorMap, "certificate")
+}
+
+func TestValidateSeedRequest(t *testing.T) {
+	seedRequest := &model.SeedRequest{Name: "sapling", Network: model.NetworkTypeTest, Type: model.SeedTypeArchive,
+		Archive: &model.SeedArchive{Url: "https://seeds.example.com/testnet.tar.gz", Sha256: strings.Repeat("ab", 32)}}
+	assert.Nil(t, ValidateSeedRequest(seedRequest))
+
+	seedRequest.Snapshot = &model.SeedSnapshot{Handle: "snap-1", Driver: "ebs.csi.aws.com"}
+	assert.Contains(t, ValidateSeedRequest(seedRequest), "snapshot")
+
+	seedRequest = &model.SeedRequest{Name: "sapling", Network: model.NetworkTypeMain, Type: model.SeedTypeSnapshot, Size: "40Gi"}
+	assert.Contains(t, ValidateSeedRequest(seedRequest), "snapshot")
+
+	seedRequest.Snapshot = &model.SeedSnapshot{Handle: "snap-1", Driver: "ebs.csi.aws.com"}
+	assert.Nil(t, ValidateSeedRequest(seedRequest))
+}
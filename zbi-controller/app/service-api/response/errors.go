@@ -4,6 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/rctx"
 )
 
 func Error(w http.ResponseWriter, status int, message interface{}) {
@@ -13,9 +18,29 @@ func Error(w http.ResponseWriter, status int, message interface{}) {
 	}
 }
 
+// ServerErrorResponse maps err to an HTTP status using its error code and
+// writes the code, a client safe message and the request txid.
 func ServerErrorResponse(w http.ResponseWriter, r *http.Request, ctx context.Context, err error) {
-	//logger.Errorf(ctx, "Server error - method: %s, url: %s, error: %s", r.Method, r.URL.String(), err)
-	Error(w, http.StatusInternalServerError, "Internal server error")
+	code := errs.Code(err)
+	status := errs.HTTPStatus(code.Category)
+
+	log := logger.GetLogger(ctx)
+	log.WithFields(logrus.Fields{"method": r.Method, "url": r.URL.String(), "code": code.Code, "status": status}).Errorf("request failed - %s", err)
+
+	txid, _ := ctx.Value(rctx.TXID).(string)
+	if err := JSON(w, status, Envelope{"success": false, "error": errs.Message(err), "code": code.Code, "txid": txid}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// CodeErrorResponse writes message with the code, its HTTP status and the
+// request txid.
+func CodeErrorResponse(w http.ResponseWriter, r *http.Request, code errs.ErrorCode, message string) {
+	txid, _ := r.Context().Value(rctx.TXID).(string)
+	err := JSON(w, errs.HTTPStatus(code.Category), Envelope{"success": false, "error": message, "code": code.Code, "txid": txid})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
 func NotFoundResponse(w http.ResponseWriter, r *http.Request) {
@@ -31,8 +56,10 @@ func BadRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
 	Error(w, http.StatusBadRequest, err.Error())
 }
 
-func FailedValidationResponse(w http.ResponseWriter, r *http.Request, errs map[string]string) {
-	err := JSON(w, http.StatusBadRequest, Envelope{"fieldErrors": errs})
+func FailedValidationResponse(w http.ResponseWriter, r *http.Request, fieldErrors map[string]string) {
+	txid, _ := r.Context().Value(rctx.TXID).(string)
+	err := JSON(w, http.StatusUnprocessableEntity, Envelope{"success": false, "error": errs.ValidationError.Message,
+		"code": errs.ValidationError.Code, "txid": txid, "fieldErrors": fieldErrors})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -58,3 +85,15 @@ func NotPermittedResponse(w http.ResponseWriter, r *http.Request) {
 func InactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
 	Error(w, http.StatusForbidden, "Your account must be activated to access this resource")
 }
+
+func QuotaExceededResponse(w http.ResponseWriter, r *http.Request, err error) {
+	CodeErrorResponse(w, r, errs.QuotaExceededError, err.Error())
+}
+
+func ConflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	CodeErrorResponse(w, r, errs.ConflictError, err.Error())
+}
+
+func BadGatewayResponse(w http.ResponseWriter, r *http.Request, err error) {
+	CodeErrorResponse(w, r, errs.NodeError, err.Error())
+}
@@ -49,7 +49,7 @@ func (s *HttpServer) serve(ctx context.Context) error {
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.server.port),
-		Handler:      c.Handler(s.router), //s.recoverPanic(s.enableCORS(s.router)),
+		Handler:      c.Handler(s.router),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  time.Minute,
@@ -5,6 +5,7 @@ import (
 
 	klient "github.com/zbitech/controller/fake-zbi/klient/k8s-client"
 	zklient "github.com/zbitech/controller/fake-zbi/klient/zbi-klient"
+	"github.com/zbitech/controller/internal/secrets"
 	"github.com/zbitech/controller/pkg/interfaces"
 )
 
@@ -14,8 +15,9 @@ type KlientFactory struct {
 }
 
 type FakeKlientFactory struct {
-	client interfaces.ZBIClientIF
-	rscMon interfaces.KlientMonitorIF
+	client  interfaces.ZBIClientIF
+	rscMon  interfaces.KlientMonitorIF
+	secrets interfaces.SecretProviderIF
 }
 
 func NewFakeKlientFactory() interfaces.KlientFactoryIF {
@@ -30,6 +32,7 @@ func (k *FakeKlientFactory) Init(ctx context.Context, repoSvc interfaces.Reposit
 	}
 
 	k.client = zklient.NewFakeZBIClient(klient)
+	k.secrets = secrets.NewKubernetesSecretProvider(klient)
 	return nil
 }
 
@@ -37,6 +40,10 @@ func (k *FakeKlientFactory) GetZBIClient() interfaces.ZBIClientIF {
 	return k.client
 }
 
+func (k *FakeKlientFactory) GetSecretProvider() interfaces.SecretProviderIF {
+	return k.secrets
+}
+
 func (k *FakeKlientFactory) StartMonitor(ctx context.Context) {
 	k.rscMon.Start()
 }
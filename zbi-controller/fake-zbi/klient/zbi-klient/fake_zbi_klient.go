@@ -2,6 +2,8 @@ package zklient
 
 import (
 	"context"
+	"time"
+
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/model"
 )
@@ -14,6 +16,7 @@ type FakeZBIClient struct {
 	FakeCreateProject             func(ctx context.Context, project *model.Project) error
 	FakeGetProjects               func(ctx context.Context) ([]model.Project, error)
 	FakeRepairProject             func(ctx context.Context, project *model.Project) error
+	FakeUpdateProject             func(ctx context.Context, project *model.Project) error
 	FakeDeleteProject             func(ctx context.Context, project *model.Project, instances []model.Instance) error
 	FakeGetProjectResources       func(ctx context.Context, project string) ([]model.KubernetesResource, error)
 	FakeGetProjectResource        func(ctx context.Context, project, resourceName string, resourceType model.ResourceObjectType) (*model.KubernetesResource, error)
@@ -28,7 +31,13 @@ type FakeZBIClient struct {
 	FakeRepairInstance            func(ctx context.Context, project *model.Project, instance *model.Instance) error
 	FakeStopInstance              func(ctx context.Context, project *model.Project, instance *model.Instance) error
 	FakeStartInstance             func(ctx context.Context, project *model.Project, instance *model.Instance) error
-	FakeRotateInstanceCredentials func(ctx context.Context, project *model.Project, instance *model.Instance) error
+	FakeRotateInstanceCredentials func(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error
+	FakeExpireInstanceCredentials func(ctx context.Context, project *model.Project, instance *model.Instance) error
+	FakeGetInstanceCredentials    func(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error)
+	FakeUpdateDomain              func(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) error
+	FakeDeleteDomain              func(ctx context.Context, project *model.Project, instance *model.Instance) error
+	FakeUpdateInstanceIngress     func(ctx context.Context, project *model.Project, instance *model.Instance) error
+	FakeGetNetworkPolicies        func(ctx context.Context, project *model.Project, instance *model.Instance) (*model.NetworkPolicies, error)
 	FakeCreateSnapshot            func(ctx context.Context, project *model.Project, instance *model.Instance) error
 	FakeCreateSnapshotSchedule    func(ctx context.Context, project *model.Project, instance *model.Instance, schedule model.SnapshotScheduleType) error
 	FakeGetProject                func(ctx context.Context, project string) (*model.Project, error)
@@ -67,6 +76,10 @@ func (f FakeZBIClient) RepairProject(ctx context.Context, project *model.Project
 	return f.FakeRepairProject(ctx, project)
 }
 
+func (f FakeZBIClient) UpdateProject(ctx context.Context, project *model.Project) error {
+	return f.FakeUpdateProject(ctx, project)
+}
+
 func (f FakeZBIClient) DeleteProject(ctx context.Context, project *model.Project, instances []model.Instance) error {
 	return f.FakeDeleteProject(ctx, project, instances)
 }
@@ -119,8 +132,32 @@ func (f FakeZBIClient) StartInstance(ctx context.Context, project *model.Project
 	return f.FakeStartInstance(ctx, project, instance)
 }
 
-func (f FakeZBIClient) RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
-	return f.FakeRotateInstanceCredentials(ctx, project, instance)
+func (f FakeZBIClient) RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error {
+	return f.FakeRotateInstanceCredentials(ctx, project, instance, grace)
+}
+
+func (f FakeZBIClient) ExpireInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return f.FakeExpireInstanceCredentials(ctx, project, instance)
+}
+
+func (f FakeZBIClient) GetInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	return f.FakeGetInstanceCredentials(ctx, project, instance)
+}
+
+func (f FakeZBIClient) UpdateDomain(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) error {
+	return f.FakeUpdateDomain(ctx, project, instance, domain, certificate, privateKey)
+}
+
+func (f FakeZBIClient) DeleteDomain(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return f.FakeDeleteDomain(ctx, project, instance)
+}
+
+func (f FakeZBIClient) UpdateInstanceIngress(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return f.FakeUpdateInstanceIngress(ctx, project, instance)
+}
+
+func (f FakeZBIClient) GetNetworkPolicies(ctx context.Context, project *model.Project, instance *model.Instance) (*model.NetworkPolicies, error) {
+	return f.FakeGetNetworkPolicies(ctx, project, instance)
 }
 
 func (f FakeZBIClient) CreateSnapshot(ctx context.Context, project *model.Project, instance *model.Instance) error {
@@ -14,10 +14,11 @@ type FakeInstanceResourceManager struct {
 	FakeCreateIngressResource          func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, action model.EventAction) (*unstructured.Unstructured, error)
 	FakeCreateStartResource            func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateStopResource             func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error)
-	FakeCreateRepairResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error)
+	FakeCreateRepairResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateSnapshotResource         func(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateSnapshotScheduleResource func(ctx context.Context, project *model.Project, instance *model.Instance, scheduleType model.SnapshotScheduleType) ([]unstructured.Unstructured, error)
-	FakeCreateRotationResource         func(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
+	FakeCreateRotationResource         func(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error)
+	FakeCreateDomainResource           func(ctx context.Context, project *model.Project, instance *model.Instance, domain model.DomainSpec) ([]unstructured.Unstructured, error)
 	FakeCreateDeleteResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error)
 }
 
@@ -45,8 +46,8 @@ func (f FakeInstanceResourceManager) CreateStopResource(ctx context.Context, pro
 	return f.FakeCreateStopResource(ctx, projIngress, project, instance)
 }
 
-func (f FakeInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error) {
-	return f.FakeCreateRepairResource(ctx, projIngress, project, instance, peers...)
+func (f FakeInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateRepairResource(ctx, projIngress, project, instance, credentials, peers...)
 }
 
 func (f FakeInstanceResourceManager) CreateSnapshotResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
@@ -57,8 +58,12 @@ func (f FakeInstanceResourceManager) CreateSnapshotScheduleResource(ctx context.
 	return f.FakeCreateSnapshotScheduleResource(ctx, project, instance, scheduleType)
 }
 
-func (f FakeInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
-	return f.FakeCreateRotationResource(ctx, project, instance)
+func (f FakeInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateRotationResource(ctx, project, instance, credentials)
+}
+
+func (f FakeInstanceResourceManager) CreateDomainResource(ctx context.Context, project *model.Project, instance *model.Instance, domain model.DomainSpec) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateDomainResource(ctx, project, instance, domain)
 }
 
 func (f FakeInstanceResourceManager) CreateDeleteResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
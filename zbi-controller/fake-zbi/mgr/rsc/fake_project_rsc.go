@@ -11,15 +11,17 @@ import (
 type FakeProjectResourceManager struct {
 	FakeCreateProjectResource          func(ctx context.Context, project *model.Project) ([]unstructured.Unstructured, error)
 	FakeCreateProjectIngressResource   func(ctx context.Context, appIngress *unstructured.Unstructured, project *model.Project, action model.EventAction) ([]unstructured.Unstructured, error)
+	FakeCreateInstanceListResource     func(ctx context.Context, project *model.Project) ([]unstructured.Unstructured, error)
 	FakeCreateInstanceResource         func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, [][]unstructured.Unstructured, error)
 	FakeCreateUpdateResource           func(ctx context.Context, project *model.Project, instance *model.Instance, peers ...model.Instance) ([][]unstructured.Unstructured, error)
 	FakeCreateStartResource            func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateStopResource             func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error)
-	FakeCreateRepairResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error)
+	FakeCreateRepairResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateIngressResource          func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, action model.EventAction) (*unstructured.Unstructured, error)
 	FakeCreateSnapshotResource         func(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
 	FakeCreateSnapshotScheduleResource func(ctx context.Context, project *model.Project, instance *model.Instance, schedule model.SnapshotScheduleType) ([]unstructured.Unstructured, error)
-	FakeCreateRotationResource         func(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error)
+	FakeCreateRotationResource         func(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error)
+	FakeCreateDomainResource           func(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) ([]unstructured.Unstructured, error)
 	FakeCreateDeleteResource           func(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error)
 }
 
@@ -35,6 +37,10 @@ func (f FakeProjectResourceManager) CreateProjectIngressResource(ctx context.Con
 	return f.FakeCreateProjectIngressResource(ctx, appIngress, project, action)
 }
 
+func (f FakeProjectResourceManager) CreateInstanceListResource(ctx context.Context, project *model.Project) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateInstanceListResource(ctx, project)
+}
+
 func (f FakeProjectResourceManager) CreateInstanceResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, [][]unstructured.Unstructured, error) {
 	return f.FakeCreateInstanceResource(ctx, projIngress, project, instance, peers...)
 }
@@ -51,8 +57,8 @@ func (f FakeProjectResourceManager) CreateStopResource(ctx context.Context, proj
 	return f.FakeCreateStopResource(ctx, projIngress, project, instance)
 }
 
-func (f FakeProjectResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error) {
-	return f.FakeCreateRepairResource(ctx, projIngress, project, instance, peers...)
+func (f FakeProjectResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateRepairResource(ctx, projIngress, project, instance, credentials, peers...)
 }
 
 func (f FakeProjectResourceManager) CreateIngressResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, action model.EventAction) (*unstructured.Unstructured, error) {
@@ -67,8 +73,12 @@ func (f FakeProjectResourceManager) CreateSnapshotScheduleResource(ctx context.C
 	return f.FakeCreateSnapshotScheduleResource(ctx, project, instance, schedule)
 }
 
-func (f FakeProjectResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
-	return f.FakeCreateRotationResource(ctx, project, instance)
+func (f FakeProjectResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateRotationResource(ctx, project, instance, credentials)
+}
+
+func (f FakeProjectResourceManager) CreateDomainResource(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) ([]unstructured.Unstructured, error) {
+	return f.FakeCreateDomainResource(ctx, project, instance, domain, certificate, privateKey)
 }
 
 func (f FakeProjectResourceManager) CreateDeleteResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
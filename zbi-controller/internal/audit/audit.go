@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+const (
+	AUDIT_SINK_FILE       = "file"
+	AUDIT_SINK_REPOSITORY = "repository"
+	AUDIT_SINK_WEBHOOK    = "webhook"
+)
+
+var (
+	WriteFailuresTotal = metrics.NewCounter("zbi_audit_write_failures_total", "Number of audit records a sink failed to write.", "sink")
+)
+
+// Auditor writes audit records to a set of sinks.
+type Auditor struct {
+	sinks []interfaces.AuditSinkIF
+}
+
+func NewAuditor(sinks ...interfaces.AuditSinkIF) *Auditor {
+	return &Auditor{sinks: sinks}
+}
+
+// NewAuditService creates an auditor writing to the sinks listed in
+// ZBI_AUDIT_SINKS.
+func NewAuditService(ctx context.Context, repo interfaces.RepositoryServiceIF) (interfaces.AuditServiceIF, error) {
+	var sinks = make([]interfaces.AuditSinkIF, 0)
+	for _, name := range strings.Split(vars.ZBI_AUDIT_SINKS, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case AUDIT_SINK_FILE:
+			sink, err := NewFileSink(vars.ZBI_AUDIT_FILE_PATH)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case AUDIT_SINK_REPOSITORY:
+			sinks = append(sinks, NewRepositorySink(repo))
+		case AUDIT_SINK_WEBHOOK:
+			if vars.ZBI_AUDIT_WEBHOOK_URL == "" {
+				return nil, fmt.Errorf("audit webhook sink requires ZBI_AUDIT_WEBHOOK_URL")
+			}
+			sinks = append(sinks, NewWebhookSink(vars.ZBI_AUDIT_WEBHOOK_URL, time.Duration(vars.ZBI_AUDIT_WEBHOOK_TIMEOUT)*time.Second))
+		default:
+			return nil, fmt.Errorf("unknown audit sink %s", name)
+		}
+	}
+	return NewAuditor(sinks...), nil
+}
+
+// Record completes the record from the request context and writes it to
+// every sink. Failures are logged and counted; they do not fail the audited
+// operation.
+func (a *Auditor) Record(ctx context.Context, record *model.AuditRecord) {
+	log := logger.GetServiceLogger(ctx, "audit.Record")
+
+	if record.Id == "" {
+		record.Id = uuid.New().String()
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+	setFromContext(ctx, &record.TxId, rctx.TXID)
+	setFromContext(ctx, &record.Actor, rctx.USERID)
+	setFromContext(ctx, &record.Role, rctx.ROLE)
+	setFromContext(ctx, &record.SourceIP, rctx.IP)
+	setFromContext(ctx, &record.ForwardedFor, rctx.XIP)
+	if record.Outcome == "" {
+		if record.Error != "" {
+			record.Outcome = model.AuditOutcomeFailure
+		} else {
+			record.Outcome = model.AuditOutcomeSuccess
+		}
+	}
+
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			log.Errorf("failed to write audit record %s to %s sink - %s", record.Id, sink.Name(), err)
+			WriteFailuresTotal.Inc(sink.Name())
+		}
+	}
+}
+
+func setFromContext(ctx context.Context, field *string, key string) {
+	if *field == "" {
+		*field, _ = ctx.Value(key).(string)
+	}
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+	"github.com/zbitech/controller/pkg/rctx"
+)
+
+type memorySink struct {
+	records []model.AuditRecord
+	err     error
+}
+
+func (m *memorySink) Name() string {
+	return "memory"
+}
+
+func (m *memorySink) Write(ctx context.Context, record *model.AuditRecord) error {
+	m.records = append(m.records, *record)
+	return m.err
+}
+
+func TestAuditor_Record(t *testing.T) {
+	ctx := context.WithValue(context.Background(), rctx.TXID, "tx-1")
+	ctx = context.WithValue(ctx, rctx.USERID, "alice")
+	ctx = context.WithValue(ctx, rctx.ROLE, "admin")
+	ctx = context.WithValue(ctx, rctx.IP, "10.0.0.1:5000")
+	ctx = context.WithValue(ctx, rctx.XIP, "203.0.113.7")
+
+	failing := &memorySink{err: errors.New("unavailable")}
+	sink := &memorySink{}
+	failures := WriteFailuresTotal.Value("memory")
+
+	NewAuditor(failing, sink).Record(ctx, &model.AuditRecord{Source: model.AuditSourceCluster, Action: "StopInstance", Error: "failed"})
+
+	assert.Len(t, sink.records, 1)
+	record := sink.records[0]
+	assert.NotEmpty(t, record.Id)
+	assert.False(t, record.Timestamp.IsZero())
+	assert.Equal(t, "tx-1", record.TxId)
+	assert.Equal(t, "alice", record.Actor)
+	assert.Equal(t, "admin", record.Role)
+	assert.Equal(t, "10.0.0.1:5000", record.SourceIP)
+	assert.Equal(t, "203.0.113.7", record.ForwardedFor)
+	assert.Equal(t, model.AuditOutcomeFailure, record.Outcome)
+	assert.Equal(t, failures+1, WriteFailuresTotal.Value("memory"))
+}
+
+func TestObjects(t *testing.T) {
+	AddObject(context.Background(), model.AuditOperationApply, "services", "ns", "ignored")
+
+	parent := WithObjects(context.Background())
+	AddObject(parent, model.AuditOperationApply, "configmaps", "ns", "conf")
+
+	child := WithObjects(parent)
+	AddObject(child, model.AuditOperationDelete, "services", "ns", "svc")
+
+	assert.Equal(t, []model.AuditObject{{Operation: model.AuditOperationDelete, Resource: "services", Namespace: "ns", Name: "svc"}}, Objects(child))
+	assert.Len(t, Objects(parent), 2)
+	assert.Nil(t, Objects(context.Background()))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	sink, err := NewFileSink(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, sink.Write(context.Background(), &model.AuditRecord{Id: "1", Action: "CreateInstance"}))
+	assert.NoError(t, sink.Write(context.Background(), &model.AuditRecord{Id: "2", Action: "DeleteInstance"}))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record model.AuditRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		ids = append(ids, record.Id)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received model.AuditRecord
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	assert.NoError(t, sink.Write(context.Background(), &model.AuditRecord{Id: "1", TxId: "tx-1"}))
+	assert.Equal(t, "tx-1", received.TxId)
+
+	status = http.StatusInternalServerError
+	assert.Error(t, sink.Write(context.Background(), &model.AuditRecord{Id: "2"}))
+}
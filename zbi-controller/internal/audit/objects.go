@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+type objectsKey struct{}
+
+// collector gathers the Kubernetes objects changed while an audited
+// operation runs. Objects are also added to the collector of the enclosing
+// operation so that an API request lists everything its cluster operations
+// changed.
+type collector struct {
+	mu      sync.Mutex
+	parent  *collector
+	objects []model.AuditObject
+	detail  string
+}
+
+// WithObjects returns a context collecting the objects changed under it.
+func WithObjects(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(objectsKey{}).(*collector)
+	return context.WithValue(ctx, objectsKey{}, &collector{parent: parent})
+}
+
+// AddObject records an object changed by the current operation. It does
+// nothing outside of an audited operation.
+func AddObject(ctx context.Context, operation model.AuditOperation, resource, namespace, name string) {
+	object := model.AuditObject{Operation: operation, Resource: resource, Namespace: namespace, Name: name}
+	for c, _ := ctx.Value(objectsKey{}).(*collector); c != nil; c = c.parent {
+		c.mu.Lock()
+		c.objects = append(c.objects, object)
+		c.mu.Unlock()
+	}
+}
+
+// Objects returns the objects collected under ctx.
+func Objects(ctx context.Context) []model.AuditObject {
+	c, _ := ctx.Value(objectsKey{}).(*collector)
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]model.AuditObject(nil), c.objects...)
+}
+
+// SetDetail describes the current operation beyond its action, e.g. the RPC
+// methods a request called. It does nothing outside of an audited operation.
+func SetDetail(ctx context.Context, detail string) {
+	if c, _ := ctx.Value(objectsKey{}).(*collector); c != nil {
+		c.mu.Lock()
+		c.detail = detail
+		c.mu.Unlock()
+	}
+}
+
+// Detail returns the detail set under ctx.
+func Detail(ctx context.Context) string {
+	c, _ := ctx.Value(objectsKey{}).(*collector)
+	if c == nil {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.detail
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// FileSink appends audit records to a file as JSON lines.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (interfaces.AuditSinkIF, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (f *FileSink) Name() string {
+	return AUDIT_SINK_FILE
+}
+
+func (f *FileSink) Write(ctx context.Context, record *model.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+// RepositorySink stores audit records in the repository, where they can be
+// queried through the audit API.
+type RepositorySink struct {
+	repo interfaces.RepositoryServiceIF
+}
+
+func NewRepositorySink(repo interfaces.RepositoryServiceIF) interfaces.AuditSinkIF {
+	return &RepositorySink{repo: repo}
+}
+
+func (r *RepositorySink) Name() string {
+	return AUDIT_SINK_REPOSITORY
+}
+
+func (r *RepositorySink) Write(ctx context.Context, record *model.AuditRecord) error {
+	return r.repo.AddAuditRecord(ctx, record)
+}
+
+// WebhookSink posts each audit record as JSON to a URL. Any response other
+// than 2xx is a failure.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) interfaces.AuditSinkIF {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (w *WebhookSink) Name() string {
+	return AUDIT_SINK_WEBHOOK
+}
+
+func (w *WebhookSink) Write(ctx context.Context, record *model.AuditRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	// the audited request may already be finished, so its cancellation is
+	// not passed on to the webhook
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+	return nil
+}
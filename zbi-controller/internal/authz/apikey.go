@@ -0,0 +1,97 @@
+package authz
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	API_KEY_PREFIX = "zbi_"
+	API_KEY_BYTES  = 32
+)
+
+var (
+	ErrMalformedKey = errors.New("malformed api key")
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrExpiredKey   = errors.New("api key expired")
+	ErrRevokedKey   = errors.New("api key revoked")
+)
+
+// GenerateAPIKey issues a key for an instance. It returns the record to store
+// and the key to hand to the consumer, which has the form zbi_<id>.<secret>.
+func GenerateAPIKey(request *model.APIKeyRequest, instance *model.Instance, owner string) (*model.APIKey, string, error) {
+	secret := make([]byte, API_KEY_BYTES)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	key := &model.APIKey{
+		Id:           uuid.New().String(),
+		InstanceId:   instance.Id,
+		InstanceName: instance.Name,
+		Owner:        owner,
+		Consumer:     request.Consumer,
+		Labels:       request.Labels,
+		Hash:         hashSecret(encoded),
+		CreatedAt:    &createdAt,
+	}
+	if instance.Project != nil {
+		key.Namespace = instance.Project.GetNamespace()
+	}
+
+	if request.ExpiresIn != "" {
+		duration, err := utils.ParseDuration(request.ExpiresIn)
+		if err != nil {
+			return nil, "", err
+		}
+		expiresAt := createdAt.Add(duration)
+		key.ExpiresAt = &expiresAt
+	}
+
+	return key, API_KEY_PREFIX + key.Id + "." + encoded, nil
+}
+
+// ParseAPIKey splits a key into its id and secret.
+func ParseAPIKey(token string) (string, string, error) {
+	if !strings.HasPrefix(token, API_KEY_PREFIX) {
+		return "", "", ErrMalformedKey
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(token, API_KEY_PREFIX), ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrMalformedKey
+	}
+	return parts[0], parts[1], nil
+}
+
+// VerifyAPIKey checks the secret presented for a stored key and that the key
+// is still in force.
+func VerifyAPIKey(key *model.APIKey, secret string, now time.Time) error {
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return ErrInvalidKey
+	}
+	if key.RevokedAt != nil {
+		return ErrRevokedKey
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return ErrExpiredKey
+	}
+	return nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,38 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	instance := &model.Instance{Id: "instance-id", Name: "zcash", Project: &model.Project{Name: "project"}}
+	key, token, err := GenerateAPIKey(&model.APIKeyRequest{Consumer: "wallet", ExpiresIn: "1d"}, instance, "owner")
+	assert.NoError(t, err)
+	assert.Equal(t, "instance-id", key.InstanceId)
+	assert.Equal(t, instance.Project.GetNamespace(), key.Namespace)
+	assert.Equal(t, key.CreatedAt.Add(24*time.Hour), *key.ExpiresAt)
+	assert.NotContains(t, key.Hash, token)
+
+	id, secret, err := ParseAPIKey(token)
+	assert.NoError(t, err)
+	assert.Equal(t, key.Id, id)
+
+	now := time.Now()
+	assert.NoError(t, VerifyAPIKey(key, secret, now))
+	assert.ErrorIs(t, VerifyAPIKey(key, secret+"x", now), ErrInvalidKey)
+	assert.ErrorIs(t, VerifyAPIKey(key, secret, now.Add(25*time.Hour)), ErrExpiredKey)
+
+	key.RevokedAt = &now
+	assert.ErrorIs(t, VerifyAPIKey(key, secret, now), ErrRevokedKey)
+}
+
+func TestParseAPIKey(t *testing.T) {
+	for _, token := range []string{"", "key", "zbi_", "zbi_id", "zbi_.secret", "zbi_id.", "abc_id.secret"} {
+		_, _, err := ParseAPIKey(token)
+		assert.ErrorIs(t, err, ErrMalformedKey, token)
+	}
+}
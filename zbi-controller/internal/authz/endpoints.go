@@ -0,0 +1,127 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	ENDPOINTS_PROPERTY = "endpoints"
+)
+
+// GetEndpointPolicy returns the endpoint policy of an instance, or nil when
+// all methods are exposed.
+func GetEndpointPolicy(instance *model.Instance) *model.EndpointPolicy {
+	if instance.Request == nil {
+		return nil
+	}
+
+	value, ok := instance.Request.Properties[ENDPOINTS_PROPERTY]
+	if !ok || value == nil {
+		return nil
+	}
+
+	var policy model.EndpointPolicy
+	if err := utils.UnMarshalObject(utils.MarshalObject(value), &policy); err != nil {
+		return nil
+	}
+	return &policy
+}
+
+// ValidateEndpointPolicy returns the policy entries that do not name a group
+// or a method of a group in endpoints.
+func ValidateEndpointPolicy(policy *model.EndpointPolicy, endpoints map[string][]string) []string {
+	var invalid []string
+	for _, entry := range append(append([]string{}, policy.Allow...), policy.Deny...) {
+		if len(expandEntry(entry, endpoints)) == 0 {
+			invalid = append(invalid, entry)
+		}
+	}
+	return invalid
+}
+
+// MethodFilter decides which RPC methods an instance exposes.
+type MethodFilter struct {
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+// NewMethodFilter resolves a policy against the endpoint groups of a node.
+// A nil policy allows every method.
+func NewMethodFilter(policy *model.EndpointPolicy, endpoints map[string][]string) *MethodFilter {
+	filter := &MethodFilter{denied: make(map[string]bool)}
+	if policy == nil {
+		return filter
+	}
+
+	if len(policy.Allow) > 0 {
+		filter.allowed = make(map[string]bool)
+		for _, entry := range policy.Allow {
+			for _, method := range expandEntry(entry, endpoints) {
+				filter.allowed[method] = true
+			}
+		}
+	}
+
+	for _, entry := range policy.Deny {
+		for _, method := range expandEntry(entry, endpoints) {
+			filter.denied[method] = true
+		}
+	}
+
+	return filter
+}
+
+// Restricted reports whether the filter limits any method.
+func (f *MethodFilter) Restricted() bool {
+	return f.allowed != nil || len(f.denied) > 0
+}
+
+func (f *MethodFilter) Allowed(method string) bool {
+	if f.denied[method] {
+		return false
+	}
+	return f.allowed == nil || f.allowed[method]
+}
+
+// IsEndpointMethod reports whether a method belongs to an endpoint group of
+// a node.
+func IsEndpointMethod(method string, endpoints map[string][]string) bool {
+	for _, methods := range endpoints {
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandEntry returns the methods named by a group or group.method entry.
+func expandEntry(entry string, endpoints map[string][]string) []string {
+	parts := strings.SplitN(entry, ".", 2)
+	methods, ok := endpoints[parts[0]]
+	if !ok {
+		return nil
+	}
+	if len(parts) == 1 {
+		return methods
+	}
+
+	for _, method := range methods {
+		if method == parts[1] {
+			return []string{method}
+		}
+	}
+	return nil
+}
+
+func methodNotAllowed(method string) string {
+	if method == "" {
+		return "rpc method could not be determined"
+	}
+	return fmt.Sprintf("rpc method %s is not allowed", method)
+}
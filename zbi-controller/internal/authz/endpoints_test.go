@@ -0,0 +1,56 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+var testEndpoints = map[string][]string{
+	"blockchain": {"getblock", "getblockcount"},
+	"control":    {"getinfo", "stop"},
+	"wallet":     {"getbalance", "sendmany"},
+}
+
+func TestMethodFilter(t *testing.T) {
+	filter := NewMethodFilter(nil, testEndpoints)
+	assert.False(t, filter.Restricted())
+	assert.True(t, filter.Allowed("stop"))
+
+	filter = NewMethodFilter(&model.EndpointPolicy{Deny: []string{"wallet", "control.stop"}}, testEndpoints)
+	assert.True(t, filter.Restricted())
+	assert.True(t, filter.Allowed("getinfo"))
+	assert.True(t, filter.Allowed("unlisted"))
+	assert.False(t, filter.Allowed("stop"))
+	assert.False(t, filter.Allowed("sendmany"))
+
+	filter = NewMethodFilter(&model.EndpointPolicy{Allow: []string{"blockchain", "control"}, Deny: []string{"control.stop"}}, testEndpoints)
+	assert.True(t, filter.Allowed("getblock"))
+	assert.True(t, filter.Allowed("getinfo"))
+	assert.False(t, filter.Allowed("stop"))
+	assert.False(t, filter.Allowed("getbalance"))
+	assert.False(t, filter.Allowed("unlisted"))
+}
+
+func TestValidateEndpointPolicy(t *testing.T) {
+	policy := &model.EndpointPolicy{Allow: []string{"blockchain", "mining"}, Deny: []string{"control.stop", "control.restart"}}
+	assert.Equal(t, []string{"mining", "control.restart"}, ValidateEndpointPolicy(policy, testEndpoints))
+
+	policy = &model.EndpointPolicy{Allow: []string{"blockchain"}, Deny: []string{"control.stop"}}
+	assert.Empty(t, ValidateEndpointPolicy(policy, testEndpoints))
+}
+
+func TestGetEndpointPolicy(t *testing.T) {
+	instance := &model.Instance{Request: &model.ResourceRequest{}}
+	assert.Nil(t, GetEndpointPolicy(instance))
+
+	instance.Request.Properties = map[string]interface{}{ENDPOINTS_PROPERTY: map[string]interface{}{"allow": []interface{}{"wallet"}}}
+	assert.Equal(t, &model.EndpointPolicy{Allow: []string{"wallet"}}, GetEndpointPolicy(instance))
+}
+
+func TestIsEndpointMethod(t *testing.T) {
+	assert.True(t, IsEndpointMethod("getinfo", testEndpoints))
+	assert.False(t, IsEndpointMethod("unlisted", testEndpoints))
+	assert.False(t, IsEndpointMethod("", testEndpoints))
+}
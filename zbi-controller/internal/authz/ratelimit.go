@@ -0,0 +1,74 @@
+package authz
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	LIMIT_RATE  = "rate"
+	LIMIT_QUOTA = "quota"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	day    time.Time
+	used   int
+}
+
+// Limiter applies a RateLimit to each API key. Requests per second are
+// tracked with a token bucket and the daily quota resets at midnight UTC.
+// State is held in memory, so limits apply per controller replica.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow records a request for id. When the request is over a limit it
+// returns the limit that was reached and how long until a retry can succeed.
+func (l *Limiter) Allow(id string, limit *model.RateLimit, now time.Time) (bool, string, time.Duration) {
+	if limit == nil {
+		return true, "", 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, day: day}
+		l.buckets[id] = b
+	}
+
+	if b.day.Before(day) {
+		b.day = day
+		b.used = 0
+	}
+
+	if limit.DailyQuota > 0 && b.used >= limit.DailyQuota {
+		return false, LIMIT_QUOTA, day.Add(24 * time.Hour).Sub(now)
+	}
+
+	if limit.RequestsPerSecond > 0 {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*float64(limit.RequestsPerSecond))
+		b.last = now
+		if b.tokens < 1 {
+			wait := (1 - b.tokens) / float64(limit.RequestsPerSecond)
+			return false, LIMIT_RATE, time.Duration(wait * float64(time.Second))
+		}
+		b.tokens--
+	}
+
+	b.used++
+	return true, "", 0
+}
@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestLimiter_Rate(t *testing.T) {
+	limiter := NewLimiter()
+	limit := &model.RateLimit{RequestsPerSecond: 2, Burst: 3}
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for index := 0; index < 3; index++ {
+		allowed, _, _ := limiter.Allow("key", limit, now)
+		assert.True(t, allowed)
+	}
+
+	allowed, reason, retry := limiter.Allow("key", limit, now)
+	assert.False(t, allowed)
+	assert.Equal(t, LIMIT_RATE, reason)
+	assert.Equal(t, 500*time.Millisecond, retry)
+
+	// other keys have their own bucket
+	allowed, _, _ = limiter.Allow("other", limit, now)
+	assert.True(t, allowed)
+
+	allowed, _, _ = limiter.Allow("key", limit, now.Add(500*time.Millisecond))
+	assert.True(t, allowed)
+
+	allowed, _, _ = limiter.Allow("key", nil, now)
+	assert.True(t, allowed)
+}
+
+func TestLimiter_Quota(t *testing.T) {
+	limiter := NewLimiter()
+	limit := &model.RateLimit{DailyQuota: 2}
+	now := time.Date(2022, 1, 1, 23, 0, 0, 0, time.UTC)
+
+	for index := 0; index < 2; index++ {
+		allowed, _, _ := limiter.Allow("key", limit, now)
+		assert.True(t, allowed)
+	}
+
+	allowed, reason, retry := limiter.Allow("key", limit, now)
+	assert.False(t, allowed)
+	assert.Equal(t, LIMIT_QUOTA, reason)
+	assert.Equal(t, time.Hour, retry)
+
+	// the quota resets at midnight UTC
+	allowed, _, _ = limiter.Allow("key", limit, now.Add(time.Hour))
+	assert.True(t, allowed)
+}
@@ -0,0 +1,354 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	API_KEY_HEADER       = "x-api-key"
+	AUTHORIZATION_HEADER = "authorization"
+	CONSUMER_HEADER      = "x-zbi-consumer"
+	KEY_ID_HEADER        = "x-zbi-key-id"
+
+	// context extensions set by the instance Envoy configuration
+	NAMESPACE_EXTENSION = "namespace"
+	INSTANCE_EXTENSION  = "instance"
+
+	MAX_MESSAGE_SIZE = 4 << 20
+)
+
+var (
+	ChecksTotal      = metrics.NewCounter("zbi_authz_checks_total", "Number of ext-authz checks by result.", "result")
+	RequestsTotal    = metrics.NewCounter("zbi_instance_requests_total", "Number of authorized instance requests by consumer.", "namespace", "instance", "consumer")
+	RateLimitedTotal = metrics.NewCounter("zbi_instance_rate_limited_total", "Number of instance requests denied by a key rate limit or quota.", "namespace", "instance", "limit")
+)
+
+type cacheEntry struct {
+	key     *model.APIKey
+	err     error
+	fetched time.Time
+}
+
+// instancePolicy is the endpoint policy and key rate limit of an instance.
+type instancePolicy struct {
+	filter   *MethodFilter
+	keyLimit *model.RateLimit
+	fetched  time.Time
+}
+
+// Server implements the Envoy ext_authz Authorization service over cleartext
+// gRPC. It authorizes requests carrying an instance API key and enforces the
+// endpoint policy and key rate limit of the instance.
+type Server struct {
+	repo     interfaces.RepositoryServiceIF
+	ttl      time.Duration
+	limiter  *Limiter
+	mu       sync.Mutex
+	cache    map[string]cacheEntry
+	policies map[string]instancePolicy
+	srv      *grpc.Server
+}
+
+// NewServer creates a server that caches keys and instance policies read from
+// the repository for ttl, which bounds how long a revoked key or a previous
+// policy is still applied.
+func NewServer(repo interfaces.RepositoryServiceIF, ttl time.Duration) *Server {
+	return &Server{repo: repo, ttl: ttl, limiter: NewLimiter(), cache: make(map[string]cacheEntry),
+		policies: make(map[string]instancePolicy)}
+}
+
+// ListenAndServe serves checks on port until Stop is called.
+func (s *Server) ListenAndServe(ctx context.Context, port int) {
+	log := logger.GetLogger(ctx)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Errorf("ext-authz server failed - %s", err)
+		return
+	}
+
+	log.Infof("starting ext-authz server on port %d", port)
+	if err = s.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		log.Errorf("ext-authz server failed - %s", err)
+	}
+}
+
+// Serve registers the Authorization service and serves checks on listener.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.srv = grpc.NewServer(grpc.MaxRecvMsgSize(MAX_MESSAGE_SIZE))
+	authv3.RegisterAuthorizationServer(s.srv, s)
+	s.mu.Unlock()
+
+	return s.srv.Serve(listener)
+}
+
+func (s *Server) Stop(ctx context.Context) {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv != nil {
+		srv.GracefulStop()
+	}
+}
+
+// Check authorizes a request to an instance endpoint. Denied requests are
+// returned as a denied response rather than an rpc error.
+func (s *Server) Check(ctx context.Context, request *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	extensions := request.GetAttributes().GetContextExtensions()
+	namespace := extensions[NAMESPACE_EXTENSION]
+	instance := extensions[INSTANCE_EXTENSION]
+
+	httpRequest := request.GetAttributes().GetRequest().GetHttp()
+	methods := rpcMethods(requestBody(httpRequest))
+	log := logger.GetServiceLogger(ctx, "authz.Check").WithFields(logrus.Fields{"audit": true, "namespace": namespace,
+		"instance": instance, "path": httpRequest.GetPath(), "rpc": strings.Join(methods, ",")})
+
+	token, removeHeader := requestToken(httpRequest.GetHeaders())
+	if token == "" {
+		ChecksTotal.Inc("missing")
+		log.Infof("request denied, no api key")
+		return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "api key required"), nil
+	}
+
+	id, secret, err := ParseAPIKey(token)
+	if err != nil {
+		ChecksTotal.Inc("invalid")
+		log.Infof("request denied - %s", err)
+		return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "invalid api key"), nil
+	}
+
+	log = log.WithFields(logrus.Fields{"key": id})
+	key, err := s.getKey(ctx, id)
+	if err != nil {
+		if errs.Code(err).Category == errs.CategoryNotFound {
+			ChecksTotal.Inc("invalid")
+			log.Infof("request denied, unknown api key")
+			return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "invalid api key"), nil
+		}
+		ChecksTotal.Inc("error")
+		log.Errorf("unable to retrieve api key - %s", err)
+		return denied(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, "authorization unavailable"), nil
+	}
+
+	log = log.WithFields(logrus.Fields{"consumer": key.Consumer})
+	if err = VerifyAPIKey(key, secret, time.Now()); err != nil {
+		result := "invalid"
+		if errors.Is(err, ErrExpiredKey) {
+			result = "expired"
+		} else if errors.Is(err, ErrRevokedKey) {
+			result = "revoked"
+		}
+		ChecksTotal.Inc(result)
+		log.Infof("request denied - %s", err)
+		return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, err.Error()), nil
+	}
+
+	if key.Namespace != namespace || key.InstanceName != instance {
+		ChecksTotal.Inc("forbidden")
+		log.Infof("request denied, key belongs to %s/%s", key.Namespace, key.InstanceName)
+		return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, "api key is not valid for this instance"), nil
+	}
+
+	policy, err := s.getPolicy(ctx, key.InstanceId)
+	if err != nil {
+		ChecksTotal.Inc("error")
+		log.Errorf("unable to retrieve instance policy - %s", err)
+		return denied(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, "authorization unavailable"), nil
+	}
+
+	if policy.filter.Restricted() {
+		if len(methods) == 0 {
+			methods = []string{""}
+		}
+		for _, method := range methods {
+			if !policy.filter.Allowed(method) {
+				ChecksTotal.Inc("method")
+				log.Infof("request denied, %s", methodNotAllowed(method))
+				return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, methodNotAllowed(method)), nil
+			}
+		}
+	}
+
+	if allowed, limit, retry := s.limiter.Allow(key.Id, policy.keyLimit, time.Now()); !allowed {
+		ChecksTotal.Inc("rate_limited")
+		RateLimitedTotal.Inc(namespace, instance, limit)
+		log.Infof("request denied, %s limit reached", limit)
+		return denied(codes.ResourceExhausted, typev3.StatusCode_TooManyRequests, limit+" limit exceeded",
+			header("retry-after", fmt.Sprintf("%d", int(math.Ceil(retry.Seconds()))))), nil
+	}
+
+	ChecksTotal.Inc("allowed")
+	RequestsTotal.Inc(namespace, instance, key.Consumer)
+	log.Infof("request allowed")
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{
+			Headers:         []*corev3.HeaderValueOption{header(CONSUMER_HEADER, key.Consumer), header(KEY_ID_HEADER, key.Id)},
+			HeadersToRemove: []string{removeHeader},
+		}},
+	}, nil
+}
+
+func (s *Server) getKey(ctx context.Context, id string) (*model.APIKey, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[id]
+	s.mu.Unlock()
+	if ok && time.Since(entry.fetched) < s.ttl {
+		return entry.key, entry.err
+	}
+
+	key, err := s.repo.GetAPIKey(ctx, id)
+	if err != nil && errs.Code(err).Category != errs.CategoryNotFound {
+		// unavailable repository errors are not cached
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[id] = cacheEntry{key: key, err: err, fetched: time.Now()}
+	s.mu.Unlock()
+
+	return key, err
+}
+
+// getPolicy returns the method filter and key rate limit of an instance.
+func (s *Server) getPolicy(ctx context.Context, instanceId string) (*instancePolicy, error) {
+	s.mu.Lock()
+	entry, ok := s.policies[instanceId]
+	s.mu.Unlock()
+	if ok && time.Since(entry.fetched) < s.ttl {
+		return &entry, nil
+	}
+
+	instance, err := s.repo.GetInstance(ctx, instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := s.repo.GetPolicyInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyLimit *model.RateLimit
+	if instance.Request != nil {
+		keyLimit = instance.Request.KeyRateLimit
+	}
+
+	filter := NewMethodFilter(nil, nil)
+	if policy := GetEndpointPolicy(instance); policy != nil {
+		blockchain := "zcash"
+		if instance.Project != nil && instance.Project.Blockchain != "" {
+			blockchain = instance.Project.Blockchain
+		}
+
+		nodeType := instance.InstanceType
+		if nodeType == "" {
+			nodeType = instance.Type
+		}
+
+		node, err := s.repo.GetBlockchainNodeInfo(ctx, blockchain, string(nodeType))
+		if err != nil {
+			return nil, err
+		}
+		filter = NewMethodFilter(policy, node.Endpoints)
+	}
+
+	entry = instancePolicy{filter: filter, keyLimit: keyLimit.Effective(defaults.KeyRateLimit), fetched: time.Now()}
+	s.mu.Lock()
+	s.policies[instanceId] = entry
+	s.mu.Unlock()
+
+	return &entry, nil
+}
+
+// requestToken returns the API key of a request and the header carrying it,
+// either x-api-key or a bearer authorization.
+func requestToken(headers map[string]string) (string, string) {
+	if token := headers[API_KEY_HEADER]; token != "" {
+		return token, API_KEY_HEADER
+	}
+
+	authorization := headers[AUTHORIZATION_HEADER]
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:]), AUTHORIZATION_HEADER
+	}
+	return "", ""
+}
+
+// requestBody returns the request body, sent as raw_body when pack_as_bytes is
+// set.
+func requestBody(request *authv3.AttributeContext_HttpRequest) []byte {
+	if body := request.GetRawBody(); len(body) > 0 {
+		return body
+	}
+	return []byte(request.GetBody())
+}
+
+// rpcMethods returns the JSON-RPC methods in a request body, if any.
+func rpcMethods(body []byte) []string {
+	if len(body) == 0 {
+		return nil
+	}
+
+	type call struct {
+		Method string `json:"method"`
+	}
+
+	var single call
+	if err := json.Unmarshal(body, &single); err == nil {
+		return []string{single.Method}
+	}
+
+	var batch []call
+	if err := json.Unmarshal(body, &batch); err == nil {
+		methods := make([]string, 0, len(batch))
+		for _, c := range batch {
+			methods = append(methods, c.Method)
+		}
+		return methods
+	}
+
+	return nil
+}
+
+func denied(code codes.Code, httpStatus typev3.StatusCode, message string, headers ...*corev3.HeaderValueOption) *authv3.CheckResponse {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status:  &typev3.HttpStatus{Code: httpStatus},
+			Headers: append([]*corev3.HeaderValueOption{header("content-type", "application/json")}, headers...),
+			Body:    string(body),
+		}},
+	}
+}
+
+// header returns a header that replaces any existing value.
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
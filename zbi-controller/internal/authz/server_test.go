@@ -0,0 +1,169 @@
+package authz
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/internal/repository"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testConfigDir = "../../../deploy/charts/zbi/zbi-conf"
+
+// serve starts the server on an in-memory listener and returns a client.
+func serve(t *testing.T, server *Server) authv3.AuthorizationClient {
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///authz", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return authv3.NewAuthorizationClient(conn)
+}
+
+// check sends a CheckRequest and returns the rpc status code and the denied
+// http status.
+func check(t *testing.T, client authv3.AuthorizationClient, headers, extensions map[string]string, rpc string) (codes.Code, int) {
+	request := &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+			Method: http.MethodPost, Headers: headers, Path: "/", RawBody: []byte(rpc),
+		}},
+		ContextExtensions: extensions,
+	}}
+
+	response, err := client.Check(context.Background(), request)
+	assert.NoError(t, err)
+	return codes.Code(response.GetStatus().GetCode()), int(response.GetDeniedResponse().GetStatus().GetCode())
+}
+
+func newTestInstance(t *testing.T) (interfaces.RepositoryServiceIF, *model.Instance, *model.APIKey, string) {
+	ctx := context.Background()
+	repo, err := repository.NewEmbeddedRepositoryService("", testConfigDir, "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "project", Owner: "owner", Blockchain: "zcash"})
+	assert.NoError(t, err)
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "zcash", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+
+	key, token, err := GenerateAPIKey(&model.APIKeyRequest{Consumer: "wallet"}, instance, "owner")
+	assert.NoError(t, err)
+	_, err = repo.CreateAPIKey(ctx, key)
+	assert.NoError(t, err)
+
+	return repo, instance, key, token
+}
+
+func TestServer_Check(t *testing.T) {
+	ctx := context.Background()
+	repo, _, key, token := newTestInstance(t)
+
+	server := NewServer(repo, time.Minute)
+	client := serve(t, server)
+	extensions := map[string]string{NAMESPACE_EXTENSION: key.Namespace, INSTANCE_EXTENSION: "zcash"}
+	getinfo := func(headers, extensions map[string]string) (codes.Code, int) {
+		return check(t, client, headers, extensions, `{"method":"getinfo"}`)
+	}
+
+	code, _ := getinfo(map[string]string{API_KEY_HEADER: token}, extensions)
+	assert.Equal(t, codes.OK, code)
+
+	code, _ = getinfo(map[string]string{AUTHORIZATION_HEADER: "Bearer " + token}, extensions)
+	assert.Equal(t, codes.OK, code)
+
+	code, status := getinfo(map[string]string{}, extensions)
+	assert.Equal(t, codes.Unauthenticated, code)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	code, status = getinfo(map[string]string{API_KEY_HEADER: token + "x"}, extensions)
+	assert.Equal(t, codes.Unauthenticated, code)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	code, status = getinfo(map[string]string{API_KEY_HEADER: token}, map[string]string{NAMESPACE_EXTENSION: key.Namespace, INSTANCE_EXTENSION: "other"})
+	assert.Equal(t, codes.PermissionDenied, code)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// revocation takes effect once the cached key expires
+	assert.NoError(t, repo.RevokeAPIKey(ctx, key.Id))
+	server.ttl = 0
+	code, status = getinfo(map[string]string{API_KEY_HEADER: token}, extensions)
+	assert.Equal(t, codes.Unauthenticated, code)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestServer_EndpointPolicy(t *testing.T) {
+	ctx := context.Background()
+	repo, instance, key, token := newTestInstance(t)
+
+	server := NewServer(repo, time.Minute)
+	client := serve(t, server)
+	headers := map[string]string{API_KEY_HEADER: token}
+	extensions := map[string]string{NAMESPACE_EXTENSION: key.Namespace, INSTANCE_EXTENSION: "zcash"}
+
+	code, _ := check(t, client, headers, extensions, `{"method":"stop"}`)
+	assert.Equal(t, codes.OK, code)
+
+	request := &model.InstanceRequest{Name: "zcash", Type: model.InstanceTypeZCASH, Properties: map[string]interface{}{
+		ENDPOINTS_PROPERTY: map[string]interface{}{"allow": []interface{}{"blockchain", "control"}, "deny": []interface{}{"control.stop"}},
+	}}
+	_, err := repo.UpdateInstance(ctx, instance.Id, request)
+	assert.NoError(t, err)
+
+	// the previous policy applies until the cached entry expires
+	code, _ = check(t, client, headers, extensions, `{"method":"stop"}`)
+	assert.Equal(t, codes.OK, code)
+
+	server.ttl = 0
+	code, status := check(t, client, headers, extensions, `{"method":"stop"}`)
+	assert.Equal(t, codes.PermissionDenied, code)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	code, _ = check(t, client, headers, extensions, `{"method":"getinfo"}`)
+	assert.Equal(t, codes.OK, code)
+
+	code, _ = check(t, client, headers, extensions, `[{"method":"getblockcount"},{"method":"getbalance"}]`)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	code, _ = check(t, client, headers, extensions, `{"method":`)
+	assert.Equal(t, codes.PermissionDenied, code)
+}
+
+func TestServer_KeyRateLimit(t *testing.T) {
+	ctx := context.Background()
+	repo, instance, key, token := newTestInstance(t)
+
+	request := &model.InstanceRequest{Name: "zcash", Type: model.InstanceTypeZCASH, KeyRateLimit: &model.RateLimit{DailyQuota: 2}}
+	_, err := repo.UpdateInstance(ctx, instance.Id, request)
+	assert.NoError(t, err)
+
+	server := NewServer(repo, time.Minute)
+	client := serve(t, server)
+	headers := map[string]string{API_KEY_HEADER: token}
+	extensions := map[string]string{NAMESPACE_EXTENSION: key.Namespace, INSTANCE_EXTENSION: "zcash"}
+	requests := RequestsTotal.Value(key.Namespace, "zcash", "wallet")
+	limited := RateLimitedTotal.Value(key.Namespace, "zcash", LIMIT_QUOTA)
+
+	for index := 0; index < 2; index++ {
+		code, _ := check(t, client, headers, extensions, `{"method":"getinfo"}`)
+		assert.Equal(t, codes.OK, code)
+	}
+
+	code, status := check(t, client, headers, extensions, `{"method":"getinfo"}`)
+	assert.Equal(t, codes.ResourceExhausted, code)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, requests+2, RequestsTotal.Value(key.Namespace, "zcash", "wallet"))
+	assert.Equal(t, limited+1, RateLimitedTotal.Value(key.Namespace, "zcash", LIMIT_QUOTA))
+}
@@ -0,0 +1,194 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+
+	saplingAddressLength    = 43
+	minUnifiedAddressLength = 48
+)
+
+// addressEncodings are the address encodings of a network: the version
+// prefixes of transparent addresses and the human readable parts of Sapling
+// and Unified addresses.
+type addressEncodings struct {
+	transparent [][]byte
+	sapling     string
+	unified     string
+}
+
+var networkAddresses = map[model.NetworkType]addressEncodings{
+	model.NetworkTypeMain: {
+		transparent: [][]byte{{0x1c, 0xb8}, {0x1c, 0xbd}},
+		sapling:     "zs",
+		unified:     "u",
+	},
+	model.NetworkTypeTest: {
+		transparent: [][]byte{{0x1d, 0x25}, {0x1c, 0xba}},
+		sapling:     "ztestsapling",
+		unified:     "utest",
+	},
+}
+
+// ValidateZcashAddress checks that an address is a transparent, Sapling or
+// Unified address of a network. Networks other than mainnet use the testnet
+// encodings.
+func ValidateZcashAddress(network model.NetworkType, address string) error {
+	encodings, ok := networkAddresses[network]
+	if !ok {
+		encodings = networkAddresses[model.NetworkTypeTest]
+	}
+
+	if strings.HasPrefix(address, "t") {
+		payload, err := decodeBase58Check(address)
+		if err != nil {
+			return err
+		}
+		for _, prefix := range encodings.transparent {
+			if len(payload) == 22 && bytes.HasPrefix(payload, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("is not a %s transparent address", network)
+	}
+
+	hrp, data, encoding, err := decodeBech32(address)
+	if err != nil {
+		return err
+	}
+	switch {
+	case hrp == encodings.sapling && encoding == bech32Const && len(data) == saplingAddressLength:
+		return nil
+	case hrp == encodings.unified && encoding == bech32mConst && len(data) >= minUnifiedAddressLength:
+		return nil
+	}
+	return fmt.Errorf("is not a %s transparent, sapling or unified address", network)
+}
+
+// decodeBase58Check decodes a base58 string and verifies its double SHA-256
+// checksum.
+func decodeBase58Check(value string) ([]byte, error) {
+	var number = new(big.Int)
+	for _, c := range value {
+		index := strings.IndexRune(base58Alphabet, c)
+		if index < 0 {
+			return nil, errors.New("is not a valid base58 address")
+		}
+		number.Mul(number, big.NewInt(58))
+		number.Add(number, big.NewInt(int64(index)))
+	}
+
+	decoded := number.Bytes()
+	for _, c := range value {
+		if c != '1' {
+			break
+		}
+		decoded = append([]byte{0}, decoded...)
+	}
+
+	if len(decoded) < 5 {
+		return nil, errors.New("is not a valid base58 address")
+	}
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, errors.New("has an invalid checksum")
+	}
+	return payload, nil
+}
+
+// decodeBech32 decodes a bech32 or bech32m string into its human readable
+// part and 8-bit data, and reports which checksum constant it matched.
+func decodeBech32(value string) (string, []byte, int, error) {
+	if strings.ToLower(value) != value && strings.ToUpper(value) != value {
+		return "", nil, 0, errors.New("must not mix upper and lower case")
+	}
+	value = strings.ToLower(value)
+
+	separator := strings.LastIndex(value, "1")
+	if separator < 1 || separator+7 > len(value) {
+		return "", nil, 0, errors.New("is not a valid address")
+	}
+	hrp := value[:separator]
+
+	var values = make([]byte, 0, len(value)-separator-1)
+	for _, c := range value[separator+1:] {
+		index := strings.IndexRune(bech32Charset, c)
+		if index < 0 {
+			return "", nil, 0, errors.New("is not a valid bech32 address")
+		}
+		values = append(values, byte(index))
+	}
+
+	encoding := bech32Polymod(append(bech32ExpandHRP(hrp), values...))
+	if encoding != bech32Const && encoding != bech32mConst {
+		return "", nil, 0, errors.New("has an invalid checksum")
+	}
+
+	data, err := convertBits(values[:len(values)-6], 5, 8)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	return hrp, data, encoding, nil
+}
+
+func bech32Polymod(values []byte) int {
+	generator := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ int(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	var expanded = make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+	expanded = append(expanded, 0)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+	return expanded
+}
+
+// convertBits regroups 5-bit values into bytes, rejecting non-zero padding.
+func convertBits(values []byte, from, to uint) ([]byte, error) {
+	var acc, bits uint
+	var result []byte
+	maxv := uint(1)<<to - 1
+	for _, v := range values {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			result = append(result, byte(acc>>bits&maxv))
+		}
+	}
+	if bits >= from || (acc<<(to-bits))&maxv != 0 {
+		return nil, errors.New("has invalid padding")
+	}
+	return result, nil
+}
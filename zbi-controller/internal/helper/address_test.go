@@ -0,0 +1,42 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestValidateZcashAddress(t *testing.T) {
+	var tests = []struct {
+		name    string
+		network model.NetworkType
+		address string
+		valid   bool
+	}{
+		{"mainnet transparent", model.NetworkTypeMain, "t1Hsc1LR8yKnbbe3twRp88p6vFfC5t7DLbs", true},
+		{"mainnet sapling", model.NetworkTypeMain, "zs1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5z5tpwxqergd3c8g7ruszzg3rysjjvfeg9y4zkvtfdeq", true},
+		{"mainnet unified", model.NetworkTypeMain, "u1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0jqgfzyvjz2f389q5j52ev95hz78yuhr6", true},
+		{"testnet transparent", model.NetworkTypeTest, "tm9ogR9KukTCiTKvrsSxQwFv2x1vhZTydav", true},
+		{"testnet script", model.NetworkTypeTest, "t26e94XS5n9cxwx1bFZKK3qnrc3MmURMBS5", true},
+		{"testnet sapling", model.NetworkTypeTest, "ztestsapling1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5z5tpwxqergd3c8g7ruszzg3rysjjvfeg9y4zkyumw75", true},
+		{"testnet unified", model.NetworkTypeTest, "utest1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0jqgfzyvjz2f389q5j52ev95hz7622w5h", true},
+		{"wrong network", model.NetworkTypeTest, "t1Hxw6JqWMnhDK5jRCieg5bFHM2qt7UtQvu", false},
+		{"wrong network sapling", model.NetworkTypeMain, "ztestsapling1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5z5tpwxqergd3c8g7ruszzg3rysjjvfeg9y4zkyumw75", false},
+		{"bad checksum", model.NetworkTypeTest, "tm9ogR9KukTCiTKvrsSxQwFv2x1vhZTydaw", false},
+		{"bad bech32 checksum", model.NetworkTypeMain, "zs1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5z5tpwxqergd3c8g7ruszzg3rysjjvfeg9y4zkvtfdeg", false},
+		{"sprout", model.NetworkTypeMain, "zcU1Cd6zYyZCd2VJF8yKgmzjxdiiU1rgTTjEwoN1CGUWCziPkUTXUjXmX7TMqdMNsTfuiGN1jQoVN4kGxUR4sAPN4XZ7pxb", false},
+		{"empty", model.NetworkTypeMain, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateZcashAddress(test.network, test.address)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
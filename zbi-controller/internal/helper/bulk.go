@@ -0,0 +1,147 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const ZCASH_INSTANCE_PROPERTY = "zcashInstance"
+
+// GetInstanceDependencies returns the names of the instances an instance
+// needs to run. Lightwallet instances depend on their zcash backend.
+func GetInstanceDependencies(instance *model.Instance) []string {
+	if instance.InstanceType != model.InstanceTypeLWD || instance.Request == nil {
+		return nil
+	}
+
+	var dependencies = append([]string{}, instance.Request.Peers...)
+	if name, ok := instance.Request.Properties[ZCASH_INSTANCE_PROPERTY].(string); ok && len(name) > 0 {
+		dependencies = append(dependencies, name)
+	}
+	return dependencies
+}
+
+// OrderInstances groups instances into stages so that every instance comes
+// after the instances it depends on, or before them when reverse is set.
+// Dependencies outside of the list are ignored.
+func OrderInstances(instances []model.Instance, reverse bool) [][]model.Instance {
+	var byName = make(map[string]*model.Instance, len(instances))
+	for index := range instances {
+		byName[instances[index].Name] = &instances[index]
+	}
+
+	var depths = make(map[string]int, len(instances))
+	var depth func(instance *model.Instance, visiting map[string]bool) int
+	depth = func(instance *model.Instance, visiting map[string]bool) int {
+		if value, ok := depths[instance.Name]; ok {
+			return value
+		}
+		visiting[instance.Name] = true
+		var value = 0
+		for _, name := range GetInstanceDependencies(instance) {
+			if dependency, ok := byName[name]; ok && !visiting[name] {
+				if d := depth(dependency, visiting) + 1; d > value {
+					value = d
+				}
+			}
+		}
+		delete(visiting, instance.Name)
+		depths[instance.Name] = value
+		return value
+	}
+
+	var stages [][]model.Instance
+	for index := range instances {
+		d := depth(&instances[index], make(map[string]bool))
+		for len(stages) <= d {
+			stages = append(stages, nil)
+		}
+		stages[d] = append(stages[d], instances[index])
+	}
+
+	if reverse {
+		for i, j := 0, len(stages)-1; i < j; i, j = i+1, j-1 {
+			stages[i], stages[j] = stages[j], stages[i]
+		}
+	}
+	return stages
+}
+
+// RunInstanceStages runs fn for the instances of a stage with up to
+// concurrency instances at a time, one stage after the other. An instance is
+// skipped when an instance it waits for failed or was skipped; with reverse
+// set, instances wait for the instances that depend on them.
+func RunInstanceStages(ctx context.Context, stages [][]model.Instance, concurrency int, reverse bool,
+	fn func(ctx context.Context, instance *model.Instance) error) []model.BulkActionResult {
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var waits = make(map[string][]string)
+	for _, stage := range stages {
+		for index := range stage {
+			for _, name := range GetInstanceDependencies(&stage[index]) {
+				if reverse {
+					waits[name] = append(waits[name], stage[index].Name)
+				} else {
+					waits[stage[index].Name] = append(waits[stage[index].Name], name)
+				}
+			}
+		}
+	}
+
+	var results []model.BulkActionResult
+	var failed = make(map[string]bool)
+	for _, stage := range stages {
+		var stageResults = make([]model.BulkActionResult, len(stage))
+		var slots = make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+
+		for index := range stage {
+			instance := &stage[index]
+			stageResults[index] = model.BulkActionResult{Id: instance.Id, Instance: instance.Name}
+
+			if name, ok := blocked(waits[instance.Name], failed); ok {
+				stageResults[index].Status = model.BulkResultSkipped
+				stageResults[index].Error = fmt.Sprintf("instance %s did not succeed", name)
+				continue
+			}
+
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(result *model.BulkActionResult) {
+				defer func() { <-slots; wg.Done() }()
+				if err := fn(ctx, instance); err != nil {
+					result.Status = model.BulkResultFailed
+					result.Error = err.Error()
+				} else {
+					result.Status = model.BulkResultSucceeded
+				}
+			}(&stageResults[index])
+		}
+		wg.Wait()
+
+		for _, result := range stageResults {
+			if result.Status != model.BulkResultSucceeded {
+				failed[result.Instance] = true
+			}
+		}
+		results = append(results, stageResults...)
+	}
+
+	return results
+}
+
+// blocked returns the first of names that did not succeed.
+func blocked(names []string, failed map[string]bool) (string, bool) {
+	for _, name := range names {
+		if failed[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
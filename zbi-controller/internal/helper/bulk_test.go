@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func newBulkInstances() []model.Instance {
+	return []model.Instance{
+		{Id: "1", Name: "lwd-1", InstanceType: model.InstanceTypeLWD, Request: &model.ResourceRequest{Peers: []string{"zcash-1"}}},
+		{Id: "2", Name: "zcash-1", InstanceType: model.InstanceTypeZCASH, Request: &model.ResourceRequest{Peers: []string{"zcash-2"}}},
+		{Id: "3", Name: "zcash-2", InstanceType: model.InstanceTypeZCASH},
+		{Id: "4", Name: "lwd-2", InstanceType: model.InstanceTypeLWD,
+			Request: &model.ResourceRequest{Properties: map[string]interface{}{ZCASH_INSTANCE_PROPERTY: "zcash-2"}}},
+	}
+}
+
+func stageNames(stages [][]model.Instance) [][]string {
+	var result [][]string
+	for _, stage := range stages {
+		var names []string
+		for _, instance := range stage {
+			names = append(names, instance.Name)
+		}
+		result = append(result, names)
+	}
+	return result
+}
+
+func TestOrderInstances(t *testing.T) {
+	assert.Equal(t, [][]string{{"zcash-1", "zcash-2"}, {"lwd-1", "lwd-2"}}, stageNames(OrderInstances(newBulkInstances(), false)))
+	assert.Equal(t, [][]string{{"lwd-1", "lwd-2"}, {"zcash-1", "zcash-2"}}, stageNames(OrderInstances(newBulkInstances(), true)))
+	assert.Empty(t, OrderInstances(nil, false))
+}
+
+func TestRunInstanceStages(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	fn := func(ctx context.Context, instance *model.Instance) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, instance.Name)
+		if instance.Name == "zcash-1" || instance.Name == "lwd-2" {
+			return fmt.Errorf("%s failed", instance.Name)
+		}
+		return nil
+	}
+
+	results := RunInstanceStages(context.Background(), OrderInstances(newBulkInstances(), false), 2, false, fn)
+	var statuses = make(map[string]model.BulkResultStatus)
+	for _, result := range results {
+		statuses[result.Instance] = result.Status
+	}
+	assert.Equal(t, model.BulkResultFailed, statuses["zcash-1"])
+	assert.Equal(t, model.BulkResultSucceeded, statuses["zcash-2"])
+	assert.Equal(t, model.BulkResultSkipped, statuses["lwd-1"])
+	assert.Equal(t, model.BulkResultFailed, statuses["lwd-2"])
+	assert.NotContains(t, order, "lwd-1")
+
+	// stopping skips the zcash backends of lightwallet instances that failed
+	order = nil
+	results = RunInstanceStages(context.Background(), OrderInstances(newBulkInstances(), true), 1, true, fn)
+	statuses = make(map[string]model.BulkResultStatus)
+	for _, result := range results {
+		statuses[result.Instance] = result.Status
+	}
+	assert.Equal(t, model.BulkResultSucceeded, statuses["lwd-1"])
+	assert.Equal(t, model.BulkResultFailed, statuses["lwd-2"])
+	assert.Equal(t, model.BulkResultFailed, statuses["zcash-1"])
+	assert.Equal(t, model.BulkResultSkipped, statuses["zcash-2"])
+	assert.Equal(t, []string{"lwd-1", "lwd-2", "zcash-1"}, order)
+}
@@ -0,0 +1,242 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const MAX_CONFIG_VALUE_LENGTH = 256
+
+// managedConfig are the settings the platform sets for each instance type.
+// They cannot be overridden even if a config schema allows them.
+var managedConfig = map[model.InstanceType][]string{
+	model.InstanceTypeZCASH: {
+		"rpcuser", "rpcpassword", "rpcauth", "rpcbind", "rpcallowip", "rpcport", "rpccookiefile",
+		"bind", "port", "datadir", "conf", "testnet", "regtest", "connect", "externalip",
+		"gen", "genproclimit", "equihashsolver", "mineraddress", "minetolocalwallet",
+	},
+	model.InstanceTypeLWD: {
+		"rpchost", "rpcport", "rpcuser", "rpcpassword", "grpc-bind-addr", "http-bind-addr",
+		"tls-cert", "tls-key", "no-tls-very-insecure", "zcash-conf-path", "data-dir", "log-file",
+		"log-level", "config", "darkside-very-insecure", "gen-cert-very-insecure",
+	},
+}
+
+// IsForbiddenConfig returns true if a setting cannot be overridden on
+// instances of a type.
+func IsForbiddenConfig(iType model.InstanceType, schema *model.ConfigSchema, key string) bool {
+	key = strings.ToLower(key)
+	for _, managed := range managedConfig[iType] {
+		if key == managed {
+			return true
+		}
+	}
+	if schema != nil {
+		for _, forbidden := range schema.Forbidden {
+			if key == strings.ToLower(forbidden) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateConfigOverrides checks the config overrides of an instance
+// against the schema of its node type. It returns the overrides with bool,
+// int64 or string values, and errors keyed by "config.<key>".
+func ValidateConfigOverrides(iType model.InstanceType, schema *model.ConfigSchema, overrides map[string]interface{}) (map[string]interface{}, map[string]string) {
+	var values = make(map[string]interface{})
+	var fieldErrors = make(map[string]string)
+
+	for key, value := range overrides {
+		field := "config." + key
+		if IsForbiddenConfig(iType, schema, key) {
+			fieldErrors[field] = "is managed by the platform"
+			continue
+		}
+
+		var option model.ConfigOption
+		var ok bool
+		if schema != nil {
+			option, ok = schema.Options[key]
+		}
+		if !ok {
+			fieldErrors[field] = "is not a supported setting"
+			continue
+		}
+
+		converted, err := convertConfigValue(option, value)
+		if err != nil {
+			fieldErrors[field] = err.Error()
+			continue
+		}
+		values[key] = converted
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+	return values, nil
+}
+
+func convertConfigValue(option model.ConfigOption, value interface{}) (interface{}, error) {
+	switch option.Type {
+	case model.ConfigValueBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+
+	case model.ConfigValueInt:
+		var number int64
+		switch v := value.(type) {
+		case float64:
+			if v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+				return nil, fmt.Errorf("must be an integer")
+			}
+			number = int64(v)
+		case int:
+			number = int64(v)
+		case int64:
+			number = v
+		default:
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if option.Min != nil && number < *option.Min {
+			return nil, fmt.Errorf("must be at least %d", *option.Min)
+		}
+		if option.Max != nil && number > *option.Max {
+			return nil, fmt.Errorf("must be at most %d", *option.Max)
+		}
+		return number, nil
+
+	case model.ConfigValueString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if len(s) > MAX_CONFIG_VALUE_LENGTH || strings.ContainsAny(s, "\r\n\x00") {
+			return nil, fmt.Errorf("must be a single line of at most %d characters", MAX_CONFIG_VALUE_LENGTH)
+		}
+		if len(option.Values) > 0 {
+			for _, allowed := range option.Values {
+				if s == allowed {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("must be one of %s", strings.Join(option.Values, ", "))
+		}
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("has an unsupported type %s", option.Type)
+}
+
+// MergeZcashConf applies config overrides to zcash.conf settings. An
+// override replaces the settings with the same key and other overrides are
+// appended in key order. Booleans are written as 1 or 0.
+func MergeZcashConf(conf []model.KVPair, overrides map[string]interface{}) []model.KVPair {
+	if len(overrides) == 0 {
+		return conf
+	}
+
+	var merged = make([]model.KVPair, 0, len(conf)+len(overrides))
+	var applied = make(map[string]bool)
+	for _, setting := range conf {
+		value, ok := overrides[setting.Key]
+		if !ok {
+			merged = append(merged, setting)
+			continue
+		}
+		if !applied[setting.Key] {
+			merged = append(merged, model.KVPair{Key: setting.Key, Value: zcashConfValue(value)})
+			applied[setting.Key] = true
+		}
+	}
+
+	for _, key := range sortedKeys(overrides) {
+		if !applied[key] {
+			merged = append(merged, model.KVPair{Key: key, Value: zcashConfValue(overrides[key])})
+		}
+	}
+	return merged
+}
+
+// CreateMiningConf returns the zcash.conf settings of the mining options of
+// an instance, to be merged over the miner settings of its node type.
+func CreateMiningConf(mining *model.MiningConfig) map[string]interface{} {
+	var settings = make(map[string]interface{})
+	if mining == nil {
+		return settings
+	}
+
+	if mining.MinerAddress != "" {
+		settings["mineraddress"] = mining.MinerAddress
+	}
+	if mining.GenProcLimit != nil {
+		settings["genproclimit"] = *mining.GenProcLimit
+	}
+	if mining.Solver != "" {
+		settings["equihashsolver"] = mining.Solver
+	}
+	if mining.MineToLocalWallet != nil {
+		settings["minetolocalwallet"] = *mining.MineToLocalWallet
+	}
+	return settings
+}
+
+// zcashConfValue formats a value for zcash.conf. Stored overrides are read
+// back from JSON, so integers may arrive as float64.
+func zcashConfValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v)
+		}
+	}
+	return value
+}
+
+// CreateLWDConfig renders config overrides as the lines of a lightwalletd
+// YAML config file, with one quoted or plain scalar per key.
+func CreateLWDConfig(overrides map[string]interface{}) []string {
+	if len(overrides) == 0 {
+		return []string{"{}"}
+	}
+
+	var lines = make([]string, 0, len(overrides))
+	for _, key := range sortedKeys(overrides) {
+		var value string
+		switch v := overrides[key].(type) {
+		case string:
+			value = strconv.Quote(v)
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			value = fmt.Sprintf("%v", v)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", key, value))
+	}
+	return lines
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	var keys = make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
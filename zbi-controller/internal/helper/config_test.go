@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestValidateConfigOverrides(t *testing.T) {
+	min, max := int64(4), int64(1024)
+	schema := &model.ConfigSchema{
+		Options: map[string]model.ConfigOption{
+			"txindex": {Type: model.ConfigValueBool},
+			"dbcache": {Type: model.ConfigValueInt, Min: &min, Max: &max},
+			"debug":   {Type: model.ConfigValueString, Values: []string{"net", "rpc"}},
+			"rpcbind": {Type: model.ConfigValueString},
+		},
+		Forbidden: []string{"blocknotify"},
+	}
+
+	values, fieldErrors := ValidateConfigOverrides(model.InstanceTypeZCASH, schema,
+		map[string]interface{}{"txindex": true, "dbcache": float64(512), "debug": "rpc"})
+	assert.Nil(t, fieldErrors)
+	assert.Equal(t, map[string]interface{}{"txindex": true, "dbcache": int64(512), "debug": "rpc"}, values)
+
+	var tests = []struct {
+		name    string
+		key     string
+		value   interface{}
+		message string
+	}{
+		{"managed", "rpcbind", "0.0.0.0", "is managed by the platform"},
+		{"forbidden", "blocknotify", "sh", "is managed by the platform"},
+		{"unknown", "reindex", true, "is not a supported setting"},
+		{"wrong type", "txindex", "yes", "must be a boolean"},
+		{"fraction", "dbcache", 4.5, "must be an integer"},
+		{"below min", "dbcache", float64(2), "must be at least 4"},
+		{"above max", "dbcache", float64(2048), "must be at most 1024"},
+		{"not allowed", "debug", "all", "must be one of net, rpc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, fieldErrors := ValidateConfigOverrides(model.InstanceTypeZCASH, schema, map[string]interface{}{test.key: test.value})
+			assert.Nil(t, values)
+			assert.Equal(t, map[string]string{"config." + test.key: test.message}, fieldErrors)
+		})
+	}
+}
+
+func TestMergeZcashConf(t *testing.T) {
+	conf := []model.KVPair{{Key: "txindex", Value: 1}, {Key: "maxconnections", Value: 6}, {Key: "addnode", Value: "testnet.z.cash"}}
+
+	merged := MergeZcashConf(conf, map[string]interface{}{"txindex": false, "dbcache": float64(512), "debug": "rpc"})
+	assert.Equal(t, []model.KVPair{
+		{Key: "txindex", Value: 0},
+		{Key: "maxconnections", Value: 6},
+		{Key: "addnode", Value: "testnet.z.cash"},
+		{Key: "dbcache", Value: int64(512)},
+		{Key: "debug", Value: "rpc"},
+	}, merged)
+
+	assert.Equal(t, conf, MergeZcashConf(conf, nil))
+}
+
+func TestCreateLWDConfig(t *testing.T) {
+	assert.Equal(t, []string{"{}"}, CreateLWDConfig(nil))
+	assert.Equal(t, []string{"cache-size: 400000", "nocache: true", "sync-from-height: \"1\""},
+		CreateLWDConfig(map[string]interface{}{"nocache": true, "cache-size": float64(400000), "sync-from-height": "1"}))
+}
+
+func TestCreateMiningConf(t *testing.T) {
+	assert.Empty(t, CreateMiningConf(nil))
+
+	limit, local := 2, false
+	mining := &model.MiningConfig{MinerAddress: "tm9ogR9KukTCiTKvrsSxQwFv2x1vhZTydav", GenProcLimit: &limit, Solver: "tromp", MineToLocalWallet: &local}
+	conf := MergeZcashConf([]model.KVPair{{Key: "gen", Value: 1}, {Key: "genproclimit", Value: 1}}, CreateMiningConf(mining))
+	assert.Equal(t, []model.KVPair{
+		{Key: "gen", Value: 1},
+		{Key: "genproclimit", Value: 2},
+		{Key: "equihashsolver", Value: "tromp"},
+		{Key: "mineraddress", Value: "tm9ogR9KukTCiTKvrsSxQwFv2x1vhZTydav"},
+		{Key: "minetolocalwallet", Value: 0},
+	}, conf)
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	COMPONENT_LABEL  = "component"
+	DOMAIN_COMPONENT = "domain"
+
+	PROJECT_DOMAIN_NAME = "project-domain"
+
+	CERTIFICATE_NOT_BEFORE_PROPERTY = "notBefore"
+	CERTIFICATE_NOT_AFTER_PROPERTY  = "notAfter"
+	CERTIFICATE_RENEWAL_PROPERTY    = "renewalTime"
+	CERTIFICATE_DNS_NAMES_PROPERTY  = "dnsNames"
+	CERTIFICATE_ISSUER_PROPERTY     = "issuer"
+	CERTIFICATE_SECRET_PROPERTY     = "secretName"
+
+	CERTIFICATE_READY   = "ready"
+	CERTIFICATE_PENDING = "pending"
+	CERTIFICATE_EXPIRED = "expired"
+)
+
+// DomainResourceName returns the name of the HTTPProxy and Certificate of the
+// custom domain of an instance, or of the project when instance is nil.
+func DomainResourceName(instance *model.Instance) string {
+	if instance == nil {
+		return PROJECT_DOMAIN_NAME
+	}
+	return "domain-" + instance.Name
+}
+
+// DomainSecretName returns the name of the TLS secret of a custom domain.
+func DomainSecretName(instance *model.Instance) string {
+	return DomainResourceName(instance) + "-tls"
+}
+
+// CreateDomainLabels adds the domain component to the labels of the owner so
+// that the monitor can tell the domain resources apart from the others.
+func CreateDomainLabels(labels map[string]string) map[string]string {
+	return createComponentLabels(labels, DOMAIN_COMPONENT)
+}
+
+func createComponentLabels(labels map[string]string, component string) map[string]string {
+	var result = make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		result[key] = value
+	}
+	result[COMPONENT_LABEL] = component
+	return result
+}
+
+func IsDomainObject(labels map[string]string) bool {
+	return labels[COMPONENT_LABEL] == DOMAIN_COMPONENT
+}
+
+// ParseCertificate checks that a PEM certificate chain matches the private key
+// and returns the leaf certificate.
+func ParseCertificate(certificate, privateKey string) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(pair.Certificate[0])
+}
+
+// VerifyCertificateHost checks that the certificate is valid now and covers
+// host.
+func VerifyCertificateHost(cert *x509.Certificate, host string, now time.Time) error {
+	if now.After(cert.NotAfter) {
+		return errors.New("certificate has expired")
+	}
+	if now.Before(cert.NotBefore) {
+		return errors.New("certificate is not valid yet")
+	}
+	return cert.VerifyHostname(strings.ToLower(host))
+}
+
+// GetTLSSecretProperties describes the certificate of an uploaded TLS secret.
+// It returns the certificate status and its validity, never the key.
+func GetTLSSecretProperties(secret *corev1.Secret, now time.Time) (string, map[string]interface{}) {
+	var properties = map[string]interface{}{CERTIFICATE_SECRET_PROPERTY: secret.Name}
+
+	certs, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return CERTIFICATE_PENDING, properties
+	}
+
+	leaf, err := x509.ParseCertificate(certs.Certificate[0])
+	if err != nil {
+		return CERTIFICATE_PENDING, properties
+	}
+
+	properties[CERTIFICATE_NOT_BEFORE_PROPERTY] = leaf.NotBefore.UTC()
+	properties[CERTIFICATE_NOT_AFTER_PROPERTY] = leaf.NotAfter.UTC()
+	properties[CERTIFICATE_DNS_NAMES_PROPERTY] = leaf.DNSNames
+	properties[CERTIFICATE_ISSUER_PROPERTY] = leaf.Issuer.CommonName
+
+	return getCertificateStatus(true, leaf.NotAfter, now), properties
+}
+
+// getCertificateStatus returns expired once notAfter has passed, ready when
+// the certificate has been issued and pending otherwise.
+func getCertificateStatus(issued bool, notAfter, now time.Time) string {
+	if !notAfter.IsZero() && now.After(notAfter) {
+		return CERTIFICATE_EXPIRED
+	}
+	if issued {
+		return CERTIFICATE_READY
+	}
+	return CERTIFICATE_PENDING
+}
+
+// getCertManagerStatus returns the status of a cert-manager Certificate from
+// its Ready condition and expiry.
+func getCertManagerStatus(obj *unstructured.Unstructured, now time.Time) string {
+	var issued = false
+	if conditions, ok := GetResourceField(obj, "status.conditions").([]interface{}); ok {
+		for _, c := range conditions {
+			condition, _ := c.(map[string]interface{})
+			if condition["type"] == "Ready" {
+				issued = condition["status"] == "True"
+			}
+		}
+	}
+
+	notAfter, _ := GetResourceField(obj, "status.notAfter").(string)
+	return getCertificateStatus(issued, ParseTime(notAfter), now)
+}
@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newTestCertificate returns a PEM self-signed certificate for host and its key.
+func newTestCertificate(t *testing.T, host string, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		Issuer:       pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func Test_DomainResourceName(t *testing.T) {
+	instance := &model.Instance{Name: "node"}
+	assert.Equal(t, "domain-node", DomainResourceName(instance))
+	assert.Equal(t, "domain-node-tls", DomainSecretName(instance))
+	assert.Equal(t, PROJECT_DOMAIN_NAME, DomainResourceName(nil))
+
+	labels := CreateDomainLabels(map[string]string{"platform": "zbi", "id": "1"})
+	assert.True(t, IsDomainObject(labels))
+	assert.Equal(t, "1", labels["id"])
+}
+
+func Test_ParseCertificate(t *testing.T) {
+	now := time.Now()
+	certificate, key := newTestCertificate(t, "rpc.example.com", now.Add(24*time.Hour))
+
+	cert, err := ParseCertificate(certificate, key)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyCertificateHost(cert, "RPC.example.com", now))
+	assert.Error(t, VerifyCertificateHost(cert, "other.example.com", now))
+	assert.EqualError(t, VerifyCertificateHost(cert, "rpc.example.com", now.Add(48*time.Hour)), "certificate has expired")
+
+	_, otherKey := newTestCertificate(t, "rpc.example.com", now.Add(24*time.Hour))
+	_, err = ParseCertificate(certificate, otherKey)
+	assert.Error(t, err)
+}
+
+func Test_CreateCoreResource_Certificate(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	certificate, key := newTestCertificate(t, "rpc.example.com", notAfter)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "domain-node-tls", Namespace: "project"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte(certificate), corev1.TLSPrivateKeyKey: []byte(key)},
+	}
+
+	resource := CreateCoreResource(context.Background(), model.ResourceCertificate, secret, nil)
+	assert.Equal(t, model.ResourceCertificate, resource.Type)
+	assert.Equal(t, CERTIFICATE_READY, resource.Status)
+	assert.Equal(t, notAfter.UTC(), resource.Properties[CERTIFICATE_NOT_AFTER_PROPERTY])
+	assert.Equal(t, []string{"rpc.example.com"}, resource.Properties[CERTIFICATE_DNS_NAMES_PROPERTY])
+	assert.NotContains(t, resource.Properties, corev1.TLSPrivateKeyKey)
+
+	status, _ := GetTLSSecretProperties(secret, notAfter.Add(time.Hour))
+	assert.Equal(t, CERTIFICATE_EXPIRED, status)
+}
+
+func Test_GetResourceStatus_Certificate(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     "Certificate",
+		"metadata": map[string]interface{}{"name": "domain-node", "namespace": "project", "creationTimestamp": "2022-01-01T00:00:00Z"},
+		"spec": map[string]interface{}{
+			"secretName": "domain-node-tls",
+			"dnsNames":   []interface{}{"rpc.example.com"},
+			"issuerRef":  map[string]interface{}{"kind": "ClusterIssuer", "name": "cert-issuer"},
+		},
+	}}
+
+	assert.Equal(t, CERTIFICATE_PENDING, GetResourceStatusField(obj))
+
+	notAfter := time.Now().Add(24 * time.Hour).UTC().Format(TIME_LAYOUT)
+	obj.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+		"notAfter":   notAfter,
+	}
+
+	resource := CreateCoreResource(context.Background(), model.ResourceCertificate, obj, nil)
+	assert.Equal(t, CERTIFICATE_READY, resource.Status)
+	assert.Equal(t, "cert-issuer", resource.Properties[CERTIFICATE_ISSUER_PROPERTY])
+	assert.Equal(t, notAfter, resource.Properties[CERTIFICATE_NOT_AFTER_PROPERTY])
+
+	obj.Object["status"].(map[string]interface{})["notAfter"] = "2022-01-01T00:00:00Z"
+	assert.Equal(t, CERTIFICATE_EXPIRED, GetResourceStatusField(obj))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -43,8 +44,13 @@ var (
 		model.ResourcePersistentVolumeClaim: {Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
 		model.ResourceVolumeSnapshot:        {Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"},
 		model.ResourceVolumeSnapshotClass:   {Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotclasses"},
+		model.ResourceVolumeSnapshotContent: {Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotcontents"},
 		model.ResourceSnapshotSchedule:      {Group: "snapscheduler.backube", Version: "v1", Resource: "snapshotschedules"},
 		model.ResourceHTTPProxy:             {Group: "projectcontour.io", Version: "v1", Resource: "httpproxies"},
+		model.ResourceCertificate:           {Group: "cert-manager.io", Version: "v1", Resource: "certificates"},
+		model.ResourceNetworkPolicy:         {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+		model.ResourceExternalSecret:        {Group: "external-secrets.io", Version: "v1beta1", Resource: "externalsecrets"},
+		model.ResourceP2PService:            {Group: "", Version: "v1", Resource: "services"},
 	}
 
 	JSONSerializer = k8sjson.NewSerializerWithOptions(k8sjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, k8sjson.SerializerOptions{Pretty: true})
@@ -75,7 +81,7 @@ func FilterLabels(objLabels map[string]string, filterLabels map[string]string) b
 func DecodeFromYaml(resourceYaml string) (*unstructured.Unstructured, *schema.GroupVersionKind, error) {
 	object := &unstructured.Unstructured{}
 
-	s := k8sjson.NewSerializerWithOptions(k8sjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, k8sjson.SerializerOptions{false, false, false})
+	s := k8sjson.NewSerializerWithOptions(k8sjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, k8sjson.SerializerOptions{Yaml: false, Pretty: false, Strict: false})
 	s.Decode([]byte(resourceYaml), nil, object)
 
 	_, gvk, err := decUnstructured.Decode([]byte(resourceYaml), nil, object)
@@ -165,9 +171,7 @@ func CreateCoreResource(ctx context.Context, rType model.ResourceObjectType, res
 		namespace = cmap.Namespace
 		status = "active"
 		created = cmap.ObjectMeta.CreationTimestamp.Time
-		for key, value := range cmap.Data {
-			properties[key] = value
-		}
+		properties = GetConfigMapProperties(cmap.Data)
 
 	} else if rType == model.ResourceSecret {
 
@@ -176,9 +180,7 @@ func CreateCoreResource(ctx context.Context, rType model.ResourceObjectType, res
 		namespace = secret.Namespace
 		status = "active"
 		created = secret.ObjectMeta.CreationTimestamp.Time
-		for key, value := range secret.Data {
-			properties[key] = string(value)
-		}
+		properties = GetSecretProperties(secret.Data)
 
 	} else if rType == model.ResourceDeployment {
 
@@ -225,6 +227,14 @@ func CreateCoreResource(ctx context.Context, rType model.ResourceObjectType, res
 		status = "active"
 		created = svc.ObjectMeta.CreationTimestamp.Time
 
+	} else if rType == model.ResourceP2PService {
+
+		svc := result.(*corev1.Service)
+		name = svc.Name
+		namespace = svc.Namespace
+		created = svc.ObjectMeta.CreationTimestamp.Time
+		status, properties = GetP2PServiceProperties(ctx, svc, client)
+
 	} else if rType == model.ResourcePersistentVolumeClaim {
 
 		pvc := result.(*corev1.PersistentVolumeClaim)
@@ -253,6 +263,21 @@ func CreateCoreResource(ctx context.Context, rType model.ResourceObjectType, res
 
 	} else if rType == model.ResourceSnapshotSchedule {
 
+	} else if rType == model.ResourceCertificate {
+
+		// uploaded certificates are tracked through their TLS secret
+		if secret, ok := result.(*corev1.Secret); ok {
+			name = secret.Name
+			namespace = secret.Namespace
+			created = secret.ObjectMeta.CreationTimestamp.Time
+			status, properties = GetTLSSecretProperties(secret, time.Now())
+		} else if cert, ok := result.(*unstructured.Unstructured); ok {
+			name = cert.GetName()
+			created = GetResourceCreationTime(cert)
+			namespace = cert.GetNamespace()
+			status = GetResourceStatusField(cert)
+			properties = GetResourceProperties(cert)
+		}
 	}
 
 	return &model.KubernetesResource{
@@ -440,16 +465,36 @@ func CreateYAMLObject(yamlString string) (*unstructured.Unstructured, error) {
 	return &object, nil
 }
 
+// reservedLabels are set by the platform on project and instance objects and
+// cannot be used as user labels.
+var reservedLabels = []string{"platform", "project", "owner", "level", "id", "instance", "type", "network", "app", COMPONENT_LABEL}
+
+// IsReservedLabel returns true if key is a label set by the platform.
+func IsReservedLabel(key string) bool {
+	for _, label := range reservedLabels {
+		if label == key {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateProjectLabels returns the labels of the project objects, which are
+// the user labels of the project plus the platform labels.
 func CreateProjectLabels(project *model.Project) map[string]string {
-	return map[string]string{
-		"platform": "zbi",
-		"project":  project.Name,
-		"owner":    project.Owner,
-		"level":    "project",
-		"id":       project.Id,
-		//"team":     project.TeamId,
-		//"network":  string(project.Network),
+	labels := make(map[string]string, len(project.Labels)+5)
+	for key, value := range project.Labels {
+		if !IsReservedLabel(key) {
+			labels[key] = value
+		}
 	}
+
+	labels["platform"] = "zbi"
+	labels["project"] = project.Name
+	labels["owner"] = project.Owner
+	labels["level"] = "project"
+	labels["id"] = project.Id
+	return labels
 }
 
 func CreateInstanceLabels(instance *model.Instance) map[string]string {
@@ -463,9 +508,14 @@ func CreateInstanceLabels(instance *model.Instance) map[string]string {
 	}
 }
 
-func CreateEnvoySpec(policy *model.PolicyInfo, envoyServicePort int32) model.EnvoySpec {
+func CreateEnvoySpec(policy *model.PolicyInfo, instance *model.Instance, envoyServicePort int32) model.EnvoySpec {
 	envoy := policy.EnvoyConfig
 
+	var rateLimit *model.RateLimit
+	if instance.Request != nil {
+		rateLimit = instance.Request.RateLimit
+	}
+
 	return model.EnvoySpec{
 		Image:                 envoy.Image,
 		Command:               utils.MarshalObject(envoy.Command),
@@ -475,6 +525,9 @@ func CreateEnvoySpec(policy *model.PolicyInfo, envoyServicePort int32) model.Env
 		AuthServerURL:         envoy.AuthServerURL,
 		AuthServerPort:        envoy.AuthServerPort,
 		AuthenticationEnabled: envoy.AuthenticationEnabled,
+		MetricsPort:           envoy.MetricsPort,
+		RateLimit:             rateLimit.Effective(policy.RateLimit),
+		IPAllowPolicy:         GetInstanceIPAllowPolicy(policy, instance),
 	}
 }
 
@@ -512,22 +565,19 @@ func UpdateIngressRoute(ctx context.Context, projIngress *unstructured.Unstructu
 
 	if err = RemoveResourceField(projIngress, "metadata.managedFields"); err != nil {
 		//		logger.Errorf(ctx, "Error removing metadata.managedFields - %s", err)
-		//		return errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return err
+		return errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	if err = RemoveResourceField(projIngress, "spec.status"); err != nil {
 		//		logger.Errorf(ctx, "Error removing spec.status - %s", err)
-		//		return errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return err
+		return errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	routeData := utils.MarshalObject(GetResourceField(projIngress, "spec.routes"))
 	var routes []model.IngressRoute
 	if err = json.Unmarshal([]byte(routeData), &routes); err != nil {
 		//		logger.Errorf(ctx, "Error unmarshalling ingress routes - %s", err)
-		//		return errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return err
+		return errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	var updated = false
@@ -554,8 +604,7 @@ func UpdateIngressRoute(ctx context.Context, projIngress *unstructured.Unstructu
 	//	logger.Debugf(ctx, "Ingress routes: %s", utils.MarshalObject(routes))
 	if err = SetResourceField(projIngress, "spec.routes", routes); err != nil {
 		//		logger.Errorf(ctx, "Error setting spec.status - %s", err)
-		//		return errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return err
+		return errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	return nil
@@ -675,7 +724,7 @@ func RemoveResourceField(obj *unstructured.Unstructured, path string) error {
 
 // GetResourceProperties returns the corresponding property type for a kubernetes resource
 // returns map of data entries for ConfigMap
-// returns map of data entries (base64 decoded) for Secret
+// returns the key names and content hash for Secret
 // returns map of requested size, actual size, storage class name and volume name for PersistentVolumeClaim
 // returns an empty map for all other resources
 func GetResourceProperties(obj *unstructured.Unstructured) map[string]interface{} {
@@ -684,15 +733,17 @@ func GetResourceProperties(obj *unstructured.Unstructured) map[string]interface{
 	switch model.ResourceObjectType(kind) {
 	case model.ResourceConfigMap:
 
-		return GetResourceField(obj, "data").(map[string]interface{})
+		var data = make(map[string]string)
+		if values, ok := GetResourceField(obj, "data").(map[string]interface{}); ok {
+			for key, value := range values {
+				data[key], _ = value.(string)
+			}
+		}
+		return GetConfigMapProperties(data)
 
 	case model.ResourceSecret:
 
-		data := make(map[string]interface{}, 0)
-		for key, value := range GetResourceField(obj, "data").(map[string]interface{}) {
-			data[key] = utils.Base64DecodeString(value.(string))
-		}
-		return data
+		return GetSecretProperties(getUnstructuredSecretData(GetResourceField(obj, "data")))
 
 	case model.ResourcePersistentVolumeClaim:
 
@@ -724,6 +775,15 @@ func GetResourceProperties(obj *unstructured.Unstructured) map[string]interface{
 			}
 		}
 
+	case model.ResourceCertificate:
+		return map[string]interface{}{
+			CERTIFICATE_SECRET_PROPERTY:    GetResourceField(obj, "spec.secretName"),
+			CERTIFICATE_DNS_NAMES_PROPERTY: GetResourceField(obj, "spec.dnsNames"),
+			CERTIFICATE_ISSUER_PROPERTY:    GetResourceField(obj, "spec.issuerRef.name"),
+			CERTIFICATE_NOT_AFTER_PROPERTY: GetResourceField(obj, "status.notAfter"),
+			CERTIFICATE_RENEWAL_PROPERTY:   GetResourceField(obj, "status.renewalTime"),
+		}
+
 	case model.ResourcePod:
 		ownerReferences := GetResourceField(obj, "metadata.ownerReferences").([]interface{})
 		ownerReference := ownerReferences[0].(map[string]interface{})
@@ -801,6 +861,9 @@ func GetResourceStatusField(obj *unstructured.Unstructured) string {
 
 	case model.ResourceHTTPProxy:
 		status = strings.ToLower(GetResourceField(obj, "status.currentStatus").(string))
+
+	case model.ResourceCertificate:
+		status = getCertManagerStatus(obj, time.Now())
 	}
 
 	return status
@@ -814,3 +877,11 @@ func ParseTime(t string) time.Time {
 
 	return pt
 }
+
+const ZCASH_SERVICE_PREFIX = "zcashd-svc"
+
+// GetZcashServiceHost returns the in-cluster host of the RPC service of a
+// zcash instance.
+func GetZcashServiceHost(name, namespace string) string {
+	return fmt.Sprintf("%s-%s.%s.svc.cluster.local", ZCASH_SERVICE_PREFIX, name, namespace)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -118,6 +119,16 @@ func Test_FilterLabels(t *testing.T) {
 	}
 }
 
+func Test_CreateProjectLabels(t *testing.T) {
+	project := &model.Project{Id: "1", Name: "proj", Owner: "alice", Labels: map[string]string{"env": "dev", "owner": "bob"}}
+	labels := CreateProjectLabels(project)
+	assert.Equal(t, "dev", labels["env"])
+	assert.Equal(t, "alice", labels["owner"])
+	assert.Equal(t, "zbi", labels["platform"])
+	assert.True(t, IsReservedLabel(COMPONENT_LABEL))
+	assert.False(t, IsReservedLabel("env"))
+}
+
 func Test_DecodeFromYaml(t *testing.T) {
 
 	obj, gvk, err := DecodeFromYaml(NginxYAML)
@@ -276,3 +287,20 @@ func Test_GetDynamicResourceInterface(t *testing.T) {
 func Test_GenerateKubernetesObjects(t *testing.T) {
 
 }
+
+func Test_CreateEnvoySpec(t *testing.T) {
+	policy := &model.PolicyInfo{RateLimit: &model.RateLimit{RequestsPerSecond: 50, Burst: 100}}
+	policy.EnvoyConfig.MetricsPort = 9902
+	instance := &model.Instance{Request: &model.ResourceRequest{}}
+
+	spec := CreateEnvoySpec(policy, instance, 28232)
+	assert.Equal(t, int32(28232), spec.Port)
+	assert.Equal(t, int32(9902), spec.MetricsPort)
+	assert.Equal(t, &model.RateLimit{RequestsPerSecond: 50, Burst: 100}, spec.RateLimit)
+
+	instance.Request.RateLimit = &model.RateLimit{RequestsPerSecond: 5, DailyQuota: 1000}
+	assert.Equal(t, &model.RateLimit{RequestsPerSecond: 5, Burst: 5, DailyQuota: 1000}, CreateEnvoySpec(policy, instance, 28232).RateLimit)
+
+	instance.Request.RateLimit = &model.RateLimit{}
+	assert.Nil(t, CreateEnvoySpec(policy, instance, 28232).RateLimit)
+}
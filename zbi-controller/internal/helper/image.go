@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// nodeImages are the images that run the node of an instance type. They
+// are the images an instance is pinned to; other images of the node type,
+// such as the metrics exporter, follow the configured default.
+var nodeImages = map[model.InstanceType]string{
+	model.InstanceTypeZCASH: "node",
+	model.InstanceTypeLWD:   "lwd",
+}
+
+// GetNodeImageName returns the name of the node image of an instance type.
+func GetNodeImageName(iType model.InstanceType) string {
+	return nodeImages[iType]
+}
+
+// GetInstanceImage returns the repository of an image for an instance. The
+// node image resolves to the version the instance is pinned to and falls
+// back to the default version when the instance is not pinned or its
+// version is no longer configured.
+func GetInstanceImage(ic *model.BlockchainNodeInfo, instance *model.Instance, name string) string {
+	if instance != nil && instance.Image != nil && instance.Image.Name == name {
+		if image := ic.GetImageVersion(name, instance.Image.Version); image != nil {
+			return image.Url
+		}
+	}
+	return ic.GetImageRepository(name)
+}
+
+// GetImagePin returns the image an instance is pinned to. An instance that
+// is not pinned runs the default version of its node image.
+func GetImagePin(ic *model.BlockchainNodeInfo, instance *model.Instance) *model.ImagePin {
+	if instance.Image != nil {
+		return instance.Image
+	}
+
+	name := GetNodeImageName(instance.InstanceType)
+	image := ic.GetImage(name)
+	if image == nil {
+		return nil
+	}
+	return &model.ImagePin{Name: name, Version: image.Version}
+}
+
+// NewImagePin pins an instance to version, recording its current version
+// as the one to roll back to.
+func NewImagePin(current *model.ImagePin, name, version string, now time.Time) *model.ImagePin {
+	pin := &model.ImagePin{Name: name, Version: version, UpdatedAt: &now}
+	if current != nil && current.Version != version {
+		pin.Previous = current.Version
+	} else if current != nil {
+		pin.Previous = current.Previous
+	}
+	return pin
+}
+
+// GetImageCatalog returns the image versions available to each node type,
+// with the default version of each image marked.
+func GetImageCatalog() map[string][]model.ImageInfo {
+	var catalog = make(map[string][]model.ImageInfo)
+	for nodeType, node := range blockchainnodes {
+		images := make([]model.ImageInfo, 0, len(node.Images))
+		for _, image := range node.Images {
+			image.Default = image.Version == node.GetImage(image.Name).Version
+			images = append(images, image)
+		}
+		sort.SliceStable(images, func(i, j int) bool { return images[i].Name < images[j].Name })
+		catalog[nodeType] = images
+	}
+	return catalog
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestGetInstanceImage(t *testing.T) {
+	ic := &model.BlockchainNodeInfo{Images: []model.ImageInfo{
+		{Name: "node", Version: "v1", Url: "zcashd:v1"},
+		{Name: "metrics", Version: "v3", Url: "exporter:v3"},
+		{Name: "node", Version: "v2", Url: "zcashd:v2", Default: true},
+	}}
+
+	var tests = []struct {
+		name     string
+		instance *model.Instance
+		image    string
+		expected string
+	}{
+		{"unpinned", &model.Instance{InstanceType: model.InstanceTypeZCASH}, "node", "zcashd:v2"},
+		{"pinned", &model.Instance{Image: &model.ImagePin{Name: "node", Version: "v1"}}, "node", "zcashd:v1"},
+		{"removed version", &model.Instance{Image: &model.ImagePin{Name: "node", Version: "v0"}}, "node", "zcashd:v2"},
+		{"other image", &model.Instance{Image: &model.ImagePin{Name: "node", Version: "v1"}}, "metrics", "exporter:v3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, GetInstanceImage(ic, test.instance, test.image))
+		})
+	}
+
+	pin := GetImagePin(ic, &model.Instance{InstanceType: model.InstanceTypeZCASH})
+	assert.Equal(t, &model.ImagePin{Name: "node", Version: "v2"}, pin)
+	assert.Nil(t, GetImagePin(ic, &model.Instance{InstanceType: model.InstanceTypeLWD}))
+}
+
+func TestNewImagePin(t *testing.T) {
+	now := time.Now()
+
+	pin := NewImagePin(&model.ImagePin{Name: "node", Version: "v1"}, "node", "v2", now)
+	assert.Equal(t, "v2", pin.Version)
+	assert.Equal(t, "v1", pin.Previous)
+
+	// re-applying the current version keeps the version to roll back to
+	pin = NewImagePin(pin, "node", "v2", now)
+	assert.Equal(t, "v1", pin.Previous)
+
+	pin = NewImagePin(nil, "node", "v2", now)
+	assert.Empty(t, pin.Previous)
+}
@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"net"
+
+	"github.com/zbitech/controller/pkg/model"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	IP_ALLOW_SOURCE_PEER   = "Peer"
+	IP_ALLOW_SOURCE_REMOTE = "Remote"
+)
+
+// NormalizeIPAllowList converts the IP addresses of an allow-list to single
+// host CIDR ranges and the CIDR ranges to their network address, dropping
+// duplicates. Entries that do not parse are dropped; requests are validated
+// before they get here.
+func NormalizeIPAllowList(allowList []string) []string {
+	var result = make([]string, 0, len(allowList))
+	var seen = make(map[string]bool, len(allowList))
+
+	for _, entry := range allowList {
+		var cidr string
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip.To4() != nil {
+				cidr = ip.String() + "/32"
+			} else {
+				cidr = ip.String() + "/128"
+			}
+		} else if _, network, err := net.ParseCIDR(entry); err == nil {
+			cidr = network.String()
+		} else {
+			continue
+		}
+
+		if !seen[cidr] {
+			seen[cidr] = true
+			result = append(result, cidr)
+		}
+	}
+
+	return result
+}
+
+// CreateIPAllowPolicy returns the HTTPProxy ipAllowPolicy of an allow-list.
+// The source defaults to Peer, the address of the connecting client.
+func CreateIPAllowPolicy(allowList []string, source string) []model.IPFilterPolicy {
+	if len(allowList) == 0 {
+		return nil
+	}
+
+	if source != IP_ALLOW_SOURCE_REMOTE {
+		source = IP_ALLOW_SOURCE_PEER
+	}
+
+	var policies = make([]model.IPFilterPolicy, 0, len(allowList))
+	for _, cidr := range NormalizeIPAllowList(allowList) {
+		policies = append(policies, model.IPFilterPolicy{Source: source, Cidr: cidr})
+	}
+	return policies
+}
+
+// GetInstanceIPAllowPolicy returns the ipAllowPolicy of the instance ingress.
+func GetInstanceIPAllowPolicy(policy *model.PolicyInfo, instance *model.Instance) []model.IPFilterPolicy {
+	if instance.Request == nil {
+		return nil
+	}
+	return CreateIPAllowPolicy(instance.Request.IPAllowList, policy.NetworkPolicy.IPAllowSource)
+}
+
+func CreateNetworkPolicySpec(policy *model.PolicyInfo) model.NetworkPolicySpec {
+	return model.NetworkPolicySpec{
+		IngressNamespace:    policy.NetworkPolicy.IngressNamespace,
+		MonitoringNamespace: policy.NetworkPolicy.MonitoringNamespace,
+	}
+}
+
+// GetNetworkPolicyInfo describes a NetworkPolicy. When podLabels is not nil
+// it reports whether the policy selects pods with those labels.
+func GetNetworkPolicyInfo(obj *unstructured.Unstructured, podLabels map[string]string) (*model.NetworkPolicyInfo, bool, error) {
+	var policy networkingv1.NetworkPolicy
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &policy); err != nil {
+		return nil, false, err
+	}
+
+	info := &model.NetworkPolicyInfo{Name: policy.Name, Spec: policy.Spec}
+	if podLabels == nil {
+		return info, true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return info, selector.Matches(labels.Set(podLabels)), nil
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_NormalizeIPAllowList(t *testing.T) {
+	allowList := NormalizeIPAllowList([]string{"203.0.113.7", "198.51.100.12/24", "203.0.113.7/32", "2001:db8::1", "invalid"})
+	assert.Equal(t, []string{"203.0.113.7/32", "198.51.100.0/24", "2001:db8::1/128"}, allowList)
+	assert.Empty(t, NormalizeIPAllowList(nil))
+}
+
+func Test_CreateEnvoySpec_IPAllowPolicy(t *testing.T) {
+	var policy model.PolicyInfo
+	instance := &model.Instance{Name: "node", Request: &model.ResourceRequest{IPAllowList: []string{"203.0.113.7"}}}
+
+	envoy := CreateEnvoySpec(&policy, instance, 25000)
+	assert.Equal(t, []model.IPFilterPolicy{{Source: IP_ALLOW_SOURCE_PEER, Cidr: "203.0.113.7/32"}}, envoy.IPAllowPolicy)
+
+	policy.NetworkPolicy.IPAllowSource = IP_ALLOW_SOURCE_REMOTE
+	envoy = CreateEnvoySpec(&policy, instance, 25000)
+	assert.Equal(t, IP_ALLOW_SOURCE_REMOTE, envoy.IPAllowPolicy[0].Source)
+
+	instance.Request.IPAllowList = nil
+	assert.Nil(t, CreateEnvoySpec(&policy, instance, 25000).IPAllowPolicy)
+}
+
+func Test_GetNetworkPolicyInfo(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata":   map[string]interface{}{"name": "allow-lwd-to-zcash", "namespace": "project"},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"level": "instance", "type": "zcash"}},
+			"policyTypes": []interface{}{"Ingress"},
+		},
+	}}
+
+	zcash := CreateInstanceLabels(&model.Instance{Name: "node", InstanceType: model.InstanceTypeZCASH})
+	info, selected, err := GetNetworkPolicyInfo(obj, zcash)
+	assert.NoError(t, err)
+	assert.True(t, selected)
+	assert.Equal(t, "allow-lwd-to-zcash", info.Name)
+
+	lwd := CreateInstanceLabels(&model.Instance{Name: "wallet", InstanceType: model.InstanceTypeLWD})
+	_, selected, err = GetNetworkPolicyInfo(obj, lwd)
+	assert.NoError(t, err)
+	assert.False(t, selected)
+
+	_, selected, err = GetNetworkPolicyInfo(obj, nil)
+	assert.NoError(t, err)
+	assert.True(t, selected)
+}
@@ -0,0 +1,174 @@
+package helper
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	P2P_COMPONENT       = "p2p"
+	P2P_PORT_NAME       = "p2p"
+	PUBLIC_P2P_PROPERTY = "publicP2P"
+
+	P2P_ADDRESS_PROPERTY      = "externalAddress"
+	P2P_SERVICE_TYPE_PROPERTY = "serviceType"
+
+	EXTERNALIP_ZCASH_PROPERTY = "externalip"
+	ZCASH_CONF_KEY            = "zcash.conf"
+
+	P2P_PENDING = "pending"
+	P2P_ACTIVE  = "active"
+)
+
+// IsPublicP2P reports whether the peer-to-peer port of the instance is
+// published outside the cluster.
+func IsPublicP2P(instance *model.Instance) bool {
+	if instance.Request == nil {
+		return false
+	}
+	value, _ := instance.Request.Properties[PUBLIC_P2P_PROPERTY].(bool)
+	return value
+}
+
+// P2PServiceName returns the name of the Service publishing the peer-to-peer
+// port of an instance.
+func P2PServiceName(name string) string {
+	return "zcashd-p2p-" + name
+}
+
+// ZcashConfName returns the name of the ConfigMap holding zcash.conf.
+func ZcashConfName(name string) string {
+	return "zcash-conf-" + name
+}
+
+func CreateP2PLabels(labels map[string]string) map[string]string {
+	return createComponentLabels(labels, P2P_COMPONENT)
+}
+
+func IsP2PObject(labels map[string]string) bool {
+	return labels[COMPONENT_LABEL] == P2P_COMPONENT
+}
+
+// CreateP2PSpec returns the p2p Service of an instance. The service type is
+// set by policy and defaults to LoadBalancer.
+func CreateP2PSpec(policy *model.PolicyInfo, instance *model.Instance) model.P2PSpec {
+	serviceType := string(corev1.ServiceTypeLoadBalancer)
+	if policy.P2P.ServiceType == string(corev1.ServiceTypeNodePort) {
+		serviceType = policy.P2P.ServiceType
+	}
+
+	return model.P2PSpec{
+		Name:        P2PServiceName(instance.Name),
+		ServiceType: serviceType,
+		Labels:      CreateP2PLabels(CreateInstanceLabels(instance)),
+	}
+}
+
+// GetP2PServiceAddress returns the host:port peers reach the node on, or an
+// empty string until one has been allocated. A LoadBalancer is reached on the
+// service port of its ingress address and a NodePort on the node port of
+// nodeAddress, the external address of the node running the pod.
+func GetP2PServiceAddress(svc *corev1.Service, nodeAddress string) string {
+	var port corev1.ServicePort
+	for _, p := range svc.Spec.Ports {
+		if p.Name == P2P_PORT_NAME {
+			port = p
+		}
+	}
+
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				return net.JoinHostPort(ingress.IP, strconv.Itoa(int(port.Port)))
+			}
+			if ingress.Hostname != "" {
+				return net.JoinHostPort(ingress.Hostname, strconv.Itoa(int(port.Port)))
+			}
+		}
+	case corev1.ServiceTypeNodePort:
+		if nodeAddress != "" && port.NodePort != 0 {
+			return net.JoinHostPort(nodeAddress, strconv.Itoa(int(port.NodePort)))
+		}
+	}
+
+	return ""
+}
+
+// GetNodeExternalAddress returns the external IP of a node, or its external
+// DNS name when it has no IP.
+func GetNodeExternalAddress(node *corev1.Node) string {
+	var hostname string
+	for _, address := range node.Status.Addresses {
+		switch address.Type {
+		case corev1.NodeExternalIP:
+			return address.Address
+		case corev1.NodeExternalDNS:
+			if hostname == "" {
+				hostname = address.Address
+			}
+		}
+	}
+	return hostname
+}
+
+// GetP2PServiceProperties describes the p2p Service of an instance. It is
+// pending until the service has an external address.
+func GetP2PServiceProperties(ctx context.Context, svc *corev1.Service, client interfaces.KlientIF) (string, map[string]interface{}) {
+	var nodeAddress string
+	if svc.Spec.Type == corev1.ServiceTypeNodePort {
+		for _, pod := range client.GetPods(ctx, svc.Namespace, svc.Spec.Selector) {
+			if pod.Spec.NodeName == "" {
+				continue
+			}
+			node, err := client.GetKubernetesClient().CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+			if err == nil {
+				nodeAddress = GetNodeExternalAddress(node)
+			}
+			break
+		}
+	}
+
+	var properties = map[string]interface{}{P2P_SERVICE_TYPE_PROPERTY: string(svc.Spec.Type)}
+	address := GetP2PServiceAddress(svc, nodeAddress)
+	if address == "" {
+		return P2P_PENDING, properties
+	}
+
+	properties[P2P_ADDRESS_PROPERTY] = address
+	return P2P_ACTIVE, properties
+}
+
+// GetP2PAddress returns the external address recorded for the p2p Service of
+// an instance.
+func GetP2PAddress(instance *model.Instance) string {
+	if instance.Resources == nil || instance.Resources.P2p == nil {
+		return ""
+	}
+	address, _ := instance.Resources.P2p.Properties[P2P_ADDRESS_PROPERTY].(string)
+	return address
+}
+
+// SetZcashConfExternalIP replaces the externalip setting of a rendered
+// zcash.conf. An empty address removes it.
+func SetZcashConfExternalIP(conf, address string) string {
+	var lines = make([]string, 0)
+	for _, line := range strings.Split(strings.TrimRight(conf, "\n"), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), EXTERNALIP_ZCASH_PROPERTY+"=") {
+			lines = append(lines, line)
+		}
+	}
+
+	if address != "" {
+		lines = append(lines, EXTERNALIP_ZCASH_PROPERTY+"="+address)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_IsPublicP2P(t *testing.T) {
+	assert.False(t, IsPublicP2P(&model.Instance{}))
+	assert.False(t, IsPublicP2P(&model.Instance{Request: &model.ResourceRequest{Properties: map[string]interface{}{PUBLIC_P2P_PROPERTY: "true"}}}))
+	assert.True(t, IsPublicP2P(&model.Instance{Request: &model.ResourceRequest{Properties: map[string]interface{}{PUBLIC_P2P_PROPERTY: true}}}))
+}
+
+func Test_CreateP2PSpec(t *testing.T) {
+	var policy model.PolicyInfo
+	instance := &model.Instance{Name: "node", Id: "1"}
+
+	spec := CreateP2PSpec(&policy, instance)
+	assert.Equal(t, "zcashd-p2p-node", spec.Name)
+	assert.Equal(t, "LoadBalancer", spec.ServiceType)
+	assert.True(t, IsP2PObject(spec.Labels))
+	assert.Equal(t, "node", spec.Labels["instance"])
+
+	policy.P2P.ServiceType = "NodePort"
+	assert.Equal(t, "NodePort", CreateP2PSpec(&policy, instance).ServiceType)
+
+	policy.P2P.ServiceType = "ClusterIP"
+	assert.Equal(t, "LoadBalancer", CreateP2PSpec(&policy, instance).ServiceType)
+}
+
+func Test_GetP2PServiceAddress(t *testing.T) {
+	svc := &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer,
+		Ports: []corev1.ServicePort{{Name: P2P_PORT_NAME, Port: 8233, NodePort: 30233}}}}
+	assert.Empty(t, GetP2PServiceAddress(svc, ""))
+
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.7"}}
+	assert.Equal(t, "203.0.113.7:8233", GetP2PServiceAddress(svc, ""))
+
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+	assert.Equal(t, "lb.example.com:8233", GetP2PServiceAddress(svc, ""))
+
+	svc.Spec.Type = corev1.ServiceTypeNodePort
+	assert.Empty(t, GetP2PServiceAddress(svc, ""))
+	assert.Equal(t, "198.51.100.4:30233", GetP2PServiceAddress(svc, "198.51.100.4"))
+	assert.Equal(t, "[2001:db8::1]:30233", GetP2PServiceAddress(svc, "2001:db8::1"))
+}
+
+func Test_GetNodeExternalAddress(t *testing.T) {
+	node := &corev1.Node{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.4"},
+		{Type: corev1.NodeExternalDNS, Address: "node.example.com"},
+	}}}
+	assert.Equal(t, "node.example.com", GetNodeExternalAddress(node))
+
+	node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "198.51.100.4"})
+	assert.Equal(t, "198.51.100.4", GetNodeExternalAddress(node))
+
+	node.Status.Addresses = node.Status.Addresses[:1]
+	assert.Empty(t, GetNodeExternalAddress(node))
+}
+
+func Test_SetZcashConfExternalIP(t *testing.T) {
+	conf := "txindex=1\nlisten=1\n"
+
+	conf = SetZcashConfExternalIP(conf, "203.0.113.7:8233")
+	assert.Equal(t, "txindex=1\nlisten=1\nexternalip=203.0.113.7:8233\n", conf)
+
+	conf = SetZcashConfExternalIP(conf, "203.0.113.8:8233")
+	assert.Equal(t, "txindex=1\nlisten=1\nexternalip=203.0.113.8:8233\n", conf)
+
+	assert.Equal(t, "txindex=1\nlisten=1\n", SetZcashConfExternalIP(conf, ""))
+}
+
+func Test_GetP2PAddress(t *testing.T) {
+	instance := &model.Instance{}
+	assert.Empty(t, GetP2PAddress(instance))
+
+	instance.Resources = &model.KubernetesResources{P2p: &model.KubernetesResource{Status: P2P_ACTIVE,
+		Properties: map[string]interface{}{P2P_ADDRESS_PROPERTY: "203.0.113.7:8233"}}}
+	assert.Equal(t, "203.0.113.7:8233", GetP2PAddress(instance))
+}
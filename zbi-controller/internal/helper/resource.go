@@ -38,6 +38,17 @@ var (
 	SNAPSHOT        = "SNAPSHOT"
 	VOLUME_SNAPSHOT = "VOLUME_SNAPSHOT"
 	INSTANCE_LIST   = "INSTANCE_LIST"
+
+	DOMAIN_CERTIFICATE = "DOMAIN_CERTIFICATE"
+	DOMAIN_SECRET      = "DOMAIN_SECRET"
+	DOMAIN_INGRESS     = "DOMAIN_INGRESS"
+
+	NETWORK_POLICY_DEFAULT    = "NETWORK_POLICY_DEFAULT"
+	NETWORK_POLICY_INGRESS    = "NETWORK_POLICY_INGRESS"
+	NETWORK_POLICY_PEERS      = "NETWORK_POLICY_PEERS"
+	NETWORK_POLICY_LWD        = "NETWORK_POLICY_LWD"
+	NETWORK_POLICY_MONITORING = "NETWORK_POLICY_MONITORING"
+	NETWORK_POLICY_P2P        = "NETWORK_POLICY_P2P"
 )
 
 var cache *ttlcache.Cache[string, interface{}]
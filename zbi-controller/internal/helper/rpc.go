@@ -0,0 +1,167 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	RPC_SERVICE_PORT = "service"
+
+	MAX_RPC_BATCH         = 20
+	MAX_RPC_RESPONSE_SIZE = 1 << 20
+)
+
+var ErrRPCResponseTooLarge = errors.New("rpc response is larger than the limit")
+
+// RPCError is an error returned by the RPC server of a node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d - %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JsonRPC string        `json:"jsonrpc"`
+	Id      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// CallInstanceRPC calls an RPC method of a zcash instance through its
+// service, with the instance credentials, and decodes the result into
+// result.
+func CallInstanceRPC(ctx context.Context, client *http.Client, project *model.Project, instance *model.Instance, method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	payload, err := json.Marshal(rpcRequest{JsonRPC: "1.0", Id: "zbi", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := newInstanceRPCRequest(ctx, project, instance, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response rpcResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s returned status %d", method, resp.StatusCode)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return fmt.Errorf("%s returned no result", method)
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// ForwardInstanceRPC posts a JSON-RPC request body to a zcash instance, with
+// the instance credentials, and returns the response body of the node. RPC
+// errors are part of the response; a response that is not JSON or is larger
+// than limit bytes is an error.
+func ForwardInstanceRPC(ctx context.Context, client *http.Client, project *model.Project, instance *model.Instance, body []byte, limit int64) ([]byte, error) {
+	req, err := newInstanceRPCRequest(ctx, project, instance, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(result)) > limit {
+		return nil, fmt.Errorf("%w of %d bytes", ErrRPCResponseTooLarge, limit)
+	}
+	if !json.Valid(result) {
+		return nil, fmt.Errorf("node returned status %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+// RPCMethods returns the methods of a JSON-RPC call or batch of calls.
+// Batches must hold between one and MAX_RPC_BATCH calls and every call must
+// name a method.
+func RPCMethods(body []byte) ([]string, error) {
+	type rpcCall struct {
+		Method string `json:"method"`
+	}
+
+	var calls []rpcCall
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &calls); err != nil {
+			return nil, errors.New("body must be a JSON-RPC call or batch of calls")
+		}
+		if len(calls) == 0 || len(calls) > MAX_RPC_BATCH {
+			return nil, fmt.Errorf("batch must hold between 1 and %d calls", MAX_RPC_BATCH)
+		}
+	} else {
+		var call rpcCall
+		if err := json.Unmarshal(body, &call); err != nil {
+			return nil, errors.New("body must be a JSON-RPC call or batch of calls")
+		}
+		calls = append(calls, call)
+	}
+
+	var methods = make([]string, 0, len(calls))
+	for _, call := range calls {
+		if call.Method == "" {
+			return nil, errors.New("every call must name a method")
+		}
+		methods = append(methods, call.Method)
+	}
+	return methods, nil
+}
+
+func newInstanceRPCRequest(ctx context.Context, project *model.Project, instance *model.Instance, payload []byte) (*http.Request, error) {
+	ic, err := GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	credentials, err := zclient.GetInstanceCredentials(ctx, project, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("http://%s:%d/", GetZcashServiceHost(instance.Name, project.GetNamespace()), ic.GetPort(RPC_SERVICE_PORT))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(credentials.Username, credentials.Password)
+	return req, nil
+}
@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCMethods(t *testing.T) {
+	methods, err := RPCMethods([]byte(`{"jsonrpc": "1.0", "id": 1, "method": "getblockcount", "params": []}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"getblockcount"}, methods)
+
+	methods, err = RPCMethods([]byte(` [{"method": "getinfo"}, {"method": "getblock", "params": ["1"]}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"getinfo", "getblock"}, methods)
+
+	batch := "[" + strings.TrimSuffix(strings.Repeat(`{"method": "getinfo"},`, MAX_RPC_BATCH+1), ",") + "]"
+	for _, body := range []string{`[]`, batch, `{"id": 1}`, `[{"method": "getinfo"}, {}]`, `{"method": 1}`, `"getinfo"`} {
+		_, err = RPCMethods([]byte(body))
+		assert.Error(t, err, body)
+	}
+}
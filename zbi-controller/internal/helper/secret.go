@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	SECRET_KEYS_PROPERTY = "keys"
+	SECRET_HASH_PROPERTY = "hash"
+
+	SECRET_USERNAME_KEY = "username"
+	SECRET_PASSWORD_KEY = "password"
+
+	SECRET_PREVIOUS_USERNAME_KEY   = "previous-username"
+	SECRET_PREVIOUS_PASSWORD_KEY   = "previous-password"
+	SECRET_PREVIOUS_EXPIRES_AT_KEY = "previous-expires-at"
+)
+
+// sensitiveConfigKeys are ConfigMap entries that embed credentials, such as
+// the Envoy configuration with the upstream Authorization header.
+var sensitiveConfigKeys = map[string]bool{"envoy.yaml": true}
+
+// nodeConfigKeys are ConfigMap entries holding node configuration files,
+// which can carry RPC credentials through config overrides.
+var nodeConfigKeys = map[string]bool{"zcash.conf": true, "lightwalletd.yaml": true}
+
+// credentialSettings are the node settings removed from reported node
+// configuration files.
+var credentialSettings = map[string]bool{"rpcuser": true, "rpcpassword": true, "rpcauth": true}
+
+// GetConfigMapProperties returns the ConfigMap data with sensitive entries
+// replaced by a hash of their content and credentials removed from node
+// configuration files.
+func GetConfigMapProperties(data map[string]string) map[string]interface{} {
+	var properties = make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if sensitiveConfigKeys[key] {
+			sum := sha256.Sum256([]byte(value))
+			properties[key] = "sha256:" + hex.EncodeToString(sum[:])
+		} else if nodeConfigKeys[key] {
+			properties[key] = stripCredentials(value)
+		} else {
+			properties[key] = value
+		}
+	}
+	return properties
+}
+
+// stripCredentials removes the credential settings from a node configuration
+// file, written either as key=value or as key: value lines.
+func stripCredentials(config string) string {
+	var lines = strings.Split(config, "\n")
+	var result = make([]string, 0, len(lines))
+	for _, line := range lines {
+		setting := strings.TrimSpace(line)
+		if index := strings.IndexAny(setting, "=:"); index >= 0 {
+			setting = strings.TrimSpace(setting[:index])
+		}
+		if credentialSettings[strings.ToLower(setting)] {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// GetSecretProperties describes secret data without exposing it. Only the
+// key names and a hash of the content are reported so that changes can be
+// detected without the values leaving the cluster.
+func GetSecretProperties(data map[string][]byte) map[string]interface{} {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+
+	return map[string]interface{}{
+		SECRET_KEYS_PROPERTY: keys,
+		SECRET_HASH_PROPERTY: "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+	}
+}
+
+// getUnstructuredSecretData decodes the base64 data of a secret read through
+// the dynamic client.
+func getUnstructuredSecretData(data interface{}) map[string][]byte {
+	var result = make(map[string][]byte)
+	if values, ok := data.(map[string]interface{}); ok {
+		for key, value := range values {
+			if str, ok := value.(string); ok {
+				result[key] = []byte(utils.Base64DecodeString(str))
+			}
+		}
+	}
+	return result
+}
+
+// CredentialsSecretName returns the name of the Secret holding the RPC
+// credentials of an instance.
+func CredentialsSecretName(instance string) string {
+	return "credentials-" + instance
+}
+
+// GetInstanceCredentials extracts the RPC credentials from an instance
+// secret, including the previous credentials during a rotation grace period.
+func GetInstanceCredentials(secret *corev1.Secret) *model.InstanceCredentials {
+	credentials := &model.InstanceCredentials{
+		Username: string(secret.Data[SECRET_USERNAME_KEY]),
+		Password: string(secret.Data[SECRET_PASSWORD_KEY]),
+	}
+
+	if username, ok := secret.Data[SECRET_PREVIOUS_USERNAME_KEY]; ok {
+		expiresAt, err := time.Parse(time.RFC3339, string(secret.Data[SECRET_PREVIOUS_EXPIRES_AT_KEY]))
+		if err == nil {
+			credentials.Previous = &model.PreviousCredentials{
+				Username:  string(username),
+				Password:  string(secret.Data[SECRET_PREVIOUS_PASSWORD_KEY]),
+				ExpiresAt: expiresAt,
+			}
+		}
+	}
+
+	return credentials
+}
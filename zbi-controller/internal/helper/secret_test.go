@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_GetSecretProperties(t *testing.T) {
+	data := map[string][]byte{"username": []byte("zcash"), "password": []byte("secret")}
+	properties := GetSecretProperties(data)
+
+	assert.Equal(t, []string{"password", "username"}, properties[SECRET_KEYS_PROPERTY])
+	assert.Contains(t, properties[SECRET_HASH_PROPERTY], "sha256:")
+	assert.NotContains(t, properties, "password")
+
+	data["password"] = []byte("changed")
+	assert.NotEqual(t, properties[SECRET_HASH_PROPERTY], GetSecretProperties(data)[SECRET_HASH_PROPERTY])
+}
+
+func Test_CreateCoreResource_Secret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "project"},
+		Data:       map[string][]byte{"username": []byte("zcash"), "password": []byte("secret")},
+	}
+
+	resource := CreateCoreResource(context.Background(), model.ResourceSecret, secret, nil)
+	assert.Equal(t, GetSecretProperties(secret.Data), resource.Properties)
+}
+
+func Test_GetResourceProperties_Secret(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "Secret",
+		"data": map[string]interface{}{"username": "emNhc2g=", "password": "c2VjcmV0"},
+	}}
+
+	properties := GetResourceProperties(obj)
+	assert.Equal(t, GetSecretProperties(map[string][]byte{"username": []byte("zcash"), "password": []byte("secret")}), properties)
+}
+
+func Test_GetInstanceCredentials(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{"username": []byte("zcash"), "password": []byte("secret")}}
+	assert.Equal(t, &model.InstanceCredentials{Username: "zcash", Password: "secret"}, GetInstanceCredentials(secret))
+
+	secret.Data["previous-username"] = []byte("old")
+	secret.Data["previous-password"] = []byte("old-secret")
+	secret.Data["previous-expires-at"] = []byte("2022-06-01T12:00:00Z")
+	previous := GetInstanceCredentials(secret).Previous
+	assert.Equal(t, "old", previous.Username)
+	assert.Equal(t, "old-secret", previous.Password)
+	assert.Equal(t, time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC), previous.ExpiresAt)
+}
+
+func Test_GetConfigMapProperties(t *testing.T) {
+	properties := GetConfigMapProperties(map[string]string{"zcash.conf": "testnet=1", "envoy.yaml": "Basic dXNlcjpwYXNz"})
+
+	assert.Equal(t, "testnet=1", properties["zcash.conf"])
+	assert.Contains(t, properties["envoy.yaml"], "sha256:")
+	assert.NotContains(t, properties["envoy.yaml"], "Basic")
+
+	properties = GetConfigMapProperties(map[string]string{
+		"zcash.conf":        "testnet=1\nrpcuser=zcash\nrpcpassword=secret\nrpcport=18232",
+		"lightwalletd.yaml": "cache-size: 400000\nrpcpassword: \"secret\"",
+	})
+	assert.Equal(t, "testnet=1\nrpcport=18232", properties["zcash.conf"])
+	assert.Equal(t, "cache-size: 400000", properties["lightwalletd.yaml"])
+}
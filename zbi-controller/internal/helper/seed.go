@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"github.com/zbitech/controller/pkg/model"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const SEED_PREFIX = "seed-"
+
+// SeedSnapshotName is the name of the VolumeSnapshot a snapshot seed is bound
+// to in a project namespace. Its VolumeSnapshotContent is named after it and
+// the namespace.
+func SeedSnapshotName(seed *model.Seed) string {
+	return SEED_PREFIX + seed.Name
+}
+
+// GetSeedArchive returns the archive a data volume is loaded from, or nil
+// when the seed is not an archive seed.
+func GetSeedArchive(seed *model.Seed) *model.SeedArchive {
+	if seed == nil || seed.Type != model.SeedTypeArchive || seed.Archive == nil {
+		return nil
+	}
+
+	var archive = *seed.Archive
+	if archive.Format == "" {
+		archive.Format = model.ArchiveFormatTarGz
+	}
+	return &archive
+}
+
+// SeedFits returns false when a volume of the given size is smaller than the
+// size a seed requires.
+func SeedFits(seed *model.Seed, size string) bool {
+	if seed.Size == "" || size == "" {
+		return true
+	}
+
+	required, err := resource.ParseQuantity(seed.Size)
+	if err != nil {
+		return true
+	}
+	requested, err := resource.ParseQuantity(size)
+	if err != nil {
+		return false
+	}
+	return requested.Cmp(required) >= 0
+}
@@ -0,0 +1,26 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestGetSeedArchive(t *testing.T) {
+	assert.Nil(t, GetSeedArchive(nil))
+	assert.Nil(t, GetSeedArchive(&model.Seed{Type: model.SeedTypeSnapshot, Snapshot: &model.SeedSnapshot{Handle: "snap-1"}}))
+
+	seed := &model.Seed{Type: model.SeedTypeArchive, Archive: &model.SeedArchive{Url: "https://seeds.example.com/testnet.tar.gz"}}
+	archive := GetSeedArchive(seed)
+	assert.Equal(t, model.ArchiveFormatTarGz, archive.Format)
+	assert.Empty(t, seed.Archive.Format)
+}
+
+func TestSeedFits(t *testing.T) {
+	seed := &model.Seed{Size: "40Gi"}
+	assert.True(t, SeedFits(seed, "50Gi"))
+	assert.True(t, SeedFits(seed, "40Gi"))
+	assert.False(t, SeedFits(seed, "30Gi"))
+	assert.True(t, SeedFits(&model.Seed{}, "1Gi"))
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"fmt"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const ADMIN_ROLE = "admin"
+
+// GetActionAccess returns the access an action needs. Creating and deleting
+// projects, instances and their resources is reserved to team admins.
+func GetActionAccess(action model.EventAction) model.AccessLevel {
+	switch action {
+	case model.EventActionCreate, model.EventActionDelete, model.EventActionDeleteResource:
+		return model.AccessManage
+	case model.EventActionView, model.EventActionGetPolicies:
+		return model.AccessView
+	}
+	return model.AccessOperate
+}
+
+// CanAccessProject reports whether a user has access to a project. Admins
+// have access to every project. Projects of a team are authorized through
+// team membership and other projects through their owner. team must be the
+// team of the project, or nil when it could not be found.
+func CanAccessProject(project *model.Project, team *model.Team, userid, role string, access model.AccessLevel) bool {
+	if role == ADMIN_ROLE {
+		return true
+	}
+
+	if project.TeamId == "" {
+		return userid != "" && userid == project.Owner
+	}
+
+	if team == nil || team.Id != project.TeamId {
+		return false
+	}
+
+	member := team.GetMember(userid)
+	if member == nil {
+		return false
+	}
+	return member.Role == model.TeamRoleAdmin || access != model.AccessManage
+}
+
+// CanManageTeam reports whether a user can change the membership of a team.
+func CanManageTeam(team *model.Team, userid, role string) bool {
+	if role == ADMIN_ROLE {
+		return true
+	}
+	member := team.GetMember(userid)
+	return member != nil && member.Role == model.TeamRoleAdmin
+}
+
+// CheckProjectQuota returns an error when a team owning projects projects
+// cannot create another one.
+func CheckProjectQuota(team *model.Team, projects int) error {
+	if team.Quota.MaxProjects > 0 && projects >= team.Quota.MaxProjects {
+		return fmt.Errorf("team %s has reached its quota of %d projects", team.Name, team.Quota.MaxProjects)
+	}
+	return nil
+}
+
+// CheckInstanceQuota returns an error when a team owning instances instances
+// across its projects cannot create another one.
+func CheckInstanceQuota(team *model.Team, instances int) error {
+	if team.Quota.MaxInstances > 0 && instances >= team.Quota.MaxInstances {
+		return fmt.Errorf("team %s has reached its quota of %d instances", team.Name, team.Quota.MaxInstances)
+	}
+	return nil
+}
+
+// CountAdmins returns the number of admins of a team.
+func CountAdmins(team *model.Team) int {
+	var count int
+	for _, member := range team.Members {
+		if member.Role == model.TeamRoleAdmin {
+			count++
+		}
+	}
+	return count
+}
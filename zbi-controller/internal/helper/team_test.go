@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func TestCanAccessProject(t *testing.T) {
+	team := &model.Team{Id: "team1", Members: []model.TeamMember{
+		{UserId: "alice", Role: model.TeamRoleAdmin},
+		{UserId: "bob", Role: model.TeamRoleUser},
+	}}
+	teamProject := &model.Project{Owner: "alice", TeamId: "team1"}
+	ownedProject := &model.Project{Owner: "carol"}
+
+	tests := []struct {
+		name    string
+		project *model.Project
+		team    *model.Team
+		userid  string
+		role    string
+		access  model.AccessLevel
+		allowed bool
+	}{
+		{"team admin manages", teamProject, team, "alice", "user", model.AccessManage, true},
+		{"team user operates", teamProject, team, "bob", "user", model.AccessOperate, true},
+		{"team user cannot delete", teamProject, team, "bob", "user", model.AccessManage, false},
+		{"non member denied", teamProject, team, "carol", "user", model.AccessView, false},
+		{"missing team denied", teamProject, nil, "alice", "user", model.AccessView, false},
+		{"other team denied", teamProject, &model.Team{Id: "team2", Members: team.Members}, "alice", "user", model.AccessView, false},
+		{"admin allowed", teamProject, nil, "dave", ADMIN_ROLE, model.AccessManage, true},
+		{"owner allowed", ownedProject, nil, "carol", "user", model.AccessManage, true},
+		{"other user denied", ownedProject, team, "alice", "user", model.AccessView, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, CanAccessProject(test.project, test.team, test.userid, test.role, test.access))
+		})
+	}
+}
+
+func TestGetActionAccess(t *testing.T) {
+	assert.Equal(t, model.AccessManage, GetActionAccess(model.EventActionDelete))
+	assert.Equal(t, model.AccessManage, GetActionAccess(model.EventActionCreate))
+	assert.Equal(t, model.AccessOperate, GetActionAccess(model.EventActionStopInstance))
+	assert.Equal(t, model.AccessView, GetActionAccess(model.EventActionView))
+}
+
+func TestTeamQuota(t *testing.T) {
+	team := &model.Team{Name: "team1", Quota: model.TeamQuota{MaxProjects: 2}}
+	assert.NoError(t, CheckProjectQuota(team, 1))
+	assert.Error(t, CheckProjectQuota(team, 2))
+	assert.NoError(t, CheckInstanceQuota(team, 100))
+
+	team.Quota.MaxInstances = 3
+	assert.Error(t, CheckInstanceQuota(team, 3))
+}
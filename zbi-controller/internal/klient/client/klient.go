@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/internal/audit"
 	"github.com/zbitech/controller/internal/helper"
 	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
 	"k8s.io/client-go/dynamic"
@@ -65,8 +67,7 @@ func NewKlient(ctx context.Context) (*Klient, error) {
 	cfg, err := NewRestConfig(ctx)
 	if err != nil {
 		log.Errorf("Unable to create kubernetes configuration - %s", err)
-		//		return nil, errs.NewApplicationError(errs.KubernetesError)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.KubernetesError, err)
 	}
 
 	kubernetesClient, err := kubernetes.NewForConfig(cfg)
@@ -102,17 +103,17 @@ func (k *Klient) ApplyResource(ctx context.Context, object *unstructured.Unstruc
 	data, err := json.Marshal(object)
 	if err != nil {
 		log.Errorf("failed to marshal resource - %s", err)
-		// return nil, errs.NewApplicationError(errs.MarshalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.MarshalError, err)
 	}
 
 	dr := helper.GetDynamicResourceInterface(k.DynamicClient, object)
 	result, err := dr.Patch(ctx, object.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: "zbi-controller"})
 	if err != nil {
 		log.Errorf("failed to create resource - %s", err)
-		return nil, fmt.Errorf("failed to create %s %s - %s", object.GetKind(), object.GetName(), err)
+		return nil, errs.NewKubernetesError(fmt.Errorf("failed to create %s %s - %w", object.GetKind(), object.GetName(), err))
 	} else {
 		log.Infof("successfully created %s of kind %s", result.GetName(), result.GetKind())
+		audit.AddObject(ctx, model.AuditOperationApply, helper.GvrMap[model.ResourceObjectType(object.GetKind())].Resource, object.GetNamespace(), object.GetName())
 	}
 
 	// set all resources to active after initial creation
@@ -204,14 +205,24 @@ func (k *Klient) DeleteDynamicResource(ctx context.Context, namespace, name stri
 	var log = logger.GetServiceLogger(ctx, "klient.DeleteDynamicResource")
 	defer func() { logger.LogServiceTime(log) }()
 
-	return k.DynamicClient.Resource(resource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err := k.DynamicClient.Resource(resource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+
+	audit.AddObject(ctx, model.AuditOperationDelete, resource.Resource, namespace, name)
+	return nil
 }
 
 func (k *Klient) DeleteNamespace(ctx context.Context, namespace string) error {
 	var log = logger.GetServiceLogger(ctx, "klient.DeleteNamespace")
 	defer func() { logger.LogServiceTime(log) }()
 
-	return k.KubernetesClient.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if err := k.KubernetesClient.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+
+	audit.AddObject(ctx, model.AuditOperationDelete, "namespaces", "", namespace)
+	return nil
 }
 
 func (k *Klient) GetDynamicResource(ctx context.Context, namespace, name string, resource schema.GroupVersionResource) (*unstructured.Unstructured, error) {
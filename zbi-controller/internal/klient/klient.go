@@ -7,6 +7,8 @@ import (
 	"github.com/zbitech/controller/internal/klient/client"
 	"github.com/zbitech/controller/internal/klient/monitor"
 	"github.com/zbitech/controller/internal/klient/zbi"
+	"github.com/zbitech/controller/internal/secrets"
+	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -14,8 +16,9 @@ import (
 
 type KlientFactory struct {
 	//klient    interfaces.KlientIF
-	client interfaces.ZBIClientIF
-	rscMon interfaces.KlientMonitorIF
+	client  interfaces.ZBIClientIF
+	rscMon  interfaces.KlientMonitorIF
+	secrets interfaces.SecretProviderIF
 }
 
 func NewKlientFactory() interfaces.KlientFactoryIF {
@@ -34,6 +37,15 @@ func (k *KlientFactory) Init(ctx context.Context, repoSvc interfaces.RepositoryS
 
 	log.Infof("creating zbi client")
 	k.client = zbi.NewZBIClient(clientSvc)
+	if vars.AuditService != nil {
+		k.client = zbi.NewAuditedZBIClient(k.client, vars.AuditService)
+	}
+
+	log.Infof("creating %s secret provider", vars.ZBI_SECRET_PROVIDER)
+	k.secrets, err = secrets.NewSecretProvider(ctx, clientSvc)
+	if err != nil {
+		return err
+	}
 
 	if helper.GetPolicyInfo(ctx).EnableMonitor {
 		k.rscMon = monitor.NewKlientMonitor(ctx, clientSvc, repoSvc)
@@ -41,6 +53,11 @@ func (k *KlientFactory) Init(ctx context.Context, repoSvc interfaces.RepositoryS
 			model.ResourceService, model.ResourcePersistentVolumeClaim, model.ResourceVolumeSnapshot, model.ResourceSnapshotSchedule,
 			model.ResourceHTTPProxy}
 
+		// the Certificate resource is only available when cert-manager is installed
+		if len(helper.GetPolicyInfo(ctx).CertificateIssuer) > 0 {
+			rtypes = append(rtypes, model.ResourceCertificate)
+		}
+
 		for _, rtype := range rtypes {
 			log.Infof("Adding %s informer", rtype)
 			k.rscMon.AddInformer(rtype)
@@ -54,6 +71,10 @@ func (k *KlientFactory) GetZBIClient() interfaces.ZBIClientIF {
 	return k.client
 }
 
+func (k *KlientFactory) GetSecretProvider() interfaces.SecretProviderIF {
+	return k.secrets
+}
+
 func (k *KlientFactory) StartMonitor(ctx context.Context) {
 	if helper.GetPolicyInfo(ctx).EnableMonitor {
 		k.rscMon.Start()
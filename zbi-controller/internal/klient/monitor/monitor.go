@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"runtime/debug"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -10,12 +11,12 @@ import (
 	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
 	"github.com/zbitech/controller/pkg/model"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
@@ -107,6 +108,8 @@ func (k *KlientMonitor) AddInformer(rType model.ResourceObjectType) {
 		inf = k.dynamicFactory.ForResource(helper.GvrMap[rType]).Informer()
 	case model.ResourceHTTPProxy:
 		inf = k.dynamicFactory.ForResource(helper.GvrMap[rType]).Informer()
+	case model.ResourceCertificate:
+		inf = k.dynamicFactory.ForResource(helper.GvrMap[rType]).Informer()
 	default:
 		k.log.WithFields(logrus.Fields{"type": rType}).Warnf("Unable to create informer")
 		return
@@ -150,6 +153,7 @@ func (k *KlientMonitor) processEvent(action ResourceAction, rType model.Resource
 
 	log := logger.GetServiceLogger(k.ctx, "informer.processEvent")
 	//	defer logger.LogServiceTime(log)
+	defer k.recoverPanic(k.ctx, logrus.Fields{"action": action, "type": rType})
 
 	kObj, ok := obj.(runtime.Object)
 	if ok {
@@ -178,6 +182,8 @@ func (k *KlientMonitor) processEvent(action ResourceAction, rType model.Resource
 			rsc := kObj.(*corev1.Secret)
 			if !isZBIObject(rsc.Labels) {
 				result = &ResourceStatus{Ignore: true}
+			} else if helper.IsDomainObject(rsc.Labels) {
+				result = CertificateEvent(k.ctx, action, rsc, rsc.Labels, k.clientSvc)
 			} else {
 				result = &ResourceStatus{Resource: helper.CreateCoreResource(k.ctx, rType, rsc, k.clientSvc), Ignore: false, Reason: "",
 					Ready: true, Id: rsc.Labels["id"], Level: rsc.Labels["level"]}
@@ -193,6 +199,8 @@ func (k *KlientMonitor) processEvent(action ResourceAction, rType model.Resource
 			rsc := kObj.(*corev1.Service)
 			if !isZBIObject(rsc.Labels) {
 				result = &ResourceStatus{Ignore: true}
+			} else if helper.IsP2PObject(rsc.Labels) {
+				result = P2PServiceEvent(k.ctx, action, rsc, k.clientSvc)
 			} else {
 				result = &ResourceStatus{Resource: helper.CreateCoreResource(k.ctx, rType, rsc, k.clientSvc), Ignore: false, Reason: "",
 					Ready: true, Id: rsc.Labels["id"], Level: rsc.Labels["level"]}
@@ -210,6 +218,10 @@ func (k *KlientMonitor) processEvent(action ResourceAction, rType model.Resource
 		case model.ResourceHTTPProxy:
 			result = IngressEvent(k.ctx, action, kObj.(*unstructured.Unstructured), k.clientSvc)
 
+		case model.ResourceCertificate:
+			rsc := kObj.(*unstructured.Unstructured)
+			result = CertificateEvent(k.ctx, action, rsc, rsc.GetLabels(), k.clientSvc)
+
 		default:
 			result = &ResourceStatus{Ignore: true}
 			return
@@ -278,7 +290,7 @@ func (k *KlientMonitor) Start() {
 
 	k.log.Infof("starting monitor")
 
-	defer utilruntime.HandleCrash()
+	defer k.recoverPanic(k.ctx, logrus.Fields{"service": "monitor.Start"})
 	defer k.workQueue.queue.ShutDown()
 	//	defer close(k.stopper)
 
@@ -316,6 +328,13 @@ func (k *KlientMonitor) processNextItem(ctx context.Context) bool {
 	defer k.workQueue.Done(item)
 	qItem := item.(QueueElement)
 
+	fields := logrus.Fields{"action": qItem.Action, "key": qItem.Key, "type": qItem.Type}
+	if qItem.Object != nil {
+		fields["id"] = qItem.Object.Id
+		fields["level"] = qItem.Object.Level
+	}
+	defer k.recoverPanic(ctx, fields)
+
 	log.Infof("action: %s, Key: %s, Kind: %s, Requeue Count: %d", qItem.Action, qItem.Key, qItem.Type, qItem.RequeueCount)
 
 	indexer := k.GetIndexer(qItem.Type)
@@ -343,21 +362,32 @@ func (k *KlientMonitor) processNextItem(ctx context.Context) bool {
 	return false
 }
 
+// recoverPanic logs and counts a panic in a monitor worker so that a single
+// bad object does not stop the monitor. It must be deferred.
+func (k *KlientMonitor) recoverPanic(ctx context.Context, fields logrus.Fields) {
+	if rec := recover(); rec != nil {
+		metrics.PanicsTotal.Inc("monitor")
+
+		var entry = logger.GetLogger(ctx).WithFields(fields)
+		entry.WithFields(logrus.Fields{"stack": string(debug.Stack())}).Errorf("recovered from panic - %v", rec)
+	}
+}
+
 func (k *KlientMonitor) UpdateResourceStatus(ctx context.Context, id, level string, resource *model.KubernetesResource) {
 
 	log := logger.GetServiceLogger(ctx, "monitor.UpdateProjectResource")
-	repoService := vars.RepositoryFactory.GetRepositoryService()
+	outbox := vars.RepositoryFactory.GetStatusOutbox()
 
 	if level == "instance" {
 		log.Infof("updating instance %s resource %s (%s) status - %s", id, resource.Name, resource.Type, resource.Status)
 
-		if err := repoService.UpdateInstanceResource(ctx, id, resource); err != nil {
+		if err := outbox.AddInstanceResource(ctx, id, resource); err != nil {
 			log.Errorf("unable to update instance %s resource %s (%s) - %s", id, resource.Name, resource.Type, err)
 		}
 
 	} else if level == "project" {
 		log.Infof("updating project %s resource %s (%s) status - %s", id, resource.Name, resource.Type, resource.Status)
-		if err := repoService.UpdateProjectResource(ctx, id, resource); err != nil {
+		if err := outbox.AddProjectResource(ctx, id, resource); err != nil {
 			log.Errorf("unable to update project %s resource %s (%s) - %s", id, resource.Name, resource.Type, err)
 		}
 	}
@@ -462,7 +492,8 @@ func SnapshotScheduleEvent(ctx context.Context, action ResourceAction, obj *unst
 
 func IngressEvent(ctx context.Context, action ResourceAction, obj *unstructured.Unstructured, clientSvc interfaces.KlientIF) *ResourceStatus {
 
-	if !isZBIObject(obj.GetLabels()) {
+	// custom domains are tracked through their certificate
+	if !isZBIObject(obj.GetLabels()) || helper.IsDomainObject(obj.GetLabels()) {
 		return &ResourceStatus{Ignore: true}
 	}
 
@@ -475,3 +506,83 @@ func IngressEvent(ctx context.Context, action ResourceAction, obj *unstructured.
 
 	return &resStatus
 }
+
+// CertificateEvent reports the certificate of a custom domain, either a cert-manager Certificate or the secret of an
+// uploaded certificate. The status is recorded as it changes, including while the certificate is pending or expired.
+func CertificateEvent(ctx context.Context, action ResourceAction, obj runtime.Object, labels map[string]string, clientSvc interfaces.KlientIF) *ResourceStatus {
+
+	if !isZBIObject(labels) {
+		return &ResourceStatus{Ignore: true}
+	}
+
+	resStatus := ResourceStatus{Resource: helper.CreateCoreResource(ctx, model.ResourceCertificate, obj, clientSvc),
+		Id: labels["id"], Level: labels["level"]}
+
+	if action != DeleteResource {
+		resStatus.Ready = true
+	}
+
+	return &resStatus
+}
+
+// P2PServiceEvent reports the Service publishing the peer-to-peer port of a zcash instance and advertises its
+// external address through the externalip setting of zcash.conf. The setting is removed when the service is deleted.
+func P2PServiceEvent(ctx context.Context, action ResourceAction, obj *corev1.Service, clientSvc interfaces.KlientIF) *ResourceStatus {
+
+	log := logger.GetServiceLogger(ctx, "monitor.P2PServiceEvent")
+
+	resStatus := ResourceStatus{Resource: helper.CreateCoreResource(ctx, model.ResourceP2PService, obj, clientSvc),
+		Id: obj.GetLabels()["id"], Level: obj.GetLabels()["level"]}
+
+	var address string
+	if action != DeleteResource {
+		// requeued until the external address has been allocated
+		resStatus.Ready = resStatus.Resource.Status == helper.P2P_ACTIVE
+		if !resStatus.Ready {
+			return &resStatus
+		}
+		address, _ = resStatus.Resource.Properties[helper.P2P_ADDRESS_PROPERTY].(string)
+	}
+
+	if err := updateZcashExternalIP(ctx, obj.Namespace, obj.GetLabels()["instance"], address, clientSvc); err != nil {
+		log.WithFields(logrus.Fields{"service": obj.Name, "address": address}).Errorf("failed to update zcash externalip - %s", err)
+		resStatus.Ready = false
+	}
+
+	return &resStatus
+}
+
+// updateZcashExternalIP sets the externalip of an instance zcash.conf. The whole ConfigMap is applied with the field
+// manager that created it so that ownership of its other fields is kept.
+func updateZcashExternalIP(ctx context.Context, namespace, name, address string, clientSvc interfaces.KlientIF) error {
+
+	cm, err := clientSvc.GetConfigMapByName(ctx, namespace, helper.ZcashConfName(name))
+	if err != nil {
+		if address == "" {
+			// removed along with the instance
+			return nil
+		}
+		return err
+	}
+
+	conf := helper.SetZcashConfExternalIP(cm.Data[helper.ZCASH_CONF_KEY], address)
+	if conf == cm.Data[helper.ZCASH_CONF_KEY] {
+		return nil
+	}
+
+	var data = make(map[string]interface{}, len(cm.Data))
+	for key, value := range cm.Data {
+		data[key] = value
+	}
+	data[helper.ZCASH_CONF_KEY] = conf
+
+	object := &unstructured.Unstructured{Object: map[string]interface{}{"data": data}}
+	object.SetAPIVersion("v1")
+	object.SetKind(string(model.ResourceConfigMap))
+	object.SetName(cm.Name)
+	object.SetNamespace(cm.Namespace)
+	object.SetLabels(cm.Labels)
+
+	_, err = clientSvc.ApplyResource(ctx, object)
+	return err
+}
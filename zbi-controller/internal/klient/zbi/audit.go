@@ -0,0 +1,177 @@
+package zbi
+
+import (
+	"context"
+	"time"
+
+	"github.com/zbitech/controller/internal/audit"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// AuditedZBIClient records every operation of the wrapped client with the
+// Kubernetes objects it applied or deleted.
+type AuditedZBIClient struct {
+	client  interfaces.ZBIClientIF
+	auditor interfaces.AuditServiceIF
+}
+
+func NewAuditedZBIClient(client interfaces.ZBIClientIF, auditor interfaces.AuditServiceIF) interfaces.ZBIClientIF {
+	return &AuditedZBIClient{client: client, auditor: auditor}
+}
+
+func (a *AuditedZBIClient) record(ctx context.Context, action, target string, fn func(ctx context.Context) error) error {
+	ctx = audit.WithObjects(ctx)
+	err := fn(ctx)
+
+	record := &model.AuditRecord{Source: model.AuditSourceCluster, Action: action, Target: target, Objects: audit.Objects(ctx)}
+	if err != nil {
+		record.Outcome = model.AuditOutcomeFailure
+		record.Error = err.Error()
+	}
+	a.auditor.Record(ctx, record)
+
+	return err
+}
+
+func projectTarget(project *model.Project) string {
+	return "project/" + project.Id
+}
+
+func instanceTarget(instance *model.Instance) string {
+	return "instance/" + instance.Id
+}
+
+func (a *AuditedZBIClient) CreateProject(ctx context.Context, project *model.Project) error {
+	return a.record(ctx, "CreateProject", projectTarget(project), func(ctx context.Context) error {
+		return a.client.CreateProject(ctx, project)
+	})
+}
+
+func (a *AuditedZBIClient) RepairProject(ctx context.Context, project *model.Project) error {
+	return a.record(ctx, "RepairProject", projectTarget(project), func(ctx context.Context) error {
+		return a.client.RepairProject(ctx, project)
+	})
+}
+
+func (a *AuditedZBIClient) UpdateProject(ctx context.Context, project *model.Project) error {
+	return a.record(ctx, "UpdateProject", projectTarget(project), func(ctx context.Context) error {
+		return a.client.UpdateProject(ctx, project)
+	})
+}
+
+func (a *AuditedZBIClient) DeleteProject(ctx context.Context, project *model.Project, instances []model.Instance) error {
+	return a.record(ctx, "DeleteProject", projectTarget(project), func(ctx context.Context) error {
+		return a.client.DeleteProject(ctx, project, instances)
+	})
+}
+
+func (a *AuditedZBIClient) CreateInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "CreateInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.CreateInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) DeleteInstanceResource(ctx context.Context, project *model.Project, instance *model.Instance, resourceName string, resourceType model.ResourceObjectType) error {
+	return a.record(ctx, "DeleteInstanceResource", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.DeleteInstanceResource(ctx, project, instance, resourceName, resourceType)
+	})
+}
+
+func (a *AuditedZBIClient) UpdateInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "UpdateInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.UpdateInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) DeleteInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "DeleteInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.DeleteInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) RepairInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "RepairInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.RepairInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) StopInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "StopInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.StopInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) StartInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "StartInstance", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.StartInstance(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error {
+	return a.record(ctx, "RotateInstanceCredentials", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.RotateInstanceCredentials(ctx, project, instance, grace)
+	})
+}
+
+func (a *AuditedZBIClient) ExpireInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "ExpireInstanceCredentials", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.ExpireInstanceCredentials(ctx, project, instance)
+	})
+}
+
+// GetInstanceCredentials is audited as reading credentials is sensitive even
+// though nothing is changed.
+func (a *AuditedZBIClient) GetInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	var credentials *model.InstanceCredentials
+	err := a.record(ctx, "GetInstanceCredentials", instanceTarget(instance), func(ctx context.Context) error {
+		var err error
+		credentials, err = a.client.GetInstanceCredentials(ctx, project, instance)
+		return err
+	})
+	return credentials, err
+}
+
+func (a *AuditedZBIClient) UpdateDomain(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) error {
+	return a.record(ctx, "UpdateDomain", domainTarget(project, instance), func(ctx context.Context) error {
+		return a.client.UpdateDomain(ctx, project, instance, domain, certificate, privateKey)
+	})
+}
+
+func (a *AuditedZBIClient) DeleteDomain(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "DeleteDomain", domainTarget(project, instance), func(ctx context.Context) error {
+		return a.client.DeleteDomain(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) UpdateInstanceIngress(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "UpdateInstanceIngress", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.UpdateInstanceIngress(ctx, project, instance)
+	})
+}
+
+// GetNetworkPolicies only reads from the cluster and is not audited.
+func (a *AuditedZBIClient) GetNetworkPolicies(ctx context.Context, project *model.Project, instance *model.Instance) (*model.NetworkPolicies, error) {
+	return a.client.GetNetworkPolicies(ctx, project, instance)
+}
+
+func (a *AuditedZBIClient) CreateSnapshot(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return a.record(ctx, "CreateSnapshot", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.CreateSnapshot(ctx, project, instance)
+	})
+}
+
+func (a *AuditedZBIClient) CreateSnapshotSchedule(ctx context.Context, project *model.Project, instance *model.Instance, schedule model.SnapshotScheduleType) error {
+	return a.record(ctx, "CreateSnapshotSchedule", instanceTarget(instance), func(ctx context.Context) error {
+		return a.client.CreateSnapshotSchedule(ctx, project, instance, schedule)
+	})
+}
+
+// domainTarget is the instance when a domain is set on an instance and the
+// project otherwise.
+func domainTarget(project *model.Project, instance *model.Instance) string {
+	if instance != nil {
+		return instanceTarget(instance)
+	}
+	return projectTarget(project)
+}
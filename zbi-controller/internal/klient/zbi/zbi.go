@@ -2,14 +2,19 @@ package zbi
 
 import (
 	"context"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/helper"
 	client "github.com/zbitech/controller/internal/klient/client"
+	"github.com/zbitech/controller/internal/secrets"
 	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type ZBIClient struct {
@@ -111,7 +116,7 @@ func (z *ZBIClient) CreateProject(ctx context.Context, project *model.Project) e
 	resources, err := z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("project kubernetes resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	log.Infof("created %d resources for project %s", len(resources), project.Name)
@@ -125,7 +130,7 @@ func (z *ZBIClient) CreateProject(ctx context.Context, project *model.Project) e
 		resources, err = z.client.ApplyResources(ctx, objects)
 		if err != nil {
 			log.Errorf("Project ingress resource creation failed - %s", err)
-			return err
+			return errs.NewKubernetesError(err)
 		}
 	}
 
@@ -149,7 +154,7 @@ func (z *ZBIClient) RepairProject(ctx context.Context, project *model.Project) e
 	resources, err := z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("project kubernetes resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	log.Infof("created %d resources for project %s", len(resources), project.Name)
@@ -163,7 +168,7 @@ func (z *ZBIClient) RepairProject(ctx context.Context, project *model.Project) e
 		resources, err = z.client.ApplyResources(ctx, objects)
 		if err != nil {
 			log.Errorf("Project ingress resource creation failed - %s", err)
-			return err
+			return errs.NewKubernetesError(err)
 		}
 	}
 
@@ -172,6 +177,31 @@ func (z *ZBIClient) RepairProject(ctx context.Context, project *model.Project) e
 	return nil
 }
 
+// UpdateProject re-renders the namespace, instance list and ingress of a
+// project after its labels or settings change.
+func (z *ZBIClient) UpdateProject(ctx context.Context, project *model.Project) error {
+	var log = logger.GetServiceLogger(ctx, "zbi.UpdateProject")
+	defer func() { logger.LogServiceTime(log) }()
+
+	if err := z.RepairProject(ctx, project); err != nil {
+		return err
+	}
+
+	rscMgr := vars.ManagerFactory.GetProjectDataManager(ctx)
+	objects, err := rscMgr.CreateInstanceListResource(ctx, project)
+	if err != nil {
+		log.Errorf("project instance list generation failed - %s", err)
+		return err
+	}
+
+	if _, err = z.client.ApplyResources(ctx, objects); err != nil {
+		log.Errorf("project instance list update failed - %s", err)
+		return errs.NewKubernetesError(err)
+	}
+
+	return nil
+}
+
 func (z *ZBIClient) DeleteProject(ctx context.Context, project *model.Project, instances []model.Instance) error {
 	var log = logger.GetServiceLogger(ctx, "zbi.DeleteProject")
 	defer func() { logger.LogServiceTime(log) }()
@@ -187,7 +217,7 @@ func (z *ZBIClient) DeleteProject(ctx context.Context, project *model.Project, i
 
 	err := z.client.DeleteNamespace(ctx, project.Name)
 	if err != nil {
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	return nil
@@ -318,7 +348,7 @@ func (z *ZBIClient) CreateInstance(ctx context.Context, project *model.Project,
 	projectIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "instance": instance}).Errorf("failed to create instance")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	presources, objects, err := dataMgr.CreateInstanceResource(ctx, projectIngress, project, instance, peers...)
@@ -330,7 +360,7 @@ func (z *ZBIClient) CreateInstance(ctx context.Context, project *model.Project,
 	_, err = z.client.ApplyResources(ctx, objects[0])
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "instance": instance}).Errorf("instance kubernetes resource creation failed")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// instance.AddResources(resources...)
@@ -351,7 +381,7 @@ func (z *ZBIClient) CreateInstance(ctx context.Context, project *model.Project,
 	_, err = z.client.ApplyResources(ctx, presources)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("project kubernetes resource creation failed")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	return nil
@@ -530,7 +560,7 @@ func (z *ZBIClient) DeleteInstanceResource(ctx context.Context, project *model.P
 	defer func() { logger.LogServiceTime(log) }()
 
 	if err := z.client.DeleteDynamicResource(ctx, project.GetNamespace(), resourceName, helper.GvrMap[resourceType]); err != nil {
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	return nil
@@ -555,7 +585,15 @@ func (z *ZBIClient) UpdateInstance(ctx context.Context, project *model.Project,
 	_, err = z.client.ApplyResources(ctx, objects[0])
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "instance": instance}).Errorf("instance kubernetes resource creation failed")
-		return err
+		return errs.NewKubernetesError(err)
+	}
+
+	// releases the external address once publicP2P is turned off
+	if instance.Resources != nil && instance.Resources.P2p != nil && instance.Resources.P2p.Status != "deleted" && !helper.IsPublicP2P(instance) {
+		if err = z.client.DeleteResource(ctx, instance.Resources.P2p); err != nil && !apierrors.IsNotFound(err) {
+			log.WithFields(logrus.Fields{"error": err, "instance": instance}).Errorf("failed to delete p2p service")
+			return errs.NewKubernetesError(err)
+		}
 	}
 
 	//	instance.Resources = make([]model.KubernetesResource, 0)
@@ -585,7 +623,7 @@ func (z *ZBIClient) DeleteInstance(ctx context.Context, project *model.Project,
 	projIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project ingress")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// if !instance.HasResources() {
@@ -607,9 +645,20 @@ func (z *ZBIClient) DeleteInstance(ctx context.Context, project *model.Project,
 	_, err = z.client.ApplyResources(ctx, newResources)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to create new resources")
+		return errs.NewKubernetesError(err)
 	}
 
-	return err
+	if err = vars.KlientFactory.GetSecretProvider().DeleteCredentials(ctx, project, instance); err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to delete instance credentials")
+	}
+
+	if instance.Domain != nil {
+		if err = z.DeleteDomain(ctx, project, instance); err != nil {
+			log.WithFields(logrus.Fields{"error": err}).Errorf("failed to delete instance domain")
+		}
+	}
+
+	return nil
 }
 
 func (z *ZBIClient) RepairInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
@@ -620,7 +669,7 @@ func (z *ZBIClient) RepairInstance(ctx context.Context, project *model.Project,
 	projectIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project-ingress")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// if !instance.HasResources() {
@@ -633,7 +682,13 @@ func (z *ZBIClient) RepairInstance(ctx context.Context, project *model.Project,
 
 	rscMgr := vars.ManagerFactory.GetProjectDataManager(ctx)
 
-	objects, err := rscMgr.CreateRepairResource(ctx, projectIngress, project, instance)
+	credentials, err := z.GetInstanceCredentials(ctx, project, instance)
+	if err != nil && errs.Code(err).Category != errs.CategoryNotFound {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get instance credentials")
+		return err
+	}
+
+	objects, err := rscMgr.CreateRepairResource(ctx, projectIngress, project, instance, credentials)
 	if err != nil {
 		log.Errorf("instance kubernetes resource generation failed - %s", err)
 		return err
@@ -642,7 +697,7 @@ func (z *ZBIClient) RepairInstance(ctx context.Context, project *model.Project,
 	_, err = z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("instance kubernetes resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// instance.AddResources(newResources...)
@@ -650,6 +705,22 @@ func (z *ZBIClient) RepairInstance(ctx context.Context, project *model.Project,
 	return nil
 }
 
+// GetInstanceCredentials reads the RPC credentials of an instance from the
+// secret provider.
+func (z *ZBIClient) GetInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.GetInstanceCredentials")
+	defer func() { logger.LogServiceTime(log) }()
+
+	credentials, err := vars.KlientFactory.GetSecretProvider().GetCredentials(ctx, project, instance)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err, "instance": instance.Name}).Errorf("failed to get instance credentials")
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
 func (z *ZBIClient) StopInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
 
 	var log = logger.GetServiceLogger(ctx, "zbi.StopInstance")
@@ -660,7 +731,7 @@ func (z *ZBIClient) StopInstance(ctx context.Context, project *model.Project, in
 	projectIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project-ingress")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// if !instance.HasResources() {
@@ -703,7 +774,7 @@ func (z *ZBIClient) StartInstance(ctx context.Context, project *model.Project, i
 	projectIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project-ingress")
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// if !instance.HasResources() {
@@ -725,7 +796,7 @@ func (z *ZBIClient) StartInstance(ctx context.Context, project *model.Project, i
 
 	if err != nil {
 		log.Errorf("Instance kubernetes resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// instance.AddResources(resources...)
@@ -733,24 +804,48 @@ func (z *ZBIClient) StartInstance(ctx context.Context, project *model.Project, i
 	return nil
 }
 
-// RotateInstanceCredentials creates new resources to rotate the credentials associated with a Zcash instance and returns
-// the resources that were created.
-func (z *ZBIClient) RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+// RotateInstanceCredentials generates new credentials for an instance and applies the resources that use them. The
+// current credentials remain valid for the grace period.
+func (z *ZBIClient) RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error {
 
 	var log = logger.GetServiceLogger(ctx, "zbi.RotateInstanceCredentials")
 	defer func() { logger.LogServiceTime(log) }()
 
-	var err error
-	// if !instance.HasResources() {
-	// 	instance.Resources, err = z.GetInstanceResources(ctx, project, instance.Name)
-	// 	if err != nil {
-	// 		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get instance resources")
-	// 		return err
-	// 	}
-	// }
+	credentials, err := secrets.RotateCredentials(ctx, vars.KlientFactory.GetSecretProvider(), project, instance, grace)
+	if err != nil {
+		log.Errorf("unable to store instance credentials - %s", err)
+		return err
+	}
+
+	return z.applyCredentials(ctx, project, instance, credentials)
+}
+
+// ExpireInstanceCredentials removes previous credentials once their grace period has ended so that only the current
+// credentials are accepted.
+func (z *ZBIClient) ExpireInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.ExpireInstanceCredentials")
+	defer func() { logger.LogServiceTime(log) }()
+
+	credentials, changed, err := secrets.ExpireCredentials(ctx, vars.KlientFactory.GetSecretProvider(), project, instance)
+	if err != nil {
+		log.Errorf("unable to retrieve instance credentials - %s", err)
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return z.applyCredentials(ctx, project, instance, credentials)
+}
+
+func (z *ZBIClient) applyCredentials(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) error {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.applyCredentials")
 
 	projMgr := vars.ManagerFactory.GetProjectDataManager(ctx)
-	objects, err := projMgr.CreateRotationResource(ctx, project, instance)
+	objects, err := projMgr.CreateRotationResource(ctx, project, instance, credentials)
 	if err != nil {
 		log.Errorf("instance rotation resource generation failed - %s", err)
 		return err
@@ -759,15 +854,156 @@ func (z *ZBIClient) RotateInstanceCredentials(ctx context.Context, project *mode
 	resources, err := z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("instance rotation resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
-	// instance.AddResources(resources...)
 	log.Infof("created %d resources for instance %s in project %s", len(resources), instance.Name, project.Name)
 
 	return nil
 }
 
+// UpdateDomain applies the resources that serve an instance, or the project when instance is nil, on its custom
+// domain. The certificate and private key are only required for an uploaded certificate.
+func (z *ZBIClient) UpdateDomain(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) error {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.UpdateDomain")
+	defer func() { logger.LogServiceTime(log) }()
+
+	projMgr := vars.ManagerFactory.GetProjectDataManager(ctx)
+	objects, err := projMgr.CreateDomainResource(ctx, project, instance, domain, certificate, privateKey)
+	if err != nil {
+		log.Errorf("domain resource generation failed - %s", err)
+		return err
+	}
+
+	// cert-manager would otherwise replace an uploaded certificate
+	if domain.TLS == model.DomainTLSUploaded {
+		if err = z.deleteDomainResource(ctx, project, helper.DomainResourceName(instance), model.ResourceCertificate); err != nil {
+			log.Errorf("unable to remove domain certificate - %s", err)
+			return errs.NewKubernetesError(err)
+		}
+	}
+
+	resources, err := z.client.ApplyResources(ctx, objects)
+	if err != nil {
+		log.Errorf("domain resource creation failed - %s", err)
+		return errs.NewKubernetesError(err)
+	}
+
+	log.WithFields(logrus.Fields{"host": domain.Host, "tls": domain.TLS}).Infof("created %d domain resources in project %s", len(resources), project.Name)
+
+	return nil
+}
+
+// DeleteDomain removes the resources of the custom domain of an instance, or of the project when instance is nil.
+func (z *ZBIClient) DeleteDomain(ctx context.Context, project *model.Project, instance *model.Instance) error {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.DeleteDomain")
+	defer func() { logger.LogServiceTime(log) }()
+
+	// the certificate is removed first so that cert-manager does not issue the secret again
+	name := helper.DomainResourceName(instance)
+	resources := []model.KubernetesResource{
+		{Name: name, Type: model.ResourceCertificate},
+		{Name: name, Type: model.ResourceHTTPProxy},
+		{Name: helper.DomainSecretName(instance), Type: model.ResourceSecret},
+	}
+
+	for _, resource := range resources {
+		if err := z.deleteDomainResource(ctx, project, resource.Name, resource.Type); err != nil {
+			log.WithFields(logrus.Fields{"name": resource.Name, "type": resource.Type}).Errorf("unable to remove domain resource - %s", err)
+			return errs.NewKubernetesError(err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateInstanceIngress applies the ingress of a running instance, and of its custom domain, after a change to its
+// access rules. Stopped instances pick up the change when they are started.
+func (z *ZBIClient) UpdateInstanceIngress(ctx context.Context, project *model.Project, instance *model.Instance) error {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.UpdateInstanceIngress")
+	defer func() { logger.LogServiceTime(log) }()
+
+	if deployments := z.client.GetDeployments(ctx, project.GetNamespace(), helper.CreateInstanceLabels(instance)); len(deployments) == 0 {
+		log.Infof("instance %s is not running - ingress will be updated on start", instance.Name)
+		return nil
+	}
+
+	projIngress, err := z.client.GetIngress(ctx, project.GetNamespace(), "project-ingress")
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project-ingress")
+		return errs.NewKubernetesError(err)
+	}
+
+	rscMgr := vars.ManagerFactory.GetProjectDataManager(ctx)
+	ingress, err := rscMgr.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionUpdate)
+	if err != nil {
+		log.Errorf("instance ingress generation failed - %s", err)
+		return err
+	}
+
+	objects := []unstructured.Unstructured{*ingress}
+	if instance.Domain != nil {
+		domainObjects, err := rscMgr.CreateDomainResource(ctx, project, instance, instance.Domain, "", "")
+		if err != nil {
+			log.Errorf("domain resource generation failed - %s", err)
+			return err
+		}
+		objects = append(objects, domainObjects...)
+	}
+
+	if _, err = z.client.ApplyResources(ctx, objects); err != nil {
+		log.Errorf("instance ingress update failed - %s", err)
+		return errs.NewKubernetesError(err)
+	}
+
+	return nil
+}
+
+// GetNetworkPolicies returns the NetworkPolicies of a project namespace. For an instance, only the policies selecting
+// its pods are returned together with the allow-list of its ingress.
+func (z *ZBIClient) GetNetworkPolicies(ctx context.Context, project *model.Project, instance *model.Instance) (*model.NetworkPolicies, error) {
+
+	var log = logger.GetServiceLogger(ctx, "zbi.GetNetworkPolicies")
+	defer func() { logger.LogServiceTime(log) }()
+
+	objects, err := z.client.GetDynamicResourceList(ctx, project.GetNamespace(), helper.GvrMap[model.ResourceNetworkPolicy])
+	if err != nil {
+		log.Errorf("unable to retrieve network policies - %s", err)
+		return nil, errs.NewKubernetesError(err)
+	}
+
+	var podLabels map[string]string
+	var result = &model.NetworkPolicies{NetworkPolicies: make([]model.NetworkPolicyInfo, 0, len(objects))}
+	if instance != nil {
+		podLabels = helper.CreateInstanceLabels(instance)
+		result.IPAllowPolicy = helper.GetInstanceIPAllowPolicy(helper.GetPolicyInfo(ctx), instance)
+	}
+
+	for index := range objects {
+		info, selected, err := helper.GetNetworkPolicyInfo(&objects[index], podLabels)
+		if err != nil {
+			log.Errorf("unable to read network policy %s - %s", objects[index].GetName(), err)
+			return nil, errs.NewApplicationError(errs.MarshalError, err)
+		}
+		if selected {
+			result.NetworkPolicies = append(result.NetworkPolicies, *info)
+		}
+	}
+
+	return result, nil
+}
+
+func (z *ZBIClient) deleteDomainResource(ctx context.Context, project *model.Project, name string, resourceType model.ResourceObjectType) error {
+	err := z.client.DeleteDynamicResource(ctx, project.GetNamespace(), name, helper.GvrMap[resourceType])
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // CreateSnapshot creates a new snapshot of the Zcash instance data volume.
 func (z *ZBIClient) CreateSnapshot(ctx context.Context, project *model.Project, instance *model.Instance) error {
 
@@ -794,7 +1030,7 @@ func (z *ZBIClient) CreateSnapshot(ctx context.Context, project *model.Project,
 	resources, err := z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("instance snapshot resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// instance.AddResources(resources...)
@@ -829,7 +1065,7 @@ func (z *ZBIClient) CreateSnapshotSchedule(ctx context.Context, project *model.P
 	resources, err := z.client.ApplyResources(ctx, objects)
 	if err != nil {
 		log.Errorf("instance snapshot schedule resource creation failed - %s", err)
-		return err
+		return errs.NewKubernetesError(err)
 	}
 
 	// instance.AddResources(resources...)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/zbitech/controller/internal/helper"
 	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -28,6 +29,14 @@ func (app *AppResourceManager) CreateVolumeResource(ctx context.Context, volumes
 
 	var objects = make([]unstructured.Unstructured, 0, len(volumes))
 	for _, volume := range volumes {
+		if volume.DataSourceType == model.SeedDataSource {
+			seedObjects, err := app.createSeedResource(ctx, &volume)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, seedObjects...)
+		}
+
 		properties := make(map[string]interface{})
 		data, err := fileTemplate.ExecuteTemplate("VOLUME", volume)
 		if err != nil {
@@ -40,6 +49,8 @@ func (app *AppResourceManager) CreateVolumeResource(ctx context.Context, volumes
 			properties["source"] = "Volume"
 		} else if volume.DataSourceType == model.SnapshotDataSource {
 			properties["source"] = "Snapshot"
+		} else if volume.DataSourceType == model.SeedDataSource {
+			properties["source"] = "Seed"
 		}
 		properties["size"] = volume.Size
 
@@ -54,6 +65,31 @@ func (app *AppResourceManager) CreateVolumeResource(ctx context.Context, volumes
 	return objects, nil
 }
 
+// createSeedResource prepares a volume to be created from its seed. A
+// snapshot seed is bound into the volume namespace as a VolumeSnapshot the
+// volume is restored from. Archive seeds are loaded by the instance, so their
+// volume is created empty.
+func (app *AppResourceManager) createSeedResource(ctx context.Context, volume *model.VolumeSpec) ([]unstructured.Unstructured, error) {
+	seed := volume.Seed
+	if seed == nil {
+		return nil, errs.New(errs.InvalidStateError, "volume has no seed")
+	}
+
+	if seed.Type != model.SeedTypeSnapshot || seed.Snapshot == nil {
+		volume.DataSourceType = model.NewDataSource
+		volume.SourceName = ""
+		return nil, nil
+	}
+
+	volume.SourceName = helper.SeedSnapshotName(seed)
+	specArr, err := helper.GetAppTemplate().ExecuteTemplates([]string{"SEED_SNAPSHOT_CONTENT", "SEED_SNAPSHOT"}, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return helper.CreateYAMLObjects(specArr)
+}
+
 func (app *AppResourceManager) CreateSnapshotResource(ctx context.Context, req *model.SnapshotRequest) ([]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "app.CreateSnapshotResource")
@@ -76,8 +112,7 @@ func (app *AppResourceManager) CreateSnapshotResource(ctx context.Context, req *
 
 	if err != nil {
 		log.Errorf("backup templates for version %s failed - %s", req.Labels["version"], err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	//properties := make(map[string]interface{})
@@ -111,8 +146,7 @@ func (app *AppResourceManager) CreateSnapshotScheduleResource(ctx context.Contex
 
 	if err != nil {
 		log.Errorf("backup templates for version %s failed - %s", req.Labels["version"], err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	return helper.CreateYAMLObjects(specArr /*, project, instance*/)
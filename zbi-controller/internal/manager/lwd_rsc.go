@@ -2,13 +2,13 @@ package manager
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/helper"
 	"github.com/zbitech/controller/internal/utils"
 	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -28,7 +28,7 @@ func (L *LWDInstanceResourceManager) CreateInstanceResource(ctx context.Context,
 	defer func() { logger.LogServiceTime(log) }()
 
 	if peers == nil || len(peers) != 1 {
-		return nil, errors.New("lightwallet instances can only be paired with one zcash")
+		return nil, errs.New(errs.InvalidPeerError, "lightwallet instances can only be paired with one zcash")
 	}
 
 	var dataVolumeName, dataVolumeSize string
@@ -59,10 +59,11 @@ func (L *LWDInstanceResourceManager) CreateInstanceResource(ctx context.Context,
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     dataVolumeName,
+		Credentials:        model.CredentialsSpec{SecretName: helper.CredentialsSecretName(peers[0].Name)},
 		Images: map[string]string{
-			LIGHT_WALLET_IMAGE: ic.GetImageRepository(LWD_IMAGE),
+			LIGHT_WALLET_IMAGE: helper.GetInstanceImage(ic, instance, LWD_IMAGE),
 		},
 		Ports: map[string]int32{
 			GRPC: ic.GetPort(SERVICE_PORT),
@@ -73,6 +74,7 @@ func (L *LWDInstanceResourceManager) CreateInstanceResource(ctx context.Context,
 			ZCASH_INSTANCE:      zcashInstance,
 			ZCASH_PORT:          zcashPort,
 			LOG_LEVEL:           request.Properties[logLevelProperty],
+			LWD_CONFIG:          helper.CreateLWDConfig(request.Config),
 		},
 	}
 
@@ -83,15 +85,13 @@ func (L *LWDInstanceResourceManager) CreateInstanceResource(ctx context.Context,
 	specArr, err = fileTemplate.ExecuteTemplates([]string{LWD_CONF, ZCASH_CONF, ENVOY_CONF, DEPLOYMENT, SERVICE}, lwdSpec)
 	if err != nil {
 		log.Errorf("Lightwalletd templates failed - %s", err)
-		//return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 	if err != nil {
 		log.Errorf("failed to generate specs for Lightwalletd - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	storageClass := policy.StorageClass
@@ -106,8 +106,7 @@ func (L *LWDInstanceResourceManager) CreateInstanceResource(ctx context.Context,
 	volumes, err := appRsc.CreateVolumeResource(ctx, volumeSpecs...)
 	if err != nil {
 		log.Errorf("Lightwalletd volume templates failed - %s", err)
-		//return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	objects = append(objects, volumes...)
@@ -140,18 +139,20 @@ func (L *LWDInstanceResourceManager) CreateUpdateResource(ctx context.Context, p
 	var request = instance.Request
 
 	if peers == nil || len(peers) != 1 {
-		return nil, errors.New("lightwallet instances can only be paired with one zcash")
+		return nil, errs.New(errs.InvalidPeerError, "lightwallet instances can only be paired with one zcash")
 	}
 
 	instanceSpec := model.InstanceSpec{
-		Name:      instance.Name,
-		Namespace: project.GetNamespace(),
-		Labels:    helper.CreateInstanceLabels(instance),
+		Name:        instance.Name,
+		Namespace:   project.GetNamespace(),
+		Labels:      helper.CreateInstanceLabels(instance),
+		Credentials: model.CredentialsSpec{SecretName: helper.CredentialsSecretName(peers[0].Name)},
 		Properties: map[string]interface{}{
 			ZCASH_INSTANCE_NAME: request.Properties[zcashInstanceProperty],
 			ZCASH_INSTANCE:      getZcashInstanceHost(peers[0].Name, project.GetNamespace()),
 			ZCASH_PORT:          getZcashInstancePort(ctx),
 			LOG_LEVEL:           request.Properties[logLevelProperty],
+			LWD_CONFIG:          helper.CreateLWDConfig(request.Config),
 		},
 	}
 
@@ -197,7 +198,7 @@ func (L *LWDInstanceResourceManager) CreateIngressResource(ctx context.Context,
 		Labels:       helper.CreateInstanceLabels(instance),
 		DomainName:   policy.DomainName,
 		DomainSecret: policy.CertificateName,
-		Envoy:        helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:        helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 	}
 
 	var specObj string
@@ -208,16 +209,14 @@ func (L *LWDInstanceResourceManager) CreateIngressResource(ctx context.Context,
 	}
 	if err != nil {
 		log.Errorf("Lightwalletd templates failed - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	//create new ingress resource
 	object, err := helper.CreateYAMLObject(specObj)
 	if err != nil {
 		log.Errorf("Lightwalletd templates - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	return object, nil
@@ -228,6 +227,10 @@ func (L *LWDInstanceResourceManager) CreateStartResource(ctx context.Context, pr
 	var log = logger.GetServiceLogger(ctx, "lwd.CreateStartResource")
 	defer func() { logger.LogServiceTime(log) }()
 
+	if instance.Resources == nil || instance.Resources.Persistentvolumeclaim == nil {
+		return nil, errs.New(errs.InvalidStateError, "instance has no data volume")
+	}
+
 	pvc := instance.Resources.Persistentvolumeclaim // GetResourceByType(model.ResourcePersistentVolumeClaim)
 
 	policy := helper.GetPolicyInfo(ctx)
@@ -248,10 +251,11 @@ func (L *LWDInstanceResourceManager) CreateStartResource(ctx context.Context, pr
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     pvc.Name,
+		Credentials:        model.CredentialsSpec{SecretName: helper.CredentialsSecretName(getStringProperty(instance.Request.Properties, zcashInstanceProperty))},
 		Images: map[string]string{
-			LIGHT_WALLET_IMAGE: ic.GetImageRepository(LWD_IMAGE),
+			LIGHT_WALLET_IMAGE: helper.GetInstanceImage(ic, instance, LWD_IMAGE),
 		},
 		Ports: map[string]int32{
 			GRPC: ic.GetPort(SERVICE_PORT),
@@ -263,15 +267,13 @@ func (L *LWDInstanceResourceManager) CreateStartResource(ctx context.Context, pr
 	specArr, err = fileTemplate.ExecuteTemplates([]string{"DEPLOYMENT", "SERVICE"}, lwdSpec)
 	if err != nil {
 		log.Errorf("Lightwalletd templates failed - %s", err)
-		//return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 	if err != nil {
 		log.Errorf("Lightwalletd templates failed - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	obj, err := L.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionStartInstance)
@@ -299,7 +301,7 @@ func (L *LWDInstanceResourceManager) CreateStopResource(ctx context.Context, pro
 	}
 
 	if len(resources) == 0 {
-		return nil, nil, errors.New("instance is not active")
+		return nil, nil, errs.NewApplicationError(errs.InstanceNotActiveError, nil)
 	}
 
 	obj, err := L.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionStopInstance)
@@ -311,27 +313,32 @@ func (L *LWDInstanceResourceManager) CreateStopResource(ctx context.Context, pro
 	return resources, objects, nil
 }
 
-func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error) {
+func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "lwd.CreateRepairResource")
 	defer func() { logger.LogServiceTime(log) }()
 
 	if peers == nil || len(peers) != 1 {
-		return nil, errors.New("lightwallet instances can only be paired with one zcash")
+		return nil, errs.New(errs.InvalidPeerError, "lightwallet instances can only be paired with one zcash")
 	}
 
 	var request = instance.Request
 
 	var dataVolumeName, dataVolumeSize string
 
-	pvc := instance.Resources.Persistentvolumeclaim // GetResourceByType(model.ResourcePersistentVolumeClaim)
+	var pvc *model.KubernetesResource
+	if instance.Resources != nil {
+		pvc = instance.Resources.Persistentvolumeclaim // GetResourceByType(model.ResourcePersistentVolumeClaim)
+	}
 	if pvc != nil && pvc.Status == "active" {
 		dataVolumeName = pvc.Name
 		dataVolumeSize = request.Volume.Size
 	} else {
 		dataVolumeName = fmt.Sprintf("%s-%s", instance.Name, utils.GenerateRandomString(5, true))
 		dataVolumeSize = request.Volume.Size
-		pvc.Name = dataVolumeName // create new volume
+		if pvc != nil {
+			pvc.Name = dataVolumeName // create new volume
+		}
 	}
 
 	policy := helper.GetPolicyInfo(ctx)
@@ -355,10 +362,11 @@ func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, p
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     dataVolumeName,
+		Credentials:        model.CredentialsSpec{SecretName: helper.CredentialsSecretName(peers[0].Name)},
 		Images: map[string]string{
-			LIGHT_WALLET_IMAGE: ic.GetImageRepository(LWD_IMAGE),
+			LIGHT_WALLET_IMAGE: helper.GetInstanceImage(ic, instance, LWD_IMAGE),
 		},
 		Ports: map[string]int32{
 			GRPC: ic.GetPort(SERVICE_PORT),
@@ -369,6 +377,7 @@ func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, p
 			ZCASH_INSTANCE:      zcashInstance,
 			ZCASH_PORT:          zcashPort,
 			LOG_LEVEL:           request.Properties[logLevelProperty],
+			LWD_CONFIG:          helper.CreateLWDConfig(request.Config),
 		},
 	}
 
@@ -379,15 +388,13 @@ func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, p
 	specArr, err = fileTemplate.ExecuteTemplates([]string{LWD_CONF, ZCASH_CONF, ENVOY_CONF, DEPLOYMENT, SERVICE}, lwdSpec)
 	if err != nil {
 		log.Errorf("Lightwalletd templates failed - %s", err)
-		//return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 	if err != nil {
 		log.Errorf("failed to generate specs for Lightwalletd - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	if pvc == nil || pvc.Status != "active" {
@@ -403,8 +410,7 @@ func (L *LWDInstanceResourceManager) CreateRepairResource(ctx context.Context, p
 		volumes, err := appRsc.CreateVolumeResource(ctx, volumeSpecs...)
 		if err != nil {
 			log.Errorf("Lightwalletd volume templates failed - %s", err)
-			//return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
-			return nil, err
+			return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
 		}
 
 		objects = append(objects, volumes...)
@@ -460,7 +466,7 @@ func (L *LWDInstanceResourceManager) CreateSnapshotScheduleResource(ctx context.
 	return appRsc.CreateSnapshotScheduleResource(ctx, &req)
 }
 
-func (L *LWDInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
+func (L *LWDInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error) {
 	var log = logger.GetServiceLogger(ctx, "lwd.CreateRotationResource")
 	defer func() { logger.LogServiceTime(log) }()
 
@@ -468,6 +474,40 @@ func (L *LWDInstanceResourceManager) CreateRotationResource(ctx context.Context,
 	return []unstructured.Unstructured{}, nil
 }
 
+// CreateDomainResource generates the HTTPProxy that serves the instance on its custom domain.
+func (L *LWDInstanceResourceManager) CreateDomainResource(ctx context.Context, project *model.Project, instance *model.Instance, domain model.DomainSpec) ([]unstructured.Unstructured, error) {
+
+	var log = logger.GetServiceLogger(ctx, "lwd.CreateDomainResource")
+	defer func() { logger.LogServiceTime(log) }()
+
+	fileTemplate, err := helper.GetInstanceTemplate(instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := helper.GetPolicyInfo(ctx)
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	lwdSpec := model.InstanceSpec{
+		Name:      instance.Name,
+		Namespace: project.GetNamespace(),
+		Labels:    helper.CreateInstanceLabels(instance),
+		Envoy:     helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
+		Domain:    domain,
+	}
+
+	specObj, err := fileTemplate.ExecuteTemplate(DOMAIN_INGRESS, lwdSpec)
+	if err != nil {
+		log.Errorf("Lightwalletd templates failed - %s", err)
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+	}
+
+	return helper.CreateYAMLObjects([]string{specObj})
+}
+
 func (L *LWDInstanceResourceManager) CreateDeleteResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
 	var log = logger.GetServiceLogger(ctx, "lwd.CreateDeleteResource")
 	defer func() { logger.LogServiceTime(log) }()
@@ -28,4 +28,4 @@ func TestLWD_CreateSnapshotScheduleResource(t *testing.T) {
 
 func TestLWD_CreateRotationResource(t *testing.T) {
 
-}
\ No newline at end of file
+}
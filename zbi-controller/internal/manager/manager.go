@@ -2,10 +2,10 @@ package manager
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 
 	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/model"
 )
 
@@ -19,10 +19,8 @@ const (
 	INGRESS_STOPPED = "INGRESS_STOPPED"
 	INGRESS         = "INGRESS"
 	CREDENTIALS     = "CREDENTIALS"
-
-	ZCASH_SVC_PREFIX = "zcashd-svc"
-	USERNAME         = "Username"
-	PASSWORD         = "Password"
+	DOMAIN_INGRESS  = "DOMAIN_INGRESS"
+	P2P_SERVICE     = "P2P_SERVICE"
 
 	ZcashConf          = "ZcashConf"
 	InstanceProperties = "InstanceProperties"
@@ -30,17 +28,23 @@ const (
 	LIGHT_WALLET_IMAGE = "Lightwallet"
 	ZCASH              = "Zcash"
 	METRICS            = "Metrics"
+	SEED               = "Seed"
 	GRPC               = "GRPC"
 	HTTP               = "HTTP"
+	P2P                = "P2P"
 
 	ENVOY_PORT   = "envoy"
 	SERVICE_PORT = "service"
 	METRICS_PORT = "metrics"
 	HTTP_PORT    = "http"
 
+	MAINNET_P2P_PORT = "mainnetP2P"
+	TESTNET_P2P_PORT = "testnetP2P"
+
 	LWD_IMAGE     = "lwd"
 	NODE_IMAGE    = "node"
 	METRICS_IMAGE = "metrics"
+	SEED_IMAGE    = "seed"
 
 	DEFAULT_ZCASH_CONF        = "default"
 	MINER_ZCASH_CONF          = "miner"
@@ -55,63 +59,100 @@ const (
 	ZCASH_INSTANCE      = "ZcashInstance"
 	ZCASH_PORT          = "ZcashPort"
 	LOG_LEVEL           = "LogLevel"
+	LWD_CONFIG          = "LwdConfig"
+	SEED_ARCHIVE        = "SeedArchive"
 
 	zcashInstanceProperty = "zcashInstance"
 	logLevelProperty      = "logLevel"
 )
 
-func addLWDInstance(instance *model.Instance, name string, request model.ResourceRequest) {
-	lwdInstances := request.Properties["lwdInstance"].([]string)
+// getBoolProperty returns the boolean property, or false when it is missing
+// or holds another type.
+func getBoolProperty(properties map[string]interface{}, name string) bool {
+	value, _ := properties[name].(bool)
+	return value
+}
 
-	found := false
-	for _, inst := range lwdInstances {
-		if inst == name {
-			found = true
+// getStringProperty returns the string property, or an empty string when it
+// is missing or holds another type.
+func getStringProperty(properties map[string]interface{}, name string) string {
+	value, _ := properties[name].(string)
+	return value
+}
+
+// getStringsProperty returns a list property. Lists decoded from JSON are
+// []interface{} rather than []string.
+func getStringsProperty(properties map[string]interface{}, name string) []string {
+	switch values := properties[name].(type) {
+	case []string:
+		return values
+	case []interface{}:
+		var result = make([]string, 0, len(values))
+		for _, value := range values {
+			if str, ok := value.(string); ok {
+				result = append(result, str)
+			}
 		}
+		return result
 	}
-	if !found {
-		lwdInstances = append(lwdInstances, name)
+	return []string{}
+}
+
+func addStringProperty(request *model.ResourceRequest, property, name string) {
+	values := getStringsProperty(request.Properties, property)
+	for _, value := range values {
+		if value == name {
+			return
+		}
 	}
 
-	request.Properties["lwdInstance"] = lwdInstances
+	if request.Properties == nil {
+		request.Properties = make(map[string]interface{})
+	}
+	request.Properties[property] = append(values, name)
 }
 
-func removeLWDInstance(instance *model.Instance, name string, request model.ResourceRequest) {
-	lwdInstances := request.Properties["lwdInstance"].([]string)
-	for index, inst := range lwdInstances {
-		if inst == name {
-			request.Properties["lwdInstance"] = append(lwdInstances[:index], lwdInstances[index+1:]...)
+func removeStringProperty(request *model.ResourceRequest, property, name string) {
+	values := getStringsProperty(request.Properties, property)
+	for index, value := range values {
+		if value == name {
+			request.Properties[property] = append(values[:index], values[index+1:]...)
 			break
 		}
 	}
 }
 
-func addZcashPeer(instance *model.Instance, name string, request model.ResourceRequest) {
-	peers := request.Properties["peers"].([]string)
-	found := false
-	for _, inst := range peers {
-		if inst == name {
-			found = true
-		}
-	}
-	if !found {
-		peers = append(peers, name)
-	}
+func addLWDInstance(instance *model.Instance, name string, request model.ResourceRequest) {
+	addStringProperty(&request, "lwdInstance", name)
+}
 
-	request.Properties["peers"] = peers
+func removeLWDInstance(instance *model.Instance, name string, request model.ResourceRequest) {
+	removeStringProperty(&request, "lwdInstance", name)
+}
+
+func addZcashPeer(instance *model.Instance, name string, request model.ResourceRequest) {
+	addStringProperty(&request, "peers", name)
 }
 
 func removeZcashPeer(instance *model.Instance, name string, request model.ResourceRequest) {
-	peers := request.Properties["peers"].([]string)
-	for index, inst := range peers {
-		if inst == name {
-			request.Properties["peers"] = append(peers[:index], peers[index+1:]...)
-			break
-		}
+	removeStringProperty(&request, "peers", name)
+}
+
+// getZcashSeed returns the seed the data volume of an instance is created
+// from, or nil when it is not created from a seed.
+func getZcashSeed(ctx context.Context, request *model.ResourceRequest) (*model.Seed, error) {
+	if request == nil || request.Volume.Source.Type != model.SeedDataSource {
+		return nil, nil
 	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	return repository.GetSeed(ctx, request.Volume.Source.Ref)
 }
 
-func createZcashConf(ic *model.BlockchainNodeInfo, miner bool, network model.NetworkType, rpcport string) []model.KVPair {
+// createZcashConf builds the zcash.conf settings of an instance from the
+// settings of its node type and the mining options and config overrides of
+// its request.
+func createZcashConf(ic *model.BlockchainNodeInfo, miner bool, network model.NetworkType, rpcport string, request *model.ResourceRequest) []model.KVPair {
 
 	var zcashConf = make([]model.KVPair, 0)
 
@@ -119,6 +160,7 @@ func createZcashConf(ic *model.BlockchainNodeInfo, miner bool, network model.Net
 
 	if miner {
 		zcashConf = append(zcashConf, ic.Settings[MINER_ZCASH_CONF]...)
+		zcashConf = helper.MergeZcashConf(zcashConf, helper.CreateMiningConf(request.Mining))
 	}
 
 	if network == model.NetworkTypeMain {
@@ -127,6 +169,7 @@ func createZcashConf(ic *model.BlockchainNodeInfo, miner bool, network model.Net
 		zcashConf = append(zcashConf, ic.Settings[TESTNET_ZCASH_CONF]...)
 	}
 
+	zcashConf = helper.MergeZcashConf(zcashConf, request.Config)
 	zcashConf = append(zcashConf, model.KVPair{Key: RPCPORT_ZCASH_PROPERTY, Value: rpcport})
 	return zcashConf
 }
@@ -148,8 +191,36 @@ func getZcashPeers(conf []model.KVPair, rpcport string, namespace string, peers
 	return conf
 }
 
+// getZcashExternalIP advertises the public p2p address of the instance, as
+// recorded by the monitor, to its peers.
+func getZcashExternalIP(conf []model.KVPair, instance *model.Instance) []model.KVPair {
+	address := helper.GetP2PAddress(instance)
+	if !helper.IsPublicP2P(instance) || address == "" {
+		return conf
+	}
+
+	return append(conf, model.KVPair{Key: helper.EXTERNALIP_ZCASH_PROPERTY, Value: address})
+}
+
+// getZcashP2PPort returns the p2p port zcashd listens on by default for the
+// network, or 0 when the network cannot be published.
+func getZcashP2PPort(ic *model.BlockchainNodeInfo, network model.NetworkType) int32 {
+	var port int32 = -1
+	switch network {
+	case model.NetworkTypeMain:
+		port = ic.GetPort(MAINNET_P2P_PORT)
+	case model.NetworkTypeTest:
+		port = ic.GetPort(TESTNET_P2P_PORT)
+	}
+
+	if port < 0 {
+		return 0
+	}
+	return port
+}
+
 func getZcashInstanceHost(name, namespace string) string {
-	return fmt.Sprintf("%s-%s.%s.svc.cluster.local", ZCASH_SVC_PREFIX, name, namespace)
+	return helper.GetZcashServiceHost(name, namespace)
 }
 
 func getZcashInstancePort(ctx context.Context) string {
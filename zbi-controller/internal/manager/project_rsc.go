@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/helper"
 	"github.com/zbitech/controller/internal/utils"
 	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -44,19 +44,43 @@ func (p ProjectResourceManager) CreateProjectResource(ctx context.Context, proje
 	}
 
 	var templates = []string{"NAMESPACE", "SERVICE"}
+
+	policy := helper.GetPolicyInfo(ctx)
+	if policy.NetworkPolicy.Enabled {
+		if len(policy.NetworkPolicy.IngressNamespace) == 0 {
+			log.Warnf("network policies are enabled without an ingress namespace - skipping")
+		} else {
+			projectSpec.NetworkPolicy = helper.CreateNetworkPolicySpec(policy)
+			templates = append(templates, getNetworkPolicyTemplates(projectSpec.NetworkPolicy)...)
+		}
+	}
+
 	specArr, err := fileTemplate.ExecuteTemplates(templates, projectSpec)
 	if err != nil {
 		log.Errorf("Project templates failed - %s", err)
 		//logger.Errorf(ctx, "Project templates for version %s failed - %s", project.Version, err)
 		//		return nil, errs.ErrProjectResourceFailed
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	log.Debugf("Generated spec details - %s", specArr)
 	return helper.CreateYAMLObjects(specArr)
 }
 
+// getNetworkPolicyTemplates returns the NetworkPolicies of a project. All
+// ingress is denied except from the ingress controller, between zcash peers,
+// from lightwallet to zcash instances and from anywhere to the public p2p
+// port of zcash instances, plus metrics scraping when a monitoring namespace
+// is set.
+func getNetworkPolicyTemplates(spec model.NetworkPolicySpec) []string {
+	var templates = []string{helper.NETWORK_POLICY_DEFAULT, helper.NETWORK_POLICY_INGRESS,
+		helper.NETWORK_POLICY_PEERS, helper.NETWORK_POLICY_LWD, helper.NETWORK_POLICY_P2P}
+	if len(spec.MonitoringNamespace) > 0 {
+		templates = append(templates, helper.NETWORK_POLICY_MONITORING)
+	}
+	return templates
+}
+
 func (p ProjectResourceManager) CreateProjectIngressResource(ctx context.Context, appIngress *unstructured.Unstructured, project *model.Project, action model.EventAction) ([]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "project.CreateProjectIngressResource")
@@ -86,15 +110,13 @@ func (p ProjectResourceManager) CreateProjectIngressResource(ctx context.Context
 	var ingressObj unstructured.Unstructured
 	if err = helper.DecodeJSON(specObj[0], &ingressObj); err != nil {
 		log.Errorf("Controller app template failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	var includeObj model.IngressInclude
 	if err = json.Unmarshal([]byte(specObj[1]), &includeObj); err != nil {
 		log.Errorf("Controller app template failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	//TODO - handle appIngress == nil - Return error?
@@ -145,6 +167,36 @@ func (p ProjectResourceManager) CreateProjectIngressResource(ctx context.Context
 	return []unstructured.Unstructured{appIng, objIng}, nil
 }
 
+// CreateInstanceListResource renders the INSTANCE_LIST ConfigMap of a project
+// from its current instances.
+func (p ProjectResourceManager) CreateInstanceListResource(ctx context.Context, project *model.Project) ([]unstructured.Unstructured, error) {
+
+	var log = logger.GetServiceLogger(ctx, "project.CreateInstanceListResource")
+	defer func() { logger.LogServiceTime(log) }()
+
+	repo := vars.RepositoryFactory.GetRepositoryService()
+	instances, err := repo.GetInstances(ctx, project.Id)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to get project instances")
+		return nil, err
+	}
+
+	fileTemplate := helper.GetProjectTemplate()
+	projectSpec := model.ProjectSpec{
+		Namespace:    project.Name,
+		InstancesMap: utils.MarshalObject(instances),
+		Labels:       helper.CreateProjectLabels(project),
+	}
+
+	specArr, err := fileTemplate.ExecuteTemplates([]string{helper.INSTANCE_LIST}, projectSpec)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("Project templates failed")
+		return nil, err
+	}
+
+	return helper.CreateYAMLObjects(specArr)
+}
+
 func (p ProjectResourceManager) CreateInstanceResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, [][]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "project.CreateInstanceResource")
@@ -152,7 +204,7 @@ func (p ProjectResourceManager) CreateInstanceResource(ctx context.Context, proj
 
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, nil, errors.New("resource retrieval error")
+		return nil, nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	repo := vars.RepositoryFactory.GetRepositoryService()
@@ -194,7 +246,7 @@ func (p ProjectResourceManager) CreateInstanceResource(ctx context.Context, proj
 func (p ProjectResourceManager) CreateUpdateResource(ctx context.Context, project *model.Project, instance *model.Instance, peers ...model.Instance) ([][]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateUpdateResource(ctx, project, instance, peers...)
@@ -203,7 +255,7 @@ func (p ProjectResourceManager) CreateUpdateResource(ctx context.Context, projec
 func (p ProjectResourceManager) CreateStartResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateStartResource(ctx, projIngress, project, instance)
@@ -212,25 +264,25 @@ func (p ProjectResourceManager) CreateStartResource(ctx context.Context, projIng
 func (p ProjectResourceManager) CreateStopResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, nil, errors.New("resource retrieval error")
+		return nil, nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateStopResource(ctx, projIngress, project, instance)
 }
 
-func (p ProjectResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error) {
+func (p ProjectResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
-	return instanceManager.CreateRepairResource(ctx, projIngress, project, instance, peers...)
+	return instanceManager.CreateRepairResource(ctx, projIngress, project, instance, credentials, peers...)
 }
 
 func (p ProjectResourceManager) CreateIngressResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, action model.EventAction) (*unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateIngressResource(ctx, projIngress, project, instance, action)
@@ -239,7 +291,7 @@ func (p ProjectResourceManager) CreateIngressResource(ctx context.Context, projI
 func (p ProjectResourceManager) CreateSnapshotResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateSnapshotResource(ctx, project, instance)
@@ -248,19 +300,83 @@ func (p ProjectResourceManager) CreateSnapshotResource(ctx context.Context, proj
 func (p ProjectResourceManager) CreateSnapshotScheduleResource(ctx context.Context, project *model.Project, instance *model.Instance, schedule model.SnapshotScheduleType) ([]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	return instanceManager.CreateSnapshotScheduleResource(ctx, project, instance, schedule)
 }
 
-func (p ProjectResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
+func (p ProjectResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error) {
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, errors.New("resource retrieval error")
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
+	}
+
+	return instanceManager.CreateRotationResource(ctx, project, instance, credentials)
+}
+
+// CreateDomainResource generates the resources of the custom domain of an instance, or of the project when instance
+// is nil. The secret of an uploaded certificate is only generated when the certificate is provided.
+func (p ProjectResourceManager) CreateDomainResource(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) ([]unstructured.Unstructured, error) {
+
+	var log = logger.GetServiceLogger(ctx, "project.CreateDomainResource")
+	defer func() { logger.LogServiceTime(log) }()
+
+	var labels = helper.CreateProjectLabels(project)
+	if instance != nil {
+		labels = helper.CreateInstanceLabels(instance)
+	}
+
+	domainSpec := model.DomainSpec{
+		Name:        helper.DomainResourceName(instance),
+		Namespace:   project.GetNamespace(),
+		Host:        domain.Host,
+		SecretName:  domain.SecretName,
+		Issuer:      domain.Issuer,
+		Certificate: utils.Base64EncodeString(certificate),
+		PrivateKey:  utils.Base64EncodeString(privateKey),
+		Labels:      helper.CreateDomainLabels(labels),
+	}
+
+	var templates []string
+	if domain.TLS == model.DomainTLSCertManager {
+		templates = append(templates, helper.DOMAIN_CERTIFICATE)
+	} else if domain.TLS == model.DomainTLSUploaded && len(certificate) > 0 {
+		templates = append(templates, helper.DOMAIN_SECRET)
+	}
+
+	if instance == nil {
+		templates = append(templates, helper.DOMAIN_INGRESS)
+	}
+
+	fileTemplate := helper.GetProjectTemplate()
+	specArr, err := fileTemplate.ExecuteTemplates(templates, domainSpec)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("Project templates failed")
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+	}
+
+	objects, err := helper.CreateYAMLObjects(specArr)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err}).Errorf("failed to create domain resources")
+		return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
+	}
+
+	if instance == nil {
+		return objects, nil
+	}
+
+	instanceManager, ok := p.instances[instance.InstanceType]
+	if !ok {
+		return nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
+	}
+
+	ingress, err := instanceManager.CreateDomainResource(ctx, project, instance, domainSpec)
+	if err != nil {
+		return nil, err
 	}
 
-	return instanceManager.CreateRotationResource(ctx, project, instance)
+	return append(objects, ingress...), nil
 }
 
 func (p ProjectResourceManager) CreateDeleteResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
@@ -270,7 +386,7 @@ func (p ProjectResourceManager) CreateDeleteResource(ctx context.Context, projIn
 
 	instanceManager, ok := p.instances[instance.InstanceType]
 	if !ok {
-		return nil, nil, errors.New("resource retrieval error")
+		return nil, nil, errs.NewApplicationError(errs.InstanceTypeError, nil)
 	}
 
 	repo := vars.RepositoryFactory.GetRepositoryService()
@@ -2,15 +2,16 @@ package manager
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strconv"
 	"text/template"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/secrets"
 	"github.com/zbitech/controller/internal/utils"
 	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
 	"github.com/zbitech/controller/pkg/interfaces"
 	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
@@ -56,22 +57,29 @@ func (z *ZcashInstanceResourceManager) CreateInstanceResource(ctx context.Contex
 	}
 
 	var request = instance.Request
-	var miner = false
 	//	txIndex := instance.Properties["transactionIndex"].(bool)
-	if _, ok := request.Properties[MINER_ZCASH_PROPERTY]; ok {
-		miner = request.Properties[MINER_ZCASH_PROPERTY].(bool)
-	}
+	miner := getBoolProperty(request.Properties, MINER_ZCASH_PROPERTY)
 	//	peers := instance.Properties["peers"].([]interface{})
 
 	rpcport := strconv.FormatInt(int64(ic.GetPort(SERVICE_PORT)), 10)
-	conf := createZcashConf(ic, miner, instance.Network, rpcport)
+	conf := createZcashConf(ic, miner, instance.Network, rpcport, request)
 	conf = getZcashPeers(conf, rpcport, project.GetNamespace(), peers...)
 
 	dataVolumeName := fmt.Sprintf("%s-%s", instance.Name, utils.GenerateRandomString(5, true))
 	dataVolumeSize := request.Volume.Size
 
-	username := utils.GenerateRandomString(6, true)
-	password := utils.GenerateSecurePassword()
+	seed, err := getZcashSeed(ctx, request)
+	if err != nil {
+		log.Errorf("unable to retrieve instance seed - %s", err)
+		return nil, err
+	}
+
+	provider := vars.KlientFactory.GetSecretProvider()
+	credentials, err := secrets.CreateCredentials(ctx, provider, project, instance)
+	if err != nil {
+		log.Errorf("unable to store instance credentials - %s", err)
+		return nil, err
+	}
 
 	instanceSpec := model.InstanceSpec{
 		Name:               instance.Name,
@@ -80,21 +88,23 @@ func (z *ZcashInstanceResourceManager) CreateInstanceResource(ctx context.Contex
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     dataVolumeName,
+		Credentials:        secrets.CreateCredentialsSpec(provider, project, instance, credentials),
 		Images: map[string]string{
-			ZCASH:   ic.GetImageRepository(NODE_IMAGE),
+			ZCASH:   helper.GetInstanceImage(ic, instance, NODE_IMAGE),
 			METRICS: ic.GetImageRepository(METRICS_IMAGE),
+			SEED:    ic.GetImageRepository(SEED_IMAGE),
 		},
 		Ports: map[string]int32{
 			ZCASH:   ic.GetPort(SERVICE_PORT),
 			METRICS: ic.GetPort(METRICS_PORT),
+			P2P:     getZcashP2PPort(ic, instance.Network),
 		},
 		Properties: map[string]interface{}{
-			USERNAME:                  username,
-			PASSWORD:                  password,
 			ZcashConf:                 conf,
 			RESOURCE_REQUEST_PROPERTY: utils.MarshalObject(request),
+			SEED_ARCHIVE:              helper.GetSeedArchive(seed),
 		},
 	}
 
@@ -102,15 +112,13 @@ func (z *ZcashInstanceResourceManager) CreateInstanceResource(ctx context.Contex
 
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	storageClass := policy.StorageClass
@@ -119,19 +127,24 @@ func (z *ZcashInstanceResourceManager) CreateInstanceResource(ctx context.Contex
 		{VolumeName: dataVolumeName, StorageClass: storageClass, Namespace: project.GetNamespace(),
 			VolumeDataType: string(request.Volume.Type), DataSourceType: request.Volume.Source.Type,
 			SourceName: request.Volume.Source.Ref,
-			Size:       dataVolumeSize, Labels: instanceSpec.Labels},
+			Size:       dataVolumeSize, Labels: instanceSpec.Labels, Seed: seed},
 	}
 
 	appRsc := vars.ManagerFactory.GetAppResourceManager(ctx)
 	volumes, err := appRsc.CreateVolumeResource(ctx, volumeSpecs...)
 	if err != nil {
 		log.Errorf("Zcash volume templates failed - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
 	}
 
 	objects = append(objects, volumes...)
 
+	p2p, err := z.createP2PServiceResource(ctx, project, instance)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, p2p...)
+
 	obj, err := z.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionCreate)
 	if err != nil {
 		log.Errorf("Instance ingress object creation failed - %s", err)
@@ -185,9 +198,10 @@ func (z *ZcashInstanceResourceManager) CreateUpdatePeersResource(ctx context.Con
 
 	var request = instance.Request
 
-	miner := request.Properties["miner"].(bool)
-	conf := createZcashConf(ic, miner, instance.Network, rpcport)
+	miner := getBoolProperty(request.Properties, MINER_ZCASH_PROPERTY)
+	conf := createZcashConf(ic, miner, instance.Network, rpcport, request)
 	conf = getZcashPeers(conf, rpcport, project.GetNamespace(), peers...)
+	conf = getZcashExternalIP(conf, instance)
 
 	instanceSpec := model.InstanceSpec{
 		Name:      instance.Name,
@@ -201,10 +215,53 @@ func (z *ZcashInstanceResourceManager) CreateUpdatePeersResource(ctx context.Con
 	specArr, err := fileTemplate.ExecuteTemplates([]string{ZCASH_CONF}, instanceSpec)
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+	}
+
+	return helper.CreateYAMLObjects(specArr)
+}
+
+// createP2PServiceResource renders the Service publishing the p2p port of an
+// instance with the publicP2P property set.
+func (z *ZcashInstanceResourceManager) createP2PServiceResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
+
+	var log = logger.GetServiceLogger(ctx, "zcash.createP2PServiceResource")
+	defer func() { logger.LogServiceTime(log) }()
+
+	if !helper.IsPublicP2P(instance) {
+		return nil, nil
+	}
+
+	fileTemplate, err := helper.GetInstanceTemplate(instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := helper.GetPolicyInfo(ctx)
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
 		return nil, err
 	}
 
+	port := getZcashP2PPort(ic, instance.Network)
+	if port == 0 {
+		return nil, errs.New(errs.InvalidStateError, fmt.Sprintf("public p2p is not supported on %s", instance.Network))
+	}
+
+	instanceSpec := model.InstanceSpec{
+		Name:      instance.Name,
+		Namespace: project.GetNamespace(),
+		Labels:    helper.CreateInstanceLabels(instance),
+		Ports:     map[string]int32{P2P: port},
+		P2P:       helper.CreateP2PSpec(policy, instance),
+	}
+
+	specArr, err := fileTemplate.ExecuteTemplates([]string{P2P_SERVICE}, instanceSpec)
+	if err != nil {
+		log.Errorf("zcash templates failed - %s", err)
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+	}
+
 	return helper.CreateYAMLObjects(specArr)
 }
 
@@ -228,9 +285,10 @@ func (z *ZcashInstanceResourceManager) CreateUpdateResource(ctx context.Context,
 
 	var request = instance.Request
 
-	miner := request.Properties["miner"].(bool)
-	conf := createZcashConf(ic, miner, instance.Network, rpcport)
+	miner := getBoolProperty(request.Properties, MINER_ZCASH_PROPERTY)
+	conf := createZcashConf(ic, miner, instance.Network, rpcport, request)
 	conf = getZcashPeers(conf, rpcport, project.GetNamespace(), peers...)
+	conf = getZcashExternalIP(conf, instance)
 
 	instanceSpec := model.InstanceSpec{
 		Name:      instance.Name,
@@ -244,8 +302,7 @@ func (z *ZcashInstanceResourceManager) CreateUpdateResource(ctx context.Context,
 	specArr, err := fileTemplate.ExecuteTemplates([]string{ZCASH_CONF}, instanceSpec)
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
@@ -254,6 +311,15 @@ func (z *ZcashInstanceResourceManager) CreateUpdateResource(ctx context.Context,
 		return nil, err
 	}
 
+	// the p2p service of a stopped instance is created when it starts
+	if instance.Resources != nil && instance.Resources.Deployment != nil && instance.Resources.Deployment.Status != "deleted" {
+		p2p, err := z.createP2PServiceResource(ctx, project, instance)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, p2p...)
+	}
+
 	var resources = make([][]unstructured.Unstructured, 0)
 	resources = append(resources, objects)
 
@@ -303,7 +369,7 @@ func (z *ZcashInstanceResourceManager) CreateIngressResource(ctx context.Context
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 	}
 	var specObj string
 
@@ -315,8 +381,7 @@ func (z *ZcashInstanceResourceManager) CreateIngressResource(ctx context.Context
 
 	if err != nil {
 		log.Errorf("Zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	var route *model.IngressRoute
@@ -324,14 +389,12 @@ func (z *ZcashInstanceResourceManager) CreateIngressResource(ctx context.Context
 	route, err = helper.CreateIngressRoute(ctx, specObj)
 	if err != nil {
 		log.Errorf("zcash route marshal failed - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	if err = helper.UpdateIngressRoute(ctx, projIngress, route, action == model.EventActionDelete); err != nil {
 		log.Errorf("error updating ingress route for zcash instance - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	return projIngress, nil
@@ -342,9 +405,18 @@ func (z *ZcashInstanceResourceManager) CreateStartResource(ctx context.Context,
 	var log = logger.GetServiceLogger(ctx, "zcash.CreateStartResource")
 	defer func() { logger.LogServiceTime(log) }()
 
+	if instance.Resources == nil || instance.Resources.Persistentvolumeclaim == nil {
+		return nil, errs.New(errs.InvalidStateError, "instance has no data volume")
+	}
+
 	pvc := instance.Resources.Persistentvolumeclaim // .GetResourceByType(model.ResourcePersistentVolumeClaim)
 
-	var err error
+	seed, err := getZcashSeed(ctx, instance.Request)
+	if err != nil {
+		log.Errorf("unable to retrieve instance seed - %s", err)
+		return nil, err
+	}
+
 	fileTemplate, err := helper.GetInstanceTemplate(instance.InstanceType)
 	if err != nil {
 		return nil, err
@@ -363,26 +435,37 @@ func (z *ZcashInstanceResourceManager) CreateStartResource(ctx context.Context,
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     pvc.Name,
+		Credentials:        model.CredentialsSpec{SecretName: helper.CredentialsSecretName(instance.Name)},
 		Images: map[string]string{
-			ZCASH:   ic.GetImageRepository(NODE_IMAGE),
+			ZCASH:   helper.GetInstanceImage(ic, instance, NODE_IMAGE),
 			METRICS: ic.GetImageRepository(METRICS_IMAGE),
+			SEED:    ic.GetImageRepository(SEED_IMAGE),
 		},
 		Ports: map[string]int32{
 			ZCASH:   ic.GetPort(SERVICE_PORT),
 			METRICS: ic.GetPort(METRICS_PORT),
+			P2P:     getZcashP2PPort(ic, instance.Network),
+		},
+		Properties: map[string]interface{}{
+			SEED_ARCHIVE: helper.GetSeedArchive(seed),
 		},
 	}
 	specArr, err := fileTemplate.ExecuteTemplates([]string{DEPLOYMENT, SERVICE}, zcashSpec)
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		// return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 
+	p2p, err := z.createP2PServiceResource(ctx, project, instance)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, p2p...)
+
 	obj, err := z.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionStartInstance)
 	if err != nil {
 		log.Errorf("instance ingress object creation failed - %s", err)
@@ -408,7 +491,13 @@ func (z *ZcashInstanceResourceManager) CreateStopResource(ctx context.Context, p
 	}
 
 	if len(resources) == 0 {
-		return nil, nil, errors.New("instance is not active")
+		return nil, nil, errs.NewApplicationError(errs.InstanceNotActiveError, nil)
+	}
+
+	// releases the external address
+	p2p := instance.Resources.P2p
+	if p2p != nil && (p2p.Status == helper.P2P_ACTIVE || p2p.Status == helper.P2P_PENDING) {
+		resources = append(resources, *p2p)
 	}
 
 	obj, err := z.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionStopInstance)
@@ -420,7 +509,7 @@ func (z *ZcashInstanceResourceManager) CreateStopResource(ctx context.Context, p
 	return resources, objects, nil
 }
 
-func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, peers ...model.Instance) ([]unstructured.Unstructured, error) {
+func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials, peers ...model.Instance) ([]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "zcash.CreateRepairResource")
 	defer func() { logger.LogServiceTime(log) }()
@@ -439,17 +528,20 @@ func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context,
 		return nil, err
 	}
 
-	var username, password, dataVolumeName, dataVolumeSize string
+	var dataVolumeName, dataVolumeSize string
 
-	pvc := instance.Resources.Persistentvolumeclaim // GetResourceByType(model.ResourcePersistentVolumeClaim)
-	secret := instance.Resources.Secret             // GetResourceByType(model.ResourceSecret)
+	var pvc *model.KubernetesResource
+	if instance.Resources != nil {
+		pvc = instance.Resources.Persistentvolumeclaim // GetResourceByType(model.ResourcePersistentVolumeClaim)
+	}
 
 	var request = instance.Request
 
-	miner := request.Properties[MINER_ZCASH_PROPERTY].(bool)
+	miner := getBoolProperty(request.Properties, MINER_ZCASH_PROPERTY)
 	rpcport := strconv.FormatInt(int64(ic.GetPort(SERVICE_PORT)), 10)
-	conf := createZcashConf(ic, miner, instance.Network, rpcport)
+	conf := createZcashConf(ic, miner, instance.Network, rpcport, request)
 	conf = getZcashPeers(conf, rpcport, project.GetNamespace(), peers...)
+	conf = getZcashExternalIP(conf, instance)
 
 	if pvc != nil && pvc.Status == "active" {
 		dataVolumeName = pvc.Name
@@ -457,15 +549,24 @@ func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context,
 	} else {
 		dataVolumeName = fmt.Sprintf("%s-%s", instance.Name, utils.GenerateRandomString(5, true))
 		dataVolumeSize = request.Volume.Size
-		pvc.Name = dataVolumeName // re-create in-active volume
+		if pvc != nil {
+			pvc.Name = dataVolumeName // re-create in-active volume
+		}
 	}
 
-	if secret != nil && secret.Status == "active" {
-		username = secret.Properties["username"].(string)
-		password = secret.Properties["password"].(string)
-	} else {
-		username = utils.GenerateRandomString(6, true)
-		password = utils.GenerateSecurePassword()
+	seed, err := getZcashSeed(ctx, request)
+	if err != nil {
+		log.Errorf("unable to retrieve instance seed - %s", err)
+		return nil, err
+	}
+
+	// existing credentials are read from the secret provider by the caller
+	provider := vars.KlientFactory.GetSecretProvider()
+	if credentials == nil || credentials.Username == "" || credentials.Password == "" {
+		if credentials, err = secrets.CreateCredentials(ctx, provider, project, instance); err != nil {
+			log.Errorf("unable to store instance credentials - %s", err)
+			return nil, err
+		}
 	}
 
 	instanceSpec := model.InstanceSpec{
@@ -475,20 +576,22 @@ func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context,
 		Labels:             helper.CreateInstanceLabels(instance),
 		DomainName:         policy.DomainName,
 		DomainSecret:       policy.CertificateName,
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
 		DataVolumeName:     dataVolumeName,
+		Credentials:        secrets.CreateCredentialsSpec(provider, project, instance, credentials),
 		Images: map[string]string{
-			ZCASH:   ic.GetImageRepository(NODE_IMAGE),
+			ZCASH:   helper.GetInstanceImage(ic, instance, NODE_IMAGE),
 			METRICS: ic.GetImageRepository(METRICS_IMAGE),
+			SEED:    ic.GetImageRepository(SEED_IMAGE),
 		},
 		Ports: map[string]int32{
 			ZCASH:   ic.GetPort(SERVICE_PORT),
 			METRICS: ic.GetPort(METRICS_PORT),
+			P2P:     getZcashP2PPort(ic, instance.Network),
 		},
 		Properties: map[string]interface{}{
-			USERNAME:  username,
-			PASSWORD:  password,
-			ZcashConf: conf,
+			ZcashConf:    conf,
+			SEED_ARCHIVE: helper.GetSeedArchive(seed),
 		},
 	}
 
@@ -496,15 +599,13 @@ func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context,
 
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	objects, err := helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 	if err != nil {
 		log.Errorf("zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	if pvc == nil || pvc.Status != "active" {
@@ -513,20 +614,25 @@ func (z *ZcashInstanceResourceManager) CreateRepairResource(ctx context.Context,
 			{VolumeName: dataVolumeName, StorageClass: storageClass, Namespace: project.GetNamespace(),
 				VolumeDataType: string(request.Volume.Type), DataSourceType: request.Volume.Source.Type,
 				SourceName: request.Volume.Source.Ref,
-				Size:       dataVolumeSize, Labels: instanceSpec.Labels},
+				Size:       dataVolumeSize, Labels: instanceSpec.Labels, Seed: seed},
 		}
 
 		appRsc := vars.ManagerFactory.GetAppResourceManager(ctx)
 		volumes, err := appRsc.CreateVolumeResource(ctx, volumeSpecs...)
 		if err != nil {
 			log.Errorf("Zcash volume templates failed - %s", err)
-			// return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
-			return nil, err
+			return nil, errs.NewApplicationError(errs.ResourceGenerationError, err)
 		}
 
 		objects = append(objects, volumes...)
 	}
 
+	p2p, err := z.createP2PServiceResource(ctx, project, instance)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, p2p...)
+
 	obj, err := z.CreateIngressResource(ctx, projIngress, project, instance, model.EventActionCreate)
 	if err != nil {
 		log.Errorf("Instance ingress object creation failed - %s", err)
@@ -581,7 +687,7 @@ func (z *ZcashInstanceResourceManager) CreateSnapshotScheduleResource(ctx contex
 	return appRsc.CreateSnapshotScheduleResource(ctx, &req)
 }
 
-func (z *ZcashInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance) ([]unstructured.Unstructured, error) {
+func (z *ZcashInstanceResourceManager) CreateRotationResource(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) ([]unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "zcash.CreateRotationResource")
 	defer func() { logger.LogServiceTime(log) }()
@@ -598,18 +704,16 @@ func (z *ZcashInstanceResourceManager) CreateRotationResource(ctx context.Contex
 		return nil, err
 	}
 
-	username := utils.GenerateRandomString(6, true)
-	password := utils.GenerateSecurePassword()
-
+	provider := vars.KlientFactory.GetSecretProvider()
 	zcashSpec := model.InstanceSpec{
 		Name:               instance.Name,
 		ServiceAccountName: policy.ServiceAccount,
 		Namespace:          project.GetNamespace(),
 		Labels:             helper.CreateInstanceLabels(instance),
-		Envoy:              helper.CreateEnvoySpec(policy, ic.GetPort(ENVOY_PORT)),
-		Properties: map[string]interface{}{
-			USERNAME: username,
-			PASSWORD: password,
+		Envoy:              helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
+		Credentials:        secrets.CreateCredentialsSpec(provider, project, instance, credentials),
+		Ports: map[string]int32{
+			ZCASH: ic.GetPort(SERVICE_PORT),
 		},
 	}
 
@@ -618,13 +722,46 @@ func (z *ZcashInstanceResourceManager) CreateRotationResource(ctx context.Contex
 	specArr, err = fileTemplate.ExecuteTemplates([]string{"ENVOY_CONF", "CREDENTIALS"}, zcashSpec)
 	if err != nil {
 		log.Errorf("Zcash templates failed - %s", err)
-		//		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
-		return nil, err
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
 	}
 
 	return helper.CreateYAMLObjects(specArr /*, instance.Project, instance.Name*/)
 }
 
+// CreateDomainResource generates the HTTPProxy that serves the instance on its custom domain.
+func (z *ZcashInstanceResourceManager) CreateDomainResource(ctx context.Context, project *model.Project, instance *model.Instance, domain model.DomainSpec) ([]unstructured.Unstructured, error) {
+
+	var log = logger.GetServiceLogger(ctx, "zcash.CreateDomainResource")
+	defer func() { logger.LogServiceTime(log) }()
+
+	fileTemplate, err := helper.GetInstanceTemplate(instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := helper.GetPolicyInfo(ctx)
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	zcashSpec := model.InstanceSpec{
+		Name:      instance.Name,
+		Namespace: project.GetNamespace(),
+		Labels:    helper.CreateInstanceLabels(instance),
+		Envoy:     helper.CreateEnvoySpec(policy, instance, ic.GetPort(ENVOY_PORT)),
+		Domain:    domain,
+	}
+
+	specObj, err := fileTemplate.ExecuteTemplate(DOMAIN_INGRESS, zcashSpec)
+	if err != nil {
+		log.Errorf("Zcash templates failed - %s", err)
+		return nil, errs.NewApplicationError(errs.ResourceRetrievalError, err)
+	}
+
+	return helper.CreateYAMLObjects([]string{specObj})
+}
+
 func (z *ZcashInstanceResourceManager) CreateDeleteResource(ctx context.Context, projIngress *unstructured.Unstructured, project *model.Project, instance *model.Instance) ([]model.KubernetesResource, []unstructured.Unstructured, error) {
 
 	var log = logger.GetServiceLogger(ctx, "zcash.CreateDeleteResource")
@@ -644,5 +781,12 @@ func (z *ZcashInstanceResourceManager) CreateDeleteResource(ctx context.Context,
 		}
 	}
 
+	// the ExternalSecret is not tracked with the instance resources; deleting
+	// it also removes the Secret it owns
+	if secrets.IsExternalProvider(vars.KlientFactory.GetSecretProvider()) {
+		resources = append(resources, model.KubernetesResource{Name: helper.CredentialsSecretName(instance.Name),
+			Namespace: project.GetNamespace(), Type: model.ResourceExternalSecret})
+	}
+
 	return resources, []unstructured.Unstructured{*ingressResource}, nil
 }
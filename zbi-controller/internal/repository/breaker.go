@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker fails calls fast once threshold consecutive failures have
+// been seen. After openTimeout a single trial call is let through; its
+// outcome closes or re-opens the circuit.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, openTimeout: openTimeout, now: time.Now}
+}
+
+func (b *circuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// a trial call is already in flight
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Abort releases a trial call without recording an outcome.
+func (b *circuitBreaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/pkg/logger"
+)
+
+const maxErrorBodySize = 64 * 1024
+
+// restClient is the transport shared by all RepositoryService calls. It
+// bounds each attempt with a timeout, retries idempotent requests with
+// exponential backoff and jitter, and trips a circuit breaker while the
+// repository is unavailable.
+type restClient struct {
+	baseURL    string
+	secret     string
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	breaker    *circuitBreaker
+}
+
+type restClientConfig struct {
+	BaseURL          string
+	Secret           string
+	Timeout          time.Duration
+	Retries          int
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int
+	BreakerTimeout   time.Duration
+}
+
+func newRestClient(cfg restClientConfig) *restClient {
+	return &restClient{
+		baseURL:    cfg.BaseURL,
+		secret:     cfg.Secret,
+		httpClient: &http.Client{},
+		timeout:    cfg.Timeout,
+		retries:    cfg.Retries,
+		minBackoff: cfg.MinBackoff,
+		maxBackoff: cfg.MaxBackoff,
+		breaker:    newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerTimeout),
+	}
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+// do sends the request and decodes a successful response into result when
+// it is not nil. Non-2xx responses are returned as *RepositoryError.
+func (c *restClient) do(ctx context.Context, method, path string, headers map[string]string, body, result interface{}) error {
+
+	log := logger.GetServiceLogger(ctx, "repo.client")
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	attempts := 1
+	if isIdempotent(method) {
+		attempts += c.retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := c.backoff(attempt)
+			log.WithFields(logrus.Fields{"method": method, "path": path, "attempt": attempt, "delay": delay.String()}).Warnf("retrying repository request - %s", lastErr)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		if err := c.breaker.Allow(); err != nil {
+			return err
+		}
+
+		retry, err := c.attempt(ctx, method, path, headers, payload, result)
+		if err == nil {
+			c.breaker.Success()
+			return nil
+		}
+
+		// a cancelled caller says nothing about the health of the repository
+		if ctx.Err() != nil {
+			c.breaker.Abort()
+			return ctx.Err()
+		}
+
+		if !retry {
+			c.breaker.Success()
+			return err
+		}
+
+		c.breaker.Failure()
+		lastErr = err
+	}
+
+	log.WithFields(logrus.Fields{"method": method, "path": path}).Errorf("repository request failed - %s", lastErr)
+	return lastErr
+}
+
+// attempt performs a single request. The boolean result reports whether the
+// failure is transient and counts against the circuit breaker.
+func (c *restClient) attempt(ctx context.Context, method, path string, headers map[string]string, payload []byte, result interface{}) (bool, error) {
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("x-internal-secret", c.secret)
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, &RepositoryError{Method: method, Path: path, Message: err.Error(), Err: ErrUnavailable}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rerr := decodeError(method, path, resp)
+		return errors.Is(rerr, ErrUnavailable), rerr
+	}
+
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return false, &RepositoryError{StatusCode: resp.StatusCode, Method: method, Path: path,
+			Message: "unable to decode response: " + err.Error(), Err: ErrInvalid}
+	}
+
+	return false, nil
+}
+
+// decodeError reads a zbi-db error body, which has the form {"message": ...}.
+func decodeError(method, path string, resp *http.Response) *RepositoryError {
+	var body struct {
+		Message string `json:"message"`
+	}
+
+	content, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err := json.Unmarshal(content, &body); err != nil || body.Message == "" {
+		body.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &RepositoryError{
+		StatusCode: resp.StatusCode,
+		Message:    body.Message,
+		Method:     method,
+		Path:       path,
+		Err:        statusError(resp.StatusCode),
+	}
+}
+
+// backoff returns a delay drawn uniformly from [0, min(maxBackoff, minBackoff * 2^(attempt-1))).
+func (c *restClient) backoff(attempt int) time.Duration {
+	delay := c.minBackoff << uint(attempt-1)
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
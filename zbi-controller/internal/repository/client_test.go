@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func newTestClient(url string) *restClient {
+	return newRestClient(restClientConfig{
+		BaseURL:          url,
+		Secret:           "secret",
+		Timeout:          time.Second,
+		Retries:          2,
+		MinBackoff:       time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		BreakerThreshold: 3,
+		BreakerTimeout:   time.Minute,
+	})
+}
+
+func TestRestClient_RetryIdempotent(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("x-internal-secret"))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"storageClass": "csi-sc"}`))
+	}))
+	defer server.Close()
+
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+	policy, err := repo.GetPolicyInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "csi-sc", policy.StorageClass)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRestClient_NoRetryPost(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+	err := repo.AddProjectActivity(context.Background(), "project", model.EventActionCreate)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRestClient_TypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "instance not found"}`))
+	}))
+	defer server.Close()
+
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+	_, err := repo.GetInstance(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var rerr *RepositoryError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, http.StatusNotFound, rerr.StatusCode)
+	assert.Equal(t, "instance not found", rerr.Message)
+}
+
+func TestRestClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+	_, err := repo.GetProject(context.Background(), "project")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(3), calls)
+
+	_, err = repo.GetProject(context.Background(), "project")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRestClient_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	client := newTestClient(server.URL)
+	err := client.do(ctx, http.MethodGet, "/projects", nil, nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, client.breaker.Allow())
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(1, time.Second)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	now = now.Add(2 * time.Second)
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	breaker.Success()
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestRepositoryError_ErrorCode(t *testing.T) {
+	assert.Equal(t, errs.NotFoundError, errs.Code(ErrInstanceNotFound))
+	assert.Equal(t, errs.ConflictError, errs.Code(ErrProjectExists))
+	assert.Equal(t, errs.RepositoryError, errs.Code(ErrCircuitOpen))
+	assert.Equal(t, errs.RepositoryError.Message, errs.Message(&RepositoryError{StatusCode: 500, Message: "mongo down", Err: ErrUnavailable}))
+}
+
+func TestRepositoryService_ListProjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/list":
+			query := r.URL.Query()
+			assert.Equal(t, "testnet", query.Get("network"))
+			assert.Equal(t, "carol", query.Get("member"))
+			assert.Equal(t, "1", query.Get("limit"))
+			assert.Equal(t, "-name", query.Get("sort"))
+			if query.Get("cursor") == "bogus" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message": "invalid cursor"}`))
+				return
+			}
+			w.Write([]byte(`{"projects": [{"id": "2", "name": "alpha", "owner": "bob", "network": "testnet", "team": "t1"}],
+				"total": 2, "nextCursor": "next"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+
+	query := &model.ListQuery{Network: "testnet", Member: "carol", Limit: 1, Sort: "-name"}
+	list, err := repo.ListProjects(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	assert.Equal(t, "next", list.NextCursor)
+	assert.Equal(t, "alpha", list.Projects[0].Name)
+
+	query.Cursor = "bogus"
+	_, err = repo.ListProjects(ctx, query)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = repo.ListProjects(ctx, &model.ListQuery{LabelSelector: "env in dev"})
+	assert.ErrorIs(t, err, ErrInvalidSelector)
+}
+
+func TestRepositoryService_ListInstances(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/p1/instances/list":
+			query := r.URL.Query()
+			assert.Equal(t, "lwd", query.Get("type"))
+			assert.Equal(t, "env=dev", query.Get("labelSelector"))
+			assert.Equal(t, "alice", query.Get("owner"))
+			w.Write([]byte(`{"instances": [{"id": "2", "name": "wallet", "type": "lwd"}], "total": 1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := &RepositoryService{client: newTestClient(server.URL)}
+
+	list, err := repo.ListInstances(ctx, "p1", &model.ListQuery{Type: "lwd", LabelSelector: "env=dev", Owner: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Empty(t, list.NextCursor)
+	assert.Equal(t, "wallet", list.Instances[0].Name)
+
+	_, err = repo.ListInstances(ctx, "p1", &model.ListQuery{Sort: "color"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
@@ -0,0 +1,1045 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// EmbeddedRepositoryService implements RepositoryServiceIF on top of a local
+// store so the controller can run without the zbi-db service.
+type EmbeddedRepositoryService struct {
+	store *embeddedStore
+}
+
+func NewEmbeddedRepositoryService(path, configDir, templateDir string) (interfaces.RepositoryServiceIF, error) {
+	store, err := newEmbeddedStore(path, configDir, templateDir)
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddedRepositoryService{store: store}, nil
+}
+
+func (repo *EmbeddedRepositoryService) UpdateProjectResource(ctx context.Context, project string, resource *model.KubernetesResource) error {
+	log := logger.GetServiceLogger(ctx, "embedded.UpdateProjectResource")
+
+	return repo.store.write(func(data *embeddedData) error {
+		p, ok := data.Projects[project]
+		if !ok {
+			log.WithFields(logrus.Fields{"project": project}).Errorf("project not found")
+			return ErrProjectNotFound
+		}
+		p.Resources = setResource(p.Resources, resource)
+		p.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceResource(ctx context.Context, instance string, resource *model.KubernetesResource) error {
+	log := logger.GetServiceLogger(ctx, "embedded.UpdateInstanceResource")
+
+	return repo.store.write(func(data *embeddedData) error {
+		i, ok := data.Instances[instance]
+		if !ok {
+			log.WithFields(logrus.Fields{"instance": instance}).Errorf("instance not found")
+			return ErrInstanceNotFound
+		}
+		i.Resources = setResource(i.Resources, resource)
+		i.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) GetBlockchainInfo(ctx context.Context, blockchain string) (*model.BlockchainInfo, error) {
+	var result model.BlockchainInfo
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, info := range data.Blockchains {
+			if info.Name == blockchain {
+				return copyObject(info, &result)
+			}
+		}
+		return ErrBlockchainNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetBlockchainNodeInfo(ctx context.Context, blockchain, node string) (*model.BlockchainNodeInfo, error) {
+	var result model.BlockchainNodeInfo
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, info := range data.Blockchains {
+			if info.Name != blockchain {
+				continue
+			}
+			for _, n := range info.Nodes {
+				if n.Type == node || n.Name == node {
+					return copyObject(n, &result)
+				}
+			}
+		}
+		return ErrBlockchainNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetPolicyInfo(ctx context.Context) (*model.PolicyInfo, error) {
+	var result model.PolicyInfo
+	err := repo.store.read(func(data *embeddedData) error {
+		if data.Policy == nil {
+			return ErrPolicyNotFound
+		}
+		return copyObject(data.Policy, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) CreateProject(ctx context.Context, project *model.Project) (*model.Project, error) {
+	log := logger.GetServiceLogger(ctx, "embedded.CreateProject")
+
+	var result model.Project
+	err := repo.store.write(func(data *embeddedData) error {
+		for _, p := range data.Projects {
+			if p.Name == project.Name {
+				return ErrProjectExists
+			}
+		}
+
+		if _, ok := data.Teams[project.TeamId]; project.TeamId != "" && !ok {
+			return ErrTeamNotFound
+		}
+
+		var p model.Project
+		if err := copyObject(project, &p); err != nil {
+			return err
+		}
+		p.Id = uuid.New().String()
+		if p.Status == "" {
+			p.Status = string(model.StatusNew)
+		}
+		p.CreatedAt = now()
+		p.UpdatedAt = p.CreatedAt
+		data.Projects[p.Id] = &p
+
+		return copyObject(&p, &result)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{"name": project.Name}).Errorf("failed to create project: %s", err)
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetProject(ctx context.Context, id string) (*model.Project, error) {
+	var result model.Project
+	err := repo.store.read(func(data *embeddedData) error {
+		p, ok := data.Projects[id]
+		if !ok {
+			return ErrProjectNotFound
+		}
+		return copyObject(p, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) UpdateProject(ctx context.Context, projectId string, request *model.ProjectUpdateRequest) (*model.Project, error) {
+	var result model.Project
+	err := repo.store.write(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+		if request.Description != nil {
+			project.Description = *request.Description
+		}
+		if request.Labels != nil {
+			project.Labels = request.Labels
+		}
+		if request.Rotation != nil {
+			project.Rotation = request.Rotation
+		}
+		project.UpdatedAt = now()
+		return copyObject(project, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetProjects(ctx context.Context, owner string) ([]model.Project, error) {
+	var result = make([]model.Project, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, p := range data.Projects {
+			if owner != "" && p.Owner != owner {
+				continue
+			}
+			var project model.Project
+			if err := copyObject(p, &project); err != nil {
+				return err
+			}
+			result = append(result, project)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (repo *EmbeddedRepositoryService) ListProjects(ctx context.Context, query *model.ListQuery) (*model.ProjectList, error) {
+	var result = model.ProjectList{Projects: make([]model.Project, 0)}
+	err := repo.store.read(func(data *embeddedData) error {
+		var teams = make(map[string]bool)
+		for _, team := range data.Teams {
+			if query.Member != "" && team.GetMember(query.Member) != nil {
+				teams[team.Id] = true
+			}
+		}
+
+		var projects = make([]*model.Project, 0, len(data.Projects))
+		for _, p := range data.Projects {
+			projects = append(projects, p)
+		}
+
+		entries, err := projectEntries(projects, teams, query)
+		if err != nil {
+			return err
+		}
+
+		page, total, next, err := pageEntries(entries, query)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			var project model.Project
+			if err := copyObject(entry.object, &project); err != nil {
+				return err
+			}
+			result.Projects = append(result.Projects, project)
+		}
+		result.Total = total
+		result.NextCursor = next
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetTeamProjects(ctx context.Context, teamId string) ([]model.Project, error) {
+	var result = make([]model.Project, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		if _, ok := data.Teams[teamId]; !ok {
+			return ErrTeamNotFound
+		}
+		for _, p := range data.Projects {
+			if p.TeamId != teamId {
+				continue
+			}
+			var project model.Project
+			if err := copyObject(p, &project); err != nil {
+				return err
+			}
+			result = append(result, project)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (repo *EmbeddedRepositoryService) CountTeamInstances(ctx context.Context, teamId string) (int, error) {
+	var count int
+	err := repo.store.read(func(data *embeddedData) error {
+		if _, ok := data.Teams[teamId]; !ok {
+			return ErrTeamNotFound
+		}
+		for _, i := range data.Instances {
+			if i.Project == nil {
+				continue
+			}
+			if project, ok := data.Projects[i.Project.Id]; ok && project.TeamId == teamId {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (repo *EmbeddedRepositoryService) CreateTeam(ctx context.Context, team *model.Team) (*model.Team, error) {
+	var result model.Team
+	err := repo.store.write(func(data *embeddedData) error {
+		for _, t := range data.Teams {
+			if t.Name == team.Name {
+				return ErrTeamExists
+			}
+		}
+
+		var t model.Team
+		if err := copyObject(team, &t); err != nil {
+			return err
+		}
+		t.Id = uuid.New().String()
+		t.CreatedAt = now()
+		t.UpdatedAt = t.CreatedAt
+		data.Teams[t.Id] = &t
+
+		return copyObject(&t, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetTeam(ctx context.Context, teamId string) (*model.Team, error) {
+	var result model.Team
+	err := repo.store.read(func(data *embeddedData) error {
+		t, ok := data.Teams[teamId]
+		if !ok {
+			return ErrTeamNotFound
+		}
+		return copyObject(t, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetTeams(ctx context.Context, member string) ([]model.Team, error) {
+	var result = make([]model.Team, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, t := range data.Teams {
+			if member != "" && t.GetMember(member) == nil {
+				continue
+			}
+			var team model.Team
+			if err := copyObject(t, &team); err != nil {
+				return err
+			}
+			result = append(result, team)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// UpdateTeamMember adds a member to a team or changes the role of an
+// existing member.
+func (repo *EmbeddedRepositoryService) UpdateTeamMember(ctx context.Context, teamId string, member *model.TeamMember) error {
+	return repo.store.write(func(data *embeddedData) error {
+		t, ok := data.Teams[teamId]
+		if !ok {
+			return ErrTeamNotFound
+		}
+		if m := t.GetMember(member.UserId); m != nil {
+			m.Role = member.Role
+		} else {
+			t.Members = append(t.Members, *member)
+		}
+		t.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) RemoveTeamMember(ctx context.Context, teamId, userid string) error {
+	return repo.store.write(func(data *embeddedData) error {
+		t, ok := data.Teams[teamId]
+		if !ok {
+			return ErrTeamNotFound
+		}
+		for index, m := range t.Members {
+			if m.UserId == userid {
+				t.Members = append(t.Members[:index], t.Members[index+1:]...)
+				t.UpdatedAt = now()
+				return nil
+			}
+		}
+		return ErrMemberNotFound
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateTeamQuota(ctx context.Context, teamId string, quota *model.TeamQuota) error {
+	return repo.store.write(func(data *embeddedData) error {
+		t, ok := data.Teams[teamId]
+		if !ok {
+			return ErrTeamNotFound
+		}
+		t.Quota = *quota
+		t.UpdatedAt = now()
+		return nil
+	})
+}
+
+// CreateSeed adds a seed to the catalog. Seed names are unique within a
+// network.
+func (repo *EmbeddedRepositoryService) CreateSeed(ctx context.Context, seed *model.Seed) (*model.Seed, error) {
+	var result model.Seed
+	err := repo.store.write(func(data *embeddedData) error {
+		for _, s := range data.Seeds {
+			if s.Name == seed.Name && s.Network == seed.Network {
+				return ErrSeedExists
+			}
+		}
+
+		var s model.Seed
+		if err := copyObject(seed, &s); err != nil {
+			return err
+		}
+		s.Id = uuid.New().String()
+		s.CreatedAt = now()
+		s.UpdatedAt = s.CreatedAt
+		data.Seeds[s.Id] = &s
+
+		return copyObject(&s, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetSeed(ctx context.Context, seedId string) (*model.Seed, error) {
+	var result model.Seed
+	err := repo.store.read(func(data *embeddedData) error {
+		s, ok := data.Seeds[seedId]
+		if !ok {
+			return ErrSeedNotFound
+		}
+		return copyObject(s, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetSeeds(ctx context.Context, network string) ([]model.Seed, error) {
+	var result = make([]model.Seed, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, s := range data.Seeds {
+			if network != "" && string(s.Network) != network {
+				continue
+			}
+			var seed model.Seed
+			if err := copyObject(s, &seed); err != nil {
+				return err
+			}
+			result = append(result, seed)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Network != result[j].Network {
+			return result[i].Network < result[j].Network
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// UpdateSeed replaces the definition of a seed. Instances created from it
+// keep their data.
+func (repo *EmbeddedRepositoryService) UpdateSeed(ctx context.Context, seedId string, seed *model.Seed) (*model.Seed, error) {
+	var result model.Seed
+	err := repo.store.write(func(data *embeddedData) error {
+		current, ok := data.Seeds[seedId]
+		if !ok {
+			return ErrSeedNotFound
+		}
+		for _, s := range data.Seeds {
+			if s.Id != seedId && s.Name == seed.Name && s.Network == seed.Network {
+				return ErrSeedExists
+			}
+		}
+
+		var s model.Seed
+		if err := copyObject(seed, &s); err != nil {
+			return err
+		}
+		s.Id = seedId
+		s.CreatedBy = current.CreatedBy
+		s.CreatedAt = current.CreatedAt
+		s.UpdatedAt = now()
+		data.Seeds[seedId] = &s
+
+		return copyObject(&s, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSeed removes a seed from the catalog. Seeds that instances were
+// created from are kept, since repairing those instances may need them.
+func (repo *EmbeddedRepositoryService) DeleteSeed(ctx context.Context, seedId string) error {
+	return repo.store.write(func(data *embeddedData) error {
+		if _, ok := data.Seeds[seedId]; !ok {
+			return ErrSeedNotFound
+		}
+		for _, i := range data.Instances {
+			if i.Request != nil && i.Request.Volume.Source.Type == model.SeedDataSource && i.Request.Volume.Source.Ref == seedId {
+				return ErrSeedInUse
+			}
+		}
+		delete(data.Seeds, seedId)
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) GetInstance(ctx context.Context, id string) (*model.Instance, error) {
+	var result model.Instance
+	err := repo.store.read(func(data *embeddedData) error {
+		i, ok := data.Instances[id]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		return copyInstance(data, i, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetInstances(ctx context.Context, project string) ([]model.Instance, error) {
+	var result = make([]model.Instance, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		if _, ok := data.Projects[project]; !ok {
+			return ErrProjectNotFound
+		}
+		for _, i := range data.Instances {
+			if i.Project == nil || i.Project.Id != project {
+				continue
+			}
+			var instance model.Instance
+			if err := copyInstance(data, i, &instance); err != nil {
+				return err
+			}
+			result = append(result, instance)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (repo *EmbeddedRepositoryService) ListInstances(ctx context.Context, projectId string, query *model.ListQuery) (*model.InstanceList, error) {
+	var result = model.InstanceList{Instances: make([]model.Instance, 0)}
+	err := repo.store.read(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+
+		var instances []*model.Instance
+		for _, i := range data.Instances {
+			if i.Project != nil && i.Project.Id == projectId {
+				instances = append(instances, i)
+			}
+		}
+
+		entries, err := instanceEntries(instances, project, query)
+		if err != nil {
+			return err
+		}
+
+		page, total, next, err := pageEntries(entries, query)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			var instance model.Instance
+			if err := copyInstance(data, entry.object.(*model.Instance), &instance); err != nil {
+				return err
+			}
+			result.Instances = append(result.Instances, instance)
+		}
+		result.Total = total
+		result.NextCursor = next
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) CreateInstance(ctx context.Context, projectId, owner string, request *model.InstanceRequest) (*model.Instance, error) {
+	log := logger.GetServiceLogger(ctx, "embedded.CreateInstance")
+
+	var result model.Instance
+	err := repo.store.write(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+
+		for _, i := range data.Instances {
+			if i.Project != nil && i.Project.Id == projectId && i.Name == request.Name {
+				return ErrInstanceExists
+			}
+		}
+
+		instance := &model.Instance{
+			Id:           uuid.New().String(),
+			Name:         request.Name,
+			Type:         request.Type,
+			InstanceType: request.Type,
+			Project:      &model.Project{Id: project.Id},
+			Status:       string(model.StatusNew),
+			Owner:        owner,
+			Network:      model.NetworkType(project.Network),
+			Request:      newResourceRequest(request),
+			CreatedAt:    now(),
+		}
+		instance.UpdatedAt = instance.CreatedAt
+		data.Instances[instance.Id] = instance
+
+		return copyInstance(data, instance, &result)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{"project": projectId, "name": request.Name}).Errorf("failed to create instance: %s", err)
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstance(ctx context.Context, instanceId string, request *model.InstanceRequest) (*model.Instance, error) {
+	var result model.Instance
+	err := repo.store.write(func(data *embeddedData) error {
+		instance, ok := data.Instances[instanceId]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		instance.Request = newResourceRequest(request)
+		instance.UpdatedAt = now()
+		return copyInstance(data, instance, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceRotation(ctx context.Context, instanceId string, rotation *model.CredentialsRotation) error {
+	return repo.store.write(func(data *embeddedData) error {
+		instance, ok := data.Instances[instanceId]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		instance.Rotation = rotation
+		instance.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateProjectSchedule(ctx context.Context, projectId string, schedule *model.PowerSchedule) error {
+	return repo.store.write(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+		project.Schedule = schedule
+		project.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceSchedule(ctx context.Context, instanceId string, schedule *model.PowerSchedule) error {
+	return repo.updateInstance(instanceId, func(instance *model.Instance) {
+		instance.Schedule = schedule
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceHold(ctx context.Context, instanceId string, hold *model.ScheduleHold) error {
+	return repo.updateInstance(instanceId, func(instance *model.Instance) {
+		instance.Hold = hold
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstancePower(ctx context.Context, instanceId string, state *model.PowerState) error {
+	return repo.updateInstance(instanceId, func(instance *model.Instance) {
+		instance.Power = state
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceImage(ctx context.Context, instanceId string, image *model.ImagePin) error {
+	return repo.updateInstance(instanceId, func(instance *model.Instance) {
+		instance.Image = image
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateProjectUpgrade(ctx context.Context, projectId string, upgrade *model.ProjectUpgrade) error {
+	return repo.store.write(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+		// the upgrade is stored as a copy since its runner keeps updating it
+		var stored *model.ProjectUpgrade
+		if upgrade != nil {
+			stored = &model.ProjectUpgrade{}
+			if err := copyObject(upgrade, stored); err != nil {
+				return err
+			}
+		}
+		project.Upgrade = stored
+		project.UpdatedAt = now()
+		return nil
+	})
+}
+
+// updateInstance applies update to a stored instance.
+func (repo *EmbeddedRepositoryService) updateInstance(instanceId string, update func(instance *model.Instance)) error {
+	return repo.store.write(func(data *embeddedData) error {
+		instance, ok := data.Instances[instanceId]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		update(instance)
+		instance.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateProjectDomain(ctx context.Context, projectId string, domain *model.Domain) error {
+	return repo.store.write(func(data *embeddedData) error {
+		project, ok := data.Projects[projectId]
+		if !ok {
+			return ErrProjectNotFound
+		}
+		if domain != nil && domainInUse(data, domain.Host, projectId) {
+			return ErrDomainExists
+		}
+		project.Domain = domain
+		project.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceDomain(ctx context.Context, instanceId string, domain *model.Domain) error {
+	return repo.store.write(func(data *embeddedData) error {
+		instance, ok := data.Instances[instanceId]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		if domain != nil && domainInUse(data, domain.Host, instanceId) {
+			return ErrDomainExists
+		}
+		instance.Domain = domain
+		instance.UpdatedAt = now()
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) UpdateInstanceAllowList(ctx context.Context, instanceId string, allowList []string) error {
+	return repo.store.write(func(data *embeddedData) error {
+		instance, ok := data.Instances[instanceId]
+		if !ok {
+			return ErrInstanceNotFound
+		}
+		if instance.Request == nil {
+			instance.Request = &model.ResourceRequest{}
+		}
+		instance.Request.IPAllowList = allowList
+		instance.UpdatedAt = now()
+		return nil
+	})
+}
+
+// domainInUse reports whether host is the domain of a project or instance
+// other than owner.
+func domainInUse(data *embeddedData, host, owner string) bool {
+	for id, project := range data.Projects {
+		if id != owner && project.Domain != nil && strings.EqualFold(project.Domain.Host, host) {
+			return true
+		}
+	}
+	for id, instance := range data.Instances {
+		if id != owner && instance.Domain != nil && strings.EqualFold(instance.Domain.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *EmbeddedRepositoryService) CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error) {
+	var result model.APIKey
+	err := repo.store.write(func(data *embeddedData) error {
+		if _, ok := data.Instances[key.InstanceId]; !ok {
+			return ErrInstanceNotFound
+		}
+		if _, ok := data.APIKeys[key.Id]; ok {
+			return ErrAPIKeyExists
+		}
+
+		var k model.APIKey
+		if err := copyObject(key, &k); err != nil {
+			return err
+		}
+		if k.CreatedAt == nil {
+			k.CreatedAt = now()
+		}
+		data.APIKeys[k.Id] = &k
+
+		return copyObject(&k, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	var result model.APIKey
+	err := repo.store.read(func(data *embeddedData) error {
+		k, ok := data.APIKeys[id]
+		if !ok {
+			return ErrAPIKeyNotFound
+		}
+		return copyObject(k, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (repo *EmbeddedRepositoryService) GetAPIKeys(ctx context.Context, instanceId string) ([]model.APIKey, error) {
+	var result = make([]model.APIKey, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		for _, k := range data.APIKeys {
+			if k.InstanceId != instanceId {
+				continue
+			}
+			var key model.APIKey
+			if err := copyObject(k, &key); err != nil {
+				return err
+			}
+			result = append(result, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(*result[j].CreatedAt) })
+	return result, nil
+}
+
+func (repo *EmbeddedRepositoryService) RevokeAPIKey(ctx context.Context, id string) error {
+	return repo.store.write(func(data *embeddedData) error {
+		k, ok := data.APIKeys[id]
+		if !ok {
+			return ErrAPIKeyNotFound
+		}
+		if k.RevokedAt == nil {
+			k.RevokedAt = now()
+		}
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) AddProjectActivity(ctx context.Context, project string, op model.EventAction) error {
+	return repo.store.write(func(data *embeddedData) error {
+		if _, ok := data.Projects[project]; !ok {
+			return ErrProjectNotFound
+		}
+		data.Activities = append(data.Activities, newActivity(project, op))
+		return nil
+	})
+}
+
+func (repo *EmbeddedRepositoryService) AddInstanceActivity(ctx context.Context, instance string, op model.EventAction) error {
+	return repo.store.write(func(data *embeddedData) error {
+		if _, ok := data.Instances[instance]; !ok {
+			return ErrInstanceNotFound
+		}
+		data.Activities = append(data.Activities, newActivity(instance, op))
+		return nil
+	})
+}
+
+// AddAuditRecord appends a record to the audit log. Records are never
+// modified or removed.
+func (repo *EmbeddedRepositoryService) AddAuditRecord(ctx context.Context, record *model.AuditRecord) error {
+	return repo.store.write(func(data *embeddedData) error {
+		var r model.AuditRecord
+		if err := copyObject(record, &r); err != nil {
+			return err
+		}
+		data.Audit = append(data.Audit, r)
+		return nil
+	})
+}
+
+// GetAuditRecords returns the records matching query, newest first.
+func (repo *EmbeddedRepositoryService) GetAuditRecords(ctx context.Context, query *model.AuditQuery) ([]model.AuditRecord, error) {
+	var result = make([]model.AuditRecord, 0)
+	err := repo.store.read(func(data *embeddedData) error {
+		for index := len(data.Audit) - 1; index >= 0; index-- {
+			if query.Limit > 0 && len(result) >= query.Limit {
+				break
+			}
+
+			r := &data.Audit[index]
+			if !matchAuditRecord(r, query) {
+				continue
+			}
+
+			var record model.AuditRecord
+			if err := copyObject(r, &record); err != nil {
+				return err
+			}
+			result = append(result, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func matchAuditRecord(record *model.AuditRecord, query *model.AuditQuery) bool {
+	if query.Actor != "" && record.Actor != query.Actor {
+		return false
+	}
+	if query.Target != "" && record.Target != query.Target && !strings.HasSuffix(record.Target, "/"+query.Target) {
+		return false
+	}
+	if query.Since != nil && record.Timestamp.Before(*query.Since) {
+		return false
+	}
+	if query.Until != nil && record.Timestamp.After(*query.Until) {
+		return false
+	}
+	return true
+}
+
+func newActivity(object string, op model.EventAction) activityRecord {
+	return activityRecord{
+		Object:   object,
+		Activity: model.Activity{Operation: string(op), Success: true, Completed: true, CreatedAt: now()},
+	}
+}
+
+func newResourceRequest(request *model.InstanceRequest) *model.ResourceRequest {
+	var rr = &model.ResourceRequest{Peers: request.Peers, Properties: request.Properties,
+		RateLimit: request.RateLimit, KeyRateLimit: request.KeyRateLimit, IPAllowList: request.IPAllowList, Config: request.Config, Mining: request.Mining}
+	rr.Volume.Type = request.Volume.Type
+	rr.Volume.Size = request.Volume.Size
+	rr.Volume.Source.Type = request.Volume.Source
+	rr.Volume.Source.Ref = request.Volume.Ref
+	return rr
+}
+
+// setResource records resource under the slot matching its type, replacing
+// any previous entry with the same name.
+func setResource(resources *model.KubernetesResources, resource *model.KubernetesResource) *model.KubernetesResources {
+	if resources == nil {
+		resources = &model.KubernetesResources{}
+	}
+
+	var r = *resource
+	r.UpdatedAt = time.Now()
+
+	switch r.Type {
+	case model.ResourceNamespace:
+		resources.Namespace = &r
+	case model.ResourceConfigMap:
+		resources.Configmap = &r
+	case model.ResourceSecret:
+		resources.Secret = &r
+	case model.ResourcePersistentVolumeClaim:
+		resources.Persistentvolumeclaim = &r
+	case model.ResourceDeployment:
+		resources.Deployment = &r
+	case model.ResourceService:
+		resources.Service = &r
+	case model.ResourceHTTPProxy:
+		resources.Httpproxy = &r
+	case model.ResourceSnapshotSchedule:
+		resources.Snapshotschedule = &r
+	case model.ResourceCertificate:
+		resources.Certificate = &r
+	case model.ResourceP2PService:
+		resources.P2p = &r
+	case model.ResourceVolumeSnapshot:
+		for index, snapshot := range resources.Volumesnapshot {
+			if snapshot.Name == r.Name {
+				resources.Volumesnapshot[index] = r
+				return resources
+			}
+		}
+		resources.Volumesnapshot = append(resources.Volumesnapshot, r)
+	}
+
+	return resources
+}
+
+// copyInstance copies the stored instance and populates its project.
+func copyInstance(data *embeddedData, instance *model.Instance, target *model.Instance) error {
+	if err := copyObject(instance, target); err != nil {
+		return err
+	}
+	if instance.Project != nil {
+		if project, ok := data.Projects[instance.Project.Id]; ok {
+			target.Project = &model.Project{}
+			return copyObject(project, target.Project)
+		}
+	}
+	return nil
+}
+
+func copyObject(source, target interface{}) error {
+	content, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, target)
+}
+
+func now() *time.Time {
+	var t = time.Now()
+	return &t
+}
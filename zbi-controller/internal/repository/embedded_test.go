@@ -0,0 +1,445 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	testConfigDir   = "../../../deploy/charts/zbi/zbi-conf"
+	testTemplateDir = "../../../deploy/charts/zbi/zbi-templates"
+)
+
+func TestEmbeddedRepositoryService_LoadConfig(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", testConfigDir, testTemplateDir)
+	assert.NoError(t, err)
+
+	policy, err := repo.GetPolicyInfo(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "csi-sc", policy.StorageClass)
+
+	info, err := repo.GetBlockchainInfo(ctx, "zcash")
+	assert.NoError(t, err)
+	assert.Contains(t, info.Templates, "app")
+	assert.Contains(t, info.Templates, "project")
+	assert.Contains(t, info.Templates, "zcash")
+	assert.Contains(t, info.Templates, "lwd")
+
+	node, err := repo.GetBlockchainNodeInfo(ctx, "zcash", "lwd")
+	assert.NoError(t, err)
+	assert.Equal(t, "lwd", node.Type)
+
+	_, err = repo.GetBlockchainInfo(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrBlockchainNotFound)
+}
+
+func TestEmbeddedRepositoryService_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "zbi.json")
+
+	repo, err := NewEmbeddedRepositoryService(path, testConfigDir, testTemplateDir)
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner", Blockchain: "zcash", Network: "testnet"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, project.Id)
+
+	_, err = repo.CreateProject(ctx, &model.Project{Name: "proj"})
+	assert.ErrorIs(t, err, ErrProjectExists)
+
+	request := &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH}
+	request.Volume.Source = model.DataSourceType("new")
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", request)
+	assert.NoError(t, err)
+	assert.Equal(t, project.Name, instance.Project.Name)
+
+	err = repo.UpdateInstanceResource(ctx, instance.Id, &model.KubernetesResource{Name: "node", Type: model.ResourceDeployment, Status: "running"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.AddInstanceActivity(ctx, instance.Id, model.EventActionCreate))
+
+	reopened, err := NewEmbeddedRepositoryService(path, testConfigDir, testTemplateDir)
+	assert.NoError(t, err)
+
+	projects, err := reopened.GetProjects(ctx, "owner")
+	assert.NoError(t, err)
+	assert.Len(t, projects, 1)
+
+	instances, err := reopened.GetInstances(ctx, project.Id)
+	assert.NoError(t, err)
+	assert.Len(t, instances, 1)
+	assert.Equal(t, "running", instances[0].Resources.Deployment.Status)
+	assert.Equal(t, model.DataSourceType("new"), instances[0].Request.Volume.Source.Type)
+}
+
+func TestEmbeddedStore_Migrate(t *testing.T) {
+	store, err := newEmbeddedStore("", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].version, store.data.Version)
+	assert.NotNil(t, store.data.Projects)
+	assert.NotNil(t, store.data.Instances)
+}
+
+func TestEmbeddedRepositoryService_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner"})
+	assert.NoError(t, err)
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+
+	_, err = repo.CreateAPIKey(ctx, &model.APIKey{Id: "key", InstanceId: "unknown"})
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+
+	key, err := repo.CreateAPIKey(ctx, &model.APIKey{Id: "key", InstanceId: instance.Id, Consumer: "wallet", Hash: "hash"})
+	assert.NoError(t, err)
+	assert.NotNil(t, key.CreatedAt)
+
+	_, err = repo.CreateAPIKey(ctx, &model.APIKey{Id: "key", InstanceId: instance.Id})
+	assert.ErrorIs(t, err, ErrAPIKeyExists)
+
+	keys, err := repo.GetAPIKeys(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	assert.NoError(t, repo.RevokeAPIKey(ctx, "key"))
+	key, err = repo.GetAPIKey(ctx, "key")
+	assert.NoError(t, err)
+	assert.NotNil(t, key.RevokedAt)
+	assert.Equal(t, "hash", key.Hash)
+
+	assert.ErrorIs(t, repo.RevokeAPIKey(ctx, "unknown"), ErrAPIKeyNotFound)
+}
+
+func TestEmbeddedRepositoryService_Domains(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner"})
+	assert.NoError(t, err)
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+
+	domain := &model.Domain{Host: "rpc.example.com", TLS: model.DomainTLSCertManager, SecretName: "domain-node-tls"}
+	assert.NoError(t, repo.UpdateInstanceDomain(ctx, instance.Id, domain))
+	assert.NoError(t, repo.UpdateInstanceDomain(ctx, instance.Id, domain))
+
+	err = repo.UpdateProjectDomain(ctx, project.Id, &model.Domain{Host: "RPC.example.com"})
+	assert.ErrorIs(t, err, ErrDomainExists)
+	assert.NoError(t, repo.UpdateProjectDomain(ctx, project.Id, &model.Domain{Host: "nodes.example.com"}))
+
+	err = repo.UpdateInstanceResource(ctx, instance.Id, &model.KubernetesResource{Name: "domain-node", Type: model.ResourceCertificate, Status: "ready"})
+	assert.NoError(t, err)
+
+	instance, err = repo.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "rpc.example.com", instance.Domain.Host)
+	assert.Equal(t, "ready", instance.Resources.Certificate.Status)
+	assert.Equal(t, "nodes.example.com", instance.Project.Domain.Host)
+
+	assert.NoError(t, repo.UpdateInstanceDomain(ctx, instance.Id, nil))
+	instance, err = repo.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Nil(t, instance.Domain)
+
+	assert.ErrorIs(t, repo.UpdateProjectDomain(ctx, "unknown", nil), ErrProjectNotFound)
+}
+
+func TestEmbeddedRepositoryService_P2PResource(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner"})
+	assert.NoError(t, err)
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH,
+		Properties: map[string]interface{}{"publicP2P": true}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.UpdateInstanceResource(ctx, instance.Id, &model.KubernetesResource{Name: "zcashd-svc-node", Type: model.ResourceService, Status: "active"}))
+	assert.NoError(t, repo.UpdateInstanceResource(ctx, instance.Id, &model.KubernetesResource{Name: "zcashd-p2p-node", Type: model.ResourceP2PService, Status: "active",
+		Properties: map[string]interface{}{"externalAddress": "203.0.113.7:8233"}}))
+
+	instance, err = repo.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "zcashd-svc-node", instance.Resources.Service.Name)
+	assert.Equal(t, "203.0.113.7:8233", instance.Resources.P2p.Properties["externalAddress"])
+	assert.Len(t, instance.GetResourceArray(), 2)
+}
+
+func TestEmbeddedRepositoryService_AllowList(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner"})
+	assert.NoError(t, err)
+	instance, err := repo.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH,
+		IPAllowList: []string{"203.0.113.7/32"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.7/32"}, instance.Request.IPAllowList)
+
+	assert.NoError(t, repo.UpdateInstanceAllowList(ctx, instance.Id, []string{"198.51.100.0/24"}))
+	instance, err = repo.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"198.51.100.0/24"}, instance.Request.IPAllowList)
+
+	assert.ErrorIs(t, repo.UpdateInstanceAllowList(ctx, "unknown", nil), ErrInstanceNotFound)
+}
+
+func TestEmbeddedRepositoryService_Audit(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for index, actor := range []string{"alice", "bob", "alice"} {
+		assert.NoError(t, repo.AddAuditRecord(ctx, &model.AuditRecord{Id: strconv.Itoa(index), Actor: actor, Target: "instance/" + strconv.Itoa(index),
+			Timestamp: start.Add(time.Duration(index) * time.Hour), Outcome: model.AuditOutcomeSuccess}))
+	}
+
+	records, err := repo.GetAuditRecords(ctx, &model.AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "2", records[0].Id)
+
+	records, err = repo.GetAuditRecords(ctx, &model.AuditQuery{Actor: "alice"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	records, err = repo.GetAuditRecords(ctx, &model.AuditQuery{Target: "1"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "bob", records[0].Actor)
+
+	since, until := start.Add(30*time.Minute), start.Add(90*time.Minute)
+	records, err = repo.GetAuditRecords(ctx, &model.AuditQuery{Since: &since, Until: &until})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "1", records[0].Id)
+
+	records, err = repo.GetAuditRecords(ctx, &model.AuditQuery{Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestEmbeddedRepositoryService_Teams(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	team, err := repo.CreateTeam(ctx, &model.Team{Name: "team1", Members: []model.TeamMember{{UserId: "alice", Role: model.TeamRoleAdmin}}})
+	assert.NoError(t, err)
+	_, err = repo.CreateTeam(ctx, &model.Team{Name: "team1"})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	assert.NoError(t, repo.UpdateTeamMember(ctx, team.Id, &model.TeamMember{UserId: "bob", Role: model.TeamRoleUser}))
+	assert.NoError(t, repo.UpdateTeamMember(ctx, team.Id, &model.TeamMember{UserId: "bob", Role: model.TeamRoleAdmin}))
+	assert.NoError(t, repo.UpdateTeamQuota(ctx, team.Id, &model.TeamQuota{MaxProjects: 1}))
+
+	team, err = repo.GetTeam(ctx, team.Id)
+	assert.NoError(t, err)
+	assert.Len(t, team.Members, 2)
+	assert.Equal(t, model.TeamRoleAdmin, team.GetMember("bob").Role)
+	assert.Equal(t, 1, team.Quota.MaxProjects)
+
+	teams, err := repo.GetTeams(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Len(t, teams, 1)
+	teams, err = repo.GetTeams(ctx, "carol")
+	assert.NoError(t, err)
+	assert.Len(t, teams, 0)
+
+	_, err = repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "alice", TeamId: "missing"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "alice", TeamId: team.Id})
+	assert.NoError(t, err)
+	other, err := repo.CreateProject(ctx, &model.Project{Name: "other", Owner: "alice"})
+	assert.NoError(t, err)
+
+	projects, err := repo.GetTeamProjects(ctx, team.Id)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 1)
+	assert.Equal(t, "proj", projects[0].Name)
+
+	_, err = repo.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+	_, err = repo.CreateInstance(ctx, other.Id, "alice", &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+
+	count, err := repo.CountTeamInstances(ctx, team.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	_, err = repo.CountTeamInstances(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, repo.RemoveTeamMember(ctx, team.Id, "bob"))
+	assert.ErrorIs(t, repo.RemoveTeamMember(ctx, team.Id, "bob"), ErrNotFound)
+}
+
+func TestEmbeddedRepositoryService_UpdateProject(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "alice", Description: "old",
+		Labels: map[string]string{"env": "dev"}})
+	assert.NoError(t, err)
+
+	description := "new"
+	project, err = repo.UpdateProject(ctx, project.Id, &model.ProjectUpdateRequest{Description: &description})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", project.Description)
+	assert.Equal(t, map[string]string{"env": "dev"}, project.Labels)
+
+	project, err = repo.UpdateProject(ctx, project.Id, &model.ProjectUpdateRequest{Labels: map[string]string{},
+		Rotation: &model.RotationPolicy{Interval: "30d"}})
+	assert.NoError(t, err)
+	assert.Empty(t, project.Labels)
+	assert.Equal(t, "30d", project.Rotation.Interval)
+
+	_, err = repo.UpdateProject(ctx, "missing", &model.ProjectUpdateRequest{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestEmbeddedRepositoryService_ListProjects(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	team, err := repo.CreateTeam(ctx, &model.Team{Name: "team1", Members: []model.TeamMember{{UserId: "bob", Role: model.TeamRoleUser}}})
+	assert.NoError(t, err)
+
+	for index, name := range []string{"delta", "alpha", "charlie", "bravo", "echo"} {
+		project := &model.Project{Name: name, Owner: "alice", Network: "testnet", Labels: map[string]string{"env": "dev"}}
+		if index%2 == 0 {
+			project.Network = "mainnet"
+			project.Labels = map[string]string{"env": "prod"}
+		}
+		if name == "echo" {
+			project.TeamId = team.Id
+		}
+		_, err = repo.CreateProject(ctx, project)
+		assert.NoError(t, err)
+	}
+
+	names := func(list *model.ProjectList) []string {
+		var result []string
+		for _, project := range list.Projects {
+			result = append(result, project.Name)
+		}
+		return result
+	}
+
+	list, err := repo.ListProjects(ctx, &model.ListQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bravo"}, names(list))
+	assert.Equal(t, 5, list.Total)
+
+	list, err = repo.ListProjects(ctx, &model.ListQuery{Limit: 2, Cursor: list.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"charlie", "delta"}, names(list))
+
+	list, err = repo.ListProjects(ctx, &model.ListQuery{Limit: 2, Cursor: list.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo"}, names(list))
+	assert.Empty(t, list.NextCursor)
+
+	list, err = repo.ListProjects(ctx, &model.ListQuery{Sort: "-name", Network: "mainnet"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo", "delta", "charlie"}, names(list))
+
+	list, err = repo.ListProjects(ctx, &model.ListQuery{LabelSelector: "env=dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bravo"}, names(list))
+
+	list, err = repo.ListProjects(ctx, &model.ListQuery{Member: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo"}, names(list))
+
+	future := time.Now().Add(time.Hour)
+	list, err = repo.ListProjects(ctx, &model.ListQuery{CreatedAfter: &future})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, list.Total)
+
+	_, err = repo.ListProjects(ctx, &model.ListQuery{Sort: "color"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = repo.ListProjects(ctx, &model.ListQuery{Cursor: "bad cursor"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = repo.ListProjects(ctx, &model.ListQuery{LabelSelector: "env in (dev"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestEmbeddedRepositoryService_ListInstances(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "alice", Network: "testnet",
+		Labels: map[string]string{"env": "dev"}})
+	assert.NoError(t, err)
+
+	_, err = repo.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "zcash-1", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+	_, err = repo.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "lwd-1", Type: model.InstanceTypeLWD})
+	assert.NoError(t, err)
+
+	list, err := repo.ListInstances(ctx, project.Id, &model.ListQuery{Type: string(model.InstanceTypeLWD)})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "lwd-1", list.Instances[0].Name)
+
+	list, err = repo.ListInstances(ctx, project.Id, &model.ListQuery{Sort: "-createdAt", LabelSelector: "env=dev"})
+	assert.NoError(t, err)
+	assert.Len(t, list.Instances, 2)
+
+	_, err = repo.ListInstances(ctx, "missing", &model.ListQuery{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestEmbeddedRepositoryService_Seeds(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	seed, err := repo.CreateSeed(ctx, &model.Seed{Name: "sapling", Blockchain: "zcash", Network: model.NetworkTypeTest, Type: model.SeedTypeArchive,
+		Archive: &model.SeedArchive{Url: "https://seeds.example.com/testnet.tar.gz"}, CreatedBy: "admin"})
+	assert.NoError(t, err)
+	assert.NotNil(t, seed.CreatedAt)
+
+	_, err = repo.CreateSeed(ctx, &model.Seed{Name: "sapling", Network: model.NetworkTypeTest})
+	assert.ErrorIs(t, err, ErrSeedExists)
+	other, err := repo.CreateSeed(ctx, &model.Seed{Name: "sapling", Network: model.NetworkTypeMain})
+	assert.NoError(t, err)
+
+	seeds, err := repo.GetSeeds(ctx, string(model.NetworkTypeTest))
+	assert.NoError(t, err)
+	assert.Len(t, seeds, 1)
+
+	updated, err := repo.UpdateSeed(ctx, seed.Id, &model.Seed{Name: "sapling", Blockchain: "zcash", Network: model.NetworkTypeTest, Type: model.SeedTypeArchive, Height: 2000000})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000000), updated.Height)
+	assert.Equal(t, "admin", updated.CreatedBy)
+
+	project, err := repo.CreateProject(ctx, &model.Project{Name: "proj", Owner: "owner"})
+	assert.NoError(t, err)
+	request := &model.InstanceRequest{Name: "node", Type: model.InstanceTypeZCASH}
+	request.Volume.Source = model.SeedDataSource
+	request.Volume.Ref = seed.Id
+	_, err = repo.CreateInstance(ctx, project.Id, "owner", request)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, repo.DeleteSeed(ctx, seed.Id), ErrSeedInUse)
+
+	assert.NoError(t, repo.DeleteSeed(ctx, other.Id))
+	_, err = repo.GetSeed(ctx, other.Id)
+	assert.ErrorIs(t, err, ErrSeedNotFound)
+}
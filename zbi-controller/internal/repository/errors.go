@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/zbitech/controller/pkg/errs"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalid      = errors.New("invalid request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUnavailable  = errors.New("repository unavailable")
+)
+
+var (
+	ErrCircuitOpen        = &RepositoryError{StatusCode: http.StatusServiceUnavailable, Message: "circuit breaker open", Err: ErrUnavailable}
+	ErrProjectNotFound    = newError(http.StatusNotFound, "project not found")
+	ErrInstanceNotFound   = newError(http.StatusNotFound, "instance not found")
+	ErrBlockchainNotFound = newError(http.StatusNotFound, "blockchain not found")
+	ErrPolicyNotFound     = newError(http.StatusNotFound, "policy not found")
+	ErrAPIKeyNotFound     = newError(http.StatusNotFound, "api key not found")
+	ErrTeamNotFound       = newError(http.StatusNotFound, "team not found")
+	ErrSeedNotFound       = newError(http.StatusNotFound, "seed not found")
+	ErrMemberNotFound     = newError(http.StatusNotFound, "team member not found")
+	ErrProjectExists      = newError(http.StatusConflict, "project already exists")
+	ErrInstanceExists     = newError(http.StatusConflict, "instance already exists")
+	ErrAPIKeyExists       = newError(http.StatusConflict, "api key already exists")
+	ErrDomainExists       = newError(http.StatusConflict, "domain already in use")
+	ErrTeamExists         = newError(http.StatusConflict, "team already exists")
+	ErrSeedExists         = newError(http.StatusConflict, "seed already exists")
+	ErrSeedInUse          = newError(http.StatusConflict, "seed is used by instances")
+	ErrInvalidCursor      = newError(http.StatusBadRequest, "invalid cursor")
+	ErrInvalidSort        = newError(http.StatusBadRequest, "invalid sort field")
+	ErrInvalidSelector    = newError(http.StatusBadRequest, "invalid label selector")
+)
+
+// RepositoryError is returned when the repository rejects a request. It
+// unwraps to one of the sentinel errors above so callers can use errors.Is.
+type RepositoryError struct {
+	StatusCode int
+	Message    string
+	Method     string
+	Path       string
+	Err        error
+}
+
+func newError(status int, message string) *RepositoryError {
+	return &RepositoryError{StatusCode: status, Message: message, Err: statusError(status)}
+}
+
+func (e *RepositoryError) Error() string {
+	if e.Method == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+func (e *RepositoryError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode maps the failure to the controller's error model. Errors that
+// originate in the repository service itself are reported as upstream errors.
+func (e *RepositoryError) ErrorCode() errs.ErrorCode {
+	switch {
+	case errors.Is(e.Err, ErrNotFound):
+		return errs.NotFoundError
+	case errors.Is(e.Err, ErrConflict):
+		return errs.ConflictError
+	case errors.Is(e.Err, ErrInvalid) && e.StatusCode >= 400:
+		return errs.ValidationError
+	}
+	return errs.RepositoryError
+}
+
+func (e *RepositoryError) ClientMessage() string {
+	if e.ErrorCode() == errs.RepositoryError {
+		return errs.RepositoryError.Message
+	}
+	return e.Message
+}
+
+func statusError(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status >= 500 || status == http.StatusTooManyRequests:
+		return ErrUnavailable
+	case status >= 400:
+		return ErrInvalid
+	}
+	return nil
+}
@@ -2,11 +2,23 @@ package repository
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+)
+
+const (
+	REPOSITORY_TYPE_REST     = "rest"
+	REPOSITORY_TYPE_EMBEDDED = "embedded"
 )
 
 type RepositoryFactory struct {
 	service interfaces.RepositoryServiceIF
+	outbox  interfaces.StatusOutboxIF
 }
 
 func NewRepositoryFactory() interfaces.RepositoryServiceFactoryIF {
@@ -14,9 +26,39 @@ func NewRepositoryFactory() interfaces.RepositoryServiceFactoryIF {
 }
 
 func (r *RepositoryFactory) Init(ctx context.Context) {
-	r.service = NewRepositoryService()
+	log := logger.GetLogger(ctx)
+
+	switch vars.ZBI_REPOSITORY_TYPE {
+	case REPOSITORY_TYPE_EMBEDDED:
+		log.Infof("creating embedded repository at %s", vars.ZBI_EMBEDDED_DB_PATH)
+		service, err := NewEmbeddedRepositoryService(vars.ZBI_EMBEDDED_DB_PATH, vars.ZBI_CONFIG_DIRECTORY, vars.ZBI_TEMPLATE_DIRECTORY)
+		if err != nil {
+			log.Fatalf("failed to create embedded repository: %s", err)
+		}
+		r.service = service
+	default:
+		r.service = NewRepositoryService()
+	}
+
+	path := vars.ZBI_OUTBOX_PATH
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			log.Warnf("unable to create outbox directory, status updates will not be persisted - %s", err)
+			path = ""
+		}
+	}
+
+	outbox, err := NewStatusOutbox(ctx, path, r.service, time.Duration(vars.ZBI_OUTBOX_INTERVAL)*time.Second)
+	if err != nil {
+		log.Fatalf("failed to create status outbox: %s", err)
+	}
+	r.outbox = outbox
 }
 
 func (r *RepositoryFactory) GetRepositoryService() interfaces.RepositoryServiceIF {
 	return r.service
 }
+
+func (r *RepositoryFactory) GetStatusOutbox() interfaces.StatusOutboxIF {
+	return r.outbox
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zbitech/controller/pkg/model"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const DEFAULT_SORT_FIELD = "name"
+
+// listValues are the fields of a project or instance that listings can be
+// filtered and sorted by.
+type listValues struct {
+	Name      string
+	Owner     string
+	Status    string
+	State     string
+	Type      string
+	Network   string
+	Labels    map[string]string
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+// listEntry is a matching object with its sort key.
+type listEntry struct {
+	id     string
+	key    string
+	object interface{}
+}
+
+// listCursor marks the last entry of a page. It is returned to clients as an
+// opaque base64 string.
+type listCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	Id   string `json:"i"`
+}
+
+func projectValues(project *model.Project) listValues {
+	return listValues{Name: project.Name, Owner: project.Owner, Status: project.Status, State: project.State,
+		Network: project.Network, Labels: project.Labels, CreatedAt: project.CreatedAt, UpdatedAt: project.UpdatedAt}
+}
+
+// instanceValues returns the list fields of an instance. Instances are
+// matched against the labels of their project, and take the owner and
+// network of the project when the repository does not record them.
+func instanceValues(instance *model.Instance, project *model.Project) listValues {
+	var values = listValues{Name: instance.Name, Owner: instance.Owner, Status: instance.Status, State: instance.State,
+		Type: string(instance.InstanceType), Network: string(instance.Network),
+		CreatedAt: instance.CreatedAt, UpdatedAt: instance.UpdatedAt}
+	if values.Type == "" {
+		values.Type = string(instance.Type)
+	}
+	if project != nil {
+		values.Labels = project.Labels
+		if values.Owner == "" {
+			values.Owner = project.Owner
+		}
+		if values.Network == "" {
+			values.Network = project.Network
+		}
+	}
+	return values
+}
+
+// projectEntries returns the entries of the projects matching a query. teams
+// holds the teams of the query member, whose projects are included.
+func projectEntries(projects []*model.Project, teams map[string]bool, query *model.ListQuery) ([]listEntry, error) {
+	selector, err := parseSelector(query.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []listEntry
+	for _, p := range projects {
+		if query.Member != "" && p.Owner != query.Member && !teams[p.TeamId] {
+			continue
+		}
+		values := projectValues(p)
+		if !values.match(query, selector) {
+			continue
+		}
+		entry, err := newListEntry(p.Id, values, query, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// instanceEntries returns the entries of the instances of a project matching
+// a query.
+func instanceEntries(instances []*model.Instance, project *model.Project, query *model.ListQuery) ([]listEntry, error) {
+	selector, err := parseSelector(query.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []listEntry
+	for _, i := range instances {
+		values := instanceValues(i, project)
+		if !values.match(query, selector) {
+			continue
+		}
+		entry, err := newListEntry(i.Id, values, query, i)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, ErrInvalidSelector
+	}
+	return parsed, nil
+}
+
+func (v listValues) match(query *model.ListQuery, selector labels.Selector) bool {
+	switch {
+	case query.Owner != "" && v.Owner != query.Owner:
+		return false
+	case query.Status != "" && v.Status != query.Status:
+		return false
+	case query.State != "" && v.State != query.State:
+		return false
+	case query.Type != "" && v.Type != query.Type:
+		return false
+	case query.Network != "" && v.Network != query.Network:
+		return false
+	case query.CreatedBefore != nil && (v.CreatedAt == nil || !v.CreatedAt.Before(*query.CreatedBefore)):
+		return false
+	case query.CreatedAfter != nil && (v.CreatedAt == nil || !v.CreatedAt.After(*query.CreatedAfter)):
+		return false
+	}
+	return selector.Matches(labels.Set(v.Labels))
+}
+
+func (v listValues) sortKey(field string) (string, bool) {
+	switch field {
+	case "name":
+		return v.Name, true
+	case "owner":
+		return v.Owner, true
+	case "status":
+		return v.Status, true
+	case "state":
+		return v.State, true
+	case "type":
+		return v.Type, true
+	case "network":
+		return v.Network, true
+	case "createdAt":
+		return sortTime(v.CreatedAt), true
+	case "updatedAt":
+		return sortTime(v.UpdatedAt), true
+	}
+	return "", false
+}
+
+// sortTime formats a time so that keys sort in time order.
+func sortTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+func parseSort(value string) (string, bool) {
+	if value == "" {
+		return DEFAULT_SORT_FIELD, false
+	}
+	if strings.HasPrefix(value, "-") {
+		return value[1:], true
+	}
+	return value, false
+}
+
+// newListEntry returns the entry of an object for the sort field of a query.
+func newListEntry(id string, values listValues, query *model.ListQuery, object interface{}) (listEntry, error) {
+	field, _ := parseSort(query.Sort)
+	key, ok := values.sortKey(field)
+	if !ok {
+		return listEntry{}, ErrInvalidSort
+	}
+	return listEntry{id: id, key: key, object: object}, nil
+}
+
+// pageEntries sorts the entries and returns the page after the query cursor,
+// the total number of entries and the cursor of the next page.
+func pageEntries(entries []listEntry, query *model.ListQuery) ([]listEntry, int, string, error) {
+	_, desc := parseSort(query.Sort)
+	less := func(a, b listEntry) bool {
+		if a.key != b.key {
+			return (a.key < b.key) != desc
+		}
+		return (a.id < b.id) != desc
+	}
+	sort.Slice(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+
+	var page = entries
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil || cursor.Sort != query.Sort {
+			return nil, 0, "", ErrInvalidCursor
+		}
+		last := listEntry{id: cursor.Id, key: cursor.Key}
+		index := sort.Search(len(entries), func(i int) bool { return less(last, entries[i]) })
+		page = entries[index:]
+	}
+
+	var next string
+	if query.Limit > 0 && len(page) > query.Limit {
+		page = page[:query.Limit]
+		last := page[len(page)-1]
+		next = encodeCursor(listCursor{Sort: query.Sort, Key: last.key, Id: last.id})
+	}
+
+	return page, len(entries), next, nil
+}
+
+func encodeCursor(cursor listCursor) string {
+	content, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func decodeCursor(value string) (*listCursor, error) {
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err = json.Unmarshal(content, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// listQueryValues encodes a query as the parameters of the repository service
+// list routes. The selector and sort field are checked first so that invalid
+// queries fail as they do with the embedded repository.
+func listQueryValues(query *model.ListQuery) (url.Values, error) {
+	if _, err := parseSelector(query.LabelSelector); err != nil {
+		return nil, err
+	}
+	if field, _ := parseSort(query.Sort); !validSortField(field) {
+		return nil, ErrInvalidSort
+	}
+
+	var values = url.Values{}
+	var set = func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("member", query.Member)
+	set("owner", query.Owner)
+	set("status", query.Status)
+	set("state", query.State)
+	set("type", query.Type)
+	set("network", query.Network)
+	set("labelSelector", query.LabelSelector)
+	set("sort", query.Sort)
+	set("cursor", query.Cursor)
+	if query.CreatedBefore != nil {
+		values.Set("createdBefore", query.CreatedBefore.UTC().Format(time.RFC3339Nano))
+	}
+	if query.CreatedAfter != nil {
+		values.Set("createdAfter", query.CreatedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	return values, nil
+}
+
+func validSortField(field string) bool {
+	_, ok := listValues{}.sortKey(field)
+	return ok
+}
+
+// listError maps the list errors of the repository service to the errors of
+// the embedded repository.
+func listError(err error) error {
+	var rerr *RepositoryError
+	if errors.As(err, &rerr) && rerr.StatusCode == ErrInvalidCursor.StatusCode {
+		switch rerr.Message {
+		case ErrInvalidCursor.Message:
+			return ErrInvalidCursor
+		case ErrInvalidSort.Message:
+			return ErrInvalidSort
+		case ErrInvalidSelector.Message:
+			return ErrInvalidSelector
+		}
+	}
+	return err
+}
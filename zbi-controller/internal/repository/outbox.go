@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	OUTBOX_LEVEL_PROJECT  = "project"
+	OUTBOX_LEVEL_INSTANCE = "instance"
+)
+
+type outboxEntry struct {
+	Seq       uint64                   `json:"seq"`
+	Level     string                   `json:"level"`
+	Id        string                   `json:"id"`
+	Resource  model.KubernetesResource `json:"resource"`
+	Attempts  int                      `json:"attempts"`
+	CreatedAt time.Time                `json:"createdAt"`
+}
+
+func (e *outboxEntry) key() string {
+	return e.Level + "/" + e.Id + "/" + string(e.Resource.Type) + "/" + e.Resource.Name
+}
+
+type outboxData struct {
+	Seq     uint64        `json:"seq"`
+	Entries []outboxEntry `json:"entries"`
+}
+
+// StatusOutbox records resource status updates from the monitor and delivers
+// them to the repository in order. Pending updates are written to disk so
+// they survive a repository outage and a controller restart. A newer update
+// for the same resource replaces one that has not been delivered yet.
+type StatusOutbox struct {
+	mu       sync.Mutex
+	path     string
+	data     outboxData
+	repo     interfaces.RepositoryServiceIF
+	interval time.Duration
+	notify   chan struct{}
+	stopper  chan struct{}
+	once     sync.Once
+}
+
+func NewStatusOutbox(ctx context.Context, path string, repo interfaces.RepositoryServiceIF, interval time.Duration) (*StatusOutbox, error) {
+	o := &StatusOutbox{
+		path:     path,
+		repo:     repo,
+		interval: interval,
+		notify:   make(chan struct{}, 1),
+		stopper:  make(chan struct{}),
+	}
+
+	if path != "" {
+		if err := readJSONFile(path, &o.data); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(o.data.Entries) > 0 {
+			logger.GetLogger(ctx).Infof("loaded %d pending status updates from %s", len(o.data.Entries), path)
+		}
+	}
+
+	return o, nil
+}
+
+func (o *StatusOutbox) AddProjectResource(ctx context.Context, project string, resource *model.KubernetesResource) error {
+	return o.add(OUTBOX_LEVEL_PROJECT, project, resource)
+}
+
+func (o *StatusOutbox) AddInstanceResource(ctx context.Context, instance string, resource *model.KubernetesResource) error {
+	return o.add(OUTBOX_LEVEL_INSTANCE, instance, resource)
+}
+
+func (o *StatusOutbox) add(level, id string, resource *model.KubernetesResource) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.data.Seq++
+	entry := outboxEntry{Seq: o.data.Seq, Level: level, Id: id, Resource: *resource, CreatedAt: time.Now()}
+
+	replaced := false
+	for index := range o.data.Entries {
+		if o.data.Entries[index].key() == entry.key() {
+			o.data.Entries[index] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		o.data.Entries = append(o.data.Entries, entry)
+	}
+
+	if err := o.persist(); err != nil {
+		return err
+	}
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (o *StatusOutbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.data.Entries)
+}
+
+// Start delivers pending updates until Stop is called or ctx is done.
+func (o *StatusOutbox) Start(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		o.Flush(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.stopper:
+			return
+		case <-ticker.C:
+		case <-o.notify:
+		}
+	}
+}
+
+func (o *StatusOutbox) Stop() {
+	o.once.Do(func() { close(o.stopper) })
+}
+
+// Flush attempts delivery of pending updates in order. Delivery stops at the
+// first update that fails because the repository is unavailable; updates the
+// repository rejects outright are dropped.
+func (o *StatusOutbox) Flush(ctx context.Context) {
+	log := logger.GetServiceLogger(ctx, "outbox.Flush")
+
+	o.mu.Lock()
+	pending := make([]outboxEntry, len(o.data.Entries))
+	copy(pending, o.data.Entries)
+	o.mu.Unlock()
+
+	for _, entry := range pending {
+		var err error
+		if entry.Level == OUTBOX_LEVEL_INSTANCE {
+			err = o.repo.UpdateInstanceResource(ctx, entry.Id, &entry.Resource)
+		} else {
+			err = o.repo.UpdateProjectResource(ctx, entry.Id, &entry.Resource)
+		}
+
+		fields := logrus.Fields{"level": entry.Level, "id": entry.Id, "resource": entry.Resource.Name, "type": entry.Resource.Type}
+		if err != nil && (errors.Is(err, ErrUnavailable) || ctx.Err() != nil) {
+			log.WithFields(fields).Warnf("unable to deliver status update, will retry - %s", err)
+			o.attempted(entry.Seq)
+			return
+		}
+
+		if err != nil {
+			log.WithFields(fields).Errorf("status update rejected by repository, dropping - %s", err)
+		}
+		o.remove(entry.Seq)
+	}
+}
+
+func (o *StatusOutbox) attempted(seq uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for index := range o.data.Entries {
+		if o.data.Entries[index].Seq == seq {
+			o.data.Entries[index].Attempts++
+			_ = o.persist()
+			return
+		}
+	}
+}
+
+// remove deletes a delivered entry unless it was replaced by a newer update
+// while it was in flight.
+func (o *StatusOutbox) remove(seq uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for index := range o.data.Entries {
+		if o.data.Entries[index].Seq == seq {
+			o.data.Entries = append(o.data.Entries[:index], o.data.Entries[index+1:]...)
+			_ = o.persist()
+			return
+		}
+	}
+}
+
+// persist writes pending entries to disk. The caller must hold the lock.
+func (o *StatusOutbox) persist() error {
+	if o.path == "" {
+		return nil
+	}
+	return writeJSONFile(o.path, &o.data)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+type outboxTestRepository struct {
+	interfaces.RepositoryServiceIF
+	err       error
+	delivered []model.KubernetesResource
+}
+
+func (r *outboxTestRepository) UpdateInstanceResource(ctx context.Context, instance string, resource *model.KubernetesResource) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.delivered = append(r.delivered, *resource)
+	return nil
+}
+
+func (r *outboxTestRepository) UpdateProjectResource(ctx context.Context, project string, resource *model.KubernetesResource) error {
+	return r.UpdateInstanceResource(ctx, project, resource)
+}
+
+func TestStatusOutbox_Durable(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	repo := &outboxTestRepository{err: ErrCircuitOpen}
+
+	outbox, err := NewStatusOutbox(ctx, path, repo, time.Second)
+	assert.NoError(t, err)
+
+	assert.NoError(t, outbox.AddInstanceResource(ctx, "i1", &model.KubernetesResource{Name: "node", Type: model.ResourceDeployment, Status: "pending"}))
+	assert.NoError(t, outbox.AddProjectResource(ctx, "p1", &model.KubernetesResource{Name: "ns", Type: model.ResourceNamespace, Status: "active"}))
+	assert.NoError(t, outbox.AddInstanceResource(ctx, "i1", &model.KubernetesResource{Name: "node", Type: model.ResourceDeployment, Status: "running"}))
+	assert.Equal(t, 2, outbox.Len())
+
+	outbox.Flush(ctx)
+	assert.Equal(t, 2, outbox.Len())
+	assert.Empty(t, repo.delivered)
+
+	repo.err = nil
+	reloaded, err := NewStatusOutbox(ctx, path, repo, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reloaded.Len())
+
+	reloaded.Flush(ctx)
+	assert.Equal(t, 0, reloaded.Len())
+	assert.Len(t, repo.delivered, 2)
+	assert.Equal(t, "running", repo.delivered[0].Status)
+}
+
+func TestStatusOutbox_DropRejected(t *testing.T) {
+	ctx := context.Background()
+	repo := &outboxTestRepository{err: ErrInstanceNotFound}
+
+	outbox, err := NewStatusOutbox(ctx, "", repo, time.Second)
+	assert.NoError(t, err)
+
+	assert.NoError(t, outbox.AddInstanceResource(ctx, "i1", &model.KubernetesResource{Name: "node", Type: model.ResourceDeployment}))
+	outbox.Flush(ctx)
+	assert.Equal(t, 0, outbox.Len())
+}
@@ -1,516 +1,423 @@
 package repository
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
-	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
-	"github.com/sirupsen/logrus"
 	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/interfaces"
-	"github.com/zbitech/controller/pkg/logger"
 	"github.com/zbitech/controller/pkg/model"
 )
 
-var client = &http.Client{}
-
 type RepositoryService struct {
+	client *restClient
 }
 
 func NewRepositoryService() interfaces.RepositoryServiceIF {
-	return &RepositoryService{}
+	return &RepositoryService{
+		client: newRestClient(restClientConfig{
+			BaseURL:          vars.ZBI_REPOSITORY_URL,
+			Secret:           vars.ZBI_INTERNAL_CLIENT_SECRET,
+			Timeout:          time.Duration(vars.ZBI_REPOSITORY_TIMEOUT) * time.Second,
+			Retries:          vars.ZBI_REPOSITORY_RETRIES,
+			MinBackoff:       200 * time.Millisecond,
+			MaxBackoff:       5 * time.Second,
+			BreakerThreshold: vars.ZBI_REPOSITORY_BREAKER_THRESHOLD,
+			BreakerTimeout:   time.Duration(vars.ZBI_REPOSITORY_BREAKER_TIMEOUT) * time.Second,
+		}),
+	}
 }
 
 func (repo *RepositoryService) UpdateProjectResource(ctx context.Context, project string, resource *model.KubernetesResource) error {
-
-	log := logger.GetServiceLogger(ctx, "repo.UpdateProjectResource")
-
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/" + project + "/resources"
-	jsonReq, _ := json.Marshal(resource)
-	req, err := http.NewRequest(http.MethodPut, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		return err
-	}
-
-	log.WithFields(logrus.Fields{"repo": repository}).Infof("updating project resource")
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		return nil
-	} else {
-
-		return errors.New("failed to update project resource")
-	}
+	return repo.client.do(ctx, http.MethodPut, "/projects/"+project+"/resources", nil, resource, nil)
 }
 
 func (repo *RepositoryService) UpdateInstanceResource(ctx context.Context, instance string, resource *model.KubernetesResource) error {
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instance+"/resources", nil, resource, nil)
+}
 
-	log := logger.GetServiceLogger(ctx, "repo.UpdateInstanceResource")
-
-	//	var repository = helper.Config.GetSettings().Repository + "/projects/" + project + "/instances/" + instance + "/resources"
-	var repository = vars.ZBI_REPOSITORY_URL + "/instances/" + instance + "/resources"
-
-	jsonReq, _ := json.Marshal(resource)
-	req, err := http.NewRequest(http.MethodPut, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		log.Errorf("failed to update instance resource: %s", err)
-		return err
+func (repo *RepositoryService) GetBlockchainInfo(ctx context.Context, blockchain string) (*model.BlockchainInfo, error) {
+	var result model.BlockchainInfo
+	if err := repo.client.do(ctx, http.MethodGet, "/config/blockchains/"+blockchain, nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
-	log.WithFields(logrus.Fields{"repo": repository}).Infof("updating instance resource")
-
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+func (repo *RepositoryService) GetBlockchainNodeInfo(ctx context.Context, blockchain, node string) (*model.BlockchainNodeInfo, error) {
+	var result model.BlockchainNodeInfo
+	if err := repo.client.do(ctx, http.MethodGet, "/config/blockchains/"+blockchain+"/"+node, nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusOK {
-		return nil
-	} else {
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf("failed to update instance resource")
-		return errors.New("failed to update instance resource")
+func (repo *RepositoryService) GetPolicyInfo(ctx context.Context) (*model.PolicyInfo, error) {
+	var result model.PolicyInfo
+	if err := repo.client.do(ctx, http.MethodGet, "/config/policy", nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
 }
 
-func (repo *RepositoryService) GetBlockchainInfo(ctx context.Context, blockchain string) (*model.BlockchainInfo, error) {
-
-	log := logger.GetServiceLogger(ctx, "repo.GetBlockchainInfo")
-	var repository = vars.ZBI_REPOSITORY_URL + "/config/blockchains/" + blockchain
-
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get blockchain info: %s", err)
+func (repo *RepositoryService) CreateProject(ctx context.Context, project *model.Project) (*model.Project, error) {
+	var result model.Project
+	if err := repo.client.do(ctx, http.MethodPost, "/projects/", nil, project, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) GetProject(ctx context.Context, project string) (*model.Project, error) {
+	var result model.Project
+	if err := repo.client.do(ctx, http.MethodGet, "/projects/"+project, nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.BlockchainInfo
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve blockchain info")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get blockchain info"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+func (repo *RepositoryService) UpdateProject(ctx context.Context, projectId string, request *model.ProjectUpdateRequest) (*model.Project, error) {
+	var result model.Project
+	if err := repo.client.do(ctx, http.MethodPut, "/projects/"+projectId, nil, request, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
 }
 
-func (repo *RepositoryService) GetBlockchainNodeInfo(ctx context.Context, blockchain, node string) (*model.BlockchainNodeInfo, error) {
-	log := logger.GetServiceLogger(ctx, "repo.GetBlockchainInfo")
-	var repository = vars.ZBI_REPOSITORY_URL + "/config/blockchains/" + blockchain + "/" + node
+func (repo *RepositoryService) GetProjects(ctx context.Context, owner string) ([]model.Project, error) {
+	var headers map[string]string
+	if owner != "" {
+		headers = map[string]string{"x-owner-id": owner}
+	}
 
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get blockchain info: %s", err)
+	var result []model.Project
+	if err := repo.client.do(ctx, http.MethodGet, "/projects", headers, nil, &result); err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
+// ListProjects returns a page of the projects matching a query. The query is
+// applied by the repository service.
+func (repo *RepositoryService) ListProjects(ctx context.Context, query *model.ListQuery) (*model.ProjectList, error) {
+	values, err := listQueryValues(query)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.BlockchainNodeInfo
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve blockchain info")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get blockchain info"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+	var result model.ProjectList
+	if err = repo.client.do(ctx, http.MethodGet, "/projects/list?"+values.Encode(), nil, nil, &result); err != nil {
+		return nil, listError(err)
+	}
+	if result.Projects == nil {
+		result.Projects = make([]model.Project, 0)
 	}
+	return &result, nil
 }
 
-func (repo *RepositoryService) GetPolicyInfo(ctx context.Context) (*model.PolicyInfo, error) {
-
-	log := logger.GetServiceLogger(ctx, "repo.GetPolicyInfo")
-	var repository = vars.ZBI_REPOSITORY_URL + "/config/policy"
-
-	log.Debugf("connecting to %s", repository)
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get policy info: %s", err)
+func (repo *RepositoryService) GetTeamProjects(ctx context.Context, teamId string) ([]model.Project, error) {
+	var result []model.Project
+	if err := repo.client.do(ctx, http.MethodGet, "/teams/"+teamId+"/projects", nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// CountTeamInstances returns the number of instances of the projects of a
+// team.
+func (repo *RepositoryService) CountTeamInstances(ctx context.Context, teamId string) (int, error) {
+	var result struct {
+		Count int `json:"count"`
 	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.PolicyInfo
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			log.Errorf("failed to read response - %s", err)
-			return nil, errors.New("unable to retrieve policy info")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get policy info"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+	if err := repo.client.do(ctx, http.MethodGet, "/teams/"+teamId+"/instances/count", nil, nil, &result); err != nil {
+		return 0, err
 	}
+	return result.Count, nil
 }
 
-func (repo *RepositoryService) CreateProject(ctx context.Context, project *model.Project) (*model.Project, error) {
-
-	log := logger.GetServiceLogger(ctx, "repo.GetProject")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/"
-
-	jsonReq, _ := json.Marshal(project)
-	req, err := http.NewRequest(http.MethodPost, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
+func (repo *RepositoryService) CreateTeam(ctx context.Context, team *model.Team) (*model.Team, error) {
+	var result model.Team
+	if err := repo.client.do(ctx, http.MethodPost, "/teams", nil, team, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) GetTeam(ctx context.Context, teamId string) (*model.Team, error) {
+	var result model.Team
+	if err := repo.client.do(ctx, http.MethodGet, "/teams/"+teamId, nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.Project
+// GetTeams returns the teams a user is a member of, or every team when member
+// is empty.
+func (repo *RepositoryService) GetTeams(ctx context.Context, member string) ([]model.Team, error) {
+	var path = "/teams"
+	if member != "" {
+		path += "?member=" + url.QueryEscape(member)
+	}
 
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve project")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get project"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+	var result []model.Team
+	if err := repo.client.do(ctx, http.MethodGet, path, nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
+func (repo *RepositoryService) UpdateTeamMember(ctx context.Context, teamId string, member *model.TeamMember) error {
+	return repo.client.do(ctx, http.MethodPut, "/teams/"+teamId+"/members/"+url.PathEscape(member.UserId), nil, member, nil)
 }
 
-func (repo *RepositoryService) GetProject(ctx context.Context, project string) (*model.Project, error) {
+func (repo *RepositoryService) RemoveTeamMember(ctx context.Context, teamId, userid string) error {
+	return repo.client.do(ctx, http.MethodDelete, "/teams/"+teamId+"/members/"+url.PathEscape(userid), nil, nil, nil)
+}
 
-	log := logger.GetServiceLogger(ctx, "repo.GetProject")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/" + project
+func (repo *RepositoryService) UpdateTeamQuota(ctx context.Context, teamId string, quota *model.TeamQuota) error {
+	return repo.client.do(ctx, http.MethodPut, "/teams/"+teamId+"/quota", nil, quota, nil)
+}
 
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get project: %s", err)
+func (repo *RepositoryService) CreateSeed(ctx context.Context, seed *model.Seed) (*model.Seed, error) {
+	var result model.Seed
+	if err := repo.client.do(ctx, http.MethodPost, "/seeds", nil, seed, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) GetSeed(ctx context.Context, seedId string) (*model.Seed, error) {
+	var result model.Seed
+	if err := repo.client.do(ctx, http.MethodGet, "/seeds/"+url.PathEscape(seedId), nil, nil, &result); err != nil {
 		return nil, err
 	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.Project
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve project")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get project"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
-	}
+	return &result, nil
 }
 
-func (repo *RepositoryService) GetProjects(ctx context.Context, owner string) ([]model.Project, error) {
-
-	log := logger.GetServiceLogger(ctx, "repo.GetProject")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects"
+// GetSeeds returns the seeds of a network, or every seed when network is
+// empty.
+func (repo *RepositoryService) GetSeeds(ctx context.Context, network string) ([]model.Seed, error) {
+	var path = "/seeds"
+	if network != "" {
+		path += "?network=" + url.QueryEscape(network)
+	}
 
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get project: %s", err)
+	var result []model.Seed
+	if err := repo.client.do(ctx, http.MethodGet, path, nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) UpdateSeed(ctx context.Context, seedId string, seed *model.Seed) (*model.Seed, error) {
+	var result model.Seed
+	if err := repo.client.do(ctx, http.MethodPut, "/seeds/"+url.PathEscape(seedId), nil, seed, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result []model.Project
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve project")
-		}
-		return result, nil
-	} else {
-		message := "failed to get project"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
-	}
+func (repo *RepositoryService) DeleteSeed(ctx context.Context, seedId string) error {
+	return repo.client.do(ctx, http.MethodDelete, "/seeds/"+url.PathEscape(seedId), nil, nil, nil)
 }
 
 func (repo *RepositoryService) GetInstance(ctx context.Context, instance string) (*model.Instance, error) {
+	var result model.Instance
+	if err := repo.client.do(ctx, http.MethodGet, "/instances/"+instance, nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
 
-	log := logger.GetServiceLogger(ctx, "repo.GetInstance")
-	var repository = vars.ZBI_REPOSITORY_URL + "/instances/" + instance
-
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get instance: %s", err)
+func (repo *RepositoryService) GetInstances(ctx context.Context, project string) ([]model.Instance, error) {
+	var result []model.Instance
+	if err := repo.client.do(ctx, http.MethodGet, "/projects/"+project+"/instances", nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
+// ListInstances returns a page of the instances of a project matching a
+// query, as for ListProjects.
+func (repo *RepositoryService) ListInstances(ctx context.Context, projectId string, query *model.ListQuery) (*model.InstanceList, error) {
+	values, err := listQueryValues(query)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.Instance
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve instance")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get instance"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+	var result model.InstanceList
+	if err = repo.client.do(ctx, http.MethodGet, "/projects/"+projectId+"/instances/list?"+values.Encode(), nil, nil, &result); err != nil {
+		return nil, listError(err)
+	}
+	if result.Instances == nil {
+		result.Instances = make([]model.Instance, 0)
 	}
+	return &result, nil
 }
 
-func (repo *RepositoryService) GetInstances(ctx context.Context, project string) ([]model.Instance, error) {
-
-	log := logger.GetServiceLogger(ctx, "repo.GetInstance")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/" + project + "/instances"
-
-	req, err := http.NewRequest(http.MethodGet, repository, nil)
-	if err != nil {
-		log.Errorf("failed to get instance: %s", err)
+func (repo *RepositoryService) CreateInstance(ctx context.Context, projectId, owner string, request *model.InstanceRequest) (*model.Instance, error) {
+	var result model.Instance
+	headers := map[string]string{"x-owner-id": owner}
+	if err := repo.client.do(ctx, http.MethodPost, "/projects/"+projectId+"/instances", headers, request, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) UpdateInstance(ctx context.Context, instanceId string, request *model.InstanceRequest) (*model.Instance, error) {
+	var result model.Instance
+	if err := repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId, nil, request, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
+func (repo *RepositoryService) UpdateInstanceRotation(ctx context.Context, instanceId string, rotation *model.CredentialsRotation) error {
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/rotation", nil, rotation, nil)
+}
 
-	if resp.StatusCode == http.StatusOK {
-		var result []model.Instance
+// UpdateProjectDomain sets the custom domain of a project, or removes it when
+// domain is nil.
+func (repo *RepositoryService) UpdateProjectDomain(ctx context.Context, projectId string, domain *model.Domain) error {
+	if domain == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/projects/"+projectId+"/domain", nil, nil, nil)
+	}
+	return repo.client.do(ctx, http.MethodPut, "/projects/"+projectId+"/domain", nil, domain, nil)
+}
 
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve instance")
-		}
-		return result, nil
-	} else {
-		message := "failed to get instance"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+// UpdateInstanceDomain sets the custom domain of an instance, or removes it
+// when domain is nil.
+func (repo *RepositoryService) UpdateInstanceDomain(ctx context.Context, instanceId string, domain *model.Domain) error {
+	if domain == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/instances/"+instanceId+"/domain", nil, nil, nil)
 	}
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/domain", nil, domain, nil)
 }
 
-func (repo *RepositoryService) CreateInstance(ctx context.Context, projectId, owner string, request *model.InstanceRequest) (*model.Instance, error) {
+// UpdateInstanceAllowList replaces the IP allow-list of an instance.
+func (repo *RepositoryService) UpdateInstanceAllowList(ctx context.Context, instanceId string, allowList []string) error {
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/allowlist", nil,
+		model.IPAllowListRequest{IPAllowList: allowList}, nil)
+}
 
-	log := logger.GetServiceLogger(ctx, "repo.GetProject")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/" + projectId + "/instances"
+// UpdateProjectSchedule sets the power schedule of a project, or removes it
+// when schedule is nil.
+func (repo *RepositoryService) UpdateProjectSchedule(ctx context.Context, projectId string, schedule *model.PowerSchedule) error {
+	if schedule == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/projects/"+projectId+"/schedule", nil, nil, nil)
+	}
+	return repo.client.do(ctx, http.MethodPut, "/projects/"+projectId+"/schedule", nil, schedule, nil)
+}
 
-	jsonReq, _ := json.Marshal(request)
-	req, err := http.NewRequest(http.MethodPost, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		return nil, err
+// UpdateInstanceSchedule sets the power schedule of an instance, or removes
+// it when schedule is nil.
+func (repo *RepositoryService) UpdateInstanceSchedule(ctx context.Context, instanceId string, schedule *model.PowerSchedule) error {
+	if schedule == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/instances/"+instanceId+"/schedule", nil, nil, nil)
 	}
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/schedule", nil, schedule, nil)
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	req.Header.Add("x-owner-id", owner)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// UpdateInstanceHold places a schedule hold on an instance, or releases it
+// when hold is nil.
+func (repo *RepositoryService) UpdateInstanceHold(ctx context.Context, instanceId string, hold *model.ScheduleHold) error {
+	if hold == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/instances/"+instanceId+"/hold", nil, nil, nil)
 	}
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/hold", nil, hold, nil)
+}
 
-	defer resp.Body.Close()
+func (repo *RepositoryService) UpdateInstancePower(ctx context.Context, instanceId string, state *model.PowerState) error {
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/power", nil, state, nil)
+}
 
-	if resp.StatusCode == http.StatusOK {
-		var result model.Instance
+// UpdateInstanceImage pins an instance to an image version.
+func (repo *RepositoryService) UpdateInstanceImage(ctx context.Context, instanceId string, image *model.ImagePin) error {
+	return repo.client.do(ctx, http.MethodPut, "/instances/"+instanceId+"/image", nil, image, nil)
+}
 
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to create instance")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get instance"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+// UpdateProjectUpgrade records the state of the rolling upgrade of a
+// project, or removes it when upgrade is nil.
+func (repo *RepositoryService) UpdateProjectUpgrade(ctx context.Context, projectId string, upgrade *model.ProjectUpgrade) error {
+	if upgrade == nil {
+		return repo.client.do(ctx, http.MethodDelete, "/projects/"+projectId+"/upgrade", nil, nil, nil)
 	}
+	return repo.client.do(ctx, http.MethodPut, "/projects/"+projectId+"/upgrade", nil, upgrade, nil)
 }
 
-func (repo *RepositoryService) UpdateInstance(ctx context.Context, instanceId string, request *model.InstanceRequest) (*model.Instance, error) {
-	log := logger.GetServiceLogger(ctx, "repo.GetProject")
-	var repository = vars.ZBI_REPOSITORY_URL + "/instances/" + instanceId
-
-	jsonReq, _ := json.Marshal(request)
-	req, err := http.NewRequest(http.MethodPut, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
+func (repo *RepositoryService) CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error) {
+	var result model.APIKey
+	if err := repo.client.do(ctx, http.MethodPost, "/instances/"+key.InstanceId+"/apikeys", nil, key, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
+func (repo *RepositoryService) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	var result model.APIKey
+	if err := repo.client.do(ctx, http.MethodGet, "/apikeys/"+id, nil, nil, &result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		var result model.Instance
-
-		body, err := io.ReadAll(resp.Body)
-		if err = json.Unmarshal(body, &result); err != nil {
-			return nil, errors.New("unable to retrieve instance")
-		}
-		return &result, nil
-	} else {
-		message := "failed to get instance"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return nil, errors.New(message)
+func (repo *RepositoryService) GetAPIKeys(ctx context.Context, instanceId string) ([]model.APIKey, error) {
+	var result []model.APIKey
+	if err := repo.client.do(ctx, http.MethodGet, "/instances/"+instanceId+"/apikeys", nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
+
+// RevokeAPIKey marks a key as revoked. Revoked keys are kept so that past
+// requests can still be attributed to their consumer.
+func (repo *RepositoryService) RevokeAPIKey(ctx context.Context, id string) error {
+	return repo.client.do(ctx, http.MethodDelete, "/apikeys/"+id, nil, nil, nil)
 }
 
 func (repo *RepositoryService) AddProjectActivity(ctx context.Context, project string, op model.EventAction) error {
+	activity := map[string]interface{}{"op": op}
+	return repo.client.do(ctx, http.MethodPost, "/projects/"+project+"/activity", nil, activity, nil)
+}
 
-	log := logger.GetServiceLogger(ctx, "repo.AddProjectActivity")
-	var repository = vars.ZBI_REPOSITORY_URL + "/projects/" + project + "/activity"
+func (repo *RepositoryService) AddInstanceActivity(ctx context.Context, instance string, op model.EventAction) error {
+	activity := map[string]interface{}{"op": op}
+	return repo.client.do(ctx, http.MethodPost, "/instances/"+instance+"/activity", nil, activity, nil)
+}
 
-	activity := make(map[string]interface{})
-	activity["op"] = op
+func (repo *RepositoryService) AddAuditRecord(ctx context.Context, record *model.AuditRecord) error {
+	return repo.client.do(ctx, http.MethodPost, "/audit", nil, record, nil)
+}
 
-	jsonReq, _ := json.Marshal(activity)
-	req, err := http.NewRequest(http.MethodPost, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		return err
+func (repo *RepositoryService) GetAuditRecords(ctx context.Context, query *model.AuditQuery) ([]model.AuditRecord, error) {
+	var params = url.Values{}
+	if query.Actor != "" {
+		params.Set("actor", query.Actor)
 	}
-
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if query.Target != "" {
+		params.Set("target", query.Target)
 	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		return nil
-	} else {
-		message := "failed to get instance resources"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return errors.New(message)
+	if query.Since != nil {
+		params.Set("since", query.Since.Format(time.RFC3339))
 	}
-}
-
-func (repo *RepositoryService) AddInstanceActivity(ctx context.Context, instance string, op model.EventAction) error {
-
-	log := logger.GetServiceLogger(ctx, "repo.AddInstanceActivity")
-	var repository = vars.ZBI_REPOSITORY_URL + "/instances/" + instance + "/activity"
-
-	activity := make(map[string]interface{})
-	activity["op"] = op
-
-	jsonReq, _ := json.Marshal(activity)
-	req, err := http.NewRequest(http.MethodPost, repository, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		return err
+	if query.Until != nil {
+		params.Set("until", query.Until.Format(time.RFC3339))
 	}
-
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-internal-secret", vars.ZBI_INTERNAL_CLIENT_SECRET)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
 	}
 
-	defer resp.Body.Close()
+	var path = "/audit"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
 
-	if resp.StatusCode == http.StatusOK {
-		return nil
-	} else {
-		message := "failed to get instance resources"
-		log.WithFields(logrus.Fields{"status": resp.StatusCode, "detail": resp.Body}).Errorf(message)
-		return errors.New(message)
+	var result []model.AuditRecord
+	if err := repo.client.do(ctx, http.MethodGet, path, nil, nil, &result); err != nil {
+		return nil, err
 	}
+	return result, nil
 }
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	POLICIES_FILE    = "policies.json"
+	BLOCKCHAINS_FILE = "blockchains.json"
+	TEMPLATE_SUFFIX  = "_templates.tmpl"
+)
+
+// activityRecord is an activity entry kept against a project or instance.
+type activityRecord struct {
+	Object string `json:"object"`
+	model.Activity
+}
+
+// embeddedData is the document persisted by the embedded store. Version
+// records the schema level reached by the migrations below.
+type embeddedData struct {
+	Version     int                        `json:"version"`
+	Policy      *model.PolicyInfo          `json:"policy,omitempty"`
+	Blockchains []model.BlockchainInfo     `json:"blockchains,omitempty"`
+	Projects    map[string]*model.Project  `json:"projects,omitempty"`
+	Instances   map[string]*model.Instance `json:"instances,omitempty"`
+	APIKeys     map[string]*model.APIKey   `json:"apikeys,omitempty"`
+	Teams       map[string]*model.Team     `json:"teams,omitempty"`
+	Seeds       map[string]*model.Seed     `json:"seeds,omitempty"`
+	Activities  []activityRecord           `json:"activities,omitempty"`
+	Audit       []model.AuditRecord        `json:"audit,omitempty"`
+}
+
+type migration struct {
+	version int
+	name    string
+	apply   func(s *embeddedStore, data *embeddedData) error
+}
+
+// migrations are applied in order to bring a stored document up to date.
+// New migrations must be appended with the next version number.
+var migrations = []migration{
+	{version: 1, name: "initialize collections", apply: func(s *embeddedStore, data *embeddedData) error {
+		if data.Projects == nil {
+			data.Projects = make(map[string]*model.Project)
+		}
+		if data.Instances == nil {
+			data.Instances = make(map[string]*model.Instance)
+		}
+		return nil
+	}},
+	{version: 2, name: "add api keys", apply: func(s *embeddedStore, data *embeddedData) error {
+		if data.APIKeys == nil {
+			data.APIKeys = make(map[string]*model.APIKey)
+		}
+		return nil
+	}},
+	{version: 3, name: "add teams", apply: func(s *embeddedStore, data *embeddedData) error {
+		if data.Teams == nil {
+			data.Teams = make(map[string]*model.Team)
+		}
+		return nil
+	}},
+	{version: 4, name: "add seeds", apply: func(s *embeddedStore, data *embeddedData) error {
+		if data.Seeds == nil {
+			data.Seeds = make(map[string]*model.Seed)
+		}
+		return nil
+	}},
+}
+
+// embeddedStore keeps repository data in memory and, when a path is given,
+// persists it to a single JSON document after every change.
+type embeddedStore struct {
+	mu          sync.RWMutex
+	path        string
+	configDir   string
+	templateDir string
+	data        *embeddedData
+}
+
+func newEmbeddedStore(path, configDir, templateDir string) (*embeddedStore, error) {
+	s := &embeddedStore{path: path, configDir: configDir, templateDir: templateDir, data: &embeddedData{}}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(content) > 0 {
+			if err = json.Unmarshal(content, s.data); err != nil {
+				return nil, fmt.Errorf("unable to read embedded store %s: %s", path, err)
+			}
+		}
+	}
+
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	// configuration is reloaded on every start, as the zbi-db init job does
+	if err := s.write(s.loadConfig); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *embeddedStore) migrate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range migrations {
+		if m.version <= s.data.Version {
+			continue
+		}
+		if err := m.apply(s, s.data); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %s", m.version, m.name, err)
+		}
+		s.data.Version = m.version
+	}
+
+	return nil
+}
+
+// loadConfig reads the policy and blockchain definitions from configDir and
+// attaches base64 encoded templates from templateDir, the same layout the
+// zbi-db init script uses.
+func (s *embeddedStore) loadConfig(data *embeddedData) error {
+	if s.configDir == "" {
+		return nil
+	}
+
+	var policy model.PolicyInfo
+	if err := readJSONFile(filepath.Join(s.configDir, POLICIES_FILE), &policy); err != nil {
+		return err
+	}
+	data.Policy = &policy
+
+	var blockchains []model.BlockchainInfo
+	if err := readJSONFile(filepath.Join(s.configDir, BLOCKCHAINS_FILE), &blockchains); err != nil {
+		return err
+	}
+
+	if s.templateDir != "" {
+		for index := range blockchains {
+			if blockchains[index].Templates == nil {
+				blockchains[index].Templates = make(map[string]string)
+			}
+
+			names := []string{"app", "project"}
+			for _, node := range blockchains[index].Nodes {
+				names = append(names, node.Type)
+			}
+
+			for _, name := range names {
+				content, err := os.ReadFile(filepath.Join(s.templateDir, name+TEMPLATE_SUFFIX))
+				if os.IsNotExist(err) {
+					continue
+				} else if err != nil {
+					return err
+				}
+				blockchains[index].Templates[name] = base64.StdEncoding.EncodeToString(content)
+			}
+		}
+	}
+	data.Blockchains = blockchains
+
+	return nil
+}
+
+// persist writes the document atomically. The caller must hold the lock.
+func (s *embeddedStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s.data)
+}
+
+func (s *embeddedStore) read(fn func(data *embeddedData) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.data)
+}
+
+func (s *embeddedStore) write(fn func(data *embeddedData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := fn(s.data); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func readJSONFile(path string, target interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("unable to parse %s: %s", path, err)
+	}
+	return nil
+}
+
+// writeJSONFile replaces path atomically with the JSON encoding of source.
+func writeJSONFile(path string, source interface{}) error {
+	content, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,253 @@
+package schedule
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	SCHEDULE_SOURCE_INSTANCE = "instance"
+	SCHEDULE_SOURCE_PROJECT  = "project"
+)
+
+// GetSchedule returns the schedule that applies to an instance and where it
+// is set. An instance schedule overrides the project schedule.
+func GetSchedule(instance *model.Instance, project *model.Project) (*model.PowerSchedule, string) {
+	if instance.Schedule != nil {
+		return instance.Schedule, SCHEDULE_SOURCE_INSTANCE
+	}
+	if project != nil && project.Schedule != nil {
+		return project.Schedule, SCHEDULE_SOURCE_PROJECT
+	}
+	return nil, ""
+}
+
+// ResetState returns the state of an instance after its schedule changed.
+// Transitions of the new schedule before now are not caught up.
+func ResetState(instance *model.Instance, schedule *model.PowerSchedule, now time.Time) *model.PowerState {
+	var state model.PowerState
+	if instance.Power != nil {
+		state = *instance.Power
+	}
+	state.LastCheck = &now
+	state.NextTransition, state.NextAction = nil, ""
+
+	if schedule != nil {
+		if table, err := NewTimetable(schedule); err == nil {
+			if next, action, ok := table.Next(now); ok {
+				state.NextTransition, state.NextAction = &next, action
+			}
+		}
+	}
+	return &state
+}
+
+// PowerScheduler starts and stops instances according to their power
+// schedule. Transitions missed while the controller was down are caught up
+// within the catch-up period; only the latest missed transition is applied.
+type PowerScheduler struct {
+	interval    time.Duration
+	catchup     time.Duration
+	concurrency int
+	stopper     chan struct{}
+	once        sync.Once
+}
+
+func NewPowerScheduler(interval, catchup time.Duration, concurrency int) *PowerScheduler {
+	return &PowerScheduler{interval: interval, catchup: catchup, concurrency: concurrency, stopper: make(chan struct{})}
+}
+
+// Start runs the scheduler until Stop is called or ctx is done.
+func (s *PowerScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopper:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *PowerScheduler) Stop() {
+	s.once.Do(func() { close(s.stopper) })
+}
+
+// Run handles the transitions of every project that are due at now.
+func (s *PowerScheduler) Run(ctx context.Context, now time.Time) {
+	log := logger.GetServiceLogger(ctx, "schedule.PowerScheduler")
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	projects, err := repository.GetProjects(ctx, "")
+	if err != nil {
+		log.Errorf("unable to list projects - %s", err)
+		return
+	}
+
+	for index := range projects {
+		s.runProject(ctx, &projects[index], now)
+	}
+}
+
+func (s *PowerScheduler) runProject(ctx context.Context, project *model.Project, now time.Time) {
+	log := logger.GetServiceLogger(ctx, "schedule.PowerScheduler").WithFields(logrus.Fields{"project": project.Name})
+	defer func() {
+		if rec := recover(); rec != nil {
+			metrics.PanicsTotal.Inc("schedule")
+			log.WithFields(logrus.Fields{"stack": string(debug.Stack())}).Errorf("recovered from panic - %v", rec)
+		}
+	}()
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instances, err := repository.GetInstances(ctx, project.Id)
+	if err != nil {
+		log.Errorf("unable to list instances - %s", err)
+		return
+	}
+
+	var states = make(map[string]*model.PowerState)
+	var due = make(map[model.EventAction][]model.Instance)
+	for _, instance := range instances {
+		if instance.Project == nil {
+			instance.Project = project
+		}
+
+		state, action := s.check(ctx, &instance, project, now)
+		if state != nil {
+			states[instance.Id] = state
+		}
+		if action != "" {
+			due[action] = append(due[action], instance)
+		}
+	}
+
+	// lightwallet instances stop before and start after their zcash backend
+	for _, action := range []model.EventAction{model.EventActionStopInstance, model.EventActionStartInstance} {
+		if len(due[action]) == 0 {
+			continue
+		}
+
+		reverse := action == model.EventActionStopInstance
+		stages := helper.OrderInstances(due[action], reverse)
+		results := helper.RunInstanceStages(ctx, stages, s.concurrency, reverse, func(ctx context.Context, instance *model.Instance) error {
+			return runTransition(ctx, action, instance)
+		})
+
+		for _, result := range results {
+			state := states[result.Id]
+			state.LastResult = result.Status
+			state.LastError = result.Error
+		}
+	}
+
+	for id, state := range states {
+		if err = repository.UpdateInstancePower(ctx, id, state); err != nil {
+			log.WithFields(logrus.Fields{"instance": id}).Errorf("failed to record schedule state - %s", err)
+		}
+	}
+}
+
+// check returns the new state of an instance when it changed and the action
+// that is due, if any.
+func (s *PowerScheduler) check(ctx context.Context, instance *model.Instance, project *model.Project, now time.Time) (*model.PowerState, model.EventAction) {
+	log := logger.GetServiceLogger(ctx, "schedule.PowerScheduler").WithFields(logrus.Fields{"project": project.Name, "instance": instance.Name})
+
+	schedule, _ := GetSchedule(instance, project)
+	if schedule == nil {
+		if instance.Power != nil && instance.Power.NextTransition != nil {
+			var state = *instance.Power
+			state.NextTransition, state.NextAction = nil, ""
+			return &state, ""
+		}
+		return nil, ""
+	}
+
+	table, err := NewTimetable(schedule)
+	if err != nil {
+		log.Errorf("invalid schedule - %s", err)
+		return nil, ""
+	}
+
+	var state model.PowerState
+	if instance.Power != nil {
+		state = *instance.Power
+	}
+
+	var action model.EventAction
+	var changed = state.LastCheck == nil
+	if state.LastCheck != nil {
+		from := *state.LastCheck
+		if earliest := now.Add(-s.catchup); from.Before(earliest) {
+			from = earliest
+		}
+
+		if at, a, ok := table.Last(from, now); ok {
+			log.Infof("%s is due at %s", a, at.Format(time.RFC3339))
+			state.LastTransition, state.LastAction = &at, a
+			if instance.Hold.Active(now) {
+				state.LastResult, state.LastError = model.BulkResultSkipped, "instance is on hold"
+			} else {
+				action = a
+			}
+			changed = true
+		}
+	}
+
+	var next *time.Time
+	nextTime, nextAction, ok := table.Next(now)
+	if ok {
+		next = &nextTime
+	}
+	if !sameTime(state.NextTransition, next) || state.NextAction != nextAction {
+		state.NextTransition, state.NextAction = next, nextAction
+		changed = true
+	}
+
+	if !changed {
+		return nil, ""
+	}
+
+	state.LastCheck = &now
+	return &state, action
+}
+
+func runTransition(ctx context.Context, action model.EventAction, instance *model.Instance) error {
+	zclient := vars.KlientFactory.GetZBIClient()
+
+	var err error
+	if action == model.EventActionStopInstance {
+		err = zclient.StopInstance(ctx, instance.Project, instance)
+	} else {
+		err = zclient.StartInstance(ctx, instance.Project, instance)
+	}
+	if err != nil {
+		return err
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err = repository.AddInstanceActivity(ctx, instance.Id, action); err != nil {
+		logger.GetLogger(ctx).Errorf("failed to add %s activity for instance %s - %s", action, instance.Id, err)
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
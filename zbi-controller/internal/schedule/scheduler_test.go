@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	zklient "github.com/zbitech/controller/fake-zbi/klient/zbi-klient"
+	"github.com/zbitech/controller/internal/repository"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+type testKlientFactory struct {
+	interfaces.KlientFactoryIF
+	client interfaces.ZBIClientIF
+}
+
+func (f *testKlientFactory) GetZBIClient() interfaces.ZBIClientIF {
+	return f.client
+}
+
+type testRepositoryFactory struct {
+	interfaces.RepositoryServiceFactoryIF
+	service interfaces.RepositoryServiceIF
+}
+
+func (f *testRepositoryFactory) GetRepositoryService() interfaces.RepositoryServiceIF {
+	return f.service
+}
+
+func TestPowerScheduler(t *testing.T) {
+	ctx := context.Background()
+	service, err := repository.NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	project, err := service.CreateProject(ctx, &model.Project{Name: "dev", Owner: "alice", Network: "testnet",
+		Schedule: &model.PowerSchedule{StartCron: "0 8 * * *", StopCron: "0 18 * * *"}})
+	assert.NoError(t, err)
+
+	zcash, err := service.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "zcash-1", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+	lwd, err := service.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "lwd-1", Type: model.InstanceTypeLWD, Peers: []string{"zcash-1"}})
+	assert.NoError(t, err)
+	held, err := service.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: "zcash-2", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+	assert.NoError(t, service.UpdateInstanceHold(ctx, held.Id, &model.ScheduleHold{Reason: "sync"}))
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(action string, instance *model.Instance) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, fmt.Sprintf("%s %s", action, instance.Name))
+		return nil
+	}
+	client := zklient.FakeZBIClient{
+		FakeStopInstance: func(ctx context.Context, project *model.Project, instance *model.Instance) error {
+			return record("stop", instance)
+		},
+		FakeStartInstance: func(ctx context.Context, project *model.Project, instance *model.Instance) error {
+			return record("start", instance)
+		},
+	}
+	vars.KlientFactory = &testKlientFactory{client: client}
+	vars.RepositoryFactory = &testRepositoryFactory{service: service}
+
+	scheduler := NewPowerScheduler(time.Minute, 24*time.Hour, 2)
+
+	// the first run records the next transition without acting
+	scheduler.Run(ctx, parseTime(t, "2024-03-01T07:00:00Z"))
+	assert.Empty(t, calls)
+	instance, err := service.GetInstance(ctx, zcash.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventActionStartInstance, instance.Power.NextAction)
+	assert.Equal(t, parseTime(t, "2024-03-01T08:00:00Z"), instance.Power.NextTransition.UTC())
+
+	scheduler.Run(ctx, parseTime(t, "2024-03-01T07:30:00Z"))
+	assert.Empty(t, calls)
+
+	// a restart after 18:00 catches up the missed stop only
+	scheduler.Run(ctx, parseTime(t, "2024-03-01T19:00:00Z"))
+	assert.Equal(t, []string{"stop lwd-1", "stop zcash-1"}, calls)
+
+	instance, err = service.GetInstance(ctx, held.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.BulkResultSkipped, instance.Power.LastResult)
+
+	instance, err = service.GetInstance(ctx, lwd.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventActionStopInstance, instance.Power.LastAction)
+	assert.Equal(t, model.BulkResultSucceeded, instance.Power.LastResult)
+	assert.Equal(t, parseTime(t, "2024-03-02T08:00:00Z"), instance.Power.NextTransition.UTC())
+
+	calls = nil
+	scheduler.Run(ctx, parseTime(t, "2024-03-02T08:01:00Z"))
+	assert.Equal(t, []string{"start zcash-1", "start lwd-1"}, calls)
+}
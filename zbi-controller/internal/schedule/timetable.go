@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// MAX_TRANSITIONS bounds the transitions examined when catching up.
+const MAX_TRANSITIONS = 10000
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Timetable computes the start and stop transitions of a power schedule.
+type Timetable struct {
+	location *time.Location
+	start    *Cron
+	stop     *Cron
+	windows  []window
+}
+
+type window struct {
+	days       map[time.Weekday]bool
+	start, end int
+}
+
+// NewTimetable parses a power schedule.
+func NewTimetable(schedule *model.PowerSchedule) (*Timetable, error) {
+	var table = Timetable{location: time.UTC}
+
+	if schedule.Timezone != "" {
+		location, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %s", schedule.Timezone)
+		}
+		table.location = location
+	}
+
+	var err error
+	if schedule.StartCron != "" {
+		if table.start, err = ParseCron(schedule.StartCron); err != nil {
+			return nil, err
+		}
+	}
+	if schedule.StopCron != "" {
+		if table.stop, err = ParseCron(schedule.StopCron); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range schedule.Windows {
+		var entry = window{days: make(map[time.Weekday]bool)}
+		for _, day := range w.Days {
+			weekday, ok := weekdays[day]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %s", day)
+			}
+			entry.days[weekday] = true
+		}
+		if entry.start, err = ParseClock(w.Start); err != nil {
+			return nil, err
+		}
+		if entry.end, err = ParseClock(w.End); err != nil {
+			return nil, err
+		}
+		if entry.start == entry.end {
+			return nil, fmt.Errorf("window start and end must differ")
+		}
+		table.windows = append(table.windows, entry)
+	}
+
+	if len(table.windows) > 0 && (table.start != nil || table.stop != nil) {
+		return nil, fmt.Errorf("a schedule has either cron expressions or windows")
+	}
+	if len(table.windows) == 0 && table.start == nil && table.stop == nil {
+		return nil, fmt.Errorf("a schedule needs cron expressions or windows")
+	}
+
+	return &table, nil
+}
+
+// ParseClock parses a HH:MM time of day into minutes after midnight.
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Next returns the first transition after t.
+func (table *Timetable) Next(t time.Time) (time.Time, model.EventAction, bool) {
+	var next time.Time
+	var action model.EventAction
+
+	candidate := func(at time.Time, a model.EventAction) {
+		if !at.IsZero() && at.After(t) && (next.IsZero() || at.Before(next)) {
+			next, action = at, a
+		}
+	}
+
+	local := t.In(table.location)
+	if table.start != nil {
+		candidate(table.start.Next(local), model.EventActionStartInstance)
+	}
+	if table.stop != nil {
+		candidate(table.stop.Next(local), model.EventActionStopInstance)
+	}
+
+	// windows that started the day before can end today
+	for _, w := range table.windows {
+		for offset := -1; offset <= 7; offset++ {
+			day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, table.location)
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			end := w.end
+			if end < w.start {
+				end += 24 * 60
+			}
+			candidate(clockTime(day, w.start), model.EventActionStartInstance)
+			candidate(clockTime(day, end), model.EventActionStopInstance)
+		}
+	}
+
+	return next, action, !next.IsZero()
+}
+
+// Last returns the latest transition after from and up to to.
+func (table *Timetable) Last(from, to time.Time) (time.Time, model.EventAction, bool) {
+	var last time.Time
+	var action model.EventAction
+	var found bool
+
+	t := from
+	for count := 0; count < MAX_TRANSITIONS; count++ {
+		next, a, ok := table.Next(t)
+		if !ok || next.After(to) {
+			break
+		}
+		last, action, found = next, a, true
+		t = next
+	}
+	return last, action, found
+}
+
+func clockTime(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+}
@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+func parseTime(t *testing.T, value string) time.Time {
+	result, err := time.Parse(time.RFC3339, value)
+	assert.NoError(t, err)
+	return result
+}
+
+func TestParseCron(t *testing.T) {
+	for _, expression := range []string{"* * * * *", "0 8 * * 1-5", "*/15 9-17 * * *", "0 0 1,15 * *", "0 0 * * 7"} {
+		_, err := ParseCron(expression)
+		assert.NoError(t, err, expression)
+	}
+
+	// day and month names are not supported
+	for _, expression := range []string{"", "0 8 * *", "60 * * * *", "0 24 * * *", "5-1 * * * *", "*/0 * * * *", "0 8 * * mon"} {
+		_, err := ParseCron(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	weekdays, _ := ParseCron("0 8 * * 1-5")
+	// friday 2024-03-01 09:00 is followed by monday 08:00
+	assert.Equal(t, parseTime(t, "2024-03-04T08:00:00Z"), weekdays.Next(parseTime(t, "2024-03-01T09:00:00Z")))
+	assert.Equal(t, parseTime(t, "2024-03-01T08:00:00Z"), weekdays.Next(parseTime(t, "2024-03-01T07:59:30Z")))
+
+	sunday, _ := ParseCron("30 18 * * 7")
+	assert.Equal(t, parseTime(t, "2024-03-03T18:30:00Z"), sunday.Next(parseTime(t, "2024-03-01T00:00:00Z")))
+
+	monthly, _ := ParseCron("0 0 31 * *")
+	assert.Equal(t, parseTime(t, "2024-03-31T00:00:00Z"), monthly.Next(parseTime(t, "2024-02-01T00:00:00Z")))
+}
+
+func TestTimetable_Cron(t *testing.T) {
+	table, err := NewTimetable(&model.PowerSchedule{Timezone: "America/New_York", StartCron: "0 8 * * 1-5", StopCron: "0 18 * * 1-5"})
+	assert.NoError(t, err)
+
+	// 12:00 UTC is 07:00 in New York
+	next, action, ok := table.Next(parseTime(t, "2024-03-01T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, model.EventActionStartInstance, action)
+	assert.Equal(t, parseTime(t, "2024-03-01T13:00:00Z"), next.UTC())
+
+	last, action, ok := table.Last(parseTime(t, "2024-03-01T12:00:00Z"), parseTime(t, "2024-03-02T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, model.EventActionStopInstance, action)
+	assert.Equal(t, parseTime(t, "2024-03-01T23:00:00Z"), last.UTC())
+
+	_, _, ok = table.Last(parseTime(t, "2024-03-02T12:00:00Z"), parseTime(t, "2024-03-03T12:00:00Z"))
+	assert.False(t, ok)
+}
+
+func TestTimetable_Windows(t *testing.T) {
+	table, err := NewTimetable(&model.PowerSchedule{Windows: []model.ScheduleWindow{
+		{Days: []string{"fri"}, Start: "22:00", End: "02:00"},
+	}})
+	assert.NoError(t, err)
+
+	next, action, ok := table.Next(parseTime(t, "2024-03-01T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, model.EventActionStartInstance, action)
+	assert.Equal(t, parseTime(t, "2024-03-01T22:00:00Z"), next)
+
+	next, action, ok = table.Next(next)
+	assert.True(t, ok)
+	assert.Equal(t, model.EventActionStopInstance, action)
+	assert.Equal(t, parseTime(t, "2024-03-02T02:00:00Z"), next)
+
+	next, _, _ = table.Next(next)
+	assert.Equal(t, parseTime(t, "2024-03-08T22:00:00Z"), next)
+}
+
+func TestNewTimetable_Invalid(t *testing.T) {
+	for _, schedule := range []model.PowerSchedule{
+		{},
+		{Timezone: "Mars/Olympus", StartCron: "0 8 * * *"},
+		{StartCron: "0 8 * * *", Windows: []model.ScheduleWindow{{Days: []string{"mon"}, Start: "08:00", End: "18:00"}}},
+		{Windows: []model.ScheduleWindow{{Days: []string{"mon"}, Start: "08:00", End: "08:00"}}},
+		{Windows: []model.ScheduleWindow{{Days: []string{"monday"}, Start: "08:00", End: "18:00"}}},
+		{Windows: []model.ScheduleWindow{{Days: []string{"mon"}, Start: "8am", End: "18:00"}}},
+	} {
+		_, err := NewTimetable(&schedule)
+		assert.Error(t, err, schedule)
+	}
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// FileSecretProvider keeps encrypted credentials in a local file.
+type FileSecretProvider struct {
+	mu      sync.Mutex
+	path    string
+	cipher  Cipher
+	entries map[string][]byte
+}
+
+func NewFileSecretProvider(path string, cipher Cipher) (interfaces.SecretProviderIF, error) {
+	f := &FileSecretProvider{path: path, cipher: cipher, entries: make(map[string][]byte)}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(content) > 0 {
+		if err = json.Unmarshal(content, &f.entries); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (f *FileSecretProvider) Type() string {
+	return SECRET_PROVIDER_FILE
+}
+
+func (f *FileSecretProvider) GetCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ciphertext, ok := f.entries[credentialsKey(project, instance)]
+	if !ok {
+		return nil, notFound(nil)
+	}
+
+	plaintext, err := f.cipher.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials model.InstanceCredentials
+	if err = json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+func (f *FileSecretProvider) PutCredentials(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := f.cipher.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries[credentialsKey(project, instance)] = ciphertext
+	return f.persist()
+}
+
+func (f *FileSecretProvider) DeleteCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.entries, credentialsKey(project, instance))
+	return f.persist()
+}
+
+// persist writes the entries atomically. The caller must hold the lock.
+func (f *FileSecretProvider) persist() error {
+	content, err := json.Marshal(f.entries)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := f.path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
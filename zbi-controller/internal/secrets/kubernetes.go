@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"context"
+
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// KubernetesSecretProvider keeps credentials only in the instance Secret,
+// which is written when the CREDENTIALS template is applied.
+type KubernetesSecretProvider struct {
+	client interfaces.KlientIF
+}
+
+func NewKubernetesSecretProvider(client interfaces.KlientIF) interfaces.SecretProviderIF {
+	return &KubernetesSecretProvider{client: client}
+}
+
+func (k *KubernetesSecretProvider) Type() string {
+	return SECRET_PROVIDER_KUBERNETES
+}
+
+func (k *KubernetesSecretProvider) GetCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	secret, err := k.client.GetSecretByName(ctx, project.GetNamespace(), helper.CredentialsSecretName(instance.Name))
+	if err != nil {
+		if errs.Code(err).Category == errs.CategoryNotFound {
+			return nil, notFound(err)
+		}
+		return nil, errs.NewKubernetesError(err)
+	}
+	return helper.GetInstanceCredentials(secret), nil
+}
+
+func (k *KubernetesSecretProvider) PutCredentials(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) error {
+	return nil
+}
+
+func (k *KubernetesSecretProvider) DeleteCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	return nil
+}
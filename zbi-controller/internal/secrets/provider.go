@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	SECRET_PROVIDER_KUBERNETES = "kubernetes"
+	SECRET_PROVIDER_FILE       = "file"
+	SECRET_PROVIDER_VAULT      = "vault"
+)
+
+// NewSecretProvider creates the provider selected by ZBI_SECRET_PROVIDER.
+func NewSecretProvider(ctx context.Context, client interfaces.KlientIF) (interfaces.SecretProviderIF, error) {
+	switch vars.ZBI_SECRET_PROVIDER {
+	case SECRET_PROVIDER_KUBERNETES, "":
+		return NewKubernetesSecretProvider(client), nil
+	case SECRET_PROVIDER_FILE:
+		key, err := os.ReadFile(vars.ZBI_SECRET_KEY_FILE)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read secret key - %w", err)
+		}
+		cipher, err := NewAESCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileSecretProvider(vars.ZBI_SECRET_FILE_PATH, cipher)
+	case SECRET_PROVIDER_VAULT:
+		return NewVaultSecretProvider(vars.ZBI_VAULT_ADDR, vars.ZBI_VAULT_TOKEN, vars.ZBI_VAULT_MOUNT, vars.ZBI_VAULT_PATH), nil
+	}
+	return nil, fmt.Errorf("unknown secret provider %s", vars.ZBI_SECRET_PROVIDER)
+}
+
+// GenerateCredentials creates a new RPC username and password.
+func GenerateCredentials() *model.InstanceCredentials {
+	return &model.InstanceCredentials{
+		Username: utils.GenerateRandomString(6, true),
+		Password: utils.GenerateSecurePassword(),
+	}
+}
+
+// CreateCredentials generates credentials and stores them with the provider.
+func CreateCredentials(ctx context.Context, provider interfaces.SecretProviderIF, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	credentials := GenerateCredentials()
+	if err := provider.PutCredentials(ctx, project, instance, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// CreateCredentialsSpec describes the instance Secret for the templates. The
+// vault provider keeps the credentials out of the rendered objects: the Secret
+// is synced from the ZBI_SECRET_STORE ClusterSecretStore, which must resolve
+// the vault credentials key. The file provider encrypts its store with a key
+// only the controller holds, so its credentials are rendered into the Secret.
+func CreateCredentialsSpec(provider interfaces.SecretProviderIF, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) model.CredentialsSpec {
+	var spec = model.CredentialsSpec{
+		Provider:   provider.Type(),
+		SecretName: helper.CredentialsSecretName(instance.Name),
+		Previous:   validPrevious(credentials),
+	}
+
+	if spec.Provider == SECRET_PROVIDER_VAULT {
+		spec.Store = vars.ZBI_SECRET_STORE
+		spec.Key = strings.Trim(vars.ZBI_VAULT_PATH, "/") + "/" + credentialsKey(project, instance)
+	} else {
+		spec.Username = credentials.Username
+		spec.Password = credentials.Password
+	}
+
+	return spec
+}
+
+// IsExternalProvider reports whether the credentials of a provider are synced
+// into the cluster by an ExternalSecret.
+func IsExternalProvider(provider interfaces.SecretProviderIF) bool {
+	return provider != nil && provider.Type() == SECRET_PROVIDER_VAULT
+}
+
+// validPrevious returns the previous credentials while they are still valid.
+func validPrevious(credentials *model.InstanceCredentials) *model.PreviousCredentials {
+	if credentials.Previous == nil || !time.Now().Before(credentials.Previous.ExpiresAt) {
+		return nil
+	}
+	return credentials.Previous
+}
+
+// credentialsKey identifies the instance credentials in an external store.
+func credentialsKey(project *model.Project, instance *model.Instance) string {
+	return project.GetNamespace() + "/" + instance.Name
+}
+
+func notFound(err error) error {
+	return errs.NewApplicationError(errs.CredentialsNotFoundError, err)
+}
@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"context"
+	"time"
+
+	"github.com/zbitech/controller/internal/utils"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	ROTATION_PROPERTY = "rotation"
+
+	// MAX_ROTATION_HISTORY bounds the rotation records kept per instance
+	MAX_ROTATION_HISTORY = 20
+)
+
+// RotateCredentials generates and stores new credentials for an instance.
+// When grace is positive the current credentials remain valid until it has
+// elapsed.
+func RotateCredentials(ctx context.Context, provider interfaces.SecretProviderIF, project *model.Project, instance *model.Instance, grace time.Duration) (*model.InstanceCredentials, error) {
+	credentials := GenerateCredentials()
+
+	if grace > 0 {
+		current, err := provider.GetCredentials(ctx, project, instance)
+		if err != nil && errs.Code(err) != errs.CredentialsNotFoundError {
+			return nil, err
+		}
+		if current != nil && current.Username != "" {
+			credentials.Previous = &model.PreviousCredentials{
+				Username:  current.Username,
+				Password:  current.Password,
+				ExpiresAt: time.Now().Add(grace).UTC().Truncate(time.Second),
+			}
+		}
+	}
+
+	if err := provider.PutCredentials(ctx, project, instance, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// ExpireCredentials removes previous credentials whose grace period has
+// ended. It reports whether the stored credentials changed.
+func ExpireCredentials(ctx context.Context, provider interfaces.SecretProviderIF, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, bool, error) {
+	credentials, err := provider.GetCredentials(ctx, project, instance)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if credentials.Previous == nil || time.Now().Before(credentials.Previous.ExpiresAt) {
+		return credentials, false, nil
+	}
+
+	credentials.Previous = nil
+	if err = provider.PutCredentials(ctx, project, instance, credentials); err != nil {
+		return nil, false, err
+	}
+	return credentials, true, nil
+}
+
+// GetRotationPolicy returns the rotation policy of an instance, which is set
+// with the rotation property or inherited from its project.
+func GetRotationPolicy(instance *model.Instance) *model.RotationPolicy {
+	if instance.Request != nil {
+		if value, ok := instance.Request.Properties[ROTATION_PROPERTY]; ok && value != nil {
+			var policy model.RotationPolicy
+			if err := utils.UnMarshalObject(utils.MarshalObject(value), &policy); err == nil {
+				return &policy
+			}
+		}
+	}
+
+	if instance.Project != nil && instance.Project.Rotation != nil {
+		return instance.Project.Rotation
+	}
+
+	return &model.RotationPolicy{}
+}
+
+// ParseRotationPolicy returns the rotation interval and grace period of a
+// policy. A zero interval means credentials are only rotated on request.
+func ParseRotationPolicy(policy *model.RotationPolicy) (interval, grace time.Duration, err error) {
+	if policy.Interval != "" {
+		if interval, err = utils.ParseDuration(policy.Interval); err != nil {
+			return 0, 0, err
+		}
+	}
+	if policy.GracePeriod != "" {
+		if grace, err = utils.ParseDuration(policy.GracePeriod); err != nil {
+			return 0, 0, err
+		}
+	}
+	return interval, grace, nil
+}
+
+// NextRotation returns when the credentials of an instance are next due for
+// rotation, or nil when no rotation is scheduled.
+func NextRotation(instance *model.Instance, interval time.Duration) *time.Time {
+	if interval <= 0 {
+		return nil
+	}
+
+	last := instance.CreatedAt
+	if instance.Rotation != nil && instance.Rotation.LastRotation != nil {
+		last = instance.Rotation.LastRotation
+	}
+	if last == nil {
+		return nil
+	}
+
+	next := last.Add(interval)
+	return &next
+}
+
+// RecordRotation returns the rotation state of an instance after its
+// credentials were rotated at rotatedAt.
+func RecordRotation(instance *model.Instance, trigger model.RotationTrigger, grace time.Duration, rotatedAt time.Time) *model.CredentialsRotation {
+	var rotation model.CredentialsRotation
+	if instance.Rotation != nil {
+		rotation.History = append(rotation.History, instance.Rotation.History...)
+	}
+
+	record := model.RotationRecord{RotatedAt: rotatedAt, Trigger: trigger}
+	rotation.LastRotation = &rotatedAt
+	if grace > 0 {
+		graceUntil := rotatedAt.Add(grace)
+		rotation.GraceUntil = &graceUntil
+		record.GracePeriod = grace.String()
+	}
+
+	rotation.History = append(rotation.History, record)
+	if len(rotation.History) > MAX_ROTATION_HISTORY {
+		rotation.History = rotation.History[len(rotation.History)-MAX_ROTATION_HISTORY:]
+	}
+
+	updated := *instance
+	updated.Rotation = &rotation
+	interval, _, _ := ParseRotationPolicy(GetRotationPolicy(instance))
+	rotation.NextRotation = NextRotation(&updated, interval)
+
+	return &rotation
+}
+
+// RotateInstanceCredentials rotates the credentials of an instance and
+// records the rotation with the repository.
+func RotateInstanceCredentials(ctx context.Context, instance *model.Instance, trigger model.RotationTrigger, grace time.Duration) (*model.CredentialsRotation, error) {
+
+	var log = logger.GetServiceLogger(ctx, "secrets.RotateInstanceCredentials")
+	defer func() { logger.LogServiceTime(log) }()
+
+	if instance.InstanceType != model.InstanceTypeZCASH {
+		return nil, errs.New(errs.InstanceTypeError, "credentials can only be rotated for zcash instances")
+	}
+
+	if err := vars.KlientFactory.GetZBIClient().RotateInstanceCredentials(ctx, instance.Project, instance, grace); err != nil {
+		return nil, err
+	}
+
+	// taken after the rotation so the recorded grace period never ends
+	// before the previous credentials expire
+	rotatedAt := time.Now().UTC().Truncate(time.Second)
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	rotation := RecordRotation(instance, trigger, grace, rotatedAt)
+	if err := repository.UpdateInstanceRotation(ctx, instance.Id, rotation); err != nil {
+		log.Errorf("failed to record rotation for instance %s - %s", instance.Id, err)
+		return nil, err
+	}
+
+	if err := repository.AddInstanceActivity(ctx, instance.Id, model.EventActionRotate); err != nil {
+		log.Errorf("failed to add rotate activity for instance %s - %s", instance.Id, err)
+	}
+
+	return rotation, nil
+}
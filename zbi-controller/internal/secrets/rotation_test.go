@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	zklient "github.com/zbitech/controller/fake-zbi/klient/zbi-klient"
+	"github.com/zbitech/controller/internal/repository"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+type testKlientFactory struct {
+	interfaces.KlientFactoryIF
+	client interfaces.ZBIClientIF
+}
+
+func (f *testKlientFactory) GetZBIClient() interfaces.ZBIClientIF {
+	return f.client
+}
+
+type testRepositoryFactory struct {
+	interfaces.RepositoryServiceFactoryIF
+	service interfaces.RepositoryServiceIF
+}
+
+func (f *testRepositoryFactory) GetRepositoryService() interfaces.RepositoryServiceIF {
+	return f.service
+}
+
+func TestRotateCredentials(t *testing.T) {
+	ctx := context.Background()
+	cipher, _ := NewAESCipher(testKey)
+	provider, _ := NewFileSecretProvider(filepath.Join(t.TempDir(), "secrets.json"), cipher)
+
+	current, err := CreateCredentials(ctx, provider, testProject, testInstance)
+	assert.NoError(t, err)
+
+	rotated, err := RotateCredentials(ctx, provider, testProject, testInstance, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEqual(t, current.Password, rotated.Password)
+	assert.Equal(t, current.Username, rotated.Previous.Username)
+	assert.Equal(t, current.Password, rotated.Previous.Password)
+	assert.Equal(t, rotated.Previous, CreateCredentialsSpec(provider, testProject, testInstance, rotated).Previous)
+
+	// previous credentials are kept until they expire
+	_, changed, err := ExpireCredentials(ctx, provider, testProject, testInstance)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	rotated.Previous.ExpiresAt = time.Now().Add(-time.Minute)
+	assert.NoError(t, provider.PutCredentials(ctx, testProject, testInstance, rotated))
+	assert.Nil(t, CreateCredentialsSpec(provider, testProject, testInstance, rotated).Previous)
+
+	expired, changed, err := ExpireCredentials(ctx, provider, testProject, testInstance)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Nil(t, expired.Previous)
+	assert.Equal(t, rotated.Password, expired.Password)
+
+	immediate, err := RotateCredentials(ctx, provider, testProject, testInstance, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, immediate.Previous)
+}
+
+func TestGetRotationPolicy(t *testing.T) {
+	project := &model.Project{Name: "project", Rotation: &model.RotationPolicy{Interval: "30d", GracePeriod: "1d"}}
+	instance := &model.Instance{Name: "zcash", Project: project, Request: &model.ResourceRequest{}}
+	assert.Equal(t, project.Rotation, GetRotationPolicy(instance))
+
+	instance.Request.Properties = map[string]interface{}{ROTATION_PROPERTY: map[string]interface{}{"interval": "7d"}}
+	policy := GetRotationPolicy(instance)
+	assert.Equal(t, "7d", policy.Interval)
+	assert.Empty(t, policy.GracePeriod)
+
+	interval, grace, err := ParseRotationPolicy(project.Rotation)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, interval)
+	assert.Equal(t, 24*time.Hour, grace)
+}
+
+func TestRecordRotation(t *testing.T) {
+	createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	instance := &model.Instance{Name: "zcash", CreatedAt: &createdAt, Project: &model.Project{Rotation: &model.RotationPolicy{Interval: "30d"}}}
+
+	assert.Equal(t, createdAt.Add(30*24*time.Hour), *NextRotation(instance, 30*24*time.Hour))
+	assert.Nil(t, NextRotation(instance, 0))
+
+	rotatedAt := createdAt.Add(time.Hour)
+	for index := 0; index < MAX_ROTATION_HISTORY+5; index++ {
+		instance.Rotation = RecordRotation(instance, model.RotationTriggerManual, 0, rotatedAt)
+	}
+	assert.Len(t, instance.Rotation.History, MAX_ROTATION_HISTORY)
+	assert.Nil(t, instance.Rotation.GraceUntil)
+
+	rotation := RecordRotation(instance, model.RotationTriggerScheduled, time.Hour, rotatedAt)
+	assert.Equal(t, rotatedAt.Add(time.Hour), *rotation.GraceUntil)
+	assert.Equal(t, rotatedAt.Add(30*24*time.Hour), *rotation.NextRotation)
+	assert.Equal(t, model.RotationTriggerScheduled, rotation.History[len(rotation.History)-1].Trigger)
+}
+
+func TestRotationScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	service, err := repository.NewEmbeddedRepositoryService("", "", "")
+	assert.NoError(t, err)
+
+	var rotated, expired int
+	var rotatedGrace time.Duration
+	client := zklient.FakeZBIClient{
+		FakeRotateInstanceCredentials: func(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error {
+			rotated++
+			rotatedGrace = grace
+			return nil
+		},
+		FakeExpireInstanceCredentials: func(ctx context.Context, project *model.Project, instance *model.Instance) error {
+			expired++
+			return nil
+		},
+	}
+
+	vars.KlientFactory = &testKlientFactory{client: client}
+	vars.RepositoryFactory = &testRepositoryFactory{service: service}
+
+	project, err := service.CreateProject(ctx, &model.Project{Name: "project", Network: "testnet", Rotation: &model.RotationPolicy{Interval: "1h", GracePeriod: "10m"}})
+	assert.NoError(t, err)
+	instance, err := service.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "zcash", Type: model.InstanceTypeZCASH})
+	assert.NoError(t, err)
+	_, err = service.CreateInstance(ctx, project.Id, "owner", &model.InstanceRequest{Name: "lwd", Type: model.InstanceTypeLWD})
+	assert.NoError(t, err)
+
+	scheduler := NewRotationScheduler(time.Minute)
+
+	// not yet due, only the next rotation time is recorded
+	scheduler.Run(ctx, time.Now())
+	assert.Equal(t, 0, rotated)
+	instance, _ = service.GetInstance(ctx, instance.Id)
+	assert.Equal(t, instance.CreatedAt.Add(time.Hour), *instance.Rotation.NextRotation)
+
+	scheduler.Run(ctx, time.Now().Add(2*time.Hour))
+	assert.Equal(t, 1, rotated)
+	assert.Equal(t, 10*time.Minute, rotatedGrace)
+
+	instance, _ = service.GetInstance(ctx, instance.Id)
+	assert.Len(t, instance.Rotation.History, 1)
+	assert.Equal(t, model.RotationTriggerScheduled, instance.Rotation.History[0].Trigger)
+	assert.NotNil(t, instance.Rotation.GraceUntil)
+
+	scheduler.Run(ctx, instance.Rotation.GraceUntil.Add(time.Second))
+	assert.Equal(t, 1, rotated)
+	assert.Equal(t, 1, expired)
+
+	instance, _ = service.GetInstance(ctx, instance.Id)
+	assert.Nil(t, instance.Rotation.GraceUntil)
+	assert.Equal(t, instance.Rotation.LastRotation.Add(time.Hour), *instance.Rotation.NextRotation)
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// RotationScheduler periodically rotates instance credentials according to
+// their rotation policy and retires previous credentials once their grace
+// period has ended.
+type RotationScheduler struct {
+	interval time.Duration
+	stopper  chan struct{}
+	once     sync.Once
+}
+
+func NewRotationScheduler(interval time.Duration) *RotationScheduler {
+	return &RotationScheduler{interval: interval, stopper: make(chan struct{})}
+}
+
+// Start runs the scheduler until Stop is called or ctx is done.
+func (s *RotationScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Run(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopper:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RotationScheduler) Stop() {
+	s.once.Do(func() { close(s.stopper) })
+}
+
+// Run checks every instance once.
+func (s *RotationScheduler) Run(ctx context.Context, now time.Time) {
+	log := logger.GetServiceLogger(ctx, "secrets.RotationScheduler")
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	projects, err := repository.GetProjects(ctx, "")
+	if err != nil {
+		log.Errorf("unable to list projects - %s", err)
+		return
+	}
+
+	for index := range projects {
+		project := &projects[index]
+		instances, err := repository.GetInstances(ctx, project.Id)
+		if err != nil {
+			log.WithFields(logrus.Fields{"project": project.Name}).Errorf("unable to list instances - %s", err)
+			continue
+		}
+
+		for _, instance := range instances {
+			if instance.InstanceType != model.InstanceTypeZCASH {
+				continue
+			}
+			if instance.Project == nil {
+				instance.Project = project
+			}
+			s.check(ctx, &instance, now)
+		}
+	}
+}
+
+func (s *RotationScheduler) check(ctx context.Context, instance *model.Instance, now time.Time) {
+	log := logger.GetServiceLogger(ctx, "secrets.RotationScheduler").WithFields(logrus.Fields{"project": instance.Project.Name, "instance": instance.Name})
+	defer func() {
+		if rec := recover(); rec != nil {
+			metrics.PanicsTotal.Inc("rotation")
+			log.WithFields(logrus.Fields{"stack": string(debug.Stack())}).Errorf("recovered from panic - %v", rec)
+		}
+	}()
+
+	interval, grace, err := ParseRotationPolicy(GetRotationPolicy(instance))
+	if err != nil {
+		log.Errorf("invalid rotation policy - %s", err)
+		return
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	next := NextRotation(instance, interval)
+
+	if next != nil && !now.Before(*next) {
+		log.Infof("rotating credentials due at %s", next.Format(time.RFC3339))
+		if _, err = RotateInstanceCredentials(ctx, instance, model.RotationTriggerScheduled, grace); err != nil {
+			log.Errorf("scheduled rotation failed - %s", err)
+		}
+		return
+	}
+
+	var rotation model.CredentialsRotation
+	if instance.Rotation != nil {
+		rotation = *instance.Rotation
+	}
+	changed := !sameTime(rotation.NextRotation, next)
+	rotation.NextRotation = next
+
+	if rotation.GraceUntil != nil && !now.Before(*rotation.GraceUntil) {
+		log.Infof("grace period ended at %s, retiring previous credentials", rotation.GraceUntil.Format(time.RFC3339))
+		if err = vars.KlientFactory.GetZBIClient().ExpireInstanceCredentials(ctx, instance.Project, instance); err != nil {
+			log.Errorf("unable to retire previous credentials - %s", err)
+			return
+		}
+		rotation.GraceUntil = nil
+		changed = true
+	}
+
+	if changed {
+		if err = repository.UpdateInstanceRotation(ctx, instance.Id, &rotation); err != nil {
+			log.Errorf("failed to record rotation state - %s", err)
+		}
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/pkg/errs"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+var (
+	testProject  = &model.Project{Name: "project"}
+	testInstance = &model.Instance{Name: "zcash"}
+	testKey      = []byte("0123456789abcdef0123456789abcdef")
+)
+
+func TestAESCipher(t *testing.T) {
+	cipher, err := NewAESCipher(testKey)
+	assert.NoError(t, err)
+
+	ciphertext, err := cipher.Encrypt([]byte("password"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "password")
+
+	plaintext, err := cipher.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "password", string(plaintext))
+
+	_, err = NewAESCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	cipher, _ := NewAESCipher(testKey)
+
+	provider, err := NewFileSecretProvider(path, cipher)
+	assert.NoError(t, err)
+
+	_, err = provider.GetCredentials(ctx, testProject, testInstance)
+	assert.Equal(t, errs.CredentialsNotFoundError, errs.Code(err))
+
+	credentials, err := CreateCredentials(ctx, provider, testProject, testInstance)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, credentials.Username)
+	assert.NotEmpty(t, credentials.Password)
+
+	reopened, err := NewFileSecretProvider(path, cipher)
+	assert.NoError(t, err)
+	stored, err := reopened.GetCredentials(ctx, testProject, testInstance)
+	assert.NoError(t, err)
+	assert.Equal(t, credentials, stored)
+
+	other, _ := NewAESCipher([]byte("fedcba9876543210fedcba9876543210"))
+	wrongKey, err := NewFileSecretProvider(path, other)
+	assert.NoError(t, err)
+	_, err = wrongKey.GetCredentials(ctx, testProject, testInstance)
+	assert.Error(t, err)
+
+	assert.NoError(t, reopened.DeleteCredentials(ctx, testProject, testInstance))
+	_, err = reopened.GetCredentials(ctx, testProject, testInstance)
+	assert.Equal(t, errs.CredentialsNotFoundError, errs.Code(err))
+}
+
+// newVaultServer is a minimal stand-in for the Vault KV v2 API.
+func newVaultServer(t *testing.T, token string) *httptest.Server {
+	var mu sync.Mutex
+	var store = make(map[string]json.RawMessage)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"), "/v1/secret/metadata/")
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := store[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors": []}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data": ` + string(data) + `}`))
+		case http.MethodPost:
+			var body json.RawMessage
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			store[key] = body
+			_, _ = w.Write([]byte(`{"data": {"version": 1}}`))
+		case http.MethodDelete:
+			delete(store, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	ctx := context.Background()
+	server := newVaultServer(t, "token")
+	defer server.Close()
+
+	provider := NewVaultSecretProvider(server.URL, "token", "secret", "zbi")
+
+	_, err := provider.GetCredentials(ctx, testProject, testInstance)
+	assert.Equal(t, errs.CredentialsNotFoundError, errs.Code(err))
+
+	credentials := &model.InstanceCredentials{Username: "user", Password: "secret"}
+	assert.NoError(t, provider.PutCredentials(ctx, testProject, testInstance, credentials))
+
+	stored, err := provider.GetCredentials(ctx, testProject, testInstance)
+	assert.NoError(t, err)
+	assert.Equal(t, credentials, stored)
+
+	assert.NoError(t, provider.DeleteCredentials(ctx, testProject, testInstance))
+	_, err = provider.GetCredentials(ctx, testProject, testInstance)
+	assert.Equal(t, errs.CredentialsNotFoundError, errs.Code(err))
+
+	denied := NewVaultSecretProvider(server.URL, "wrong", "secret", "zbi")
+	err = denied.PutCredentials(ctx, testProject, testInstance, credentials)
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/pkg/file_template"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const testTemplateDir = "../../../deploy/charts/zbi/zbi-templates"
+
+func renderCredentialTemplates(t *testing.T, provider interfaces.SecretProviderIF) []string {
+	tmpl := file_template.CreateFilePathTemplate("zcash", filepath.Join(testTemplateDir, "zcash_templates.tmpl"), file_template.FUNCTIONS)
+
+	credentials := &model.InstanceCredentials{
+		Username: "rpcuser",
+		Password: "s3cret-password",
+		Previous: &model.PreviousCredentials{Username: "olduser", Password: "old-s3cret-password", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+
+	spec := model.InstanceSpec{
+		Name:        testInstance.Name,
+		Namespace:   testProject.GetNamespace(),
+		Labels:      map[string]string{"instance": testInstance.Name},
+		Envoy:       model.EnvoySpec{Port: 25000},
+		Credentials: CreateCredentialsSpec(provider, testProject, testInstance, credentials),
+		Properties:  map[string]interface{}{},
+	}
+
+	specArr, err := tmpl.ExecuteTemplates([]string{"ENVOY_CONF", "CREDENTIALS", "DEPLOYMENT"}, spec)
+	assert.NoError(t, err)
+
+	_, err = helper.CreateYAMLObjects(specArr)
+	assert.NoError(t, err)
+	return specArr
+}
+
+func TestCredentialTemplates(t *testing.T) {
+	secretValues := []string{
+		"s3cret-password", "old-s3cret-password",
+		file_template.Base64EncodeString("s3cret-password"),
+		file_template.Base64EncodeString("rpcuser:s3cret-password"),
+		file_template.Base64EncodeString("olduser:old-s3cret-password"),
+	}
+
+	cipher, err := NewAESCipher(testKey)
+	assert.NoError(t, err)
+	fileProvider, err := NewFileSecretProvider(filepath.Join(t.TempDir(), "secrets.json"), cipher)
+	assert.NoError(t, err)
+	vaultProvider := NewVaultSecretProvider("http://localhost:8200", "", "secret", "zbi")
+
+	specArr := renderCredentialTemplates(t, vaultProvider)
+	rendered := strings.Join(specArr, "\n---\n")
+	for _, value := range secretValues {
+		assert.NotContains(t, rendered, value)
+	}
+
+	assert.Contains(t, specArr[1], "kind: ExternalSecret")
+	assert.Contains(t, specArr[1], "name: "+helper.CredentialsSecretName(testInstance.Name))
+	assert.Contains(t, specArr[1], "property: previous.password")
+	assert.Contains(t, specArr[1], "key: zbi/"+credentialsKey(testProject, testInstance))
+	assert.Contains(t, specArr[0], `{{ printf "%s:%s" .username .password | b64enc }}`)
+	assert.Contains(t, specArr[2], "secretName: "+helper.CredentialsSecretName(testInstance.Name))
+
+	for _, provider := range []interfaces.SecretProviderIF{fileProvider, NewKubernetesSecretProvider(nil)} {
+		specArr = renderCredentialTemplates(t, provider)
+		assert.Contains(t, specArr[0], "Basic "+file_template.Base64EncodeString("rpcuser:s3cret-password"), provider.Type())
+		assert.Contains(t, specArr[1], "kind: Secret", provider.Type())
+		assert.NotContains(t, specArr[1], "kind: ExternalSecret", provider.Type())
+		assert.Contains(t, specArr[1], "password: "+file_template.Base64EncodeString("s3cret-password"), provider.Type())
+		assert.Contains(t, specArr[2], "name: envoy-proxy-conf-"+testInstance.Name, provider.Type())
+	}
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// VaultSecretProvider keeps credentials in a Vault KV version 2 secrets
+// engine. Any server implementing the same HTTP API can be used.
+type VaultSecretProvider struct {
+	addr   string
+	token  string
+	mount  string
+	path   string
+	client *http.Client
+}
+
+type vaultData struct {
+	Data model.InstanceCredentials `json:"data"`
+}
+
+type vaultResponse struct {
+	Data   vaultData `json:"data"`
+	Errors []string  `json:"errors"`
+}
+
+func NewVaultSecretProvider(addr, token, mount, path string) interfaces.SecretProviderIF {
+	return &VaultSecretProvider{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		path:   strings.Trim(path, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (v *VaultSecretProvider) Type() string {
+	return SECRET_PROVIDER_VAULT
+}
+
+func (v *VaultSecretProvider) GetCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error) {
+	var result vaultResponse
+	status, err := v.do(ctx, http.MethodGet, v.url("data", project, instance), nil, &result)
+	if status == http.StatusNotFound {
+		return nil, notFound(err)
+	} else if err != nil {
+		return nil, err
+	}
+	return &result.Data.Data, nil
+}
+
+func (v *VaultSecretProvider) PutCredentials(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) error {
+	_, err := v.do(ctx, http.MethodPost, v.url("data", project, instance), &vaultData{Data: *credentials}, nil)
+	return err
+}
+
+func (v *VaultSecretProvider) DeleteCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	status, err := v.do(ctx, http.MethodDelete, v.url("metadata", project, instance), nil, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (v *VaultSecretProvider) url(api string, project *model.Project, instance *model.Instance) string {
+	return fmt.Sprintf("%s/v1/%s/%s/%s/%s", v.addr, v.mount, api, v.path, credentialsKey(project, instance))
+}
+
+func (v *VaultSecretProvider) do(ctx context.Context, method, url string, body, result interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure vaultResponse
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return resp.StatusCode, fmt.Errorf("vault %s %s: %d %s", method, url, resp.StatusCode, strings.Join(failure.Errors, ", "))
+	}
+
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
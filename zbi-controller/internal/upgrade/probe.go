@@ -0,0 +1,35 @@
+package upgrade
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// HeightProbe reads the block height of an instance for the health gate of
+// an upgrade.
+type HeightProbe interface {
+	GetBlockHeight(ctx context.Context, project *model.Project, instance *model.Instance) (int64, error)
+}
+
+// RPCHeightProbe reads the block height of a zcash instance with the
+// getblockcount RPC, through the instance service and with the instance
+// credentials.
+type RPCHeightProbe struct {
+	client *http.Client
+}
+
+func NewRPCHeightProbe(timeout time.Duration) *RPCHeightProbe {
+	return &RPCHeightProbe{client: &http.Client{Timeout: timeout}}
+}
+
+func (p *RPCHeightProbe) GetBlockHeight(ctx context.Context, project *model.Project, instance *model.Instance) (int64, error) {
+	var height int64
+	if err := helper.CallInstanceRPC(ctx, p.client, project, instance, "getblockcount", nil, &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
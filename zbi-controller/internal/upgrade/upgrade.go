@@ -0,0 +1,218 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+var (
+	ErrUnknownVersion    = errors.New("image version is not available")
+	ErrNoPreviousVersion = errors.New("instance has no previous image version")
+	ErrNoTargets         = errors.New("no instances to upgrade")
+	ErrUnknownInstance   = errors.New("instance is not part of the project")
+	ErrNoUpgrade         = errors.New("project has no upgrade")
+	ErrUpgradeActive     = errors.New("project upgrade is in progress")
+	ErrUpgradeNotRunning = errors.New("project upgrade is not running")
+	ErrUpgradeFinished   = errors.New("project upgrade has finished")
+)
+
+// PinInstance pins an instance that is not pinned yet to the default
+// version of its node image, so that it keeps that version when the default
+// changes.
+func PinInstance(ctx context.Context, instance *model.Instance) error {
+	if instance.Image != nil {
+		return nil
+	}
+
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		return err
+	}
+
+	pin := helper.GetImagePin(ic, instance)
+	if pin == nil {
+		return nil
+	}
+	now := time.Now()
+	pin.UpdatedAt = &now
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err = repository.UpdateInstanceImage(ctx, instance.Id, pin); err != nil {
+		return err
+	}
+	instance.Image = pin
+	return nil
+}
+
+// UpgradeInstance pins an instance to a version of its node image and
+// re-renders it. The previous pin is restored if the instance cannot be
+// re-rendered.
+func UpgradeInstance(ctx context.Context, project *model.Project, instance *model.Instance, version string) error {
+	log := logger.GetServiceLogger(ctx, "upgrade.UpgradeInstance").WithFields(logrus.Fields{"instance": instance.Name, "version": version})
+
+	ic, err := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+	if err != nil {
+		return err
+	}
+
+	name := helper.GetNodeImageName(instance.InstanceType)
+	if ic.GetImageVersion(name, version) == nil {
+		return fmt.Errorf("%w - %s %s", ErrUnknownVersion, name, version)
+	}
+
+	current := instance.Image
+	pin := helper.NewImagePin(helper.GetImagePin(ic, instance), name, version, time.Now())
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err = repository.UpdateInstanceImage(ctx, instance.Id, pin); err != nil {
+		return err
+	}
+	instance.Image = pin
+
+	zclient := vars.KlientFactory.GetZBIClient()
+	if err = zclient.RepairInstance(ctx, project, instance); err != nil {
+		log.Errorf("failed to apply image - %s", err)
+		instance.Image = current
+		if perr := repository.UpdateInstanceImage(ctx, instance.Id, current); perr != nil {
+			log.Errorf("failed to restore image pin - %s", perr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RollbackInstance returns an instance to the image version it ran before
+// its last upgrade.
+func RollbackInstance(ctx context.Context, project *model.Project, instance *model.Instance) error {
+	if instance.Image == nil || instance.Image.Previous == "" {
+		return ErrNoPreviousVersion
+	}
+	return UpgradeInstance(ctx, project, instance, instance.Image.Previous)
+}
+
+// Finished returns true if an upgrade can no longer be resumed or rolled
+// back and a new upgrade may start.
+func Finished(upgrade *model.ProjectUpgrade) bool {
+	return upgrade == nil || upgrade.Status == model.UpgradeCompleted || upgrade.Status == model.UpgradeRolledBack ||
+		upgrade.Status == model.UpgradeFailed
+}
+
+// Plan returns a rolling upgrade of the instances of a project that do not
+// run the requested version yet. Targets are upgraded in name order: the
+// first Canary instances on their own, then BatchSize instances at a time.
+func Plan(ic *model.BlockchainNodeInfo, instances []model.Instance, request *model.ProjectUpgradeRequest, user string, now time.Time) (*model.ProjectUpgrade, error) {
+	name := helper.GetNodeImageName(request.Type)
+	if ic.GetImageVersion(name, request.Version) == nil {
+		return nil, fmt.Errorf("%w - %s %s", ErrUnknownVersion, name, request.Version)
+	}
+
+	if request.HealthTimeout != "" {
+		if _, err := parseTimeout(request.HealthTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	var selected = make(map[string]bool)
+	for _, instanceName := range request.Instances {
+		selected[instanceName] = false
+	}
+
+	var targets []model.UpgradeTarget
+	for index := range instances {
+		instance := &instances[index]
+		if _, ok := selected[instance.Name]; ok {
+			selected[instance.Name] = true
+		} else if len(selected) > 0 {
+			continue
+		}
+
+		if instance.InstanceType != request.Type {
+			continue
+		}
+
+		from := helper.GetImagePin(ic, instance)
+		if from != nil && from.Version == request.Version {
+			continue
+		}
+
+		target := model.UpgradeTarget{Id: instance.Id, Name: instance.Name, To: request.Version, Status: model.UpgradePending}
+		if from != nil {
+			target.From = from.Version
+		}
+		targets = append(targets, target)
+	}
+
+	for instanceName, found := range selected {
+		if !found {
+			return nil, fmt.Errorf("%w - %s", ErrUnknownInstance, instanceName)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, ErrNoTargets
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+
+	var ids = make([]string, len(targets))
+	for index, target := range targets {
+		ids[index] = target.Id
+	}
+
+	return &model.ProjectUpgrade{
+		Type:          request.Type,
+		Image:         name,
+		Version:       request.Version,
+		Status:        model.UpgradePending,
+		Canary:        request.Canary,
+		BatchSize:     request.BatchSize,
+		HealthTimeout: request.HealthTimeout,
+		Batches:       createBatches(ids, request.Canary, request.BatchSize),
+		Targets:       targets,
+		CreatedBy:     user,
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+	}, nil
+}
+
+func createBatches(ids []string, canary, size int) [][]string {
+	if size < 1 {
+		size = 1
+	}
+
+	var batches [][]string
+	if canary > 0 {
+		if canary > len(ids) {
+			canary = len(ids)
+		}
+		batches = append(batches, ids[:canary])
+		ids = ids[canary:]
+	}
+
+	for len(ids) > 0 {
+		count := size
+		if count > len(ids) {
+			count = len(ids)
+		}
+		batches = append(batches, ids[:count])
+		ids = ids[count:]
+	}
+	return batches
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid health timeout %q", value)
+	}
+	return timeout, nil
+}
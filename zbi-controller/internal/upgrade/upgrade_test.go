@@ -0,0 +1,284 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	zklient "github.com/zbitech/controller/fake-zbi/klient/zbi-klient"
+	"github.com/zbitech/controller/internal/helper"
+	"github.com/zbitech/controller/internal/repository"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/interfaces"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+const (
+	CONFIG_DIR  = "../../../deploy/charts/zbi/zbi-conf"
+	OLD_VERSION = "v5.3.2"
+	NEW_VERSION = "v5.4.1"
+)
+
+type testKlientFactory struct {
+	interfaces.KlientFactoryIF
+	client interfaces.ZBIClientIF
+}
+
+func (f *testKlientFactory) GetZBIClient() interfaces.ZBIClientIF {
+	return f.client
+}
+
+type testRepositoryFactory struct {
+	interfaces.RepositoryServiceFactoryIF
+	service interfaces.RepositoryServiceIF
+}
+
+func (f *testRepositoryFactory) GetRepositoryService() interfaces.RepositoryServiceIF {
+	return f.service
+}
+
+// testProbe advances the block height of an instance on every read, except
+// for instances that are stalled.
+type testProbe struct {
+	mu      sync.Mutex
+	heights map[string]int64
+	stalled map[string]bool
+}
+
+func (p *testProbe) GetBlockHeight(ctx context.Context, project *model.Project, instance *model.Instance) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.stalled[instance.Name] {
+		p.heights[instance.Name]++
+	}
+	return p.heights[instance.Name], nil
+}
+
+func testNodeInfo() *model.BlockchainNodeInfo {
+	return &model.BlockchainNodeInfo{Type: "zcash", Images: []model.ImageInfo{
+		{Name: "node", Version: OLD_VERSION, Url: "electriccoinco/zcashd:" + OLD_VERSION},
+		{Name: "metrics", Version: "v0.3.6", Url: "electriccoinco/zcashd_exporter:v0.3.6"},
+		{Name: "node", Version: NEW_VERSION, Url: "electriccoinco/zcashd:" + NEW_VERSION},
+	}}
+}
+
+// setup stores a project with zcash instances in an embedded repository
+// whose configuration offers a second zcashd version, and records the images
+// applied by repairs.
+func setup(t *testing.T, names ...string) (*model.Project, []model.Instance, map[string][]string) {
+	ctx := context.Background()
+
+	var blockchains []model.BlockchainInfo
+	content, err := os.ReadFile(filepath.Join(CONFIG_DIR, "blockchains.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(content, &blockchains))
+	for index, node := range blockchains[0].Nodes {
+		if node.Type == string(model.InstanceTypeZCASH) {
+			blockchains[0].Nodes[index].Images = append(node.Images,
+				model.ImageInfo{Name: "node", Version: NEW_VERSION, Url: "electriccoinco/zcashd:" + NEW_VERSION})
+		}
+	}
+
+	configDir := t.TempDir()
+	content, err = json.Marshal(blockchains)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, "blockchains.json"), content, 0600))
+	content, err = os.ReadFile(filepath.Join(CONFIG_DIR, "policies.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(configDir, "policies.json"), content, 0600))
+
+	service, err := repository.NewEmbeddedRepositoryService("", configDir, "")
+	assert.NoError(t, err)
+	vars.RepositoryFactory = &testRepositoryFactory{service: service}
+	helper.LoadConfig(ctx)
+
+	var mu sync.Mutex
+	var applied = make(map[string][]string)
+	vars.KlientFactory = &testKlientFactory{client: zklient.FakeZBIClient{
+		FakeRepairInstance: func(ctx context.Context, project *model.Project, instance *model.Instance) error {
+			mu.Lock()
+			defer mu.Unlock()
+			ic, _ := helper.GetBlockchainNodeInfo(ctx, instance.InstanceType)
+			applied[instance.Name] = append(applied[instance.Name], helper.GetInstanceImage(ic, instance, "node"))
+			return nil
+		},
+	}}
+
+	project, err := service.CreateProject(ctx, &model.Project{Name: "dev", Owner: "alice", Network: "testnet"})
+	assert.NoError(t, err)
+
+	var instances []model.Instance
+	for _, name := range names {
+		instance, err := service.CreateInstance(ctx, project.Id, "alice", &model.InstanceRequest{Name: name, Type: model.InstanceTypeZCASH})
+		assert.NoError(t, err)
+		instances = append(instances, *instance)
+	}
+
+	return project, instances, applied
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Now()
+	ic := testNodeInfo()
+	instances := []model.Instance{
+		{Id: "4", Name: "zcash-4", InstanceType: model.InstanceTypeZCASH},
+		{Id: "1", Name: "zcash-1", InstanceType: model.InstanceTypeZCASH},
+		{Id: "3", Name: "zcash-3", InstanceType: model.InstanceTypeZCASH, Image: &model.ImagePin{Name: "node", Version: OLD_VERSION}},
+		{Id: "2", Name: "zcash-2", InstanceType: model.InstanceTypeZCASH, Image: &model.ImagePin{Name: "node", Version: NEW_VERSION}},
+		{Id: "5", Name: "lwd-1", InstanceType: model.InstanceTypeLWD},
+	}
+
+	upgrade, err := Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Canary: 1, BatchSize: 2}, "alice", now)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"1"}, {"3", "4"}}, upgrade.Batches)
+	assert.Equal(t, "node", upgrade.Image)
+	assert.Equal(t, model.UpgradePending, upgrade.Status)
+	assert.Len(t, upgrade.Targets, 3)
+	assert.Equal(t, OLD_VERSION, upgrade.GetTarget("4").From)
+	assert.Equal(t, NEW_VERSION, upgrade.GetTarget("4").To)
+	assert.Nil(t, upgrade.GetTarget("2"))
+
+	upgrade, err = Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Instances: []string{"zcash-4"}}, "alice", now)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"4"}}, upgrade.Batches)
+
+	_, err = Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: "v9.9.9"}, "alice", now)
+	assert.True(t, errors.Is(err, ErrUnknownVersion))
+
+	_, err = Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Instances: []string{"zcash-9"}}, "alice", now)
+	assert.True(t, errors.Is(err, ErrUnknownInstance))
+
+	_, err = Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Instances: []string{"zcash-2"}}, "alice", now)
+	assert.Equal(t, ErrNoTargets, err)
+
+	_, err = Plan(ic, instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, HealthTimeout: "soon"}, "alice", now)
+	assert.Error(t, err)
+}
+
+func TestCreateBatches(t *testing.T) {
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}}, createBatches([]string{"a", "b", "c"}, 0, 0))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, createBatches([]string{"a", "b", "c"}, 0, 2))
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, createBatches([]string{"a", "b", "c"}, 5, 1))
+}
+
+func TestUpgradeInstance(t *testing.T) {
+	ctx := context.Background()
+	project, instances, applied := setup(t, "zcash-1")
+	service := vars.RepositoryFactory.GetRepositoryService()
+
+	instance := &instances[0]
+	assert.NoError(t, PinInstance(ctx, instance))
+	assert.Equal(t, OLD_VERSION, instance.Image.Version)
+
+	assert.True(t, errors.Is(RollbackInstance(ctx, project, instance), ErrNoPreviousVersion))
+	assert.True(t, errors.Is(UpgradeInstance(ctx, project, instance, "v9.9.9"), ErrUnknownVersion))
+
+	assert.NoError(t, UpgradeInstance(ctx, project, instance, NEW_VERSION))
+	stored, err := service.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, NEW_VERSION, stored.Image.Version)
+	assert.Equal(t, OLD_VERSION, stored.Image.Previous)
+
+	assert.NoError(t, RollbackInstance(ctx, project, stored))
+	stored, err = service.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, OLD_VERSION, stored.Image.Version)
+	assert.Equal(t, NEW_VERSION, stored.Image.Previous)
+	assert.Equal(t, []string{"electriccoinco/zcashd:" + NEW_VERSION, "electriccoinco/zcashd:" + OLD_VERSION}, applied["zcash-1"])
+
+	// a failed repair restores the pin
+	vars.KlientFactory = &testKlientFactory{client: zklient.FakeZBIClient{
+		FakeRepairInstance: func(ctx context.Context, project *model.Project, instance *model.Instance) error {
+			return errors.New("apply failed")
+		},
+	}}
+	assert.Error(t, UpgradeInstance(ctx, project, stored, NEW_VERSION))
+	stored, err = service.GetInstance(ctx, instance.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, OLD_VERSION, stored.Image.Version)
+}
+
+func TestUpgrader(t *testing.T) {
+	ctx := context.Background()
+	project, instances, applied := setup(t, "zcash-1", "zcash-2", "zcash-3")
+	service := vars.RepositoryFactory.GetRepositoryService()
+
+	probe := &testProbe{heights: map[string]int64{"zcash-1": 100, "zcash-2": 100, "zcash-3": 100}, stalled: map[string]bool{"zcash-2": true}}
+	upgrader := NewUpgrader(time.Millisecond, 20*time.Millisecond)
+	upgrader.probes[model.InstanceTypeZCASH] = probe
+
+	plan, err := Plan(testNodeInfo(), instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Canary: 1, BatchSize: 2}, "alice", time.Now())
+	assert.NoError(t, err)
+
+	// the stalled instance fails its health gate and pauses the upgrade
+	assert.NoError(t, upgrader.Run(ctx, project, plan))
+	stored, err := service.GetProject(ctx, project.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.UpgradePaused, stored.Upgrade.Status)
+	assert.Equal(t, 1, stored.Upgrade.Batch)
+	assert.NotEmpty(t, stored.Upgrade.Error)
+	assert.Equal(t, model.UpgradeCompleted, stored.Upgrade.GetTarget(instances[0].Id).Status)
+	assert.Equal(t, model.UpgradeFailed, stored.Upgrade.GetTarget(instances[1].Id).Status)
+	assert.Equal(t, int64(101), stored.Upgrade.GetTarget(instances[0].Id).StartHeight)
+	assert.Greater(t, stored.Upgrade.GetTarget(instances[0].Id).Height, int64(101))
+	assert.False(t, upgrader.Active(project.Id))
+
+	// resuming retries the failed instance
+	probe.mu.Lock()
+	probe.stalled["zcash-2"] = false
+	probe.mu.Unlock()
+	assert.NoError(t, upgrader.Run(ctx, stored, stored.Upgrade))
+	stored, err = service.GetProject(ctx, project.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.UpgradeCompleted, stored.Upgrade.Status)
+	for _, instance := range instances {
+		upgraded, err := service.GetInstance(ctx, instance.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, NEW_VERSION, upgraded.Image.Version)
+	}
+	assert.Len(t, applied["zcash-1"], 1)
+	assert.Len(t, applied["zcash-2"], 1)
+	assert.Equal(t, ErrUpgradeFinished, upgrader.Resume(ctx, stored))
+
+	// rolling back returns every instance to its previous version
+	assert.NoError(t, upgrader.Rollback(ctx, stored))
+	stored, err = service.GetProject(ctx, project.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.UpgradeRolledBack, stored.Upgrade.Status)
+	for _, instance := range instances {
+		rolledBack, err := service.GetInstance(ctx, instance.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, OLD_VERSION, rolledBack.Image.Version)
+		assert.Equal(t, model.UpgradeRolledBack, stored.Upgrade.GetTarget(instance.Id).Status)
+	}
+	assert.Equal(t, ErrUpgradeFinished, upgrader.Rollback(ctx, stored))
+}
+
+func TestUpgraderPause(t *testing.T) {
+	ctx := context.Background()
+	project, instances, _ := setup(t, "zcash-1", "zcash-2")
+
+	upgrader := NewUpgrader(time.Millisecond, time.Second)
+	upgrader.probes[model.InstanceTypeZCASH] = &testProbe{heights: map[string]int64{}}
+	assert.Equal(t, ErrUpgradeNotRunning, upgrader.Pause(project.Id))
+
+	plan, err := Plan(testNodeInfo(), instances, &model.ProjectUpgradeRequest{Type: model.InstanceTypeZCASH, Version: NEW_VERSION, Canary: 1}, "alice", time.Now())
+	assert.NoError(t, err)
+
+	run, err := upgrader.register(ctx, project, plan)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrUpgradeActive, upgrader.Start(ctx, project, plan))
+	assert.NoError(t, upgrader.Pause(project.Id))
+
+	upgrader.execute(ctx, run)
+	upgrader.unregister(project.Id)
+	assert.Equal(t, model.UpgradePaused, plan.Status)
+	assert.Equal(t, 0, plan.Batch)
+	assert.Equal(t, model.UpgradePending, plan.Targets[0].Status)
+}
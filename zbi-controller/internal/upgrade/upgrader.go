@@ -0,0 +1,386 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zbitech/controller/internal/vars"
+	"github.com/zbitech/controller/pkg/logger"
+	"github.com/zbitech/controller/pkg/metrics"
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// Upgrader runs the rolling upgrades of projects, one at a time per
+// project. The state of an upgrade is saved on its project after every
+// step. An instance that fails to upgrade or to pass its health gate pauses
+// the upgrade, which can then be resumed or rolled back.
+type Upgrader struct {
+	probes   map[model.InstanceType]HeightProbe
+	interval time.Duration
+	timeout  time.Duration
+	mu       sync.Mutex
+	runs     map[string]*upgradeRun
+}
+
+type upgradeRun struct {
+	mu      sync.Mutex
+	project *model.Project
+	upgrade *model.ProjectUpgrade
+	pause   bool
+}
+
+// NewUpgrader returns an upgrader whose health gates poll the block height
+// every interval and fail after timeout unless the upgrade sets its own.
+// Instance types without a height probe pass the health gate once their
+// new image is applied.
+func NewUpgrader(interval, timeout time.Duration) *Upgrader {
+	return &Upgrader{
+		probes:   map[model.InstanceType]HeightProbe{model.InstanceTypeZCASH: NewRPCHeightProbe(10 * time.Second)},
+		interval: interval,
+		timeout:  timeout,
+		runs:     make(map[string]*upgradeRun),
+	}
+}
+
+// Active returns true if an upgrade of the project is running.
+func (u *Upgrader) Active(projectId string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	_, ok := u.runs[projectId]
+	return ok
+}
+
+// Start runs an upgrade in the background.
+func (u *Upgrader) Start(ctx context.Context, project *model.Project, upgrade *model.ProjectUpgrade) error {
+	run, err := u.register(ctx, project, upgrade)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer u.unregister(project.Id)
+		u.execute(context.Background(), run)
+	}()
+	return nil
+}
+
+// Run runs an upgrade until it completes or pauses.
+func (u *Upgrader) Run(ctx context.Context, project *model.Project, upgrade *model.ProjectUpgrade) error {
+	run, err := u.register(ctx, project, upgrade)
+	if err != nil {
+		return err
+	}
+	defer u.unregister(project.Id)
+
+	u.execute(ctx, run)
+	return nil
+}
+
+// Resume runs a paused upgrade in the background, from the batch it paused
+// at. An upgrade that was running when the controller stopped is resumed
+// the same way.
+func (u *Upgrader) Resume(ctx context.Context, project *model.Project) error {
+	if project.Upgrade == nil {
+		return ErrNoUpgrade
+	}
+	if Finished(project.Upgrade) || project.Upgrade.Status == model.UpgradeRollingBack {
+		return ErrUpgradeFinished
+	}
+	return u.Start(ctx, project, project.Upgrade)
+}
+
+// Pause stops a running upgrade once its current batch has passed its
+// health gates.
+func (u *Upgrader) Pause(projectId string) error {
+	u.mu.Lock()
+	run, ok := u.runs[projectId]
+	u.mu.Unlock()
+	if !ok {
+		return ErrUpgradeNotRunning
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.pause = true
+	return nil
+}
+
+// Rollback returns every instance upgraded by the upgrade of a project to
+// its previous version, last batch first.
+func (u *Upgrader) Rollback(ctx context.Context, project *model.Project) error {
+	if project.Upgrade == nil {
+		return ErrNoUpgrade
+	}
+	if project.Upgrade.Status == model.UpgradeRolledBack {
+		return ErrUpgradeFinished
+	}
+
+	run, err := u.register(ctx, project, project.Upgrade)
+	if err != nil {
+		return err
+	}
+	defer u.unregister(project.Id)
+
+	log := logger.GetServiceLogger(ctx, "upgrade.Rollback").WithFields(logrus.Fields{"project": project.Name})
+	upgrade := run.upgrade
+	run.update(ctx, func() { upgrade.Status = model.UpgradeRollingBack })
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	var failed int
+	for batch := len(upgrade.Batches) - 1; batch >= 0; batch-- {
+		for _, id := range upgrade.Batches[batch] {
+			target := upgrade.GetTarget(id)
+			if target == nil || target.Status == model.UpgradePending || target.Status == model.UpgradeRolledBack {
+				continue
+			}
+
+			err := func() error {
+				if target.From == "" {
+					return ErrNoPreviousVersion
+				}
+				instance, err := repository.GetInstance(ctx, target.Id)
+				if err != nil {
+					return err
+				}
+				if instance.Image != nil && instance.Image.Version != target.To {
+					return nil
+				}
+				return UpgradeInstance(ctx, project, instance, target.From)
+			}()
+
+			run.update(ctx, func() {
+				if err != nil {
+					failed++
+					target.Error = err.Error()
+					log.WithFields(logrus.Fields{"instance": target.Name}).Errorf("rollback failed - %s", err)
+				} else {
+					target.Status = model.UpgradeRolledBack
+					target.Error = ""
+				}
+			})
+			if err == nil {
+				u.addActivity(ctx, target.Id, model.EventActionRollback)
+			}
+		}
+	}
+
+	run.update(ctx, func() {
+		if failed > 0 {
+			upgrade.Status = model.UpgradeFailed
+			upgrade.Error = fmt.Sprintf("%d instances failed to roll back", failed)
+		} else {
+			upgrade.Status = model.UpgradeRolledBack
+			upgrade.Error = ""
+		}
+	})
+	return nil
+}
+
+func (u *Upgrader) register(ctx context.Context, project *model.Project, upgrade *model.ProjectUpgrade) (*upgradeRun, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if _, ok := u.runs[project.Id]; ok {
+		return nil, ErrUpgradeActive
+	}
+
+	run := &upgradeRun{project: project, upgrade: upgrade}
+	u.runs[project.Id] = run
+	project.Upgrade = upgrade
+	return run, nil
+}
+
+func (u *Upgrader) unregister(projectId string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.runs, projectId)
+}
+
+func (u *Upgrader) execute(ctx context.Context, run *upgradeRun) {
+	log := logger.GetServiceLogger(ctx, "upgrade.Upgrader").WithFields(logrus.Fields{"project": run.project.Name})
+	upgrade := run.upgrade
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			metrics.PanicsTotal.Inc("upgrade")
+			log.WithFields(logrus.Fields{"stack": string(debug.Stack())}).Errorf("recovered from panic - %v", rec)
+			run.update(ctx, func() {
+				upgrade.Status = model.UpgradePaused
+				upgrade.Error = fmt.Sprintf("upgrade failed - %v", rec)
+			})
+		}
+	}()
+
+	timeout := u.timeout
+	if upgrade.HealthTimeout != "" {
+		if value, err := parseTimeout(upgrade.HealthTimeout); err == nil {
+			timeout = value
+		}
+	}
+
+	run.update(ctx, func() {
+		upgrade.Status = model.UpgradeRunning
+		upgrade.Error = ""
+	})
+
+	for upgrade.Batch < len(upgrade.Batches) {
+		if run.paused() {
+			log.Infof("upgrade paused at batch %d", upgrade.Batch)
+			run.update(ctx, func() { upgrade.Status = model.UpgradePaused })
+			return
+		}
+
+		var wg sync.WaitGroup
+		var failures []string
+		for _, id := range upgrade.Batches[upgrade.Batch] {
+			target := upgrade.GetTarget(id)
+			if target == nil || target.Status == model.UpgradeCompleted {
+				continue
+			}
+
+			wg.Add(1)
+			go func(target *model.UpgradeTarget) {
+				defer wg.Done()
+				if err := u.upgradeTarget(ctx, run, target, timeout); err != nil {
+					log.WithFields(logrus.Fields{"instance": target.Name}).Errorf("upgrade failed - %s", err)
+					run.update(ctx, func() { failures = append(failures, target.Name) })
+				}
+			}(target)
+		}
+		wg.Wait()
+
+		if len(failures) > 0 {
+			run.update(ctx, func() {
+				upgrade.Status = model.UpgradePaused
+				upgrade.Error = fmt.Sprintf("batch %d failed for %v", upgrade.Batch, failures)
+			})
+			return
+		}
+
+		run.update(ctx, func() { upgrade.Batch++ })
+	}
+
+	log.Infof("upgrade completed")
+	run.update(ctx, func() { upgrade.Status = model.UpgradeCompleted })
+}
+
+// upgradeTarget upgrades an instance and waits for its block height to
+// advance past the height it had before the upgrade.
+func (u *Upgrader) upgradeTarget(ctx context.Context, run *upgradeRun, target *model.UpgradeTarget, timeout time.Duration) error {
+	fail := func(err error) error {
+		run.update(ctx, func() {
+			target.Status = model.UpgradeFailed
+			target.Error = err.Error()
+		})
+		return err
+	}
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	instance, err := repository.GetInstance(ctx, target.Id)
+	if err != nil {
+		return fail(err)
+	}
+
+	run.update(ctx, func() {
+		target.Status = model.UpgradeRunning
+		target.Error = ""
+	})
+
+	probe := u.probes[instance.InstanceType]
+	if probe != nil && target.StartHeight == 0 {
+		if height, err := probe.GetBlockHeight(ctx, run.project, instance); err == nil {
+			run.update(ctx, func() { target.StartHeight = height })
+		}
+	}
+
+	if instance.Image == nil || instance.Image.Version != target.To {
+		if err = UpgradeInstance(ctx, run.project, instance, target.To); err != nil {
+			return fail(err)
+		}
+	}
+
+	if probe != nil {
+		height, err := u.waitForHeight(ctx, probe, run.project, instance, target.StartHeight, timeout)
+		run.update(ctx, func() { target.Height = height })
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	run.update(ctx, func() { target.Status = model.UpgradeCompleted })
+	u.addActivity(ctx, target.Id, model.EventActionUpgrade)
+	return nil
+}
+
+// waitForHeight polls the block height of an instance until it is above
+// start. When start is not known, the first height read is the baseline.
+func (u *Upgrader) waitForHeight(ctx context.Context, probe HeightProbe, project *model.Project, instance *model.Instance,
+	start int64, timeout time.Duration) (int64, error) {
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	var height, baseline = int64(0), start
+	var lastErr error
+	for {
+		value, err := probe.GetBlockHeight(ctx, project, instance)
+		if err == nil {
+			height, lastErr = value, nil
+			if baseline == 0 {
+				baseline = value
+			} else if value > baseline {
+				return height, nil
+			}
+		} else {
+			lastErr = err
+		}
+
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return height, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	if lastErr != nil {
+		return height, fmt.Errorf("block height unavailable after %s - %s", timeout, lastErr)
+	}
+	return height, fmt.Errorf("block height did not advance past %d within %s", baseline, timeout)
+}
+
+func (u *Upgrader) addActivity(ctx context.Context, instanceId string, action model.EventAction) {
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.AddInstanceActivity(ctx, instanceId, action); err != nil {
+		logger.GetLogger(ctx).Errorf("failed to add %s activity for instance %s - %s", action, instanceId, err)
+	}
+}
+
+func (run *upgradeRun) paused() bool {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.pause
+}
+
+// update changes the upgrade and saves it on the project.
+func (run *upgradeRun) update(ctx context.Context, change func()) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	change()
+	now := time.Now()
+	run.upgrade.UpdatedAt = &now
+
+	repository := vars.RepositoryFactory.GetRepositoryService()
+	if err := repository.UpdateProjectUpgrade(ctx, run.project.Id, run.upgrade); err != nil {
+		logger.GetLogger(ctx).Errorf("failed to save upgrade of project %s - %s", run.project.Id, err)
+	}
+}
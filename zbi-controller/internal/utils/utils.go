@@ -3,11 +3,13 @@ package utils
 import (
 	b64 "encoding/base64"
 	"encoding/json"
+	"fmt"
 	"github.com/sethvargo/go-password/password"
 	"github.com/zbitech/controller/pkg/model"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func GetEnv(key, fallback string) string {
@@ -126,3 +128,21 @@ func ResourceObjectType(value string) model.ResourceObjectType {
 
 	return ""
 }
+
+// ParseDuration parses a duration such as 12h, extended with a d suffix for
+// whole days, e.g. 30d.
+func ParseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		count, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
@@ -1,7 +1,27 @@
 package utils
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func Test_MarshalObject(t *testing.T) {
 
 }
+
+func Test_ParseDuration(t *testing.T) {
+	duration, err := ParseDuration("30d")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, duration)
+
+	duration, err = ParseDuration("90m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, duration)
+
+	for _, value := range []string{"", "d", "-1d", "1.5d", "-2h", "month"} {
+		_, err = ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -9,20 +9,51 @@ import (
 )
 
 var (
-	CTX                        context.Context
-	ASSET_PATH_DIRECTORY       = utils.GetEnv("ASSET_PATH_DIRECTORY", "tests/files/etc/zbi")
-	KUBECONFIG                 = utils.GetEnv("KUBECONFIG", "cfg/kubeconfig")
-	ZBI_LOG_LEVEL              = utils.GetIntEnv("ZBI_LOG_LEVEL", 0)
-	USE_KUBERNETES_CONFIG, _   = strconv.ParseBool(utils.GetEnv("USEKUBERNETESCONFIG", "false"))
-	CONTROLLER_METRICS, _      = strconv.ParseBool(utils.GetEnv("METRICS", "false"))
-	K8S_INCLUSTER              = true
-	EXPIRATION_HOURS, _        = strconv.Atoi(utils.GetEnv("EXPIRATION_HOURS", "8760"))
-	ZBI_NAMESPACE              = utils.GetEnv("ZBI_NAMESPACE", "")
-	ZBI_INTERNAL_CLIENT_SECRET = utils.GetEnv("ZBI_INTERNAL_CLIENT_SECRET", "zbi-internal-client")
-	ZBI_REPOSITORY_URL         = utils.GetEnv("ZBI_REPOSITORY_URL", "http://localhost:4000/api")
-	HOURS_IN_YEAR              = 8760
+	CTX                              context.Context
+	ASSET_PATH_DIRECTORY             = utils.GetEnv("ASSET_PATH_DIRECTORY", "tests/files/etc/zbi")
+	KUBECONFIG                       = utils.GetEnv("KUBECONFIG", "cfg/kubeconfig")
+	ZBI_LOG_LEVEL                    = utils.GetIntEnv("ZBI_LOG_LEVEL", 0)
+	USE_KUBERNETES_CONFIG, _         = strconv.ParseBool(utils.GetEnv("USEKUBERNETESCONFIG", "false"))
+	CONTROLLER_METRICS, _            = strconv.ParseBool(utils.GetEnv("METRICS", "false"))
+	K8S_INCLUSTER                    = true
+	EXPIRATION_HOURS, _              = strconv.Atoi(utils.GetEnv("EXPIRATION_HOURS", "8760"))
+	ZBI_NAMESPACE                    = utils.GetEnv("ZBI_NAMESPACE", "")
+	ZBI_INTERNAL_CLIENT_SECRET       = utils.GetEnv("ZBI_INTERNAL_CLIENT_SECRET", "zbi-internal-client")
+	ZBI_REPOSITORY_URL               = utils.GetEnv("ZBI_REPOSITORY_URL", "http://localhost:4000/api")
+	ZBI_REPOSITORY_TYPE              = utils.GetEnv("ZBI_REPOSITORY_TYPE", "rest")
+	ZBI_REPOSITORY_TIMEOUT           = utils.GetIntEnv("ZBI_REPOSITORY_TIMEOUT", 10)
+	ZBI_REPOSITORY_RETRIES           = utils.GetIntEnv("ZBI_REPOSITORY_RETRIES", 3)
+	ZBI_REPOSITORY_BREAKER_THRESHOLD = utils.GetIntEnv("ZBI_REPOSITORY_BREAKER_THRESHOLD", 5)
+	ZBI_REPOSITORY_BREAKER_TIMEOUT   = utils.GetIntEnv("ZBI_REPOSITORY_BREAKER_TIMEOUT", 30)
+	ZBI_OUTBOX_PATH                  = utils.GetEnv("ZBI_OUTBOX_PATH", "/var/lib/zbi/outbox.json")
+	ZBI_OUTBOX_INTERVAL              = utils.GetIntEnv("ZBI_OUTBOX_INTERVAL", 5)
+	ZBI_EMBEDDED_DB_PATH             = utils.GetEnv("ZBI_EMBEDDED_DB_PATH", "")
+	ZBI_CONFIG_DIRECTORY             = utils.GetEnv("ZBI_CONFIG_DIRECTORY", "/etc/zbi/data")
+	ZBI_TEMPLATE_DIRECTORY           = utils.GetEnv("ZBI_TEMPLATE_DIRECTORY", "/etc/zbi/templates")
+	ZBI_SECRET_PROVIDER              = utils.GetEnv("ZBI_SECRET_PROVIDER", "kubernetes")
+	ZBI_SECRET_FILE_PATH             = utils.GetEnv("ZBI_SECRET_FILE_PATH", "/var/lib/zbi/secrets.json")
+	ZBI_SECRET_KEY_FILE              = utils.GetEnv("ZBI_SECRET_KEY_FILE", "/etc/zbi/secrets/secret.key")
+	ZBI_VAULT_ADDR                   = utils.GetEnv("ZBI_VAULT_ADDR", "http://localhost:8200")
+	ZBI_VAULT_TOKEN                  = utils.GetEnv("ZBI_VAULT_TOKEN", "")
+	ZBI_VAULT_MOUNT                  = utils.GetEnv("ZBI_VAULT_MOUNT", "secret")
+	ZBI_VAULT_PATH                   = utils.GetEnv("ZBI_VAULT_PATH", "zbi")
+	ZBI_SECRET_STORE                 = utils.GetEnv("ZBI_SECRET_STORE", "zbi-secrets")
+	ZBI_ROTATION_CHECK_INTERVAL      = utils.GetIntEnv("ZBI_ROTATION_CHECK_INTERVAL", 300)
+	ZBI_AUTHZ_PORT                   = utils.GetIntEnv("ZBI_AUTHZ_PORT", 0)
+	ZBI_AUTHZ_CACHE_TTL              = utils.GetIntEnv("ZBI_AUTHZ_CACHE_TTL", 30)
+	ZBI_AUDIT_SINKS                  = utils.GetEnv("ZBI_AUDIT_SINKS", "repository")
+	ZBI_AUDIT_FILE_PATH              = utils.GetEnv("ZBI_AUDIT_FILE_PATH", "/var/lib/zbi/audit.jsonl")
+	ZBI_AUDIT_WEBHOOK_URL            = utils.GetEnv("ZBI_AUDIT_WEBHOOK_URL", "")
+	ZBI_AUDIT_WEBHOOK_TIMEOUT        = utils.GetIntEnv("ZBI_AUDIT_WEBHOOK_TIMEOUT", 5)
+	ZBI_BULK_CONCURRENCY             = utils.GetIntEnv("ZBI_BULK_CONCURRENCY", 4)
+	ZBI_SCHEDULE_CHECK_INTERVAL      = utils.GetIntEnv("ZBI_SCHEDULE_CHECK_INTERVAL", 60)
+	ZBI_SCHEDULE_CATCHUP             = utils.GetIntEnv("ZBI_SCHEDULE_CATCHUP", 24)
+	ZBI_UPGRADE_HEALTH_INTERVAL      = utils.GetIntEnv("ZBI_UPGRADE_HEALTH_INTERVAL", 15)
+	ZBI_UPGRADE_HEALTH_TIMEOUT       = utils.GetIntEnv("ZBI_UPGRADE_HEALTH_TIMEOUT", 900)
+	HOURS_IN_YEAR                    = 8760
 
 	KlientFactory     interfaces.KlientFactoryIF
 	ManagerFactory    interfaces.ResourceManagerFactoryIF
 	RepositoryFactory interfaces.RepositoryServiceFactoryIF
+	AuditService      interfaces.AuditServiceIF
 )
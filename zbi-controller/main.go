@@ -6,12 +6,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/zbitech/controller/app/service-api/http"
 	"github.com/zbitech/controller/app/service-api/server"
+	"github.com/zbitech/controller/internal/audit"
+	"github.com/zbitech/controller/internal/authz"
 	"github.com/zbitech/controller/internal/klient"
 	"github.com/zbitech/controller/internal/manager"
 	"github.com/zbitech/controller/internal/repository"
+	"github.com/zbitech/controller/internal/schedule"
+	"github.com/zbitech/controller/internal/secrets"
 	"github.com/zbitech/controller/internal/vars"
 	"github.com/zbitech/controller/pkg/logger"
 )
@@ -37,6 +42,13 @@ func main() {
 	vars.RepositoryFactory = repository.NewRepositoryFactory()
 
 	vars.RepositoryFactory.Init(ctx)
+
+	auditService, err := audit.NewAuditService(ctx, vars.RepositoryFactory.GetRepositoryService())
+	if err != nil {
+		log.Fatalf("failed to create audit service - %s", err)
+	}
+	vars.AuditService = auditService
+
 	vars.ManagerFactory.Init(ctx)
 	vars.KlientFactory.Init(ctx, vars.RepositoryFactory.GetRepositoryService())
 
@@ -46,12 +58,32 @@ func main() {
 	log.Info("starting http server")
 	go svr.Run(ctx)
 
+	go vars.RepositoryFactory.GetStatusOutbox().Start(ctx)
 	go vars.KlientFactory.StartMonitor(ctx)
 
+	rotation := secrets.NewRotationScheduler(time.Duration(vars.ZBI_ROTATION_CHECK_INTERVAL) * time.Second)
+	go rotation.Start(ctx)
+
+	power := schedule.NewPowerScheduler(time.Duration(vars.ZBI_SCHEDULE_CHECK_INTERVAL)*time.Second,
+		time.Duration(vars.ZBI_SCHEDULE_CATCHUP)*time.Hour, vars.ZBI_BULK_CONCURRENCY)
+	go power.Start(ctx)
+
+	var authzServer *authz.Server
+	if vars.ZBI_AUTHZ_PORT > 0 {
+		authzServer = authz.NewServer(vars.RepositoryFactory.GetRepositoryService(), time.Duration(vars.ZBI_AUTHZ_CACHE_TTL)*time.Second)
+		go authzServer.ListenAndServe(ctx, vars.ZBI_AUTHZ_PORT)
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	sign := <-quit
 
+	rotation.Stop()
+	power.Stop()
+	if authzServer != nil {
+		authzServer.Stop(ctx)
+	}
 	vars.KlientFactory.StopMonitor(ctx)
+	vars.RepositoryFactory.GetStatusOutbox().Stop()
 	log.Infof("Shutting down server. signal: %s", sign.String())
 }
@@ -0,0 +1,171 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type Category string
+
+const (
+	CategoryNotFound     Category = "not_found"
+	CategoryConflict     Category = "conflict"
+	CategoryValidation   Category = "validation"
+	CategoryInvalidState Category = "invalid_state"
+	CategoryQuota        Category = "quota"
+	CategoryKubernetes   Category = "upstream_kubernetes"
+	CategoryRepository   Category = "upstream_repository"
+	CategoryNode         Category = "upstream_node"
+	CategoryInternal     Category = "internal"
+)
+
+// ErrorCode identifies a class of failure. Code is the machine readable value
+// returned to API clients.
+type ErrorCode struct {
+	Code     string
+	Category Category
+	Message  string
+}
+
+var (
+	NotFoundError            = ErrorCode{"NOT_FOUND", CategoryNotFound, "resource not found"}
+	ProjectNotFoundError     = ErrorCode{"PROJECT_NOT_FOUND", CategoryNotFound, "project not found"}
+	InstanceNotFoundError    = ErrorCode{"INSTANCE_NOT_FOUND", CategoryNotFound, "instance not found"}
+	CredentialsNotFoundError = ErrorCode{"CREDENTIALS_NOT_FOUND", CategoryNotFound, "instance credentials not found"}
+	ConflictError            = ErrorCode{"CONFLICT", CategoryConflict, "resource conflict"}
+	ValidationError          = ErrorCode{"VALIDATION_ERROR", CategoryValidation, "invalid request"}
+	InvalidPeerError         = ErrorCode{"INVALID_PEER", CategoryValidation, "invalid instance peers"}
+	InstanceTypeError        = ErrorCode{"INVALID_INSTANCE_TYPE", CategoryValidation, "unsupported instance type"}
+	InvalidStateError        = ErrorCode{"INVALID_STATE", CategoryInvalidState, "operation not allowed in current state"}
+	InstanceNotActiveError   = ErrorCode{"INSTANCE_NOT_ACTIVE", CategoryInvalidState, "instance is not active"}
+	QuotaExceededError       = ErrorCode{"QUOTA_EXCEEDED", CategoryQuota, "quota exceeded"}
+	KubernetesError          = ErrorCode{"KUBERNETES_ERROR", CategoryKubernetes, "kubernetes request failed"}
+	RepositoryError          = ErrorCode{"REPOSITORY_ERROR", CategoryRepository, "repository request failed"}
+	NodeError                = ErrorCode{"NODE_ERROR", CategoryNode, "node request failed"}
+	ResourceRetrievalError   = ErrorCode{"RESOURCE_RETRIEVAL_ERROR", CategoryInternal, "unable to retrieve resource configuration"}
+	ResourceGenerationError  = ErrorCode{"RESOURCE_GENERATION_ERROR", CategoryInternal, "unable to generate resources"}
+	MarshalError             = ErrorCode{"MARSHAL_ERROR", CategoryInternal, "unable to marshal resource"}
+	InternalError            = ErrorCode{"INTERNAL_ERROR", CategoryInternal, "internal server error"}
+)
+
+// Coder is implemented by errors that carry an ErrorCode, such as
+// ApplicationError and repository.RepositoryError.
+type Coder interface {
+	ErrorCode() ErrorCode
+}
+
+// ClientMessager is implemented by errors that provide their own message
+// for API clients.
+type ClientMessager interface {
+	ClientMessage() string
+}
+
+type ApplicationError struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func NewApplicationError(code ErrorCode, err error) *ApplicationError {
+	return &ApplicationError{Code: code, Message: code.Message, Err: err}
+}
+
+// New creates an ApplicationError with a message specific to the failure.
+func New(code ErrorCode, message string) *ApplicationError {
+	return &ApplicationError{Code: code, Message: message}
+}
+
+func (e *ApplicationError) Error() string {
+	if e.Err != nil {
+		return e.Message + " - " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *ApplicationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ApplicationError) ErrorCode() ErrorCode {
+	return e.Code
+}
+
+// Is reports whether target is an ApplicationError with the same code.
+func (e *ApplicationError) Is(target error) bool {
+	t, ok := target.(*ApplicationError)
+	return ok && t.Code == e.Code
+}
+
+// NewKubernetesError classifies an error from the Kubernetes API. Errors
+// that are already ApplicationErrors are returned unchanged.
+func NewKubernetesError(err error) *ApplicationError {
+	var appErr *ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	switch {
+	case apierrors.IsNotFound(err):
+		return NewApplicationError(NotFoundError, err)
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		return NewApplicationError(ConflictError, err)
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return NewApplicationError(ValidationError, err)
+	}
+	return NewApplicationError(KubernetesError, err)
+}
+
+// Code returns the ErrorCode for err, classifying errors that do not carry one.
+func Code(err error) ErrorCode {
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return NewKubernetesError(err).Code
+	}
+
+	return InternalError
+}
+
+// Message returns a client safe message for err. Internal errors are not
+// described beyond their code.
+func Message(err error) string {
+	code := Code(err)
+	if code.Category == CategoryInternal {
+		return code.Message
+	}
+
+	var appErr *ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+
+	var messager ClientMessager
+	if errors.As(err, &messager) {
+		return messager.ClientMessage()
+	}
+
+	return err.Error()
+}
+
+// HTTPStatus maps an error category to the status code returned by the API.
+func HTTPStatus(category Category) int {
+	switch category {
+	case CategoryNotFound:
+		return http.StatusNotFound
+	case CategoryConflict, CategoryInvalidState:
+		return http.StatusConflict
+	case CategoryValidation:
+		return http.StatusUnprocessableEntity
+	case CategoryQuota:
+		return http.StatusForbidden
+	case CategoryKubernetes, CategoryRepository, CategoryNode:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,47 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestErrs_Code(t *testing.T) {
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, "node")
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "deployments"}, "node", errors.New("modified"))
+
+	assert.Equal(t, NotFoundError, Code(notFound))
+	assert.Equal(t, ConflictError, Code(fmt.Errorf("apply failed - %w", conflict)))
+	assert.Equal(t, KubernetesError, Code(NewKubernetesError(apierrors.NewInternalError(errors.New("etcd")))))
+	assert.Equal(t, InstanceNotActiveError, Code(NewApplicationError(InstanceNotActiveError, nil)))
+	assert.Equal(t, InternalError, Code(errors.New("template failed")))
+}
+
+func TestErrs_Message(t *testing.T) {
+	assert.Equal(t, "instance is not active", Message(NewApplicationError(InstanceNotActiveError, nil)))
+	assert.Equal(t, InternalError.Message, Message(errors.New("secret detail")))
+	assert.Equal(t, ResourceGenerationError.Message, Message(NewApplicationError(ResourceGenerationError, errors.New("secret detail"))))
+}
+
+func TestErrs_HTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, HTTPStatus(CategoryNotFound))
+	assert.Equal(t, http.StatusConflict, HTTPStatus(CategoryConflict))
+	assert.Equal(t, http.StatusConflict, HTTPStatus(CategoryInvalidState))
+	assert.Equal(t, http.StatusUnprocessableEntity, HTTPStatus(CategoryValidation))
+	assert.Equal(t, http.StatusForbidden, HTTPStatus(CategoryQuota))
+	assert.Equal(t, http.StatusBadGateway, HTTPStatus(CategoryKubernetes))
+	assert.Equal(t, http.StatusBadGateway, HTTPStatus(CategoryRepository))
+	assert.Equal(t, http.StatusBadGateway, HTTPStatus(CategoryNode))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(CategoryInternal))
+}
+
+func TestApplicationError_Is(t *testing.T) {
+	err := fmt.Errorf("wrapped - %w", NewApplicationError(InstanceNotActiveError, nil))
+	assert.True(t, errors.Is(err, NewApplicationError(InstanceNotActiveError, nil)))
+	assert.False(t, errors.Is(err, NewApplicationError(NotFoundError, nil)))
+}
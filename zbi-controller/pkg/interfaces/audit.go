@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// AuditSinkIF receives audit records. Records are written to every configured
+// sink; a failing sink does not stop the others.
+type AuditSinkIF interface {
+	Name() string
+	Write(ctx context.Context, record *model.AuditRecord) error
+}
+
+// AuditServiceIF records mutating API requests and cluster operations. Fields
+// of the record left empty are filled from the request context.
+type AuditServiceIF interface {
+	Record(ctx context.Context, record *model.AuditRecord)
+}
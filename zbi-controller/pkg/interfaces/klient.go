@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/zbitech/controller/pkg/model"
 	appsv1 "k8s.io/api/apps/v1"
@@ -16,6 +17,7 @@ import (
 type KlientFactoryIF interface {
 	Init(ctx context.Context, repoSvc RepositoryServiceIF) error
 	GetZBIClient() ZBIClientIF
+	GetSecretProvider() SecretProviderIF
 	StartMonitor(ctx context.Context)
 	StopMonitor(ctx context.Context)
 }
@@ -31,6 +33,7 @@ type ZBIClientIF interface {
 	//GetProject(ctx context.Context, project string) (*model.Project, error)
 	CreateProject(ctx context.Context, project *model.Project) error
 	RepairProject(ctx context.Context, project *model.Project) error
+	UpdateProject(ctx context.Context, project *model.Project) error
 	DeleteProject(ctx context.Context, project *model.Project, instances []model.Instance) error
 	//GetProjectResources(ctx context.Context, project string) ([]model.KubernetesResource, error)
 	//GetProjectResource(ctx context.Context, project, resourceName string, resourceType model.ResourceObjectType) (*model.KubernetesResource, error)
@@ -47,7 +50,13 @@ type ZBIClientIF interface {
 	RepairInstance(ctx context.Context, project *model.Project, instance *model.Instance) error
 	StopInstance(ctx context.Context, project *model.Project, instance *model.Instance) error
 	StartInstance(ctx context.Context, project *model.Project, instance *model.Instance) error
-	RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error
+	RotateInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance, grace time.Duration) error
+	ExpireInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error
+	GetInstanceCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error)
+	UpdateDomain(ctx context.Context, project *model.Project, instance *model.Instance, domain *model.Domain, certificate, privateKey string) error
+	DeleteDomain(ctx context.Context, project *model.Project, instance *model.Instance) error
+	UpdateInstanceIngress(ctx context.Context, project *model.Project, instance *model.Instance) error
+	GetNetworkPolicies(ctx context.Context, project *model.Project, instance *model.Instance) (*model.NetworkPolicies, error)
 	CreateSnapshot(ctx context.Context, project *model.Project, instance *model.Instance) error
 	CreateSnapshotSchedule(ctx context.Context, project *model.Project, instance *model.Instance, schedule model.SnapshotScheduleType) error
 }
@@ -16,19 +16,64 @@ type RepositoryServiceIF interface {
 
 	CreateProject(ctx context.Context, project *model.Project) (*model.Project, error)
 	GetProject(ctx context.Context, project string) (*model.Project, error)
+	UpdateProject(ctx context.Context, projectId string, request *model.ProjectUpdateRequest) (*model.Project, error)
 	GetProjects(ctx context.Context, owner string) ([]model.Project, error)
+	ListProjects(ctx context.Context, query *model.ListQuery) (*model.ProjectList, error)
+
+	GetTeamProjects(ctx context.Context, teamId string) ([]model.Project, error)
+	CountTeamInstances(ctx context.Context, teamId string) (int, error)
+
+	CreateTeam(ctx context.Context, team *model.Team) (*model.Team, error)
+	GetTeam(ctx context.Context, teamId string) (*model.Team, error)
+	GetTeams(ctx context.Context, member string) ([]model.Team, error)
+	UpdateTeamMember(ctx context.Context, teamId string, member *model.TeamMember) error
+	RemoveTeamMember(ctx context.Context, teamId, userid string) error
+	UpdateTeamQuota(ctx context.Context, teamId string, quota *model.TeamQuota) error
+
+	CreateSeed(ctx context.Context, seed *model.Seed) (*model.Seed, error)
+	GetSeed(ctx context.Context, seedId string) (*model.Seed, error)
+	GetSeeds(ctx context.Context, network string) ([]model.Seed, error)
+	UpdateSeed(ctx context.Context, seedId string, seed *model.Seed) (*model.Seed, error)
+	DeleteSeed(ctx context.Context, seedId string) error
 
 	CreateInstance(ctx context.Context, projectId, owner string, request *model.InstanceRequest) (*model.Instance, error)
 	UpdateInstance(ctx context.Context, instanceId string, request *model.InstanceRequest) (*model.Instance, error)
+	UpdateInstanceRotation(ctx context.Context, instanceId string, rotation *model.CredentialsRotation) error
+	UpdateProjectDomain(ctx context.Context, projectId string, domain *model.Domain) error
+	UpdateInstanceDomain(ctx context.Context, instanceId string, domain *model.Domain) error
+	UpdateInstanceAllowList(ctx context.Context, instanceId string, allowList []string) error
+	UpdateProjectSchedule(ctx context.Context, projectId string, schedule *model.PowerSchedule) error
+	UpdateInstanceSchedule(ctx context.Context, instanceId string, schedule *model.PowerSchedule) error
+	UpdateInstanceHold(ctx context.Context, instanceId string, hold *model.ScheduleHold) error
+	UpdateInstancePower(ctx context.Context, instanceId string, state *model.PowerState) error
+	UpdateInstanceImage(ctx context.Context, instanceId string, image *model.ImagePin) error
+	UpdateProjectUpgrade(ctx context.Context, projectId string, upgrade *model.ProjectUpgrade) error
 
 	GetInstance(ctx context.Context, instance string) (*model.Instance, error)
 	GetInstances(ctx context.Context, project string) ([]model.Instance, error)
+	ListInstances(ctx context.Context, projectId string, query *model.ListQuery) (*model.InstanceList, error)
+
+	CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	GetAPIKeys(ctx context.Context, instanceId string) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
 
 	AddProjectActivity(ctx context.Context, project string, op model.EventAction) error
 	AddInstanceActivity(ctx context.Context, instance string, op model.EventAction) error
+
+	AddAuditRecord(ctx context.Context, record *model.AuditRecord) error
+	GetAuditRecords(ctx context.Context, query *model.AuditQuery) ([]model.AuditRecord, error)
+}
+
+type StatusOutboxIF interface {
+	AddProjectResource(ctx context.Context, project string, resource *model.KubernetesResource) error
+	AddInstanceResource(ctx context.Context, instance string, resource *model.KubernetesResource) error
+	Start(ctx context.Context)
+	Stop()
 }
 
 type RepositoryServiceFactoryIF interface {
 	Init(ctx context.Context)
 	GetRepositoryService() RepositoryServiceIF
+	GetStatusOutbox() StatusOutboxIF
 }
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/zbitech/controller/pkg/model"
+)
+
+// SecretProviderIF stores instance credentials. The in-cluster provider keeps
+// them in the instance Secret; other providers keep them in an external store
+// and the Secret is rendered from the provider's copy.
+type SecretProviderIF interface {
+	Type() string
+	GetCredentials(ctx context.Context, project *model.Project, instance *model.Instance) (*model.InstanceCredentials, error)
+	PutCredentials(ctx context.Context, project *model.Project, instance *model.Instance, credentials *model.InstanceCredentials) error
+	DeleteCredentials(ctx context.Context, project *model.Project, instance *model.Instance) error
+}
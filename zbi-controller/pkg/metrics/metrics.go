@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	PanicsTotal = NewCounter("zbi_panics_total", "Number of recovered panics.", "component")
+)
+
+var registry = struct {
+	sync.Mutex
+	counters []*Counter
+}{}
+
+// Counter is a monotonically increasing value partitioned by label values.
+type Counter struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+// NewCounter creates a counter and registers it for export.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.counters = append(registry.counters, c)
+
+	return c
+}
+
+// Inc adds one to the counter for the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[c.key(values)] += delta
+}
+
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[c.key(values)]
+}
+
+func (c *Counter) key(values []string) string {
+	var pairs = make([]string, 0, len(c.labels))
+	for index, label := range c.labels {
+		var value string
+		if index < len(values) {
+			value = values[index]
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label, value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (c *Counter) write(w http.ResponseWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "" {
+			fmt.Fprintf(w, "%s %g\n", c.name, c.values[key])
+		} else {
+			fmt.Fprintf(w, "%s{%s} %g\n", c.name, key, c.values[key])
+		}
+	}
+}
+
+// Handler writes registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		registry.Lock()
+		defer registry.Unlock()
+		for _, c := range registry.counters {
+			c.write(w)
+		}
+	})
+}
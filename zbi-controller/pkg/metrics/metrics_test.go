@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter_Inc(t *testing.T) {
+	counter := NewCounter("test_counter_total", "Test counter.", "component")
+	counter.Inc("http")
+	counter.Inc("http")
+	counter.Add(3, "monitor")
+
+	assert.Equal(t, float64(2), counter.Value("http"))
+	assert.Equal(t, float64(3), counter.Value("monitor"))
+	assert.Equal(t, float64(0), counter.Value("other"))
+}
+
+func TestHandler(t *testing.T) {
+	counter := NewCounter("test_handler_total", "Handler counter.", "component", "code")
+	counter.Inc("http", "500")
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "# TYPE test_handler_total counter")
+	assert.Contains(t, recorder.Body.String(), `test_handler_total{component="http",code="500"} 1`)
+}
@@ -71,6 +71,10 @@ func CreateResourceArray(resources *KubernetesResources) []KubernetesResource {
 		array = append(array, *resources.Httpproxy)
 	}
 
+	if resources.P2p != nil {
+		array = append(array, *resources.P2p)
+	}
+
 	return array
 }
 
@@ -221,10 +225,31 @@ func (project *Project) GetResourceArray() []KubernetesResource {
 // 	}
 // }
 
+// GetImage returns the default version of an image.
 func (node *BlockchainNodeInfo) GetImage(name string) *ImageInfo {
-	for _, image := range node.Images {
-		if image.Name == name {
-			return &image
+	var found *ImageInfo
+	for index := range node.Images {
+		image := &node.Images[index]
+		if image.Name != name {
+			continue
+		}
+		if image.Default {
+			return image
+		}
+		if found == nil {
+			found = image
+		}
+	}
+
+	return found
+}
+
+// GetImageVersion returns a version of an image, or nil if it is not
+// available.
+func (node *BlockchainNodeInfo) GetImageVersion(name, version string) *ImageInfo {
+	for index := range node.Images {
+		if node.Images[index].Name == name && node.Images[index].Version == version {
+			return &node.Images[index]
 		}
 	}
 
@@ -232,10 +257,8 @@ func (node *BlockchainNodeInfo) GetImage(name string) *ImageInfo {
 }
 
 func (node *BlockchainNodeInfo) GetImageRepository(name string) string {
-	for _, image := range node.Images {
-		if image.Name == name {
-			return image.Url
-		}
+	if image := node.GetImage(name); image != nil {
+		return image.Url
 	}
 
 	return ""
@@ -36,18 +36,39 @@ type SnapshotScheduleRequest struct {
 
 type Project struct {
 	Id          string               `json:"id"`
-	Name        string               `json:"name"`
+	Name        string               `json:"name" validate:"required,dnslabel"`
 	Owner       string               `json:"owner"`
-	Blockchain  string               `json:"blockchain"`
-	Network     string               `json:"network"`
+	TeamId      string               `json:"team,omitempty"`
+	Blockchain  string               `json:"blockchain" validate:"required,oneof=zcash"`
+	Network     string               `json:"network" validate:"required,oneof=mainnet testnet"`
 	Status      string               `json:"status"`
 	State       string               `json:"state"`
-	Description string               `json:"description"`
+	Description string               `json:"description" validate:"max=256"`
+	Labels      map[string]string    `json:"labels,omitempty"`
+	Rotation    *RotationPolicy      `json:"rotation,omitempty"`
+	Schedule    *PowerSchedule       `json:"schedule,omitempty"`
+	Upgrade     *ProjectUpgrade      `json:"upgrade,omitempty"`
+	Domain      *Domain              `json:"domain,omitempty"`
 	Resources   *KubernetesResources `json:"resources,omitempty"`
 	CreatedAt   *time.Time           `json:"createdAt,omitempty"`
 	UpdatedAt   *time.Time           `json:"updatedAt,omitempty"`
 }
 
+// ProjectUpdateRequest changes the mutable fields of a project. Nil fields are
+// left unchanged and Labels replaces all user labels when set. Name,
+// Blockchain, Network, Owner and TeamId cannot be changed and are only read so
+// that an attempt to change them is rejected rather than ignored.
+type ProjectUpdateRequest struct {
+	Name        string            `json:"name,omitempty"`
+	Blockchain  string            `json:"blockchain,omitempty"`
+	Network     string            `json:"network,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	TeamId      string            `json:"team,omitempty"`
+	Description *string           `json:"description,omitempty" validate:"omitempty,max=256"`
+	Labels      map[string]string `json:"labels" validate:"omitempty,max=16"`
+	Rotation    *RotationPolicy   `json:"rotation,omitempty"`
+}
+
 type Instance struct {
 	Id           string               `json:"id"`
 	Name         string               `json:"name"`
@@ -59,38 +80,348 @@ type Instance struct {
 	Owner        string               `json:"owner"`
 	Network      NetworkType          `json:"network"`
 	Request      *ResourceRequest     `json:"request"`
+	Rotation     *CredentialsRotation `json:"rotation,omitempty"`
+	Schedule     *PowerSchedule       `json:"schedule,omitempty"`
+	Hold         *ScheduleHold        `json:"hold,omitempty"`
+	Power        *PowerState          `json:"power,omitempty"`
+	Image        *ImagePin            `json:"image,omitempty"`
+	Domain       *Domain              `json:"domain,omitempty"`
 	Resources    *KubernetesResources `json:"resources,omitempty"`
 	CreatedAt    *time.Time           `json:"createdAt,omitempty"`
 	UpdatedAt    *time.Time           `json:"updatedAt,omitempty"`
 }
 
 type ResourceRequest struct {
-	Cpu        string                 `json:"cpu"`
-	Memory     string                 `json:"memory"`
-	Peers      []string               `json:"peers"`
-	Properties map[string]interface{} `json:"properties"`
-	Volume     struct {
+	Cpu          string                 `json:"cpu"`
+	Memory       string                 `json:"memory"`
+	Peers        []string               `json:"peers"`
+	Properties   map[string]interface{} `json:"properties"`
+	RateLimit    *RateLimit             `json:"rateLimit,omitempty"`
+	KeyRateLimit *RateLimit             `json:"keyRateLimit,omitempty"`
+	IPAllowList  []string               `json:"ipAllowList,omitempty"`
+	Config       map[string]interface{} `json:"config,omitempty"`
+	Mining       *MiningConfig          `json:"mining,omitempty"`
+	Volume       struct {
 		Type   DataVolumeType `json:"type"`
 		Size   string         `json:"size"`
 		Source struct {
 			Type DataSourceType `json:"type"`
 			Ref  string         `json:"ref"`
-		}
-	}
+		} `json:"source"`
+	} `json:"volume"`
 }
 
 type InstanceRequest struct {
-	Name        string                 `json:"name"`
-	Type        InstanceType           `json:"type"`
-	Description string                 `json:"description"`
-	Peers       []string               `json:"peers"`
+	Name        string                 `json:"name" validate:"required,dnslabel"`
+	Type        InstanceType           `json:"type" validate:"required,oneof=zcash lwd"`
+	Description string                 `json:"description" validate:"max=256"`
+	Peers       []string               `json:"peers" validate:"unique,dive,required"`
 	Properties  map[string]interface{} `json:"properties"`
-	Volume      struct {
-		Type   DataVolumeType `json:"type"`
-		Size   string         `json:"size"`
-		Source DataSourceType `json:"source"`
+	// RateLimit applies to all requests to the instance endpoint and
+	// KeyRateLimit to the requests made with each API key. When nil the
+	// policy defaults apply; an empty limit removes them.
+	RateLimit    *RateLimit `json:"rateLimit,omitempty"`
+	KeyRateLimit *RateLimit `json:"keyRateLimit,omitempty"`
+	// IPAllowList restricts the source addresses of requests to the instance
+	// endpoint to IP addresses or CIDR ranges. Empty allows all addresses.
+	IPAllowList []string `json:"ipAllowList,omitempty" validate:"max=50,dive,cidr|ip"`
+	// Config overrides node settings, zcash.conf for zcash instances and
+	// lightwalletd options for lightwallet instances. Keys must be allowed
+	// by the config schema of the node type. Lightwallet deployments created
+	// before overrides were supported read them once repaired.
+	Config map[string]interface{} `json:"config,omitempty" validate:"omitempty,max=32"`
+	// Mining configures the miner of zcash instances with the miner
+	// property set.
+	Mining *MiningConfig `json:"mining,omitempty"`
+	Volume struct {
+		Type   DataVolumeType `json:"type" validate:"omitempty,oneof=ephemeral pvc"`
+		Size   string         `json:"size" validate:"required_if=Type pvc,omitempty,quantity"`
+		Source DataSourceType `json:"source" validate:"omitempty,oneof=none new pvc snapshot seed"`
 		Ref    string         `json:"ref"`
+	} `json:"volume"`
+}
+
+// InstanceCredentials are the RPC credentials of an instance. They are read
+// from the cluster on demand and are never stored with the instance.
+type InstanceCredentials struct {
+	Username string               `json:"username"`
+	Password string               `json:"password"`
+	Previous *PreviousCredentials `json:"previous,omitempty"`
+}
+
+// PreviousCredentials are credentials replaced by a rotation that remain
+// valid until ExpiresAt.
+type PreviousCredentials struct {
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// RotationPolicy schedules credential rotation. Interval and GracePeriod are
+// durations such as 30d or 12h; an empty Interval disables scheduled rotation.
+type RotationPolicy struct {
+	Interval    string `json:"interval,omitempty" validate:"omitempty,duration"`
+	GracePeriod string `json:"gracePeriod,omitempty" validate:"omitempty,duration"`
+}
+
+// CredentialsRotation is the rotation state of an instance.
+type CredentialsRotation struct {
+	LastRotation *time.Time       `json:"lastRotation,omitempty"`
+	NextRotation *time.Time       `json:"nextRotation,omitempty"`
+	GraceUntil   *time.Time       `json:"graceUntil,omitempty"`
+	History      []RotationRecord `json:"history,omitempty"`
+}
+
+type RotationRecord struct {
+	RotatedAt   time.Time       `json:"rotatedAt"`
+	Trigger     RotationTrigger `json:"trigger"`
+	GracePeriod string          `json:"gracePeriod,omitempty"`
+}
+
+// RateLimit limits requests to an instance endpoint. RequestsPerSecond is
+// refilled every second up to Burst, and DailyQuota caps the requests in a
+// day. Zero values are not limited. The DailyQuota of an instance rateLimit
+// is approximate: it is counted by each sidecar replica over 24 hours from
+// its start, and resets when the sidecar restarts.
+type RateLimit struct {
+	RequestsPerSecond int `json:"requestsPerSecond" validate:"min=0"`
+	Burst             int `json:"burst" validate:"min=0"`
+	DailyQuota        int `json:"dailyQuota" validate:"min=0"`
+}
+
+// Effective returns the limit to apply, using fallback when r is nil. Burst
+// is at least RequestsPerSecond. It returns nil when nothing is limited.
+func (r *RateLimit) Effective(fallback *RateLimit) *RateLimit {
+	if r == nil {
+		r = fallback
+	}
+	if r == nil || (r.RequestsPerSecond <= 0 && r.DailyQuota <= 0) {
+		return nil
+	}
+
+	limit := *r
+	if limit.Burst < limit.RequestsPerSecond {
+		limit.Burst = limit.RequestsPerSecond
+	}
+	return &limit
+}
+
+// EndpointPolicy restricts the RPC methods exposed by an instance. Entries
+// name an endpoint group from the blockchain node info, e.g. wallet, or a
+// single method of a group, e.g. control.stop. An empty Allow permits every
+// group; Deny is applied after Allow.
+type EndpointPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// APIKey grants a consumer access to the endpoints of an instance. Only a
+// hash of the secret is stored; the key itself is returned once when issued.
+type APIKey struct {
+	Id           string            `json:"id"`
+	InstanceId   string            `json:"instanceId"`
+	InstanceName string            `json:"instanceName"`
+	Namespace    string            `json:"namespace"`
+	Owner        string            `json:"owner"`
+	Consumer     string            `json:"consumer"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Hash         string            `json:"hash,omitempty"`
+	CreatedAt    *time.Time        `json:"createdAt,omitempty"`
+	ExpiresAt    *time.Time        `json:"expiresAt,omitempty"`
+	RevokedAt    *time.Time        `json:"revokedAt,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Consumer  string            `json:"consumer" validate:"required,max=64"`
+	Labels    map[string]string `json:"labels" validate:"max=16"`
+	ExpiresIn string            `json:"expiresIn" validate:"omitempty,duration"`
+}
+
+// Team owns projects on behalf of its members. Team admins can create and
+// delete projects and instances; team users can only operate them.
+type Team struct {
+	Id        string       `json:"id"`
+	Name      string       `json:"name"`
+	Members   []TeamMember `json:"members"`
+	Quota     TeamQuota    `json:"quota"`
+	CreatedAt *time.Time   `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time   `json:"updatedAt,omitempty"`
+}
+
+type TeamMember struct {
+	UserId string   `json:"userid" validate:"required,max=64"`
+	Role   TeamRole `json:"role" validate:"required,oneof=admin user"`
+}
+
+// TeamQuota limits the projects and instances a team owns. Zero is unlimited.
+type TeamQuota struct {
+	MaxProjects  int `json:"maxProjects" validate:"min=0"`
+	MaxInstances int `json:"maxInstances" validate:"min=0"`
+}
+
+type TeamRequest struct {
+	Name  string     `json:"name" validate:"required,max=64"`
+	Quota *TeamQuota `json:"quota"`
+}
+
+// GetMember returns the membership of a user, or nil when the user is not a
+// member of the team.
+func (t *Team) GetMember(userid string) *TeamMember {
+	for index := range t.Members {
+		if t.Members[index].UserId == userid {
+			return &t.Members[index]
+		}
 	}
+	return nil
+}
+
+// AuditRecord is an immutable record of a mutating API request or a cluster
+// operation. BodyHash is the hex SHA-256 of the request body; the body itself
+// is not kept.
+type AuditRecord struct {
+	Id           string        `json:"id"`
+	Timestamp    time.Time     `json:"timestamp"`
+	TxId         string        `json:"txid"`
+	Source       AuditSource   `json:"source"`
+	Actor        string        `json:"actor"`
+	Role         string        `json:"role"`
+	SourceIP     string        `json:"sourceIP,omitempty"`
+	ForwardedFor string        `json:"forwardedFor,omitempty"`
+	Action       string        `json:"action"`
+	Method       string        `json:"method,omitempty"`
+	Path         string        `json:"path,omitempty"`
+	Target       string        `json:"target,omitempty"`
+	BodyHash     string        `json:"bodyHash,omitempty"`
+	Objects      []AuditObject `json:"objects,omitempty"`
+	Detail       string        `json:"detail,omitempty"`
+	Outcome      AuditOutcome  `json:"outcome"`
+	Status       int           `json:"status,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// AuditObject is a Kubernetes object applied or deleted by an audited
+// operation.
+type AuditObject struct {
+	Operation AuditOperation `json:"operation"`
+	Resource  string         `json:"resource"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+}
+
+// AuditQuery filters audit records. Target matches either the full target,
+// e.g. instance/<id>, or its id.
+type AuditQuery struct {
+	Actor  string     `json:"actor,omitempty"`
+	Target string     `json:"target,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	Limit  int        `json:"limit,omitempty"`
+}
+
+// ListQuery filters, sorts and pages project and instance listings. Member
+// restricts projects to those owned by a user or shared through their teams.
+// Sort is a field name, prefixed with - for descending order, and Cursor is
+// the NextCursor of the previous page. Fields limits the fields returned.
+type ListQuery struct {
+	Member        string     `json:"member,omitempty"`
+	Owner         string     `json:"owner,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	State         string     `json:"state,omitempty"`
+	Type          string     `json:"type,omitempty"`
+	Network       string     `json:"network,omitempty"`
+	LabelSelector string     `json:"labelSelector,omitempty"`
+	CreatedBefore *time.Time `json:"createdBefore,omitempty"`
+	CreatedAfter  *time.Time `json:"createdAfter,omitempty"`
+	Sort          string     `json:"sort,omitempty"`
+	Limit         int        `json:"limit,omitempty"`
+	Cursor        string     `json:"cursor,omitempty"`
+	Fields        []string   `json:"fields,omitempty"`
+}
+
+// ProjectList is a page of projects. Total counts all matching projects.
+type ProjectList struct {
+	Projects   []Project `json:"projects"`
+	Total      int       `json:"total"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// InstanceList is a page of instances. Total counts all matching instances.
+type InstanceList struct {
+	Instances  []Instance `json:"instances"`
+	Total      int        `json:"total"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// Domain is a custom hostname served by a project or instance in addition to
+// the platform domain. The certificate is kept in the SecretName secret of the
+// project namespace and is either issued by cert-manager or uploaded.
+type Domain struct {
+	Host       string        `json:"host"`
+	TLS        DomainTLSType `json:"tls"`
+	SecretName string        `json:"secretName"`
+	Issuer     string        `json:"issuer,omitempty"`
+	CreatedAt  *time.Time    `json:"createdAt,omitempty"`
+}
+
+// DomainRequest sets the custom domain of a project or instance. Certificate
+// and PrivateKey are PEM encoded and are only used for uploaded certificates;
+// they are written to the cluster and never stored in the repository.
+// Seed is a curated source of chain data for new zcash instances of a
+// network. A snapshot seed is a VolumeSnapshot, identified by its CSI
+// snapshot handle, that is bound into the namespace of each project using it.
+// An archive seed is downloaded and checked into the data volume before the
+// node starts.
+type Seed struct {
+	Id          string        `json:"id"`
+	Name        string        `json:"name"`
+	Blockchain  string        `json:"blockchain"`
+	Network     NetworkType   `json:"network"`
+	Type        SeedType      `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Height      int64         `json:"height,omitempty"`
+	Size        string        `json:"size,omitempty"`
+	Snapshot    *SeedSnapshot `json:"snapshot,omitempty"`
+	Archive     *SeedArchive  `json:"archive,omitempty"`
+	CreatedBy   string        `json:"createdBy,omitempty"`
+	CreatedAt   *time.Time    `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time    `json:"updatedAt,omitempty"`
+}
+
+// SeedSnapshot identifies the snapshot of a seed to the CSI driver that
+// holds it.
+type SeedSnapshot struct {
+	Handle        string `json:"handle" validate:"required,max=512"`
+	Driver        string `json:"driver" validate:"required,fqdn"`
+	SnapshotClass string `json:"snapshotClass,omitempty" validate:"omitempty,dnslabel"`
+}
+
+// SeedArchive is an archive of the zcashd data directory, with blocks and
+// chainstate at its root.
+type SeedArchive struct {
+	Url    string        `json:"url" validate:"required,url,max=2048"`
+	Sha256 string        `json:"sha256" validate:"required,len=64,hexadecimal"`
+	Format ArchiveFormat `json:"format,omitempty" validate:"omitempty,oneof=tar tar.gz"`
+}
+
+// SeedRequest creates or replaces a seed. Size is the smallest data volume
+// the seed fits in and Height the block height it holds.
+type SeedRequest struct {
+	Name        string        `json:"name" validate:"required,dnslabel"`
+	Blockchain  string        `json:"blockchain" validate:"omitempty,oneof=zcash"`
+	Network     NetworkType   `json:"network" validate:"required,oneof=mainnet testnet"`
+	Type        SeedType      `json:"type" validate:"required,oneof=snapshot archive"`
+	Description string        `json:"description" validate:"max=256"`
+	Height      int64         `json:"height" validate:"min=0"`
+	Size        string        `json:"size" validate:"omitempty,quantity"`
+	Snapshot    *SeedSnapshot `json:"snapshot" validate:"required_if=Type snapshot"`
+	Archive     *SeedArchive  `json:"archive" validate:"required_if=Type archive"`
+}
+
+type DomainRequest struct {
+	Host        string        `json:"host" validate:"required,fqdn,max=253"`
+	TLS         DomainTLSType `json:"tls" validate:"required,oneof=certManager uploaded"`
+	Certificate string        `json:"certificate" validate:"required_if=TLS uploaded"`
+	PrivateKey  string        `json:"privateKey" validate:"required_if=TLS uploaded"`
 }
 
 type KubernetesResources struct {
@@ -103,6 +434,8 @@ type KubernetesResources struct {
 	Httpproxy             *KubernetesResource  `json:"httpproxy,omitempty"`
 	Volumesnapshot        []KubernetesResource `json:"volumesnapshot,omitempty"`
 	Snapshotschedule      *KubernetesResource  `json:"snapshotschedule,omitempty"`
+	Certificate           *KubernetesResource  `json:"certificate,omitempty"`
+	P2p                   *KubernetesResource  `json:"p2p,omitempty"`
 }
 
 type Activity struct {
@@ -147,6 +480,34 @@ type IngressRoute struct {
 	Conditions        []IngressCondition       `json:"conditions,omitempty"`
 	Services          []IngressService         `json:"services,omitempty"`
 	PathRewritePolicy IngressPathRewritePolicy `json:"pathRewritePolicy,omitempty"`
+	IPAllowPolicy     []IPFilterPolicy         `json:"ipAllowPolicy,omitempty"`
+}
+
+// IPFilterPolicy is a source address rule of an HTTPProxy route. Source is
+// Peer for the address of the connecting client or Remote for the address
+// forwarded by a load balancer.
+type IPFilterPolicy struct {
+	Source string `json:"source"`
+	Cidr   string `json:"cidr"`
+}
+
+// IPAllowListRequest replaces the IP allow-list of an instance.
+type IPAllowListRequest struct {
+	IPAllowList []string `json:"ipAllowList" validate:"max=50,dive,cidr|ip"`
+}
+
+// NetworkPolicyInfo is a NetworkPolicy in effect in a project namespace.
+type NetworkPolicyInfo struct {
+	Name string      `json:"name"`
+	Spec interface{} `json:"spec"`
+}
+
+// NetworkPolicies describes the network access of a project or instance:
+// the NetworkPolicies selecting its pods and the sources allowed by its
+// ingress. An empty IPAllowPolicy allows all sources.
+type NetworkPolicies struct {
+	IPAllowPolicy   []IPFilterPolicy    `json:"ipAllowPolicy,omitempty"`
+	NetworkPolicies []NetworkPolicyInfo `json:"networkPolicies"`
 }
 
 type IngressPathRewritePolicy struct {
@@ -206,10 +567,14 @@ type BlockchainInfo struct {
 	Templates map[string]string    `json:"templates"`
 }
 
+// ImageInfo is an image version available to a node type. A node type may
+// list several versions of an image; the one marked Default, or else the
+// first one, is used for instances that are not pinned to a version.
 type ImageInfo struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
 	Url     string `json:"url"`
+	Default bool   `json:"default,omitempty"`
 }
 
 type KVPair struct {
@@ -224,14 +589,69 @@ type BlockchainNodeInfo struct {
 	Ports      map[string]int32       `json:"ports"`
 	Images     []ImageInfo            `json:"images"`
 	Settings   map[string][]KVPair    `json:"settings"`
+	Config     *ConfigSchema          `json:"config,omitempty"`
 	Properties map[string]interface{} `json:"properties"`
 }
 
+// ConfigSchema lists the node settings an instance may override. Settings
+// in Forbidden are rejected even when they are listed in Options.
+type ConfigSchema struct {
+	Options   map[string]ConfigOption `json:"options"`
+	Forbidden []string                `json:"forbidden,omitempty"`
+}
+
+// MiningConfig are the mining options of a zcash miner. MinerAddress
+// receives the block rewards and must be an address of the project network.
+// GenProcLimit is the number of mining threads, -1 for one per core.
+type MiningConfig struct {
+	MinerAddress      string `json:"minerAddress,omitempty" validate:"omitempty,max=256"`
+	GenProcLimit      *int   `json:"genProcLimit,omitempty" validate:"omitempty,min=-1,max=64"`
+	Solver            string `json:"solver,omitempty" validate:"omitempty,oneof=default tromp"`
+	MineToLocalWallet *bool  `json:"mineToLocalWallet,omitempty"`
+}
+
+// MiningInfo is the result of the getmininginfo RPC.
+type MiningInfo struct {
+	Blocks           int64   `json:"blocks"`
+	CurrentBlockSize int64   `json:"currentblocksize"`
+	CurrentBlockTx   int64   `json:"currentblocktx"`
+	Difficulty       float64 `json:"difficulty"`
+	Errors           string  `json:"errors"`
+	Generate         bool    `json:"generate"`
+	GenProcLimit     int64   `json:"genproclimit"`
+	LocalSolPs       float64 `json:"localsolps"`
+	NetworkSolPs     float64 `json:"networksolps"`
+	NetworkHashPs    float64 `json:"networkhashps"`
+	PooledTx         int64   `json:"pooledtx"`
+	Testnet          bool    `json:"testnet"`
+	Chain            string  `json:"chain"`
+}
+
+// MiningStatus reports the mining options of an instance with the mining
+// state and solution rate read from the node.
+type MiningStatus struct {
+	Instance   string        `json:"instance"`
+	Mining     *MiningConfig `json:"mining,omitempty"`
+	Info       *MiningInfo   `json:"info"`
+	LocalSolPs float64       `json:"localSolPs"`
+}
+
+// ConfigOption describes the value of a setting. Min and Max bound int
+// values and Values lists the allowed string values.
+type ConfigOption struct {
+	Type        ConfigValueType `json:"type"`
+	Min         *int64          `json:"min,omitempty"`
+	Max         *int64          `json:"max,omitempty"`
+	Values      []string        `json:"values,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
 type PolicyInfo struct {
 	StorageClass          string `json:"storageClass"`
 	SnapshotClass         string `json:"snapshotClass"`
 	DomainName            string `json:"domainName"`
 	CertificateName       string `json:"certificateName"`
+	CertificateIssuer     string `json:"certificateIssuer"`
 	ServiceAccount        string `json:"serviceAccount"`
 	InformerResync        int32  `json:"informerResync"`
 	EnableMonitor         bool   `json:"enableMonitor"`
@@ -244,10 +664,171 @@ type PolicyInfo struct {
 		AuthServerURL         string   `json:"authServerURL"`
 		AuthServerPort        int32    `json:"authServerPort"`
 		AuthenticationEnabled bool     `json:"authenticationEnabled"`
+		MetricsPort           int32    `json:"metricsPort"`
 	} `json:"envoyConfig"`
-	Request struct {
+	NetworkPolicy struct {
+		Enabled             bool   `json:"enabled"`
+		IngressNamespace    string `json:"ingressNamespace"`
+		MonitoringNamespace string `json:"monitoringNamespace"`
+		IPAllowSource       string `json:"ipAllowSource"`
+	} `json:"networkPolicy"`
+	P2P struct {
+		ServiceType string `json:"serviceType"`
+	} `json:"p2p"`
+	RateLimit    *RateLimit `json:"rateLimit,omitempty"`
+	KeyRateLimit *RateLimit `json:"keyRateLimit,omitempty"`
+	Request      struct {
 		Cpu     string `json:"cpu"`
 		Memory  string `json:"memory"`
 		Storage string `json:"storage"`
 	} `json:"request"`
 }
+
+// BulkActionResult is the outcome of a project-wide action for one instance.
+// An instance is skipped when an instance it depends on failed.
+type BulkActionResult struct {
+	Id       string           `json:"id"`
+	Instance string           `json:"instance"`
+	Status   BulkResultStatus `json:"status"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// BulkActionResponse reports a project-wide action for every instance of the
+// project.
+type BulkActionResponse struct {
+	Project   string             `json:"project"`
+	Action    EventAction        `json:"action"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Skipped   int                `json:"skipped"`
+	Results   []BulkActionResult `json:"results"`
+}
+
+// PowerSchedule starts and stops instances on a timetable, either with a
+// pair of cron expressions or with weekly windows during which instances
+// run. Times are evaluated in Timezone, which defaults to UTC. An instance
+// schedule overrides the schedule of its project.
+type PowerSchedule struct {
+	Timezone  string           `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	StartCron string           `json:"startCron,omitempty" validate:"omitempty,cron"`
+	StopCron  string           `json:"stopCron,omitempty" validate:"omitempty,cron"`
+	Windows   []ScheduleWindow `json:"windows,omitempty" validate:"omitempty,max=14,dive"`
+}
+
+// ScheduleWindow runs instances from Start to End, as HH:MM, on the given
+// days. A window that ends before it starts runs past midnight.
+type ScheduleWindow struct {
+	Days  []string `json:"days" validate:"required,min=1,dive,oneof=mon tue wed thu fri sat sun"`
+	Start string   `json:"start" validate:"required,clock"`
+	End   string   `json:"end" validate:"required,clock"`
+}
+
+// ScheduleHold suspends scheduled transitions of an instance until Until,
+// or until it is released when Until is not set.
+type ScheduleHold struct {
+	Until     *time.Time `json:"until,omitempty"`
+	Reason    string     `json:"reason,omitempty" validate:"max=256"`
+	CreatedBy string     `json:"createdBy,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// Active returns true if the hold is in effect at now.
+func (h *ScheduleHold) Active(now time.Time) bool {
+	return h != nil && (h.Until == nil || now.Before(*h.Until))
+}
+
+// PowerState is the scheduler state of an instance. LastCheck is the time up
+// to which transitions have been handled, so transitions missed while the
+// controller was down are caught up.
+type PowerState struct {
+	LastCheck      *time.Time       `json:"lastCheck,omitempty"`
+	LastTransition *time.Time       `json:"lastTransition,omitempty"`
+	LastAction     EventAction      `json:"lastAction,omitempty"`
+	LastResult     BulkResultStatus `json:"lastResult,omitempty"`
+	LastError      string           `json:"lastError,omitempty"`
+	NextTransition *time.Time       `json:"nextTransition,omitempty"`
+	NextAction     EventAction      `json:"nextAction,omitempty"`
+}
+
+// ScheduleInfo is the effective schedule of a project or instance and its
+// next transition.
+type ScheduleInfo struct {
+	Schedule       *PowerSchedule `json:"schedule"`
+	Source         string         `json:"source,omitempty"`
+	Hold           *ScheduleHold  `json:"hold,omitempty"`
+	State          *PowerState    `json:"state,omitempty"`
+	NextTransition *time.Time     `json:"nextTransition,omitempty"`
+	NextAction     EventAction    `json:"nextAction,omitempty"`
+}
+
+// ImagePin is the version of the node image an instance runs. Previous is
+// the version replaced by the last upgrade and is restored by a rollback.
+type ImagePin struct {
+	Name      string     `json:"name"`
+	Version   string     `json:"version"`
+	Previous  string     `json:"previous,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// ImageUpgradeRequest upgrades an instance to an image version.
+type ImageUpgradeRequest struct {
+	Version string `json:"version" validate:"required,max=64"`
+}
+
+// ProjectUpgradeRequest upgrades the instances of a type in a project in
+// batches. The first Canary instances are upgraded on their own, then
+// BatchSize instances at a time. Each upgraded instance must pass a health
+// gate within HealthTimeout, a duration such as "15m", before the next batch
+// starts. Instances limits the upgrade to the named instances.
+type ProjectUpgradeRequest struct {
+	Type          InstanceType `json:"type" validate:"required,oneof=zcash lwd"`
+	Version       string       `json:"version" validate:"required,max=64"`
+	Canary        int          `json:"canary" validate:"min=0,max=100"`
+	BatchSize     int          `json:"batchSize" validate:"min=0,max=100"`
+	HealthTimeout string       `json:"healthTimeout,omitempty"`
+	Instances     []string     `json:"instances,omitempty" validate:"omitempty,unique,dive,required"`
+}
+
+// ProjectUpgrade is the state of a rolling upgrade of a project. Targets are
+// upgraded batch by batch; Batch is the index of the next batch to upgrade.
+// A failed health gate pauses the upgrade.
+type ProjectUpgrade struct {
+	Type          InstanceType    `json:"type"`
+	Image         string          `json:"image"`
+	Version       string          `json:"version"`
+	Status        UpgradeStatus   `json:"status"`
+	Canary        int             `json:"canary"`
+	BatchSize     int             `json:"batchSize"`
+	HealthTimeout string          `json:"healthTimeout,omitempty"`
+	Batches       [][]string      `json:"batches"`
+	Batch         int             `json:"batch"`
+	Targets       []UpgradeTarget `json:"targets"`
+	Error         string          `json:"error,omitempty"`
+	CreatedBy     string          `json:"createdBy,omitempty"`
+	CreatedAt     *time.Time      `json:"createdAt,omitempty"`
+	UpdatedAt     *time.Time      `json:"updatedAt,omitempty"`
+}
+
+// GetTarget returns the target of an instance, or nil if the instance is not
+// part of the upgrade.
+func (u *ProjectUpgrade) GetTarget(id string) *UpgradeTarget {
+	for index := range u.Targets {
+		if u.Targets[index].Id == id {
+			return &u.Targets[index]
+		}
+	}
+	return nil
+}
+
+// UpgradeTarget is an instance of a rolling upgrade. StartHeight and Height
+// are the block heights seen before the upgrade and by the health gate.
+type UpgradeTarget struct {
+	Id          string        `json:"id"`
+	Name        string        `json:"name"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Status      UpgradeStatus `json:"status"`
+	StartHeight int64         `json:"startHeight,omitempty"`
+	Height      int64         `json:"height,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
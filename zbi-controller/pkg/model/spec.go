@@ -7,6 +7,8 @@ type ProjectSpec struct {
 	Instances    string            `json:"instances"`
 	Labels       map[string]string `json:"labels"`
 	InstancesMap string            `json:"instanceMap"`
+	// NetworkPolicy is rendered into the project NetworkPolicies when enabled.
+	NetworkPolicy NetworkPolicySpec `json:"networkPolicy"`
 	//Network      NetworkType       `json:"network"`
 	//TeamId       string            `json:"team"`
 }
@@ -23,9 +25,47 @@ type InstanceSpec struct {
 	Envoy              EnvoySpec              `json:"envoy"`
 	Images             map[string]string      `json:"images"`
 	Ports              map[string]int32       `json:"ports"`
+	Credentials        CredentialsSpec        `json:"-"`
+	Domain             DomainSpec             `json:"domain"`
+	P2P                P2PSpec                `json:"p2p"`
 	Properties         map[string]interface{} `json:"properties"`
 }
 
+// DomainSpec renders the resources of a custom domain. Certificate and
+// PrivateKey are base64 encoded PEM data of an uploaded certificate.
+type DomainSpec struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Host        string            `json:"host"`
+	SecretName  string            `json:"secretName"`
+	Issuer      string            `json:"issuer"`
+	Certificate string            `json:"-"`
+	PrivateKey  string            `json:"-"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// P2PSpec renders the Service exposing the peer-to-peer port of a node
+// outside the cluster.
+type P2PSpec struct {
+	Name        string            `json:"name"`
+	ServiceType string            `json:"serviceType"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// CredentialsSpec references the instance credentials from templates. When
+// Store is set the credentials are kept outside the cluster and the templates
+// render an ExternalSecret reading Key from that secret store instead of the
+// username and password.
+type CredentialsSpec struct {
+	Provider   string
+	SecretName string
+	Store      string
+	Key        string
+	Username   string
+	Password   string
+	Previous   *PreviousCredentials
+}
+
 type VolumeSpec struct {
 	VolumeName     string
 	StorageClass   string
@@ -35,6 +75,7 @@ type VolumeSpec struct {
 	SourceName     string
 	Size           string
 	Labels         map[string]string
+	Seed           *Seed
 }
 
 type SnapshotSpec struct {
@@ -68,4 +109,13 @@ type EnvoySpec struct {
 	AuthServerURL         string
 	AuthServerPort        int32
 	AuthenticationEnabled bool
+	MetricsPort           int32
+	RateLimit             *RateLimit
+	IPAllowPolicy         []IPFilterPolicy
+}
+
+// NetworkPolicySpec names the namespaces allowed to reach project pods.
+type NetworkPolicySpec struct {
+	IngressNamespace    string `json:"ingressNamespace"`
+	MonitoringNamespace string `json:"monitoringNamespace"`
 }
@@ -13,8 +13,13 @@ const (
 	ResourcePersistentVolumeClaim ResourceObjectType = "PersistentVolumeClaim"
 	ResourceVolumeSnapshot        ResourceObjectType = "VolumeSnapshot"
 	ResourceVolumeSnapshotClass   ResourceObjectType = "VolumeSnapshotClass"
+	ResourceVolumeSnapshotContent ResourceObjectType = "VolumeSnapshotContent"
 	ResourceSnapshotSchedule      ResourceObjectType = "SnapshotSchedule"
 	ResourceHTTPProxy             ResourceObjectType = "HTTPProxy"
+	ResourceCertificate           ResourceObjectType = "Certificate"
+	ResourceNetworkPolicy         ResourceObjectType = "NetworkPolicy"
+	ResourceExternalSecret        ResourceObjectType = "ExternalSecret"
+	ResourceP2PService            ResourceObjectType = "P2PService"
 )
 
 type EventAction string
@@ -30,6 +35,78 @@ const (
 	EventActionStartInstance  EventAction = "start"
 	EventActionRotate         EventAction = "rotate"
 	EventActionDeleteResource EventAction = "delete_resource"
+	EventActionCredentials    EventAction = "credentials"
+	EventActionIssueKey       EventAction = "issue_key"
+	EventActionRevokeKey      EventAction = "revoke_key"
+	EventActionSetDomain      EventAction = "set_domain"
+	EventActionRemoveDomain   EventAction = "remove_domain"
+	EventActionSetAllowList   EventAction = "set_allowlist"
+	EventActionGetPolicies    EventAction = "get_policies"
+	EventActionView           EventAction = "view"
+	EventActionAddMember      EventAction = "add_member"
+	EventActionRemoveMember   EventAction = "remove_member"
+	EventActionSetQuota       EventAction = "set_quota"
+	EventActionSetSchedule    EventAction = "set_schedule"
+	EventActionRemoveSchedule EventAction = "remove_schedule"
+	EventActionHold           EventAction = "hold"
+	EventActionRelease        EventAction = "release"
+	EventActionUpgrade        EventAction = "upgrade"
+	EventActionRollback       EventAction = "rollback"
+	EventActionPauseUpgrade   EventAction = "pause_upgrade"
+	EventActionResumeUpgrade  EventAction = "resume_upgrade"
+	EventActionRPC            EventAction = "rpc"
+)
+
+type RotationTrigger string
+
+const (
+	RotationTriggerManual    RotationTrigger = "manual"
+	RotationTriggerScheduled RotationTrigger = "scheduled"
+)
+
+type TeamRole string
+
+const (
+	TeamRoleAdmin TeamRole = "admin"
+	TeamRoleUser  TeamRole = "user"
+)
+
+// AccessLevel is the access an operation needs on a project and its
+// instances.
+type AccessLevel string
+
+const (
+	AccessView    AccessLevel = "view"
+	AccessOperate AccessLevel = "operate"
+	AccessManage  AccessLevel = "manage"
+)
+
+type AuditSource string
+
+const (
+	AuditSourceAPI     AuditSource = "api"
+	AuditSourceCluster AuditSource = "cluster"
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeFailure AuditOutcome = "failure"
+)
+
+type AuditOperation string
+
+const (
+	AuditOperationApply  AuditOperation = "apply"
+	AuditOperationDelete AuditOperation = "delete"
+)
+
+type DomainTLSType string
+
+const (
+	DomainTLSCertManager DomainTLSType = "certManager"
+	DomainTLSUploaded    DomainTLSType = "uploaded"
 )
 
 type NetworkType string
@@ -78,6 +155,21 @@ const (
 	NewDataSource      DataSourceType = "new"
 	VolumeDataSource   DataSourceType = "pvc"
 	SnapshotDataSource DataSourceType = "snapshot"
+	SeedDataSource     DataSourceType = "seed"
+)
+
+type SeedType string
+
+const (
+	SeedTypeSnapshot SeedType = "snapshot"
+	SeedTypeArchive  SeedType = "archive"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveFormatTar   ArchiveFormat = "tar"
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
 )
 
 type DataVolumeType string
@@ -86,3 +178,31 @@ const (
 	EphemeralDataVolume  DataVolumeType = "ephemeral"
 	PersistentDataVolume DataVolumeType = "pvc"
 )
+
+type BulkResultStatus string
+
+const (
+	BulkResultSucceeded BulkResultStatus = "succeeded"
+	BulkResultFailed    BulkResultStatus = "failed"
+	BulkResultSkipped   BulkResultStatus = "skipped"
+)
+
+type UpgradeStatus string
+
+const (
+	UpgradePending     UpgradeStatus = "pending"
+	UpgradeRunning     UpgradeStatus = "running"
+	UpgradePaused      UpgradeStatus = "paused"
+	UpgradeCompleted   UpgradeStatus = "completed"
+	UpgradeFailed      UpgradeStatus = "failed"
+	UpgradeRollingBack UpgradeStatus = "rolling_back"
+	UpgradeRolledBack  UpgradeStatus = "rolled_back"
+)
+
+type ConfigValueType string
+
+const (
+	ConfigValueBool   ConfigValueType = "bool"
+	ConfigValueInt    ConfigValueType = "int"
+	ConfigValueString ConfigValueType = "string"
+)
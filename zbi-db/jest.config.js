@@ -0,0 +1,6 @@
+module.exports = {
+    preset: "ts-jest",
+    testEnvironment: "node",
+    roots: ["<rootDir>/tests"],
+    testTimeout: 60000
+};
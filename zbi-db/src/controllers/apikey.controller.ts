@@ -0,0 +1,106 @@
+import {Request, Response} from 'express';
+import repoFactory from "../repository";
+import { getLogger, getDuration } from '../lib/logger';
+import { HttpStatusCode } from 'axios';
+import { handleError } from '../lib/errors';
+import * as types from '../types';
+
+const createAPIKey = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('actrl-create-apikey');
+
+    try {
+
+        const key: types.APIKey = request.body;
+        key.instanceId = request.params.instance;
+
+        if (!key.id || !key.hash || !key.consumer) {
+            response.status(HttpStatusCode.BadRequest).json({message: "id, hash and consumer are required"});
+            return;
+        }
+
+        const apiKeyRepository = repoFactory.getAPIKeyRepository();
+        const apiKey = await apiKeyRepository.createAPIKey(key);
+        logger.info(`created api key ${apiKey.id} for instance ${apiKey.instanceId}`);
+        response.status(HttpStatusCode.Created).json(apiKey);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findAPIKeys = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('actrl-find-apikeys');
+
+    try {
+
+        const instanceid = request.params.instance;
+
+        const apiKeyRepository = repoFactory.getAPIKeyRepository();
+        const keys = await apiKeyRepository.findAPIKeys(instanceid);
+        response.status(HttpStatusCode.Ok).json(keys);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findAPIKey = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('actrl-find-apikey');
+
+    try {
+
+        const keyid = request.params.apikey;
+
+        const apiKeyRepository = repoFactory.getAPIKeyRepository();
+        const key = await apiKeyRepository.findAPIKey(keyid);
+        if (key) {
+            response.status(HttpStatusCode.Ok).json(key);
+        } else {
+            response.status(HttpStatusCode.NotFound).json({message: "api key not found"});
+        }
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const revokeAPIKey = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('actrl-revoke-apikey');
+
+    try {
+
+        const keyid = request.params.apikey;
+
+        const apiKeyRepository = repoFactory.getAPIKeyRepository();
+        await apiKeyRepository.revokeAPIKey(keyid);
+        response.sendStatus(HttpStatusCode.NoContent);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const apiKeyController = {
+    createAPIKey,
+    findAPIKeys,
+    findAPIKey,
+    revokeAPIKey
+}
+
+export default apiKeyController;
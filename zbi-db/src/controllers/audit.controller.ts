@@ -0,0 +1,95 @@
+import {Request, Response} from 'express';
+import repoFactory from "../repository";
+import { getLogger, getDuration } from '../lib/logger';
+import { HttpStatusCode } from 'axios';
+import { handleError } from '../lib/errors';
+import * as types from '../types';
+
+const DEFAULT_AUDIT_LIMIT = 100;
+const MAX_AUDIT_LIMIT = 1000;
+
+const createAuditRecord = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('auctrl-create-audit');
+
+    try {
+
+        const record: types.AuditRecord = request.body;
+        if (!record.id || !record.timestamp || !record.action) {
+            response.status(HttpStatusCode.BadRequest).json({message: "id, timestamp and action are required"});
+            return;
+        }
+
+        const timestamp = new Date(record.timestamp);
+        if (isNaN(timestamp.getTime())) {
+            response.status(HttpStatusCode.BadRequest).json({message: "invalid timestamp"});
+            return;
+        }
+        record.timestamp = timestamp;
+
+        const auditRepository = repoFactory.getAuditRepository();
+        await auditRepository.createAuditRecord(record);
+        response.sendStatus(HttpStatusCode.Created);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const parseDate = (value: any): Date | undefined | null => {
+    if (!value) {
+        return undefined;
+    }
+    const date = new Date(value as string);
+    return isNaN(date.getTime()) ? null : date;
+}
+
+const findAuditRecords = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('auctrl-find-audit');
+
+    try {
+
+        const since = parseDate(request.query.since);
+        const until = parseDate(request.query.until);
+        if (since === null || until === null) {
+            response.status(HttpStatusCode.BadRequest).json({message: "since and until must be RFC 3339 times"});
+            return;
+        }
+
+        let limit = DEFAULT_AUDIT_LIMIT;
+        if (request.query.limit) {
+            limit = parseInt(request.query.limit as string);
+            if (isNaN(limit) || limit <= 0 || limit > MAX_AUDIT_LIMIT) {
+                response.status(HttpStatusCode.BadRequest).json({message: `limit must be between 1 and ${MAX_AUDIT_LIMIT}`});
+                return;
+            }
+        }
+
+        const query: types.AuditQuery = {
+            actor: request.query.actor as string,
+            target: request.query.target as string,
+            since, until, limit
+        };
+
+        const auditRepository = repoFactory.getAuditRepository();
+        const records = await auditRepository.findAuditRecords(query);
+        response.status(HttpStatusCode.Ok).json(records);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const auditController = {
+    createAuditRecord,
+    findAuditRecords
+}
+
+export default auditController;
@@ -2,9 +2,13 @@ import userController from "./user.controller";
 import projectController from "./project.controller";
 import instanceController from "./instance.controller";
 import configController from "./config.controller";
+import teamController from "./team.controller";
+import apiKeyController from "./apikey.controller";
+import auditController from "./audit.controller";
+import seedController from "./seed.controller";
 import validator from "./validator";
 import middleware from "./middleware";
 
 export {
-    userController, projectController, instanceController, configController, validator, middleware
+    userController, teamController, apiKeyController, auditController, seedController, projectController, instanceController, configController, validator, middleware
 }
\ No newline at end of file
@@ -44,6 +44,12 @@ const updateInstance = async (request: Request, response: Response): Promise<voi
             logger.info(`project name = ${instance.project?.name}, instance = ${instance.name}, request = ${JSON.stringify(instanceRequest)}`);
             if(instance.request) {
                 instance.request.peers = instanceRequest.peers;
+                instance.request.properties = instanceRequest.properties;
+                instance.request.rateLimit = instanceRequest.rateLimit;
+                instance.request.keyRateLimit = instanceRequest.keyRateLimit;
+                instance.request.ipAllowList = instanceRequest.ipAllowList;
+                instance.request.config = instanceRequest.config;
+                instance.request.mining = instanceRequest.mining;
             }
             instance = await projectRepository.updateInstance(instance);
             response.status(HttpStatusCode.Ok).json( instance );
@@ -59,6 +65,63 @@ const updateInstance = async (request: Request, response: Response): Promise<voi
     }
 }
 
+// updateInstanceField returns a controller that replaces a field of an
+// instance with the request body, or removes the field on DELETE
+const updateInstanceField = (field: string) => {
+    return async (request: Request, response: Response): Promise<void> => {
+        let logger = getLogger(`pctrl-update-instance-${field}`);
+
+        try {
+            const instanceid = request.params.instance;
+            const value = request.method === "DELETE" ? undefined : request.body;
+            if (value !== undefined && (typeof value !== "object" || Array.isArray(value))) {
+                response.status(HttpStatusCode.BadRequest).json({message: `invalid ${field}`});
+                return;
+            }
+
+            const projectRepository = repoFactory.getProjectRepository();
+            const instance = await projectRepository.updateInstanceField(instanceid, field, value);
+            response.status(HttpStatusCode.Ok).json(instance);
+        } catch (err: any) {
+            const result = handleError(err);
+            logger.error(`response - ${JSON.stringify(result)}`);
+            response.status(result.code).json({ message: result.message });
+        } finally {
+            logger.info(`completed in ${getDuration()} ms`);
+        }
+    }
+}
+
+const updateInstanceRotation = updateInstanceField("rotation");
+const updateInstanceDomain = updateInstanceField("domain");
+const updateInstanceSchedule = updateInstanceField("schedule");
+const updateInstanceHold = updateInstanceField("hold");
+const updateInstancePower = updateInstanceField("power");
+const updateInstanceImage = updateInstanceField("image");
+
+const updateInstanceAllowList = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('pctrl-update-instance-allowlist');
+
+    try {
+        const instanceid = request.params.instance;
+        const allowList = request.body.ipAllowList ? request.body.ipAllowList : [];
+        if (!Array.isArray(allowList) || allowList.some((entry: any) => typeof entry !== "string")) {
+            response.status(HttpStatusCode.BadRequest).json({message: "ipAllowList must be a list of addresses"});
+            return;
+        }
+
+        const projectRepository = repoFactory.getProjectRepository();
+        const instance = await projectRepository.updateInstanceField(instanceid, "request.ipAllowList", allowList);
+        response.status(HttpStatusCode.Ok).json(instance);
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const deleteInstance = async (request: Request, response: Response): Promise<void> => {
     let logger = getLogger('pctrl-delete-instance');
 
@@ -335,6 +398,13 @@ const getInstanceUserPermision = async (request: Request, response: Response): P
 const instanceController = {
     findInstance,
     updateInstance,
+    updateInstanceRotation,
+    updateInstanceDomain,
+    updateInstanceSchedule,
+    updateInstanceHold,
+    updateInstancePower,
+    updateInstanceImage,
+    updateInstanceAllowList,
     deleteInstance,
     purgeInstance,
     getInstanceResources,
@@ -165,6 +165,17 @@ const validateProject = async (request: Request, response: Response, next: NextF
     }
 }
 
+const validateTeam = async (request: Request, response: Response, next: NextFunction) => {
+    try {
+        const teamRepository = repoFactory.getTeamRepository();
+        const valid = await teamRepository.checkTeamId(request.params.team);
+        if(valid) return next();
+        response.status(HttpStatusCode.NotFound).json({message: 'team not found'});
+    } catch (err: any) {
+        response.status(HttpStatusCode.BadRequest).json({message: 'valid team is required'});   
+    }
+}
+
 const validateInstance = async (request: Request, response: Response, next: NextFunction) => {
     const logger = getLogger("set-instance");
     try {
@@ -189,6 +200,7 @@ const logRequest =async (request: Request, response: Response, next: NextFunctio
 const middleware = {
     initRequest,
     validateProject,
+    validateTeam,
     validateInstance
 }
 
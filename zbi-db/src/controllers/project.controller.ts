@@ -2,7 +2,7 @@ import {Request, Response} from 'express';
 import repoFactory from "../repository";
 import { getLogger, getDuration } from '../lib/logger';
 import { HttpStatusCode } from 'axios';
-import { handleError } from '../lib/errors';
+import { handleError, InvalidRequestError, ItemNotFoundError } from '../lib/errors';
 import * as types from '../types';
 import { Constants } from '../constants';
 
@@ -27,8 +27,14 @@ const createProject = async (request: Request, response: Response): Promise<void
         const blockchain = projectRequest.blockchain;
         const network = projectRequest.network;
         const description = projectRequest.description as string;
+        const team = projectRequest.team ? projectRequest.team : undefined;
+        const labels = projectRequest.labels ? projectRequest.labels : undefined;
 
-        const project = await projectRepository.createProject(id, name, owner, blockchain, network, description);
+        if (team && !(await repoFactory.getTeamRepository().findTeam(team))) {
+            throw new ItemNotFoundError("team not found");
+        }
+
+        const project = await projectRepository.createProject(id, name, owner, blockchain, network, description, team, labels);
         logger.info(`created project: ${JSON.stringify(project)}`);
         response.status(HttpStatusCode.Created).json(project);
 
@@ -65,6 +71,55 @@ const findProjects = async (request: Request, response: Response): Promise<void>
 
 }
 
+const parseListDate = (value: any, name: string): Date | undefined => {
+    if (!value) {
+        return undefined;
+    }
+    const date = new Date(value as string);
+    if (isNaN(date.getTime())) {
+        throw new InvalidRequestError(`${name} must be an RFC 3339 time`);
+    }
+    return date;
+}
+
+// parseListQuery reads the filter, sort and page parameters of a list request
+const parseListQuery = (request: Request): types.ListQuery => {
+    const param = (name: string) => request.query[name] ? request.query[name] as string : undefined;
+
+    const limit = request.query.limit ? Number(request.query.limit) : 0;
+    if (!Number.isInteger(limit) || limit < 0) {
+        throw new InvalidRequestError("limit must be a positive integer");
+    }
+
+    return {
+        member: param("member"), owner: param("owner"), status: param("status"), state: param("state"),
+        type: param("type"), network: param("network"), labelSelector: param("labelSelector"),
+        createdBefore: parseListDate(request.query.createdBefore, "createdBefore"),
+        createdAfter: parseListDate(request.query.createdAfter, "createdAfter"),
+        sort: param("sort"), limit, cursor: param("cursor")
+    };
+}
+
+const listProjects = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('pctrl-list-projects');
+
+    try {
+        const query = parseListQuery(request);
+        logger.info(`request - ${JSON.stringify(query)}`);
+
+        const projectRepository = repoFactory.getProjectRepository();
+        const projects = await projectRepository.listProjects(query);
+
+        response.status(HttpStatusCode.Ok).json(projects);
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const findProject = async (request: Request, response: Response): Promise<void> => {
     let logger = getLogger('pctrl-find-project');
 
@@ -92,20 +147,64 @@ const updateProject = async (request: Request, response: Response): Promise<void
     try {
 
         const projectid = request.params.project;
+        const updateRequest: types.ProjectUpdateRequest = request.body;
 
         const projectRepository = repoFactory.getProjectRepository();
         let project = await projectRepository.findProject(projectid) as types.Project;
 
+        if (updateRequest.description !== undefined) {
+            project.description = updateRequest.description;
+        }
+        if (updateRequest.labels) {
+            project.labels = updateRequest.labels;
+        }
+        if (updateRequest.rotation) {
+            project.rotation = updateRequest.rotation;
+        }
+
         project = await projectRepository.updateProject(project);
         response.status(HttpStatusCode.Ok).json(project);
         
     } catch (err: any) {
-        
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
     } finally {
         logger.info(`completed in ${getDuration()} ms`);
     }
 }
 
+// updateProjectField returns a controller that replaces a field of a
+// project with the request body, or removes the field on DELETE
+const updateProjectField = (field: string) => {
+    return async (request: Request, response: Response): Promise<void> => {
+        let logger = getLogger(`pctrl-update-project-${field}`);
+
+        try {
+            const projectid = request.params.project;
+            const value = request.method === "DELETE" ? undefined : request.body;
+            if (value !== undefined && (typeof value !== "object" || Array.isArray(value))) {
+                response.status(HttpStatusCode.BadRequest).json({message: `invalid ${field}`});
+                return;
+            }
+
+            const projectRepository = repoFactory.getProjectRepository();
+            const project = await projectRepository.updateProjectField(projectid, field, value);
+            response.status(HttpStatusCode.Ok).json(project);
+        } catch (err: any) {
+            const result = handleError(err);
+            logger.error(`response - ${JSON.stringify(result)}`);
+            response.status(result.code).json({ message: result.message });
+        } finally {
+            logger.info(`completed in ${getDuration()} ms`);
+        }
+    }
+}
+
+const updateProjectDomain = updateProjectField("domain");
+const updateProjectSchedule = updateProjectField("schedule");
+const updateProjectUpgrade = updateProjectField("upgrade");
+
 const deleteProject = async (request: Request, response: Response): Promise<void> => {
     let logger = getLogger('pctrl-delete-project');
 
@@ -183,7 +282,8 @@ const createInstance = async (request: Request, response: Response): Promise<voi
         if( instanceRequest.volume?.source === types.VolumeSourceType.volume) {
             const instance = await projectRepository.findInstance(instanceRequest.volume?.ref as string);
             sourceName = instance?.id as string;
-        } else if( instanceRequest.volume?.source === types.VolumeSourceType.snapshot ) {
+        } else if( instanceRequest.volume?.source === types.VolumeSourceType.snapshot ||
+                   instanceRequest.volume?.source === types.VolumeSourceType.seed ) {
             sourceName = instanceRequest.volume.ref;
         }
 
@@ -191,8 +291,13 @@ const createInstance = async (request: Request, response: Response): Promise<voi
         const volumeSource = instanceRequest.volume?.source as types.VolumeSourceType;
         const peers = instanceRequest.peers as string[];
         const properties = instanceRequest.properties;
+        const rateLimit = instanceRequest.rateLimit;
+        const keyRateLimit = instanceRequest.keyRateLimit;
+        const ipAllowList = instanceRequest.ipAllowList;
+        const config = instanceRequest.config;
+        const mining = instanceRequest.mining;
 
-        const resourceRequest = {peers, properties,
+        const resourceRequest = {peers, properties, rateLimit, keyRateLimit, ipAllowList, config, mining,
             volume: {
                 type: volumeType, size: "15Gi", // add to config
                 source: {type: volumeSource,ref: sourceName}
@@ -240,6 +345,27 @@ const findInstances = async (request: Request, response: Response): Promise<void
     }
 }
 
+const listInstances = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('pctrl-list-instances');
+
+    try {
+        const projectid = request.params.project;
+        const query = parseListQuery(request);
+        logger.info(`request - ${JSON.stringify(query)}`);
+
+        const projectRepository = repoFactory.getProjectRepository();
+        const instances = await projectRepository.listInstances(projectid, query);
+
+        response.status(HttpStatusCode.Ok).json(instances);
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const getProjectResources = async (request: Request, response: Response): Promise<void> => {
     let logger = getLogger('pctrl-get-project-resources');
     try {
@@ -461,8 +587,12 @@ const getProjectUserPermision = async (request: Request, response: Response): Pr
 const projectController = {
     createProject,
     findProjects,
+    listProjects,
     findProject,
     updateProject,
+    updateProjectDomain,
+    updateProjectSchedule,
+    updateProjectUpgrade,
     deleteProject,
     purgeProject,
 
@@ -470,6 +600,7 @@ const projectController = {
 
     createInstance,
     findInstances,
+    listInstances,
 
     getProjectResources,
     updateProjectResource,
@@ -0,0 +1,122 @@
+import {Request, Response} from 'express';
+import repoFactory from "../repository";
+import { getLogger, getDuration } from '../lib/logger';
+import { HttpStatusCode } from 'axios';
+import { handleError } from '../lib/errors';
+import * as types from '../types';
+
+const createSeed = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('sctrl-create-seed');
+
+    try {
+
+        const seed: types.Seed = request.body;
+
+        const seedRepository = repoFactory.getSeedRepository();
+        const newSeed = await seedRepository.createSeed(seed);
+        logger.info(`created seed ${newSeed.name} for ${newSeed.network}`);
+        response.status(HttpStatusCode.Created).json(newSeed);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findSeeds = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('sctrl-find-seeds');
+
+    try {
+
+        const network = request.query.network as string;
+
+        const seedRepository = repoFactory.getSeedRepository();
+        const seeds = await seedRepository.findSeeds(network);
+        response.status(HttpStatusCode.Ok).json(seeds);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findSeed = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('sctrl-find-seed');
+
+    try {
+
+        const seedid = request.params.seed;
+
+        const seedRepository = repoFactory.getSeedRepository();
+        const seed = await seedRepository.findSeed(seedid);
+        if (seed) {
+            response.status(HttpStatusCode.Ok).json(seed);
+        } else {
+            response.status(HttpStatusCode.NotFound).json({message: "seed not found"});
+        }
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const updateSeed = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('sctrl-update-seed');
+
+    try {
+
+        const seedid = request.params.seed;
+        const seed: types.Seed = request.body;
+
+        const seedRepository = repoFactory.getSeedRepository();
+        const updated = await seedRepository.updateSeed(seedid, seed);
+        response.status(HttpStatusCode.Ok).json(updated);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const deleteSeed = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('sctrl-delete-seed');
+
+    try {
+
+        const seedid = request.params.seed;
+
+        const seedRepository = repoFactory.getSeedRepository();
+        await seedRepository.deleteSeed(seedid);
+        response.sendStatus(HttpStatusCode.NoContent);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const seedController = {
+    createSeed,
+    findSeeds,
+    findSeed,
+    updateSeed,
+    deleteSeed
+}
+
+export default seedController;
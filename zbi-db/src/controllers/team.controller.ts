@@ -0,0 +1,190 @@
+import {Request, Response} from 'express';
+import repoFactory from "../repository";
+import { getLogger, getDuration } from '../lib/logger';
+import { HttpStatusCode } from 'axios';
+import { handleError } from '../lib/errors';
+import * as types from '../types';
+
+const createTeam = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-create-team');
+
+    try {
+
+        const teamRequest: types.Team = request.body;
+        const teamRepository = repoFactory.getTeamRepository();
+
+        const id = repoFactory.generateId();
+        const members = teamRequest.members ? teamRequest.members : [];
+        const quota = teamRequest.quota ? teamRequest.quota : {maxProjects: 0, maxInstances: 0};
+
+        const team = await teamRepository.createTeam(id, teamRequest.name, members, quota);
+        logger.info(`created team: ${JSON.stringify(team)}`);
+        response.status(HttpStatusCode.Created).json(team);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findTeams = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-find-teams');
+
+    try {
+
+        const member = request.query.member as string | undefined;
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const teams = await teamRepository.findTeams(member);
+        response.status(HttpStatusCode.Ok).json(teams);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findTeam = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-find-team');
+
+    try {
+
+        const teamid = request.params.team;
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const team = await teamRepository.findTeam(teamid);
+        response.status(HttpStatusCode.Ok).json(team);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findTeamProjects = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-find-team-projects');
+
+    try {
+
+        const teamid = request.params.team;
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const projects = await teamRepository.findTeamProjects(teamid);
+        response.status(HttpStatusCode.Ok).json(projects);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const countTeamInstances = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-count-team-instances');
+
+    try {
+
+        const teamid = request.params.team;
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const count = await teamRepository.countTeamInstances(teamid);
+        response.status(HttpStatusCode.Ok).json({count});
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const updateTeamMember = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-update-team-member');
+
+    try {
+
+        const teamid = request.params.team;
+        const member: types.TeamMember = {userid: request.params.member, role: request.body.role};
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const team = await teamRepository.updateTeamMember(teamid, member);
+        response.status(HttpStatusCode.Ok).json(team);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const removeTeamMember = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-remove-team-member');
+
+    try {
+
+        const teamid = request.params.team;
+        const userid = request.params.member;
+
+        const teamRepository = repoFactory.getTeamRepository();
+        await teamRepository.removeTeamMember(teamid, userid);
+        response.sendStatus(HttpStatusCode.NoContent);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const updateTeamQuota = async (request: Request, response: Response): Promise<void> => {
+    let logger = getLogger('tctrl-update-team-quota');
+
+    try {
+
+        const teamid = request.params.team;
+        const quota: types.TeamQuota = {
+            maxProjects: request.body.maxProjects ? request.body.maxProjects : 0,
+            maxInstances: request.body.maxInstances ? request.body.maxInstances : 0
+        };
+
+        const teamRepository = repoFactory.getTeamRepository();
+        const team = await teamRepository.updateTeamQuota(teamid, quota);
+        response.status(HttpStatusCode.Ok).json(team);
+
+    } catch (err: any) {
+        const result = handleError(err);
+        logger.error(`response - ${JSON.stringify(result)}`);
+        response.status(result.code).json({ message: result.message });
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const teamController = {
+    createTeam,
+    findTeams,
+    findTeam,
+    findTeamProjects,
+    countTeamInstances,
+    updateTeamMember,
+    removeTeamMember,
+    updateTeamQuota
+}
+
+export default teamController;
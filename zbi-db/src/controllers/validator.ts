@@ -16,18 +16,72 @@ const projectSchema = Joi.object({
         name: Joi.string().required().label("name"),
         blockchain: Joi.string().required().label("blockchain"),
         network: Joi.string().required().label("network"),
+        team: Joi.string().allow("").label("team"),
         description: Joi.string().allow("").label("description")
-    }),
+    }).unknown(true),
+});
+
+const teamMemberSchema = Joi.object({
+    userid: Joi.string().required().max(64).label("userid"),
+    role: Joi.string().required().valid("admin", "user").label("role")
+}).unknown(true);
+
+const teamQuotaSchema = Joi.object({
+    maxProjects: Joi.number().integer().min(0).label("maxProjects"),
+    maxInstances: Joi.number().integer().min(0).label("maxInstances")
+});
+
+const teamSchema = Joi.object({
+    body: Joi.object({
+        name: Joi.string().required().max(64).label("name"),
+        members: Joi.array().items(teamMemberSchema).allow(null).label("members"),
+        quota: teamQuotaSchema.allow(null).label("quota")
+    }).unknown(true),
+});
+
+const rateLimitSchema = Joi.object({
+    requestsPerSecond: Joi.number().integer().min(0).label("requestsPerSecond"),
+    burst: Joi.number().integer().min(0).label("burst"),
+    dailyQuota: Joi.number().integer().min(0).label("dailyQuota")
 });
 
 const instanceSchema = Joi.object({
     body: Joi.object({
         name: Joi.string().required().label("name"),
         type: Joi.string().required().label("type"),
-        description: Joi.string().allow("").label("description")
-    })
+        description: Joi.string().allow("").label("description"),
+        rateLimit: rateLimitSchema.label("rateLimit"),
+        keyRateLimit: rateLimitSchema.label("keyRateLimit"),
+        ipAllowList: Joi.array().max(50).items(Joi.string()).label("ipAllowList"),
+        config: Joi.object().max(32).label("config"),
+        mining: Joi.object({
+            minerAddress: Joi.string().max(256).label("minerAddress"),
+            genProcLimit: Joi.number().integer().min(-1).max(64).label("genProcLimit"),
+            solver: Joi.string().valid("default", "tromp").label("solver"),
+            mineToLocalWallet: Joi.boolean().label("mineToLocalWallet")
+        }).label("mining")
+    }).unknown(true)
 });
 
+const seedSchema = Joi.object({
+    body: Joi.object({
+        name: Joi.string().required().max(63).label("name"),
+        blockchain: Joi.string().required().valid("zcash").label("blockchain"),
+        network: Joi.string().required().valid("mainnet", "testnet").label("network"),
+        type: Joi.string().required().valid("snapshot", "archive").label("type"),
+        description: Joi.string().allow("").max(256).label("description"),
+        height: Joi.number().integer().min(0).label("height"),
+        size: Joi.string().allow("").label("size"),
+        snapshot: Joi.object({
+            handle: Joi.string().required().label("handle"),
+            driver: Joi.string().required().label("driver")
+        }).unknown(true).when("type", {is: "snapshot", then: Joi.required()}).label("snapshot"),
+        archive: Joi.object({
+            url: Joi.string().required().label("url"),
+            sha256: Joi.string().required().length(64).hex().label("sha256")
+        }).unknown(true).when("type", {is: "archive", then: Joi.required()}).label("archive")
+    }).unknown(true)
+});
 
 const validateSchema = (schema: ObjectSchema, data: any) => {
 
@@ -86,11 +140,11 @@ const projectNameExists = async (request: Request, response: Response, next: Nex
 
 const instanceNameExists = async (request: Request, response: Response, next: NextFunction) => {
     try {
-        const project = response.locals.project;
+        const project = request.params.project;
         const name = request.body.name;
         const projectRepository = repoFactory.getProjectRepository();
 
-        const instance = await projectRepository.findInstances({project: project.id, name});
+        const instance = await projectRepository.findInstances({project, name});
         if(instance.length>0) {
             response.status(HttpStatusCode.BadRequest).json({message: 'instance already exists with name'});
         } else {
@@ -101,6 +155,27 @@ const instanceNameExists = async (request: Request, response: Response, next: Ne
     }
 }
 
+// domainNotInUse rejects a domain whose host is already served by another project or instance
+const domainNotInUse = async (request: Request, response: Response, next: NextFunction) => {
+    try {
+        const host = request.body.host;
+        if (typeof host !== "string" || host.length === 0) {
+            response.status(HttpStatusCode.BadRequest).json({message: 'domain host is required'});
+            return;
+        }
+
+        const owner = request.params.instance ? request.params.instance : request.params.project;
+        const projectRepository = repoFactory.getProjectRepository();
+        if (await projectRepository.domainInUse(host, owner)) {
+            response.status(HttpStatusCode.Conflict).json({message: 'domain already in use'});
+        } else {
+            return next();
+        }
+    } catch (err: any) {
+        response.sendStatus(HttpStatusCode.InternalServerError);
+    }
+}
+
 const validateRequest = (message: string, schema: ObjectSchema, payload: any, response: Response, next: NextFunction) => {
     const logger = getLogger('validate-request');
     try {
@@ -196,13 +271,38 @@ const validateUpdateInstance = async (request: Request, response: Response, next
 }
 
 
+const validateNewTeam = async (request: Request, response: Response, next: NextFunction) => {
+    const payload = {body: request.body};
+    validateRequest("team request could not be processed", teamSchema, payload, response, next);
+}
+
+const validateTeamMember = async (request: Request, response: Response, next: NextFunction) => {
+    const payload = {body: request.body};
+    validateRequest("team member request could not be processed", Joi.object({body: teamMemberSchema}), payload, response, next);
+}
+
+const validateTeamQuota = async (request: Request, response: Response, next: NextFunction) => {
+    const payload = {body: request.body};
+    validateRequest("team quota request could not be processed", Joi.object({body: teamQuotaSchema.unknown(true)}), payload, response, next);
+}
+
+const validateSeed = async (request: Request, response: Response, next: NextFunction) => {
+    const payload = {body: request.body};
+    validateRequest("seed request could not be processed", seedSchema, payload, response, next);
+}
+
 const validator = {
     userEmailExists,
     projectNameExists,
     instanceNameExists,
+    domainNotInUse,
     validateNewProject,
     validateNewInstance,
-    validateUpdateInstance
+    validateUpdateInstance,
+    validateNewTeam,
+    validateTeamMember,
+    validateTeamQuota,
+    validateSeed
 }
 
 export default validator;
\ No newline at end of file
@@ -52,6 +52,18 @@ export class ItemAlreadyExistsError extends AppError {
     }
 }
 
+export class ItemConflictError extends AppError {
+    constructor(message: string) {
+        super(message, HttpStatusCode.Conflict);
+    }
+}
+
+export class InvalidRequestError extends AppError {
+    constructor(message: string) {
+        super(message, HttpStatusCode.BadRequest);
+    }
+}
+
 export function handleError(err: Error) {
     if(err instanceof ItemNotFoundError) {
         return {code: HttpStatusCode.NotFound, message: err.message};
@@ -60,6 +72,14 @@ export function handleError(err: Error) {
     else if(err instanceof ItemAlreadyExistsError) {
         return {code: HttpStatusCode.BadRequest, message: err.message};
     } 
+
+    else if(err instanceof ItemConflictError) {
+        return {code: HttpStatusCode.Conflict, message: err.message};
+    }
+
+    else if(err instanceof InvalidRequestError) {
+        return {code: HttpStatusCode.BadRequest, message: err.message};
+    }
     
 
     // else if(err instanceof ServiceError) {
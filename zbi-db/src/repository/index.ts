@@ -21,6 +21,22 @@ class RepositoryFactory {
         return mongo.userRepository;
     }
 
+    getTeamRepository() {
+        return mongo.teamRepository;
+    }
+
+    getAPIKeyRepository() {
+        return mongo.apiKeyRepository;
+    }
+
+    getSeedRepository() {
+        return mongo.seedRepository;
+    }
+
+    getAuditRepository() {
+        return mongo.auditRepository;
+    }
+
     getProjectRepository() {
         return mongo.projectRepository;
     }
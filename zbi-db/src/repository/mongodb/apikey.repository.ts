@@ -0,0 +1,92 @@
+import { APIKey } from "../../types";
+import { apiKeyModel } from "./schema";
+import * as fn from "./fn";
+import { getDuration, getLogger } from "../../lib/logger";
+import { ItemConflictError, ItemNotFoundError } from "../../lib/errors";
+
+const createAPIKey = async (key: APIKey): Promise<APIKey> => {
+    let logger = getLogger('repo-create-apikey');
+    try {
+        const existing = await apiKeyModel.findById(key.id, {_id: 1});
+        if (existing) {
+            throw new ItemConflictError("api key already exists");
+        }
+
+        const apiKey = new apiKeyModel({
+            _id: key.id,
+            instance: key.instanceId,
+            instanceName: key.instanceName,
+            namespace: key.namespace,
+            owner: key.owner,
+            consumer: key.consumer,
+            labels: key.labels,
+            hash: key.hash,
+            expiresAt: key.expiresAt
+        });
+        await apiKey.save();
+        return fn.createAPIKey(apiKey);
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findAPIKey = async (id: string): Promise<APIKey | undefined> => {
+    let logger = getLogger('repo-find-apikey');
+    try {
+        const key = await apiKeyModel.findById(id);
+        if (key) {
+            return fn.createAPIKey(key);
+        }
+
+        return undefined;
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// findAPIKeys returns the keys issued for an instance, oldest first, including revoked keys
+const findAPIKeys = async (instance: string): Promise<APIKey[]> => {
+    let logger = getLogger('repo-find-apikeys');
+    try {
+        const keys = await apiKeyModel.find({instance}).sort({createdAt: 1});
+        return keys.map((key: any) => fn.createAPIKey(key));
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// revokeAPIKey marks a key as revoked. Revoking a key again keeps the first revocation time
+const revokeAPIKey = async (id: string): Promise<APIKey> => {
+    let logger = getLogger('repo-revoke-apikey');
+    try {
+        const key = await apiKeyModel.findById(id);
+        if (key) {
+            if (!key.revokedAt) {
+                key.revokedAt = new Date();
+                await key.save();
+            }
+            return fn.createAPIKey(key);
+        }
+
+        throw new ItemNotFoundError("api key not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const apiKeyMongoRepository = {
+    createAPIKey,
+    findAPIKey,
+    findAPIKeys,
+    revokeAPIKey
+}
+
+export default apiKeyMongoRepository
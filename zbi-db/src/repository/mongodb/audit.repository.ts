@@ -0,0 +1,84 @@
+import { AuditQuery, AuditRecord } from "../../types";
+import { auditModel } from "./schema";
+import * as fn from "./fn";
+import { getDuration, getLogger } from "../../lib/logger";
+import { ItemConflictError } from "../../lib/errors";
+
+const createAuditRecord = async (record: AuditRecord): Promise<AuditRecord> => {
+    let logger = getLogger('repo-create-audit');
+    try {
+        const existing = await auditModel.findById(record.id, {_id: 1});
+        if (existing) {
+            throw new ItemConflictError("audit record already exists");
+        }
+
+        const audit = new auditModel({
+            _id: record.id,
+            timestamp: record.timestamp,
+            txid: record.txid,
+            source: record.source,
+            actor: record.actor,
+            role: record.role,
+            sourceIP: record.sourceIP,
+            forwardedFor: record.forwardedFor,
+            action: record.action,
+            method: record.method,
+            path: record.path,
+            target: record.target,
+            bodyHash: record.bodyHash,
+            objects: record.objects,
+            detail: record.detail,
+            outcome: record.outcome,
+            status: record.status,
+            error: record.error
+        });
+        await audit.save();
+        return fn.createAuditRecord(audit);
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const escapeRegExp = (value: string): string => {
+    return value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
+
+// findAuditRecords returns the records matching the query, newest first. The target matches either
+// the full target, e.g. instance/<id>, or its id
+const findAuditRecords = async (query: AuditQuery): Promise<AuditRecord[]> => {
+    let logger = getLogger('repo-find-audit');
+    try {
+        const filter: any = {};
+        if (query.actor) {
+            filter.actor = query.actor;
+        }
+        if (query.target) {
+            filter.$or = [{target: query.target}, {target: {$regex: `/${escapeRegExp(query.target)}$`}}];
+        }
+        if (query.since || query.until) {
+            filter.timestamp = {};
+            if (query.since) {
+                filter.timestamp.$gte = query.since;
+            }
+            if (query.until) {
+                filter.timestamp.$lte = query.until;
+            }
+        }
+
+        const records = await auditModel.find(filter).sort({timestamp: -1}).limit(query.limit);
+        return records.map((record: any) => fn.createAuditRecord(record));
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const auditMongoRepository = {
+    createAuditRecord,
+    findAuditRecords
+}
+
+export default auditMongoRepository
@@ -1,5 +1,5 @@
 import mongoose from "mongoose";
-import { Activity, BlockchainInfo, Instance, KubernetesResource, KubernetesResources, NodeInfo, Permission, PolicyInfo, Project, ResourceType, User, UserPermissions } from "../../types";
+import { Activity, APIKey, AuditRecord, Seed, BlockchainInfo, Instance, KubernetesResource, KubernetesResources, NodeInfo, Permission, PolicyInfo, Project, ResourceType, Team, User, UserPermissions } from "../../types";
 
 const generateId = () => {
     return (new mongoose.mongo.ObjectId()).toString()
@@ -9,12 +9,18 @@ const createProject = (project: any): Project => {
     return {
         id: project._id.toString(),
         owner: project.owner ? project.owner.toString() : undefined,
+        team: project.team ? project.team.toString() : undefined,
         name: project.name,
         blockchain: project.blockchain,
         network: project.network,
         status: project.status,
         state: project.state,
         description: project.description ? project.description as string : undefined,
+        labels: project.labels ? Object.fromEntries(project.labels) : undefined,
+        rotation: project.rotation ? project.rotation : undefined,
+        schedule: project.schedule ? project.schedule : undefined,
+        upgrade: project.upgrade ? project.upgrade : undefined,
+        domain: project.domain ? project.domain : undefined,
         createdAt: project.createdAt ? new Date(project.createdAt) : undefined,
         updatedAt: project.updatedAt ? new Date(project.updatedAt) : undefined
     }
@@ -30,11 +36,96 @@ const createInstance = (instance: any): Instance => {
         request: instance.request,
         status: instance.status,
         state: instance.state,
+        rotation: instance.rotation ? instance.rotation : undefined,
+        schedule: instance.schedule ? instance.schedule : undefined,
+        hold: instance.hold ? instance.hold : undefined,
+        power: instance.power ? instance.power : undefined,
+        image: instance.image ? instance.image : undefined,
+        domain: instance.domain ? instance.domain : undefined,
         createdAt: new Date(instance.createdAt),
         updatedAt: new Date(instance.updatedAt)
     }
 }
 
+const createTeam = (team: any): Team => {
+    return {
+        id: team._id.toString(),
+        name: team.name,
+        members: team.members.map((member: any) => {
+            return {userid: member.userid, role: member.role};
+        }),
+        quota: {
+            maxProjects: team.quota?.maxProjects ?? 0,
+            maxInstances: team.quota?.maxInstances ?? 0
+        },
+        createdAt: team.createdAt ? new Date(team.createdAt) : undefined,
+        updatedAt: team.updatedAt ? new Date(team.updatedAt) : undefined
+    }
+}
+
+const createAPIKey = (key: any): APIKey => {
+    return {
+        id: key._id,
+        instanceId: key.instance.toString(),
+        instanceName: key.instanceName,
+        namespace: key.namespace,
+        owner: key.owner,
+        consumer: key.consumer,
+        labels: key.labels ? Object.fromEntries(key.labels) : undefined,
+        hash: key.hash,
+        createdAt: key.createdAt ? new Date(key.createdAt) : undefined,
+        expiresAt: key.expiresAt ? new Date(key.expiresAt) : undefined,
+        revokedAt: key.revokedAt ? new Date(key.revokedAt) : undefined
+    }
+}
+
+const createSeed = (seed: any): Seed => {
+    return {
+        id: seed._id,
+        name: seed.name,
+        blockchain: seed.blockchain,
+        network: seed.network,
+        type: seed.type,
+        description: seed.description,
+        height: seed.height,
+        size: seed.size,
+        snapshot: seed.snapshot && seed.snapshot.handle ? {
+            handle: seed.snapshot.handle, driver: seed.snapshot.driver, snapshotClass: seed.snapshot.snapshotClass
+        } : undefined,
+        archive: seed.archive && seed.archive.url ? {
+            url: seed.archive.url, sha256: seed.archive.sha256, format: seed.archive.format
+        } : undefined,
+        createdBy: seed.createdBy,
+        createdAt: seed.createdAt ? new Date(seed.createdAt) : undefined,
+        updatedAt: seed.updatedAt ? new Date(seed.updatedAt) : undefined
+    }
+}
+
+const createAuditRecord = (record: any): AuditRecord => {
+    return {
+        id: record._id,
+        timestamp: new Date(record.timestamp),
+        txid: record.txid,
+        source: record.source,
+        actor: record.actor,
+        role: record.role,
+        sourceIP: record.sourceIP,
+        forwardedFor: record.forwardedFor,
+        action: record.action,
+        method: record.method,
+        path: record.path,
+        target: record.target,
+        bodyHash: record.bodyHash,
+        objects: record.objects && record.objects.length > 0 ? record.objects.map((object: any) => {
+            return {operation: object.operation, resource: object.resource, namespace: object.namespace, name: object.name}
+        }) : undefined,
+        detail: record.detail,
+        outcome: record.outcome,
+        status: record.status,
+        error: record.error
+    }
+}
+
 const createKubernetesResource = (resource: any): KubernetesResource => {
     return {
         name: resource.name,
@@ -67,6 +158,8 @@ const createKubernetesResources = (resources: any): KubernetesResources => {
     const httpproxy = createResources( resources.filter((resource: any) => resource.type === ResourceType.httpproxy));
     const volumesnapshot = createResources( resources.filter((resource: any) => resource.type === ResourceType.volumesnapshot));
     const snapshotschedule = createResources( resources.filter((resource: any) => resource.type === ResourceType.snapshotschedule));
+    const certificate = createResources( resources.filter((resource: any) => resource.type === ResourceType.certificate));
+    const p2p = createResources( resources.filter((resource: any) => resource.type === ResourceType.p2pservice));
 
     return {
         namespace: namespace ? namespace[0] : undefined,
@@ -78,6 +171,8 @@ const createKubernetesResources = (resources: any): KubernetesResources => {
         httpproxy: httpproxy ? httpproxy[0] : undefined,
         volumesnapshot: volumesnapshot && volumesnapshot.length > 0 ? volumesnapshot : undefined,
         snapshotschedule: snapshotschedule ? snapshotschedule[0] : undefined,
+        certificate: certificate ? certificate[0] : undefined,
+        p2p: p2p ? p2p[0] : undefined,
     }
 }
 
@@ -207,7 +302,7 @@ const decodeMap = (data: any): Map<string, string> => {
 }
 
 export {
-    generateId, createProject, createUser, createInstance, 
+    generateId, createProject, createTeam, createUser, createInstance, createAPIKey, createSeed, createAuditRecord,
     createKubernetesResource, createResources,
     createKubernetesResources, createActivity, createActivities,
     createPermission, createPermissions, createUserPermissions,
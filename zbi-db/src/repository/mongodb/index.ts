@@ -1,6 +1,10 @@
 import mongoose from "mongoose";
 import projectMongoRepository from "./project.repository";
 import userMongoRepository from "./user.repository";
+import teamMongoRepository from "./team.repository";
+import apiKeyMongoRepository from "./apikey.repository";
+import auditMongoRepository from "./audit.repository";
+import seedMongoRepository from "./seed.repository";
 import configMongoRepository from "./config.repository";
 import * as schema from "./schema";
 import * as fn from "./fn";
@@ -50,9 +54,13 @@ const database = {
 
 const projectRepository = projectMongoRepository;
 const userRepository = userMongoRepository;
+const teamRepository = teamMongoRepository;
+const apiKeyRepository = apiKeyMongoRepository;
+const auditRepository = auditMongoRepository;
+const seedRepository = seedMongoRepository;
 const configRepository = configMongoRepository;
 
 export {
-    database, projectRepository, userRepository, configRepository, 
+    database, projectRepository, userRepository, teamRepository, apiKeyRepository, auditRepository, seedRepository, configRepository, 
     schema, fn
 }
\ No newline at end of file
@@ -0,0 +1,172 @@
+import { FilterQuery, Types } from "mongoose";
+import { ListQuery } from "../../types";
+import { InvalidRequestError } from "../../lib/errors";
+
+const DEFAULT_SORT_FIELD = "name";
+
+// sort fields of projects and instances. Fields that do not vary between the
+// listed objects map to undefined and sort by id only.
+const PROJECT_SORT_FIELDS: {[key: string]: string | undefined} = {
+    name: "name", owner: "owner", status: "status", state: "state", type: undefined,
+    network: "network", createdAt: "createdAt", updatedAt: "updatedAt"
+};
+
+const INSTANCE_SORT_FIELDS: {[key: string]: string | undefined} = {
+    name: "name", owner: undefined, status: "status", state: "state", type: "type",
+    network: undefined, createdAt: "createdAt", updatedAt: "updatedAt"
+};
+
+interface ListCursor {
+    s: string;
+    k: any;
+    i: string;
+}
+
+export interface ListSort {
+    field?: string;
+    desc: boolean;
+}
+
+const parseSort = (value: string | undefined, fields: {[key: string]: string | undefined}): ListSort => {
+    const sort = value ? value : DEFAULT_SORT_FIELD;
+    const desc = sort.startsWith("-");
+    const name = desc ? sort.substring(1) : sort;
+    if (!(name in fields)) {
+        throw new InvalidRequestError("invalid sort field");
+    }
+    return {field: fields[name], desc};
+}
+
+export const parseProjectSort = (value?: string): ListSort => parseSort(value, PROJECT_SORT_FIELDS);
+export const parseInstanceSort = (value?: string): ListSort => parseSort(value, INSTANCE_SORT_FIELDS);
+
+// splitSelector splits a label selector on the commas outside of value sets
+const splitSelector = (selector: string): string[] => {
+    const requirements: string[] = [];
+    let depth = 0, start = 0;
+    for (let index = 0; index < selector.length; index++) {
+        const c = selector[index];
+        if (c === "(") depth++;
+        else if (c === ")") depth--;
+        else if (c === "," && depth === 0) {
+            requirements.push(selector.substring(start, index));
+            start = index + 1;
+        }
+    }
+    requirements.push(selector.substring(start));
+    return requirements.map(requirement => requirement.trim());
+}
+
+const LABEL_KEY = /^([a-z0-9A-Z]([-a-z0-9A-Z_.]*[a-z0-9A-Z])?\/)?[a-z0-9A-Z]([-a-z0-9A-Z_.]*[a-z0-9A-Z])?$/;
+const LABEL_VALUE = /^([a-z0-9A-Z]([-a-z0-9A-Z_.]*[a-z0-9A-Z])?)?$/;
+
+const labelPath = (key: string): string => {
+    if (!LABEL_KEY.test(key)) {
+        throw new InvalidRequestError("invalid label selector");
+    }
+    return `labels.${key}`;
+}
+
+const labelValues = (values: string): string[] => {
+    const result = values.split(",").map(value => value.trim());
+    if (result.some(value => !LABEL_VALUE.test(value))) {
+        throw new InvalidRequestError("invalid label selector");
+    }
+    return result;
+}
+
+// labelSelectorFilter converts a Kubernetes label selector to a filter on
+// the labels field. It supports equality, inequality, set and existence
+// requirements.
+export const labelSelectorFilter = (selector?: string): FilterQuery<any>[] => {
+    if (!selector || selector.trim() === "") {
+        return [];
+    }
+
+    return splitSelector(selector).map(requirement => {
+        let match = requirement.match(/^(\S+)\s+(in|notin)\s+\((.*)\)$/);
+        if (match) {
+            const values = labelValues(match[3]);
+            return {[labelPath(match[1])]: match[2] === "in" ? {$in: values} : {$nin: values}};
+        }
+
+        match = requirement.match(/^([^=!\s]+)\s*(==|=|!=)\s*(\S*)$/);
+        if (match) {
+            const value = labelValues(match[3])[0];
+            return {[labelPath(match[1])]: match[2] === "!=" ? {$ne: value} : value};
+        }
+
+        match = requirement.match(/^(!?)\s*([^=!\s]+)$/);
+        if (match) {
+            return {[labelPath(match[2])]: {$exists: match[1] !== "!"}};
+        }
+
+        throw new InvalidRequestError("invalid label selector");
+    });
+}
+
+// objectIdFilter matches an ObjectId field, and nothing when the value is not
+// a valid id.
+export const objectIdFilter = (field: string, value: string): FilterQuery<any> => {
+    return Types.ObjectId.isValid(value) ? {[field]: value} : {_id: {$exists: false}};
+}
+
+export const createdFilter = (query: ListQuery): FilterQuery<any>[] => {
+    const filters: FilterQuery<any>[] = [];
+    if (query.createdBefore) filters.push({createdAt: {$lt: query.createdBefore}});
+    if (query.createdAfter) filters.push({createdAt: {$gt: query.createdAfter}});
+    return filters;
+}
+
+export const sortOrder = (sort: ListSort): {[key: string]: 1 | -1} => {
+    const direction = sort.desc ? -1 : 1;
+    return sort.field ? {[sort.field]: direction, _id: direction} : {_id: direction};
+}
+
+const sortValue = (object: any, sort: ListSort): any => {
+    if (!sort.field) return null;
+    const value = object[sort.field];
+    if (value === undefined || value === null) return null;
+    if (value instanceof Date) return value.toISOString();
+    return value.toString();
+}
+
+export const encodeCursor = (object: any, query: ListQuery, sort: ListSort): string => {
+    const cursor: ListCursor = {s: query.sort ? query.sort : "", k: sortValue(object, sort), i: object._id.toString()};
+    return Buffer.from(JSON.stringify(cursor)).toString("base64url");
+}
+
+// cursorFilter returns the filter of the objects after the cursor in sort
+// order. Missing sort values sort first.
+export const cursorFilter = (query: ListQuery, sort: ListSort): FilterQuery<any>[] => {
+    if (!query.cursor) {
+        return [];
+    }
+
+    let cursor: ListCursor;
+    try {
+        cursor = JSON.parse(Buffer.from(query.cursor, "base64url").toString());
+    } catch (err: any) {
+        throw new InvalidRequestError("invalid cursor");
+    }
+    if (!cursor || cursor.s !== (query.sort ? query.sort : "") || !Types.ObjectId.isValid(cursor.i)) {
+        throw new InvalidRequestError("invalid cursor");
+    }
+
+    const id = new Types.ObjectId(cursor.i);
+    const after = sort.desc ? "$lt" : "$gt";
+    if (!sort.field) {
+        return [{_id: {[after]: id}}];
+    }
+
+    const field = sort.field;
+    const key = field === "createdAt" || field === "updatedAt" ? (cursor.k === null ? null : new Date(cursor.k)) : cursor.k;
+    if (key === null) {
+        return sort.desc ? [{[field]: null, _id: {$lt: id}}]
+                         : [{$or: [{[field]: {$ne: null}}, {[field]: null, _id: {$gt: id}}]}];
+    }
+
+    const later: FilterQuery<any>[] = [{[field]: {[after]: key}}, {[field]: key, _id: {[after]: id}}];
+    if (sort.desc) later.push({[field]: null});
+    return [{$or: later}];
+}
@@ -1,15 +1,16 @@
-import { Activity, ActivityType, BlockchainType, Instance, KubernetesResource, KubernetesResources, NetworkType, NodeType, Permission, Project, ResourceRequest, ResourceType, StateType, StatusType} from "../../types";
-import { activityModel, instanceModel, permissionModel, projectModel, resourceModel } from "./schema";
+import { Activity, ActivityType, BlockchainType, Instance, InstanceList, KubernetesResource, KubernetesResources, ListQuery, NetworkType, NodeType, Permission, Project, ProjectList, ResourceRequest, ResourceType, StateType, StatusType} from "../../types";
+import { activityModel, instanceModel, permissionModel, projectModel, resourceModel, teamModel } from "./schema";
+import * as list from "./list";
 import { FilterQuery, Types } from "mongoose";
 import * as fn from "./fn";
 import { getDuration, getLogger } from "../../lib/logger";
 import { AppError, ItemNotFoundError } from "../../lib/errors";
 
-const createProject = async (id: string, name: string, owner: string, blockchain: BlockchainType, network: NetworkType, description: string): Promise<Project> => {
+const createProject = async (id: string, name: string, owner: string, blockchain: BlockchainType, network: NetworkType, description: string, team?: string, labels?: {[key: string]: string}): Promise<Project> => {
     let logger = getLogger('repo-create-project');
     try {
         const proj = new projectModel({
-            id, name, owner, blockchain, network, description, status: "new"
+            id, name, owner, team, blockchain, network, description, labels, status: "new"
         });
         if (proj) {
             await proj.save();
@@ -53,6 +54,55 @@ const findProjects = async (query: any): Promise<Project[]> => {
     }
 }
 
+// projectListFilter returns the filters of the projects matching the owner,
+// network and label requirements of a query
+const projectListFilter = (query: ListQuery): FilterQuery<any>[] => {
+    const filters: FilterQuery<any>[] = list.labelSelectorFilter(query.labelSelector);
+    if (query.owner) filters.push(list.objectIdFilter("owner", query.owner));
+    if (query.network) filters.push({network: query.network});
+    return filters;
+}
+
+// listProjects returns a page of the projects matching a query. Projects of a
+// member are the projects they own and the projects of their teams.
+const listProjects = async (query: ListQuery): Promise<ProjectList> => {
+    let logger = getLogger('repo-list-projects');
+    try {
+        const sort = list.parseProjectSort(query.sort);
+        const after = list.cursorFilter(query, sort);
+        const filters = projectListFilter(query).concat(list.createdFilter(query));
+        if (query.status) filters.push({status: query.status});
+        if (query.state) filters.push({state: query.state});
+        if (query.type) filters.push({_id: {$exists: false}});
+        if (query.member) {
+            const teams = await teamModel.find({"members.userid": query.member}, {_id: 1});
+            const member: FilterQuery<any>[] = [{team: {$in: teams.map((team: any) => team._id)}}];
+            if (Types.ObjectId.isValid(query.member)) member.push({owner: query.member});
+            filters.push({$or: member});
+        }
+
+        const filter = filters.length > 0 ? {$and: filters} : {};
+        const total = await projectModel.countDocuments(filter);
+
+        const pageFilters = filters.concat(after);
+        let find = projectModel.find(pageFilters.length > 0 ? {$and: pageFilters} : {}).sort(list.sortOrder(sort));
+        if (query.limit > 0) find = find.limit(query.limit + 1);
+        const projects = await find;
+
+        const more = query.limit > 0 && projects.length > query.limit;
+        const page = more ? projects.slice(0, query.limit) : projects;
+        return {
+            projects: page.map((project: any) => fn.createProject(project)),
+            total,
+            nextCursor: more ? list.encodeCursor(page[page.length - 1], query, sort) : undefined
+        };
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const findProject = async (id: string): Promise<Project | undefined> => {
     let logger = getLogger('repo-find-project');
     try {
@@ -88,9 +138,11 @@ const findProjectByName = async (name: string): Promise<Project | undefined> =>
 const updateProject = async (project: Project): Promise<Project> => {
     let logger = getLogger('repo-update-project');
     try {
-        const p = await projectModel.findById(project.id);
+        const p: any = await projectModel.findById(project.id);
         if (p) {
             p.description = project.description;
+            p.labels = project.labels;
+            p.rotation = project.rotation;
             await p.save();
             return fn.createProject(p);
         }
@@ -103,6 +155,41 @@ const updateProject = async (project: Project): Promise<Project> => {
     }
 }
 
+// updateProjectField sets a field of a project, or removes it when value is undefined
+const updateProjectField = async (id: string, field: string, value: any): Promise<Project> => {
+    let logger = getLogger('repo-update-project-field');
+    try {
+        const update = value === undefined ? {$unset: {[field]: 1}} : {$set: {[field]: value}};
+        const project = await projectModel.findByIdAndUpdate(id, update, {new: true});
+        if (project) {
+            return fn.createProject(project);
+        }
+        throw new ItemNotFoundError("project not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// domainInUse checks whether host is the domain of a project or instance other than owner
+const domainInUse = async (host: string, owner: string): Promise<boolean> => {
+    let logger = getLogger('repo-domain-in-use');
+    try {
+        const query = {"domain.host": host.toLowerCase(), _id: {$ne: owner}};
+        const project = await projectModel.findOne(query, {_id: 1});
+        if (project) {
+            return true;
+        }
+        const instance = await instanceModel.findOne(query, {_id: 1});
+        return instance ? true : false;
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 // const updateProjectStatus = async (id: string, status: string): Promise<Project> => {
 //     let logger = getLogger('repo-update-project-status');
 //     try {
@@ -183,7 +270,7 @@ const findInstances = async (query: any): Promise<Instance[]> => {
     let logger = getLogger('repo-find-instances');
     try {
         const filter = query as FilterQuery<any>;
-        const instances = await instanceModel.find(query, {_id: 1, name: 1, type: 1, network: 1, description: 1, request: 1, state: 1, createdAt: 1, updatedAt: 1});
+        const instances = await instanceModel.find(query, {_id: 1, name: 1, type: 1, network: 1, description: 1, request: 1, status: 1, state: 1, rotation: 1, schedule: 1, hold: 1, power: 1, image: 1, domain: 1, createdAt: 1, updatedAt: 1});
         logger.debug(`found instances - ${instances}`);
         if (instances) {
             return instances.map((instance: any) => {
@@ -198,6 +285,46 @@ const findInstances = async (query: any): Promise<Instance[]> => {
     }
 }
 
+// listInstances returns a page of the instances of a project matching a
+// query. Owner, network and labels are those of the project.
+const listInstances = async (project: string, query: ListQuery): Promise<InstanceList> => {
+    let logger = getLogger('repo-list-instances');
+    try {
+        const sort = list.parseInstanceSort(query.sort);
+        const after = list.cursorFilter(query, sort);
+        const projectFilters = projectListFilter(query);
+        if (projectFilters.length > 0) {
+            const matched = await projectModel.countDocuments({$and: [{_id: project}, ...projectFilters]});
+            if (matched === 0) {
+                return {instances: [], total: 0};
+            }
+        }
+
+        const filters: FilterQuery<any>[] = [{project}, ...list.createdFilter(query)];
+        if (query.status) filters.push({status: query.status});
+        if (query.state) filters.push({state: query.state});
+        if (query.type) filters.push({type: query.type});
+
+        const total = await instanceModel.countDocuments({$and: filters});
+
+        let find = instanceModel.find({$and: filters.concat(after)}).sort(list.sortOrder(sort));
+        if (query.limit > 0) find = find.limit(query.limit + 1);
+        const instances = await find;
+
+        const more = query.limit > 0 && instances.length > query.limit;
+        const page = more ? instances.slice(0, query.limit) : instances;
+        return {
+            instances: page.map((instance: any) => fn.createInstance(instance)),
+            total,
+            nextCursor: more ? list.encodeCursor(page[page.length - 1], query, sort) : undefined
+        };
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const checkInstanceId = async (id: string): Promise<boolean> => {
     let logger = getLogger('repo-check-instance');
     try {
@@ -270,6 +397,12 @@ const updateInstance = async (instance: Instance): Promise<Instance> => {
                 memory: instance.request?.memory ? instance.request.memory : undefined,
                 peers: instance.request?.peers as string[],
                 properties: instance.request?.properties,
+                rateLimit: instance.request?.rateLimit,
+                keyRateLimit: instance.request?.keyRateLimit,
+                ipAllowList: instance.request?.ipAllowList,
+                config: instance.request?.config,
+                mining: instance.request?.mining,
+                volume: _instance.request?.volume,
             }
 
             await _instance.save();
@@ -285,6 +418,23 @@ const updateInstance = async (instance: Instance): Promise<Instance> => {
     }
 }
 
+// updateInstanceField sets a field of an instance, or removes it when value is undefined
+const updateInstanceField = async (id: string, field: string, value: any): Promise<Instance> => {
+    let logger = getLogger('repo-update-instance-field');
+    try {
+        const update = value === undefined ? {$unset: {[field]: 1}} : {$set: {[field]: value}};
+        const instance = await instanceModel.findByIdAndUpdate(id, update, {new: true});
+        if (instance) {
+            return fn.createInstance(instance);
+        }
+        throw new ItemNotFoundError("instance not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
 const updateInstanceState = async (id: string, status: StatusType, state: StateType): Promise<Instance> => {
     let logger = getLogger('repo-update-instance-state');
     try {
@@ -569,17 +719,22 @@ const projectMongoRepository = {
     checkInstanceId,
     createProject,
     findProjects,
+    listProjects,
     findProject,
     findProjectByName,
     updateProject,
+    updateProjectField,
     updateProjectState,
+    domainInUse,
     getProjectSnapshots,
     deleteProject,
     createInstance,
     findInstances,
+    listInstances,
     findInstance,
     findInstanceByName,
     updateInstance,
+    updateInstanceField,
     updateInstanceState,
     deleteInstance,
  
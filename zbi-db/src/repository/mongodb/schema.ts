@@ -1,5 +1,5 @@
 import { Schema, model, Document, Types, Model } from "mongoose";
-import { ResourceType, RoleType } from "../../types";
+import { ResourceType, RoleType, TeamRoleType } from "../../types";
 
 const userSchema = new Schema({
     active: {type: Boolean, default: false},
@@ -47,7 +47,8 @@ const resourceSchema = new Schema({
         ResourceType.secret, ResourceType.persistentvolumeclaim,
         ResourceType.deployment, ResourceType.service,
         ResourceType.httpproxy, ResourceType.volumesnapshot,
-        ResourceType.snapshotschedule,
+        ResourceType.snapshotschedule, ResourceType.certificate,
+        ResourceType.p2pservice,
     ]},
     name: {type: String},
     status: {type: String},
@@ -56,13 +57,32 @@ const resourceSchema = new Schema({
     timestamps: true
 });
 
+const teamSchema = new Schema({
+    name: {type: String, required: true, unique: true},
+    members: [{
+        _id: false,
+        userid: {type: String, required: true},
+        role: {type: String, required: true, enum: [TeamRoleType.admin, TeamRoleType.user]}
+    }],
+    quota: {
+        maxProjects: {type: Number, default: 0},
+        maxInstances: {type: Number, default: 0}
+    }
+}, {timestamps: true});
+
 const projectSchema = new Schema({
     name: {type: String, required: true, immutable: true},
     owner: {type: Schema.Types.ObjectId, ref: "user", immutable: true, required: true},
+    team: {type: Schema.Types.ObjectId, ref: "team", immutable: true},
     blockchain: {type: String, required: true, immutable: true, enum: ['zcash']},
     network: {type: String, required: true, immutable: true, enum: ['testnet', 'regnet', 'mainnet']},
     status: {type: String, default: 'new', /*enum: ['new', 'pending', 'active', 'inactive']*/},
     description: {type: String},
+    labels: {type: Schema.Types.Map, of: String},
+    rotation: {type: Schema.Types.Mixed},
+    schedule: {type: Schema.Types.Mixed},
+    upgrade: {type: Schema.Types.Mixed},
+    domain: {type: Schema.Types.Mixed},
     state: {type: String}
 
 }, {timestamps: true});
@@ -78,6 +98,11 @@ const instanceSchema = new Schema({
         memory: {type: String},
         peers: {type: [String]},
         properties: {type: Schema.Types.Mixed},
+        rateLimit: {type: Schema.Types.Mixed},
+        keyRateLimit: {type: Schema.Types.Mixed},
+        ipAllowList: {type: [String]},
+        config: {type: Schema.Types.Mixed},
+        mining: {type: Schema.Types.Mixed},
         volume: {
             type: {type: String},
             size: {type: String},
@@ -87,9 +112,81 @@ const instanceSchema = new Schema({
             }
         }
     },
+    rotation: {type: Schema.Types.Mixed},
+    schedule: {type: Schema.Types.Mixed},
+    hold: {type: Schema.Types.Mixed},
+    power: {type: Schema.Types.Mixed},
+    image: {type: Schema.Types.Mixed},
+    domain: {type: Schema.Types.Mixed},
     state: {type: String}
 }, {timestamps: true});
 
+const apiKeySchema = new Schema({
+    _id: {type: String},
+    instance: {type: Schema.Types.ObjectId, ref: "instance", required: true, immutable: true},
+    instanceName: {type: String},
+    namespace: {type: String},
+    owner: {type: String},
+    consumer: {type: String, required: true},
+    labels: {type: Schema.Types.Map, of: String},
+    hash: {type: String, required: true, immutable: true},
+    expiresAt: {type: Date},
+    revokedAt: {type: Date}
+}, {timestamps: true});
+
+// seedSchema is the catalog of chain data instances can be loaded from. Names are unique per network
+const seedSchema = new Schema({
+    _id: {type: String},
+    name: {type: String, required: true},
+    blockchain: {type: String, required: true, enum: ['zcash']},
+    network: {type: String, required: true, enum: ['testnet', 'mainnet']},
+    type: {type: String, required: true, enum: ['snapshot', 'archive']},
+    description: {type: String},
+    height: {type: Number},
+    size: {type: String},
+    snapshot: {
+        handle: {type: String},
+        driver: {type: String},
+        snapshotClass: {type: String}
+    },
+    archive: {
+        url: {type: String},
+        sha256: {type: String},
+        format: {type: String}
+    },
+    createdBy: {type: String, immutable: true}
+}, {timestamps: true});
+
+seedSchema.index({network: 1, name: 1}, {unique: true});
+
+// auditSchema stores the audit log written by the controller. Records are never modified.
+const auditSchema = new Schema({
+    _id: {type: String},
+    timestamp: {type: Date, required: true, index: true},
+    txid: {type: String},
+    source: {type: String},
+    actor: {type: String, index: true},
+    role: {type: String},
+    sourceIP: {type: String},
+    forwardedFor: {type: String},
+    action: {type: String},
+    method: {type: String},
+    path: {type: String},
+    target: {type: String, index: true},
+    bodyHash: {type: String},
+    objects: [{
+        _id: false,
+        operation: {type: String},
+        resource: {type: String},
+        namespace: {type: String},
+        name: {type: String}
+    }],
+    detail: {type: String},
+    outcome: {type: String},
+    status: {type: Number},
+    error: {type: String}
+});
+
 const policySchema = new Schema({
     storageClass: {type: String},
     snapshotClass: {type: String},
@@ -137,8 +234,12 @@ const blockchainSchema = new Schema({
 }, {timestamps: true });
 
 const userModel = model("users", userSchema);
+const teamModel = model("team", teamSchema);
 const projectModel = model("project", projectSchema);
 const instanceModel = model("instance", instanceSchema);
+const apiKeyModel = model("apikey", apiKeySchema);
+const seedModel = model("seed", seedSchema);
+const auditModel = model("audit", auditSchema);
 const policyModel = model("policy", policySchema);
 const blockchainModel = model("blockchain", blockchainSchema);
 const activityModel = model("activity", activitySchema);
@@ -146,6 +247,6 @@ const permissionModel = model("permission", permissionSchema);
 const resourceModel = model("resource", resourceSchema);
 
 export {
-    userModel, projectModel, instanceModel, policyModel, blockchainModel,
-    activityModel, permissionModel, resourceModel
+    userModel, teamModel, projectModel, instanceModel, policyModel, blockchainModel,
+    activityModel, permissionModel, resourceModel, apiKeyModel, seedModel, auditModel
 }
\ No newline at end of file
@@ -0,0 +1,137 @@
+import { Seed } from "../../types";
+import { instanceModel, seedModel } from "./schema";
+import * as fn from "./fn";
+import { getDuration, getLogger } from "../../lib/logger";
+import { ItemConflictError, ItemNotFoundError } from "../../lib/errors";
+
+const seedExists = async (name: string, network: string, id?: string): Promise<boolean> => {
+    const query: any = {name, network};
+    if (id) {
+        query._id = {$ne: id};
+    }
+    const seed = await seedModel.findOne(query, {_id: 1});
+    return seed ? true : false;
+}
+
+const createSeed = async (seed: Seed): Promise<Seed> => {
+    let logger = getLogger('repo-create-seed');
+    try {
+        if (await seedExists(seed.name, seed.network)) {
+            throw new ItemConflictError("seed already exists");
+        }
+
+        const s = new seedModel({
+            _id: fn.generateId(),
+            name: seed.name,
+            blockchain: seed.blockchain,
+            network: seed.network,
+            type: seed.type,
+            description: seed.description,
+            height: seed.height,
+            size: seed.size,
+            snapshot: seed.snapshot,
+            archive: seed.archive,
+            createdBy: seed.createdBy
+        });
+        await s.save();
+        return fn.createSeed(s);
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findSeed = async (id: string): Promise<Seed | undefined> => {
+    let logger = getLogger('repo-find-seed');
+    try {
+        const seed = await seedModel.findById(id);
+        if (seed) {
+            return fn.createSeed(seed);
+        }
+
+        return undefined;
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// findSeeds returns the seeds sorted by network and name, or only the seeds of a network
+const findSeeds = async (network?: string): Promise<Seed[]> => {
+    let logger = getLogger('repo-find-seeds');
+    try {
+        const query = network ? {network} : {};
+        const seeds = await seedModel.find(query).sort({network: 1, name: 1});
+        return seeds.map((seed: any) => fn.createSeed(seed));
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// updateSeed replaces the definition of a seed. Instances created from it keep their data
+const updateSeed = async (id: string, seed: Seed): Promise<Seed> => {
+    let logger = getLogger('repo-update-seed');
+    try {
+        const s: any = await seedModel.findById(id);
+        if (!s) {
+            throw new ItemNotFoundError("seed not found");
+        }
+
+        if (await seedExists(seed.name, seed.network, id)) {
+            throw new ItemConflictError("seed already exists");
+        }
+
+        s.name = seed.name;
+        s.blockchain = seed.blockchain;
+        s.network = seed.network;
+        s.type = seed.type;
+        s.description = seed.description;
+        s.height = seed.height;
+        s.size = seed.size;
+        s.snapshot = seed.snapshot;
+        s.archive = seed.archive;
+        await s.save();
+        return fn.createSeed(s);
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// deleteSeed removes a seed from the catalog. Seeds that instances were created from are kept,
+// since repairing those instances may need them
+const deleteSeed = async (id: string): Promise<void> => {
+    let logger = getLogger('repo-delete-seed');
+    try {
+        const seed = await seedModel.findById(id, {_id: 1});
+        if (!seed) {
+            throw new ItemNotFoundError("seed not found");
+        }
+
+        const instance = await instanceModel.findOne({"request.volume.source.type": "seed", "request.volume.source.ref": id}, {_id: 1});
+        if (instance) {
+            throw new ItemConflictError("seed is used by instances");
+        }
+
+        await seedModel.deleteOne({_id: id});
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const seedMongoRepository = {
+    createSeed,
+    findSeed,
+    findSeeds,
+    updateSeed,
+    deleteSeed
+}
+
+export default seedMongoRepository
@@ -0,0 +1,170 @@
+import { Project, Team, TeamMember, TeamQuota } from "../../types";
+import { instanceModel, projectModel, teamModel } from "./schema";
+import * as fn from "./fn";
+import { getDuration, getLogger } from "../../lib/logger";
+import { AppError, ItemConflictError, ItemNotFoundError } from "../../lib/errors";
+
+const createTeam = async (id: string, name: string, members: TeamMember[], quota: TeamQuota): Promise<Team> => {
+    let logger = getLogger('repo-create-team');
+    try {
+        const existing = await teamModel.findOne({name}, {_id: 1});
+        if (existing) {
+            throw new ItemConflictError("team already exists");
+        }
+
+        const team = new teamModel({ id, name, members, quota });
+        if (team) {
+            await team.save();
+            return fn.createTeam(team);
+        }
+        throw new AppError("failed to create team");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const checkTeamId = async (id: string): Promise<boolean> => {
+    let logger = getLogger('repo-check-team');
+    try {
+        const team = await teamModel.findById(id, {_id: 1});
+        return team ? true : false;
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findTeam = async (id: string): Promise<Team | undefined> => {
+    let logger = getLogger('repo-find-team');
+    try {
+        const team = await teamModel.findById(id);
+        if (team) {
+            return fn.createTeam(team);
+        }
+
+        return undefined;
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// findTeams returns the teams sorted by name, or only the teams of a member
+const findTeams = async (member?: string): Promise<Team[]> => {
+    let logger = getLogger('repo-find-teams');
+    try {
+        const query = member ? {"members.userid": member} : {};
+        const teams = await teamModel.find(query).sort({name: 1});
+        return teams.map((team: any) => fn.createTeam(team));
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const findTeamProjects = async (id: string): Promise<Project[]> => {
+    let logger = getLogger('repo-find-team-projects');
+    try {
+        const projects = await projectModel.find({team: id});
+        return projects.map((project: any) => fn.createProject(project));
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// countTeamInstances counts the instances of the projects of a team
+const countTeamInstances = async (id: string): Promise<number> => {
+    let logger = getLogger('repo-count-team-instances');
+    try {
+        const projects = await projectModel.find({team: id}, {_id: 1});
+        return await instanceModel.countDocuments({project: {$in: projects.map((project: any) => project._id)}});
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+// updateTeamMember adds a member to a team or changes the role of an existing member
+const updateTeamMember = async (id: string, member: TeamMember): Promise<Team> => {
+    let logger = getLogger('repo-update-team-member');
+    try {
+        const team: any = await teamModel.findById(id);
+        if (team) {
+            const existing = team.members.find((m: any) => m.userid === member.userid);
+            if (existing) {
+                existing.role = member.role;
+            } else {
+                team.members.push({userid: member.userid, role: member.role});
+            }
+            await team.save();
+            return fn.createTeam(team);
+        }
+
+        throw new ItemNotFoundError("team not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const removeTeamMember = async (id: string, userid: string): Promise<Team> => {
+    let logger = getLogger('repo-remove-team-member');
+    try {
+        const team: any = await teamModel.findById(id);
+        if (team) {
+            const index = team.members.findIndex((m: any) => m.userid === userid);
+            if (index < 0) {
+                throw new ItemNotFoundError("team member not found");
+            }
+            team.members.splice(index, 1);
+            await team.save();
+            return fn.createTeam(team);
+        }
+
+        throw new ItemNotFoundError("team not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const updateTeamQuota = async (id: string, quota: TeamQuota): Promise<Team> => {
+    let logger = getLogger('repo-update-team-quota');
+    try {
+        const team = await teamModel.findByIdAndUpdate(id,
+            {$set: {quota: {maxProjects: quota.maxProjects, maxInstances: quota.maxInstances}}}, {new: true});
+        if (team) {
+            return fn.createTeam(team);
+        }
+
+        throw new ItemNotFoundError("team not found");
+    } catch (err: any) {
+        throw err;
+    } finally {
+        logger.info(`completed in ${getDuration()} ms`);
+    }
+}
+
+const teamMongoRepository = {
+    createTeam,
+    checkTeamId,
+    findTeam,
+    findTeams,
+    findTeamProjects,
+    countTeamInstances,
+    updateTeamMember,
+    removeTeamMember,
+    updateTeamQuota
+}
+
+export default teamMongoRepository
@@ -0,0 +1,9 @@
+import {Router} from "express";
+import {apiKeyController} from "../controllers";
+
+const apiKeyRoutes = Router();
+
+apiKeyRoutes.get("/:apikey", apiKeyController.findAPIKey)
+apiKeyRoutes.delete("/:apikey", apiKeyController.revokeAPIKey)
+
+export default apiKeyRoutes;
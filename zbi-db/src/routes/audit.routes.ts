@@ -0,0 +1,9 @@
+import {Router} from "express";
+import {auditController} from "../controllers";
+
+const auditRoutes = Router();
+
+auditRoutes.get("/", auditController.findAuditRecords)
+auditRoutes.post("/", auditController.createAuditRecord)
+
+export default auditRoutes;
@@ -3,6 +3,10 @@ import configRoutes from "./config.routes";
 import userRoutes from "./users.routes";
 import projectRoutes from "./projects.routes";
 import instanceRoutes from "./instances.routes";
+import teamRoutes from "./teams.routes";
+import apiKeyRoutes from "./apikeys.routes";
+import auditRoutes from "./audit.routes";
+import seedRoutes from "./seeds.routes";
 
 const routes = (app: Express) => {
 
@@ -10,6 +14,10 @@ const routes = (app: Express) => {
     app.use("/api/users", userRoutes);
     app.use("/api/projects", projectRoutes);
     app.use("/api/instances", instanceRoutes);
+    app.use("/api/teams", teamRoutes);
+    app.use("/api/apikeys", apiKeyRoutes);
+    app.use("/api/audit", auditRoutes);
+    app.use("/api/seeds", seedRoutes);
 }
 
 export default routes;
\ No newline at end of file
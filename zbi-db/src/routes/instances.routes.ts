@@ -1,5 +1,5 @@
 import {Router} from "express";
-import {instanceController, validator, middleware} from "../controllers";
+import {instanceController, apiKeyController, validator, middleware} from "../controllers";
 import * as types from "../types";
 
 const instanceRoutes = Router();
@@ -9,6 +9,20 @@ instanceRoutes.put("/:instance", middleware.validateInstance, instanceController
 instanceRoutes.delete("/:instance", middleware.validateInstance, instanceController.deleteInstance)
 instanceRoutes.purge("/:instance", middleware.validateInstance, instanceController.purgeInstance);
 
+instanceRoutes.put("/:instance/rotation", middleware.validateInstance, instanceController.updateInstanceRotation)
+instanceRoutes.put("/:instance/domain", middleware.validateInstance, validator.domainNotInUse, instanceController.updateInstanceDomain)
+instanceRoutes.delete("/:instance/domain", middleware.validateInstance, instanceController.updateInstanceDomain)
+instanceRoutes.put("/:instance/allowlist", middleware.validateInstance, instanceController.updateInstanceAllowList)
+instanceRoutes.put("/:instance/schedule", middleware.validateInstance, instanceController.updateInstanceSchedule)
+instanceRoutes.delete("/:instance/schedule", middleware.validateInstance, instanceController.updateInstanceSchedule)
+instanceRoutes.put("/:instance/hold", middleware.validateInstance, instanceController.updateInstanceHold)
+instanceRoutes.delete("/:instance/hold", middleware.validateInstance, instanceController.updateInstanceHold)
+instanceRoutes.put("/:instance/power", middleware.validateInstance, instanceController.updateInstancePower)
+instanceRoutes.put("/:instance/image", middleware.validateInstance, instanceController.updateInstanceImage)
+
+instanceRoutes.get("/:instance/apikeys", middleware.validateInstance, apiKeyController.findAPIKeys)
+instanceRoutes.post("/:instance/apikeys", middleware.validateInstance, apiKeyController.createAPIKey)
+
 instanceRoutes.get("/:instance/resources", middleware.validateInstance, instanceController.getInstanceResources)
 instanceRoutes.post("/:instance/resources", middleware.validateInstance, instanceController.updateInstanceResource)
 
@@ -5,13 +5,22 @@ import * as types from "../types";
 const projectRoutes = Router();
 
 projectRoutes.get("/", projectController.findProjects)
+projectRoutes.get("/list", projectController.listProjects)
 projectRoutes.post("/", validator.validateNewProject, validator.projectNameExists, projectController.createProject);
 projectRoutes.get("/:project", middleware.validateProject, projectController.findProject)
 projectRoutes.put("/:project", middleware.validateProject, projectController.updateProject)
 projectRoutes.delete("/:project", middleware.validateProject, projectController.deleteProject)
 projectRoutes.purge("/:project", middleware.validateProject, projectController.purgeProject);
 
+projectRoutes.put("/:project/domain", middleware.validateProject, validator.domainNotInUse, projectController.updateProjectDomain)
+projectRoutes.delete("/:project/domain", middleware.validateProject, projectController.updateProjectDomain)
+projectRoutes.put("/:project/schedule", middleware.validateProject, projectController.updateProjectSchedule)
+projectRoutes.delete("/:project/schedule", middleware.validateProject, projectController.updateProjectSchedule)
+projectRoutes.put("/:project/upgrade", middleware.validateProject, projectController.updateProjectUpgrade)
+projectRoutes.delete("/:project/upgrade", middleware.validateProject, projectController.updateProjectUpgrade)
+
 projectRoutes.get("/:project/instances", middleware.validateProject, projectController.findInstances)
+projectRoutes.get("/:project/instances/list", middleware.validateProject, projectController.listInstances)
 projectRoutes.post("/:project/instances", middleware.validateProject, validator.validateNewInstance, validator.instanceNameExists, projectController.createInstance);
 
 projectRoutes.get("/:project/snapshots", middleware.validateProject, projectController.getProjectSnapshots);
@@ -0,0 +1,12 @@
+import {Router} from "express";
+import {seedController, validator} from "../controllers";
+
+const seedRoutes = Router();
+
+seedRoutes.get("/", seedController.findSeeds)
+seedRoutes.post("/", validator.validateSeed, seedController.createSeed)
+seedRoutes.get("/:seed", seedController.findSeed)
+seedRoutes.put("/:seed", validator.validateSeed, seedController.updateSeed)
+seedRoutes.delete("/:seed", seedController.deleteSeed)
+
+export default seedRoutes;
@@ -0,0 +1,15 @@
+import {Router} from "express";
+import {teamController, validator, middleware} from "../controllers";
+
+const teamRoutes = Router();
+
+teamRoutes.get("/", teamController.findTeams)
+teamRoutes.post("/", validator.validateNewTeam, teamController.createTeam);
+teamRoutes.get("/:team", middleware.validateTeam, teamController.findTeam)
+teamRoutes.get("/:team/projects", middleware.validateTeam, teamController.findTeamProjects)
+teamRoutes.get("/:team/instances/count", middleware.validateTeam, teamController.countTeamInstances)
+teamRoutes.put("/:team/members/:member", middleware.validateTeam, validator.validateTeamMember, teamController.updateTeamMember)
+teamRoutes.delete("/:team/members/:member", middleware.validateTeam, teamController.removeTeamMember)
+teamRoutes.put("/:team/quota", middleware.validateTeam, validator.validateTeamQuota, teamController.updateTeamQuota)
+
+export default teamRoutes;
@@ -5,6 +5,11 @@ export enum RoleType {
     user = "user",
 }
 
+export enum TeamRoleType {
+    admin = "admin",
+    user = "user"
+}
+
 export enum BlockchainType {
     zcash = "zcash"
 }
@@ -29,7 +34,8 @@ export enum VolumeType {
 export enum VolumeSourceType {
     new = 'new',
     volume = 'pvc',
-    snapshot = 'snapshot'
+    snapshot = 'snapshot',
+    seed = 'seed'
 }
 
 export enum ResourceType {
@@ -42,6 +48,8 @@ export enum ResourceType {
     httpproxy = 'HTTPproxy',
     volumesnapshot = 'VolumeSnapshot',
     snapshotschedule = 'SnapshotSchedule',
+    certificate = 'Certificate',
+    p2pservice = 'P2PService',
     pod = 'Pod'
 }
 
@@ -100,11 +108,17 @@ export interface Project {
     id?: string;
     name: string;
     owner?: string;
+    team?: string;
     blockchain: BlockchainType;
     network: NetworkType;
     status?: string;
     readonly state?: string;
     description?: string;
+    labels?: {[key: string]: string};
+    rotation?: any;
+    schedule?: any;
+    upgrade?: any;
+    domain?: Domain;
     createdAt?: Date;
     updatedAt?: Date;
 }
@@ -119,6 +133,12 @@ export interface Instance {
     request?: ResourceRequest;
     status?: string;
     readonly state?: string;
+    rotation?: any;
+    schedule?: any;
+    hold?: any;
+    power?: any;
+    image?: any;
+    domain?: Domain;
     resources?: KubernetesResources;
     activities?: Activity[];
     permissions?: Permission[];
@@ -126,6 +146,136 @@ export interface Instance {
     updatedAt?: Date;
 }
 
+export interface TeamMember {
+    userid: string;
+    role: TeamRoleType;
+}
+
+export interface TeamQuota {
+    maxProjects: number;
+    maxInstances: number;
+}
+
+export interface Team {
+    id?: string;
+    name: string;
+    members: TeamMember[];
+    quota: TeamQuota;
+    createdAt?: Date;
+    updatedAt?: Date;
+}
+
+export interface APIKey {
+    id: string;
+    instanceId: string;
+    instanceName: string;
+    namespace: string;
+    owner: string;
+    consumer: string;
+    labels?: {[key: string]: string};
+    hash?: string;
+    createdAt?: Date;
+    expiresAt?: Date;
+    revokedAt?: Date;
+}
+
+export type SeedType = "snapshot" | "archive";
+
+export interface Seed {
+    id?: string;
+    name: string;
+    blockchain: BlockchainType;
+    network: NetworkType;
+    type: SeedType;
+    description?: string;
+    height?: number;
+    size?: string;
+    snapshot?: {
+        handle: string;
+        driver: string;
+        snapshotClass?: string;
+    };
+    archive?: {
+        url: string;
+        sha256: string;
+        format?: string;
+    };
+    createdBy?: string;
+    createdAt?: Date;
+    updatedAt?: Date;
+}
+
+export interface AuditObject {
+    operation: string;
+    resource: string;
+    namespace?: string;
+    name: string;
+}
+
+export interface AuditRecord {
+    id: string;
+    timestamp: Date;
+    txid: string;
+    source: string;
+    actor: string;
+    role: string;
+    sourceIP?: string;
+    forwardedFor?: string;
+    action: string;
+    method?: string;
+    path?: string;
+    target?: string;
+    bodyHash?: string;
+    objects?: AuditObject[];
+    detail?: string;
+    outcome: string;
+    status?: number;
+    error?: string;
+}
+
+export interface AuditQuery {
+    actor?: string;
+    target?: string;
+    since?: Date;
+    until?: Date;
+    limit: number;
+}
+
+export interface ListQuery {
+    member?: string;
+    owner?: string;
+    status?: string;
+    state?: string;
+    type?: string;
+    network?: string;
+    labelSelector?: string;
+    createdBefore?: Date;
+    createdAfter?: Date;
+    sort?: string;
+    limit: number;
+    cursor?: string;
+}
+
+export interface ProjectList {
+    projects: Project[];
+    total: number;
+    nextCursor?: string;
+}
+
+export interface InstanceList {
+    instances: Instance[];
+    total: number;
+    nextCursor?: string;
+}
+
+export interface Domain {
+    host: string;
+    tls: string;
+    secretName: string;
+    issuer?: string;
+    createdAt?: Date;
+}
+
 export interface Activity {
     id?: string;
     operation: ActivityType;
@@ -135,11 +285,29 @@ export interface Activity {
     updatedAt?: Date;
 }
 
+export interface RateLimit {
+    requestsPerSecond: number;
+    burst: number;
+    dailyQuota: number;
+}
+
+export interface MiningConfig {
+    minerAddress?: string;
+    genProcLimit?: number;
+    solver?: string;
+    mineToLocalWallet?: boolean;
+}
+
 export interface ResourceRequest {
     cpu?: string;
     memory?: string;
     peers?: string[];
     properties: Map<string, any>;
+    rateLimit?: RateLimit;
+    keyRateLimit?: RateLimit;
+    ipAllowList?: string[];
+    config?: {[key: string]: any};
+    mining?: MiningConfig;
     volume: {
         type: VolumeType;
         size?: string;
@@ -169,6 +337,8 @@ export interface KubernetesResources {
     httpproxy?: KubernetesResource,
     volumesnapshot?: KubernetesResource[];
     snapshotschedule?: KubernetesResource;
+    certificate?: KubernetesResource;
+    p2p?: KubernetesResource;
 }
 
 export interface Permission {
@@ -273,9 +443,17 @@ export interface PolicyInfo {
 export interface ProjectRequest {
     name: string;
     owner?: string;
+    team?: string;
     blockchain: BlockchainType;
     network: NetworkType;
     description?: string;
+    labels?: {[key: string]: string};
+}
+
+export interface ProjectUpdateRequest {
+    description?: string;
+    labels?: {[key: string]: string} | null;
+    rotation?: any;
 }
 
 
@@ -291,6 +469,11 @@ export interface InstanceRequest {
         ref: string;
     };
     properties: any;
+    rateLimit?: RateLimit;
+    keyRateLimit?: RateLimit;
+    ipAllowList?: string[];
+    config?: {[key: string]: any};
+    mining?: MiningConfig;
 }
 
 
@@ -0,0 +1,114 @@
+import request from "supertest";
+import { MongoMemoryServer } from "mongodb-memory-server";
+import app from "../src/app";
+import routes from "../src/routes";
+import repoFactory from "../src/repository";
+import { database, schema } from "../src/repository/mongodb";
+import { BlockchainType, NetworkType } from "../src/types";
+
+describe("instances", () => {
+    let mongoServer: MongoMemoryServer;
+    let projectid: string;
+
+    beforeAll(async () => {
+        mongoServer = await MongoMemoryServer.create();
+        await database.connect(mongoServer.getUri());
+        routes(app);
+
+        await schema.blockchainModel.create({name: "zcash", networks: ["testnet"], nodes: [{name: "zcash", type: "zcash"}]});
+
+        const owner = repoFactory.generateId();
+        const project = await repoFactory.getProjectRepository().createProject(repoFactory.generateId(), "project1", owner,
+                                    BlockchainType.zcash, NetworkType.testnet, "test project");
+        projectid = project.id as string;
+    });
+
+    afterAll(async () => {
+        await database.close();
+        await mongoServer.stop();
+    });
+
+    it("persists rate limits on create and update", async () => {
+        const rateLimit = {requestsPerSecond: 10, burst: 20, dailyQuota: 1000};
+        const keyRateLimit = {requestsPerSecond: 2, burst: 4, dailyQuota: 100};
+
+        const created = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance1", type: "zcash", description: "", peers: [], properties: {},
+                   volume: {type: "pvc", source: "new"}, rateLimit, keyRateLimit});
+        expect(created.status).toBe(200);
+        expect(created.body.request.rateLimit).toEqual(rateLimit);
+        expect(created.body.request.keyRateLimit).toEqual(keyRateLimit);
+
+        const instanceid = created.body.id;
+        const updatedLimit = {requestsPerSecond: 5, burst: 5, dailyQuota: 0};
+        const updated = await request(app).put(`/api/instances/${instanceid}`)
+            .send({name: "instance1", type: "zcash", description: "", peers: [], properties: {}, rateLimit: updatedLimit});
+        expect(updated.status).toBe(200);
+
+        const found = await request(app).get(`/api/instances/${instanceid}`);
+        expect(found.status).toBe(200);
+        expect(found.body.request.rateLimit).toEqual(updatedLimit);
+        expect(found.body.request.keyRateLimit).toBeUndefined();
+        expect(found.body.request.volume.type).toBe("pvc");
+    });
+
+    it("persists the ip allow list on create and update", async () => {
+        const created = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance3", type: "zcash", description: "", peers: [], properties: {},
+                   ipAllowList: ["10.0.0.0/8"]});
+        expect(created.status).toBe(200);
+        expect(created.body.request.ipAllowList).toEqual(["10.0.0.0/8"]);
+
+        const instanceid = created.body.id;
+        const updated = await request(app).put(`/api/instances/${instanceid}`)
+            .send({name: "instance3", type: "zcash", description: "", peers: [], properties: {}, ipAllowList: ["192.168.1.1"]});
+        expect(updated.status).toBe(200);
+
+        const found = await request(app).get(`/api/instances/${instanceid}`);
+        expect(found.body.request.ipAllowList).toEqual(["192.168.1.1"]);
+    });
+
+    it("persists config overrides on create and update", async () => {
+        const created = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance4", type: "zcash", description: "", peers: [], properties: {}, config: {dbcache: 512}});
+        expect(created.status).toBe(200);
+        expect(created.body.request.config).toEqual({dbcache: 512});
+
+        const instanceid = created.body.id;
+        await request(app).put(`/api/instances/${instanceid}`)
+            .send({name: "instance4", type: "zcash", description: "", peers: [], properties: {}, config: {maxconnections: 16}});
+
+        const found = await request(app).get(`/api/instances/${instanceid}`);
+        expect(found.body.request.config).toEqual({maxconnections: 16});
+    });
+
+    it("persists the mining config on create and update", async () => {
+        const mining = {minerAddress: "tmGys6dBuEGjch5LFnhdo5gpSa7jiNRWse2", genProcLimit: 1, solver: "tromp"};
+        const created = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance5", type: "zcash", description: "", peers: [], properties: {miner: true}, mining});
+        expect(created.status).toBe(200);
+        expect(created.body.request.mining).toEqual(mining);
+
+        const instanceid = created.body.id;
+        await request(app).put(`/api/instances/${instanceid}`)
+            .send({name: "instance5", type: "zcash", description: "", peers: [], properties: {miner: true},
+                   mining: {mineToLocalWallet: true}});
+
+        const found = await request(app).get(`/api/instances/${instanceid}`);
+        expect(found.body.request.mining).toEqual({mineToLocalWallet: true});
+    });
+
+    it("persists the seed volume source", async () => {
+        const created = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance6", type: "zcash", description: "", peers: [], properties: {},
+                   volume: {type: "pvc", source: "seed", ref: "seed1"}});
+        expect(created.status).toBe(200);
+        expect(created.body.request.volume.source).toEqual({type: "seed", ref: "seed1"});
+    });
+
+    it("rejects a negative rate limit", async () => {
+        const response = await request(app).post(`/api/projects/${projectid}/instances`)
+            .send({name: "instance2", type: "zcash", description: "", rateLimit: {requestsPerSecond: -1}});
+        expect(response.status).toBe(400);
+    });
+});
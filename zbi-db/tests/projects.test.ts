@@ -0,0 +1,65 @@
+import request from "supertest";
+import { MongoMemoryServer } from "mongodb-memory-server";
+import app from "../src/app";
+import routes from "../src/routes";
+import repoFactory from "../src/repository";
+import { database, schema } from "../src/repository/mongodb";
+import { BlockchainType, NetworkType } from "../src/types";
+
+describe("projects", () => {
+    let mongoServer: MongoMemoryServer;
+    const owner = repoFactory.generateId();
+    const other = repoFactory.generateId();
+    let teamProject: string;
+
+    beforeAll(async () => {
+        mongoServer = await MongoMemoryServer.create();
+        await database.connect(mongoServer.getUri());
+        routes(app);
+
+        const team = await schema.teamModel.create({name: "team1", members: [{userid: "carol", role: "user"}]});
+
+        const projectRepository = repoFactory.getProjectRepository();
+        await projectRepository.createProject(repoFactory.generateId(), "gamma", owner, BlockchainType.zcash, NetworkType.testnet, "", undefined, {env: "dev"});
+        await projectRepository.createProject(repoFactory.generateId(), "alpha", owner, BlockchainType.zcash, NetworkType.testnet, "", undefined, {env: "prod"});
+        await projectRepository.createProject(repoFactory.generateId(), "beta", owner, BlockchainType.zcash, NetworkType.mainnet, "");
+        const project = await projectRepository.createProject(repoFactory.generateId(), "delta", other, BlockchainType.zcash, NetworkType.testnet, "", team._id.toString());
+        teamProject = project.id as string;
+    });
+
+    afterAll(async () => {
+        await database.close();
+        await mongoServer.stop();
+    });
+
+    it("pages the projects matching a query", async () => {
+        let response = await request(app).get("/api/projects/list").query({owner, network: "testnet", limit: 1});
+        expect(response.status).toBe(200);
+        expect(response.body.total).toBe(2);
+        expect(response.body.projects.map((p: any) => p.name)).toEqual(["alpha"]);
+
+        response = await request(app).get("/api/projects/list")
+            .query({owner, network: "testnet", limit: 1, cursor: response.body.nextCursor});
+        expect(response.body.projects.map((p: any) => p.name)).toEqual(["gamma"]);
+        expect(response.body.nextCursor).toBeUndefined();
+
+        response = await request(app).get("/api/projects/list").query({sort: "-name", labelSelector: "env in (dev,prod)"});
+        expect(response.body.projects.map((p: any) => p.name)).toEqual(["gamma", "alpha"]);
+    });
+
+    it("lists the projects of a member's teams", async () => {
+        const response = await request(app).get("/api/projects/list").query({member: "carol"});
+        expect(response.status).toBe(200);
+        expect(response.body.projects.map((p: any) => p.id)).toEqual([teamProject]);
+    });
+
+    it("rejects invalid queries", async () => {
+        let response = await request(app).get("/api/projects/list").query({sort: "color"});
+        expect(response.status).toBe(400);
+        expect(response.body.message).toBe("invalid sort field");
+
+        response = await request(app).get("/api/projects/list").query({cursor: "bogus"});
+        expect(response.status).toBe(400);
+        expect(response.body.message).toBe("invalid cursor");
+    });
+});